@@ -6,9 +6,13 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/yndnr/tokenginx/internal/config"
+	"github.com/yndnr/tokenginx/internal/security/antireplay"
 	"github.com/yndnr/tokenginx/internal/storage"
 	"github.com/yndnr/tokenginx/internal/transport/tcp"
 )
@@ -28,14 +32,25 @@ const (
 
 	// DefaultKeysPerScan 默认每次扫描的键数
 	DefaultKeysPerScan = 100
+
+	// DefaultNonceWindow 默认 Nonce 保留窗口
+	DefaultNonceWindow = antireplay.DefaultNonceWindow
+
+	// DefaultNonceCacheSize 默认 Nonce 缓存大小
+	DefaultNonceCacheSize = antireplay.DefaultNonceCacheSize
 )
 
 var (
 	// 命令行参数
+	configPath      = flag.String("config", "", "配置文件路径 (命令行参数优先于配置文件)")
 	addr            = flag.String("addr", DefaultAddr, "监听地址 (例如: :6380 或 0.0.0.0:6380)")
 	shardCount      = flag.Int("shards", DefaultShardCount, "分片数量 (2的幂次)")
+	databases       = flag.Int("databases", storage.DefaultDatabaseCount, "逻辑数据库数量 (SELECT 0 到 databases-1)")
 	cleanupInterval = flag.Duration("cleanup-interval", DefaultCleanupInterval, "TTL 清理间隔")
 	keysPerScan     = flag.Int("keys-per-scan", DefaultKeysPerScan, "每次扫描清理的键数")
+	nonceWindow     = flag.Duration("nonce-window", DefaultNonceWindow, "防重放 Nonce 保留窗口")
+	nonceCacheSize  = flag.Int("nonce-cache-size", DefaultNonceCacheSize, "防重放 Nonce 缓存大小 (0 表示不限制)")
+	notifyEvents    = flag.String("notify-keyspace-events", "", "键空间事件通知 (格式同 Redis，如 Ex，空表示不通知)")
 	showVersion     = flag.Bool("version", false, "显示版本信息")
 	showHelp        = flag.Bool("help", false, "显示帮助信息")
 )
@@ -56,15 +71,32 @@ func main() {
 		os.Exit(0)
 	}
 
+	// 加载配置文件
+	cfg := config.Default()
+	if *configPath != "" {
+		var err error
+		cfg, err = config.Load(*configPath)
+		if err != nil {
+			log.Fatalf("[FATAL] %v", err)
+		}
+		applyConfig(cfg)
+	}
+
 	// 打印启动信息
 	printBanner()
 	log.Printf("[INFO] TokenginX %s 正在启动...", Version)
-	log.Printf("[INFO] 配置: 监听地址=%s, 分片数=%d, 清理间隔=%v, 每次扫描=%d",
-		*addr, *shardCount, *cleanupInterval, *keysPerScan)
+	log.Printf("[INFO] 配置: 监听地址=%s, 分片数=%d, 数据库数=%d, 清理间隔=%v, 每次扫描=%d",
+		*addr, *shardCount, *databases, *cleanupInterval, *keysPerScan)
 
 	// 创建存储引擎
 	log.Println("[INFO] 初始化存储引擎...")
 	sm := storage.NewShardedMap(*shardCount)
+	if cfg.Security.KeyHashing.Enabled {
+		sm.SetKeyHasher(newKeyHasher(cfg))
+		log.Printf("[INFO] 启用键哈希存储: 算法=%s, 前缀=%v",
+			cfg.Security.KeyHashing.Algorithm, cfg.Security.KeyHashing.Prefixes)
+	}
+	dbs := storage.NewDatabases(sm, *databases)
 
 	// 创建并启动 TTL 管理器
 	log.Println("[INFO] 启动 TTL 管理器...")
@@ -72,13 +104,82 @@ func main() {
 		CleanupInterval: *cleanupInterval,
 		KeysPerScan:     *keysPerScan,
 	}
-	ttlManager := storage.NewTTLManager(sm, ttlConfig)
+	ttlManager := storage.NewDatabasesTTLManager(dbs, ttlConfig)
 	ttlManager.Start()
 	defer ttlManager.Stop()
 
+	// 创建并启动防重放 Nonce 缓存
+	log.Println("[INFO] 启动防重放 Nonce 缓存...")
+	nonceStore := antireplay.NewNonceStore(&antireplay.NonceStoreConfig{
+		Window:          *nonceWindow,
+		MaxSize:         *nonceCacheSize,
+		CleanupInterval: *cleanupInterval,
+	})
+	nonceStore.Start()
+	defer nonceStore.Stop()
+
+	// 创建签名验证器
+	//
+	// 验证器使用独立的 Nonce 缓存：NONCE.CHECK 对任意连接开放，
+	// 共用缓存会让客户端提前消耗其他客户端的签名 Nonce，或写满缓存使签名验证全部失败
+	var verifier *antireplay.Verifier
+	antiReplay := cfg.Security.AntiReplay
+	if antiReplay.Enabled && antiReplay.RequireSignature {
+		signingNonces := antireplay.NewNonceStore(&antireplay.NonceStoreConfig{
+			Window:          *nonceWindow,
+			MaxSize:         *nonceCacheSize,
+			CleanupInterval: *cleanupInterval,
+		})
+		signingNonces.Start()
+		defer signingNonces.Stop()
+
+		verifier = newVerifier(cfg, signingNonces)
+		log.Printf("[INFO] 启用 RESP 请求签名验证: 算法=%s, 方式=%s, 客户端数=%d",
+			antiReplay.SignatureAlgorithm, antiReplay.SignatureMode, len(cfg.Secrets.Clients))
+	}
+
+	// 创建序列号跟踪器
+	var sequences *antireplay.SequenceTracker
+	if verifier != nil && antiReplay.EnableSequence {
+		sequences = newSequenceTracker(cfg)
+		if err := sequences.Start(); err != nil {
+			log.Fatalf("[FATAL] 启动序列号跟踪器失败: %v", err)
+		}
+		defer sequences.Stop()
+		log.Printf("[INFO] 启用序列号校验: 乱序窗口=%d, 持久化=%v",
+			antiReplay.SequenceTolerance, cfg.Storage.EnablePersistence)
+	}
+
 	// 创建并启动 TCP 服务器
 	log.Println("[INFO] 启动 TCP 服务器...")
 	server := tcp.NewServer(*addr, sm)
+	server.SetNonceStore(nonceStore)
+	server.SetDatabases(dbs)
+	if *notifyEvents != "" {
+		events, err := tcp.ParseKeyspaceEvents(*notifyEvents)
+		if err != nil {
+			log.Fatalf("[FATAL] %v", err)
+		}
+		server.SetKeyspaceEvents(events, nil)
+		log.Printf("[INFO] 启用键空间事件通知: %s", events)
+	}
+	if verifier != nil {
+		mode, err := tcp.ParseSignatureMode(antiReplay.SignatureMode)
+		if err != nil {
+			log.Fatalf("[FATAL] %v", err)
+		}
+		server.SetVerifier(verifier, mode)
+	}
+	if sequences != nil {
+		server.SetSequenceTracker(sequences, true)
+	}
+	modules, err := server.LoadModules()
+	if err != nil {
+		log.Fatalf("[FATAL] 加载扩展模块失败: %v", err)
+	}
+	if len(modules) > 0 {
+		log.Printf("[INFO] 已加载扩展模块: %s", strings.Join(modules, ", "))
+	}
 	if err := server.Start(); err != nil {
 		log.Fatalf("[FATAL] 服务器启动失败: %v", err)
 	}
@@ -90,7 +191,7 @@ func main() {
 	log.Printf("[INFO] ✓ 示例: redis-cli -h 127.0.0.1 -p 6380")
 
 	// 启动统计信息输出 Goroutine
-	go printStats(server)
+	go printStats(server, nonceStore)
 
 	// 等待退出信号
 	waitForShutdown()
@@ -98,6 +199,93 @@ func main() {
 	log.Println("[INFO] TokenginX 已退出")
 }
 
+// applyConfig 将配置文件中的值应用到未在命令行中显式指定的参数
+func applyConfig(cfg *config.Config) {
+	explicit := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	if !explicit["addr"] && cfg.Server.TCPAddr != "" {
+		*addr = cfg.Server.TCPAddr
+	}
+	if !explicit["databases"] && cfg.Storage.Databases > 0 {
+		*databases = cfg.Storage.Databases
+	}
+	if !explicit["cleanup-interval"] && cfg.TTL.CleanupInterval > 0 {
+		*cleanupInterval = time.Duration(cfg.TTL.CleanupInterval) * time.Second
+	}
+	if !explicit["keys-per-scan"] && cfg.TTL.CleanupBatchSize > 0 {
+		*keysPerScan = cfg.TTL.CleanupBatchSize
+	}
+	if !explicit["notify-keyspace-events"] && cfg.Server.NotifyKeyspaceEvents != "" {
+		*notifyEvents = cfg.Server.NotifyKeyspaceEvents
+	}
+
+	antiReplay := cfg.Security.AntiReplay
+	if antiReplay.Enabled {
+		// 时间戳允许的范围为 [now-window-skew, now+skew]，
+		// Nonce 至少要保留 window+2*skew 才能覆盖整个范围
+		if !explicit["nonce-window"] {
+			*nonceWindow = antiReplay.Window() + 2*antiReplay.ClockSkew()
+		}
+		if !explicit["nonce-cache-size"] {
+			*nonceCacheSize = antiReplay.NonceCacheSize
+		}
+	}
+}
+
+// newVerifier 根据配置文件创建签名验证器
+func newVerifier(cfg *config.Config, nonceStore *antireplay.NonceStore) *antireplay.Verifier {
+	clients := make([]antireplay.ClientKey, len(cfg.Secrets.Clients))
+	for i, client := range cfg.Secrets.Clients {
+		clients[i] = antireplay.ClientKey{
+			ClientID:  client.ClientID,
+			Secret:    []byte(client.SecretKey),
+			Algorithm: client.Algorithm,
+		}
+	}
+
+	verifier, err := antireplay.NewVerifier(&antireplay.VerifierConfig{
+		Window:    cfg.Security.AntiReplay.Window(),
+		ClockSkew: cfg.Security.AntiReplay.ClockSkew(),
+		Algorithm: cfg.Security.AntiReplay.SignatureAlgorithm,
+		Clients:   clients,
+	}, nonceStore)
+	if err != nil {
+		log.Fatalf("[FATAL] 创建签名验证器失败: %v", err)
+	}
+
+	return verifier
+}
+
+// newKeyHasher 根据配置文件创建键哈希器
+func newKeyHasher(cfg *config.Config) *storage.KeyHasher {
+	kh := cfg.Security.KeyHashing
+
+	hasher, err := storage.NewKeyHasher(kh.Algorithm, []byte(kh.Secret), kh.Prefixes)
+	if err != nil {
+		log.Fatalf("[FATAL] 创建键哈希器失败: %v", err)
+	}
+
+	return hasher
+}
+
+// newSequenceTracker 根据配置文件创建序列号跟踪器
+//
+// 启用持久化时序列号状态保存在 data_dir/sequences.json，重启后继续生效。
+func newSequenceTracker(cfg *config.Config) *antireplay.SequenceTracker {
+	var path string
+	if cfg.Storage.EnablePersistence {
+		path = filepath.Join(cfg.Storage.DataDir, "sequences.json")
+	}
+
+	return antireplay.NewSequenceTracker(&antireplay.SequenceTrackerConfig{
+		Window: cfg.Security.AntiReplay.SequenceTolerance,
+		Path:   path,
+	})
+}
+
 // printBanner 打印启动横幅
 func printBanner() {
 	banner := `
@@ -129,6 +317,8 @@ func printHelp() {
 	fmt.Printf("  %s -shards 8192                   # 使用 8192 个分片\n", os.Args[0])
 	fmt.Printf("  %s -cleanup-interval 500ms        # 每 500ms 清理一次过期键\n", os.Args[0])
 	fmt.Printf("  %s -keys-per-scan 200             # 每次扫描 200 个键\n", os.Args[0])
+	fmt.Printf("  %s -notify-keyspace-events Ex     # 键过期时发布到 __keyevent@<db>__:expired\n", os.Args[0])
+	fmt.Printf("  %s -config /etc/tokenginx/config.yaml  # 使用配置文件\n", os.Args[0])
 	fmt.Println()
 	fmt.Println("环境变量:")
 	fmt.Println("  无")
@@ -136,12 +326,56 @@ func printHelp() {
 	fmt.Println("支持的命令:")
 	fmt.Println("  PING [message]           - 测试连接")
 	fmt.Println("  ECHO message             - 回显消息")
+	fmt.Println("  HELLO [protover]         - 协商 RESP 协议版本（2 或 3）")
 	fmt.Println("  GET key                  - 获取键值")
-	fmt.Println("  SET key value [EX sec]   - 设置键值（可选过期时间）")
+	fmt.Println("  SET key value [NX|XX] [GET] [EX sec|PX ms|EXAT ts|PXAT ms-ts|KEEPTTL] - 设置键值")
+	fmt.Println("  MGET key [key ...]       - 获取多个键值")
+	fmt.Println("  MSET key value [key value ...]   - 原子地设置多个键值")
+	fmt.Println("  MSETNX key value [key value ...] - 仅当所有键都不存在时设置多个键值")
+	fmt.Println("  GETDEL key               - 获取键值并删除")
+	fmt.Println("  GETSET key value         - 设置键值并返回旧值")
+	fmt.Println("  SETNX key value          - 仅当键不存在时设置键值")
+	fmt.Println("  SETEX key seconds value  - 设置键值和过期时间")
+	fmt.Println("  APPEND key value         - 追加到键值末尾")
+	fmt.Println("  STRLEN key               - 获取键值长度")
+	fmt.Println("  INCR/DECR key            - 整数加 1 / 减 1（保留过期时间）")
+	fmt.Println("  INCRBY/DECRBY key n      - 整数增加 / 减少 n")
+	fmt.Println("  INCRBYFLOAT key f        - 数值增加浮点数 f")
+	fmt.Println("  GETVER key               - 获取键值和写入版本号")
+	fmt.Println("  CAS key ver value [EX sec|PX ms|KEEPTTL] - 仅当版本号匹配时写入")
 	fmt.Println("  DEL key [key ...]        - 删除键")
 	fmt.Println("  EXISTS key [key ...]     - 检查键是否存在")
 	fmt.Println("  TTL key                  - 获取键的剩余生存时间")
 	fmt.Println("  EXPIRE key seconds       - 设置键的过期时间")
+	fmt.Println("  SCAN cursor [MATCH p] [COUNT n] [TYPE t] - 基于游标遍历键")
+	fmt.Println("  SELECT index             - 选择逻辑数据库")
+	fmt.Println("  MOVE key db              - 将键移动到另一个逻辑数据库")
+	fmt.Println("  SWAPDB index1 index2     - 交换两个逻辑数据库")
+	fmt.Println("  FLUSHDB [ASYNC|SYNC]     - 清空当前逻辑数据库")
+	fmt.Println("  MULTI / EXEC / DISCARD   - 事务（EXEC 原子执行排队的命令）")
+	fmt.Println("  WATCH key [key ...] / UNWATCH - 乐观锁：键被修改时 EXEC 不执行")
+	fmt.Println("  SUBSCRIBE / PSUBSCRIBE ... - 订阅频道或模式（连接进入推送模式）")
+	fmt.Println("  UNSUBSCRIBE / PUNSUBSCRIBE [...] - 退订频道或模式")
+	fmt.Println("  PUBLISH channel message  - 发布消息，返回收到消息的订阅者数量")
+	fmt.Println("  COMMAND [COUNT|INFO|DOCS] - 查询命令表")
+	fmt.Println("  NONCE.CHECK nonce        - 防重放 Nonce 校验（1 接受，0 重放）")
+	fmt.Println("  TOKEN.MINT payload sec [LENGTH n] [PREFIX p] [CHECKSUM] - 生成随机令牌并存储")
+	fmt.Println("  LOCK.ACQUIRE key owner ms - 获取分布式锁，返回 fencing token")
+	fmt.Println("  LOCK.RELEASE key owner   - 由持有者释放锁")
+	fmt.Println("  LOCK.EXTEND key owner ms - 由持有者延长锁的有效期")
+	fmt.Println("  LOCK.INFO key            - 查询锁的持有者、fencing token 和剩余有效期")
+	fmt.Println("  RL.THROTTLE key burst count period [quantity] - GCRA 限流")
+	fmt.Println("  RL.WINDOW key LOG|COUNTER limit window [quantity] - 滑动窗口限流")
+	fmt.Println("  LOCKOUT.FAIL key window threshold:sec [...] - 记录失败，返回锁定状态")
+	fmt.Println("  LOCKOUT.STATUS key       - 查询失败次数和锁定状态")
+	fmt.Println("  LOCKOUT.RESET key        - 清除失败记录（登录成功或管理员解锁）")
+	fmt.Println("  OTP.SET key code sec [ATTEMPTS n] - 保存一次性验证码（加盐哈希）")
+	fmt.Println("  VERIFY key candidate     - 验证一次性验证码（OK / WRONG_CODE / LOCKED）")
+	fmt.Println("  WEBAUTHN.BEGIN key type rp_id user sec [CHALLENGE c] - 保存 WebAuthn challenge")
+	fmt.Println("  WEBAUTHN.CONSUME key type rp_id - 校验并一次性取出 challenge")
+	fmt.Println("  AUTH.SIGN id ts nonce sig            - 签名握手认证")
+	fmt.Println("  SIGNED id ts nonce sig cmd [arg ...] - 执行签名命令")
+	fmt.Println("  SEQ seq cmd [arg ...]                - 携带序列号执行命令")
 	fmt.Println()
 	fmt.Println("连接示例:")
 	fmt.Println("  redis-cli -h 127.0.0.1 -p 6380")
@@ -160,7 +394,7 @@ func waitForShutdown() {
 }
 
 // printStats 定期打印统计信息
-func printStats(server *tcp.Server) {
+func printStats(server *tcp.Server, nonceStore *antireplay.NonceStore) {
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
 
@@ -172,5 +406,9 @@ func printStats(server *tcp.Server) {
 
 		log.Printf("[STATS] 总连接: %d, 活跃连接: %d, 总命令: %d",
 			stats.TotalConnections, stats.ActiveClients, stats.TotalCommands)
+
+		nonceStats := nonceStore.GetStats()
+		log.Printf("[STATS] Nonce 接受: %d, Nonce 拒绝: %d, Nonce 缓存: %d",
+			nonceStats.Accepted, nonceStats.Rejected, nonceStats.Size)
 	}
 }
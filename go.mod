@@ -2,9 +2,14 @@ module github.com/yndnr/tokenginx
 
 go 1.25.4
 
+require (
+	github.com/go-redis/redis/v8 v8.11.5
+	github.com/tjfoc/gmsm v1.4.1
+	golang.org/x/net v0.47.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
 require (
 	github.com/cespare/xxhash/v2 v2.1.2 // indirect
 	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
-	github.com/go-redis/redis/v8 v8.11.5 // indirect
-	golang.org/x/net v0.47.0 // indirect
 )
@@ -0,0 +1,295 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config 是 TokenginX 的配置文件结构
+//
+// 只映射服务端当前实际使用的配置项，config.example.yaml 中的其他配置项
+// 会在加载时被忽略。配置文件中的 ${ENV} 引用会在解析前展开为环境变量的值，
+// 其他位置的 $ 按字面保留。
+//
+// 示例：
+//
+//	cfg, err := config.Load("/etc/tokenginx/config.yaml")
+//	if err != nil {
+//	    log.Fatalf("加载配置失败: %v", err)
+//	}
+type Config struct {
+	Server   ServerConfig   `yaml:"server"`
+	Storage  StorageConfig  `yaml:"storage"`
+	TTL      TTLConfig      `yaml:"ttl"`
+	Security SecurityConfig `yaml:"security"`
+	Secrets  SecretsConfig  `yaml:"secrets"`
+}
+
+// ServerConfig 服务器配置
+type ServerConfig struct {
+	// TCPAddr TCP (RESP) 监听地址
+	TCPAddr string `yaml:"tcp_addr"`
+
+	// NotifyKeyspaceEvents 键空间事件通知，格式与 Redis 的 notify-keyspace-events 相同（如 "Ex"）
+	NotifyKeyspaceEvents string `yaml:"notify_keyspace_events"`
+}
+
+// StorageConfig 存储配置
+type StorageConfig struct {
+	// InitialCapacity 每个分片的初始容量
+	InitialCapacity int `yaml:"initial_capacity"`
+
+	// Databases 逻辑数据库数量（SELECT 0 到 Databases-1）
+	Databases int `yaml:"databases"`
+
+	// EnablePersistence 是否启用持久化
+	EnablePersistence bool `yaml:"enable_persistence"`
+
+	// DataDir 数据目录
+	DataDir string `yaml:"data_dir"`
+}
+
+// TTLConfig TTL 配置
+type TTLConfig struct {
+	// CleanupInterval 定期清理间隔（秒）
+	CleanupInterval int `yaml:"cleanup_interval"`
+
+	// CleanupBatchSize 每次清理的最大数量
+	CleanupBatchSize int `yaml:"cleanup_batch_size"`
+}
+
+// SecurityConfig 安全配置
+type SecurityConfig struct {
+	AntiReplay AntiReplayConfig `yaml:"anti_replay"`
+	KeyHashing KeyHashingConfig `yaml:"key_hashing"`
+}
+
+// AntiReplayConfig 防重放配置
+type AntiReplayConfig struct {
+	// Enabled 启用防重放
+	Enabled bool `yaml:"enabled"`
+
+	// WindowSeconds 时间窗口（秒）
+	WindowSeconds int `yaml:"window_seconds"`
+
+	// ClockSkewSeconds 时钟偏移容忍（秒）
+	ClockSkewSeconds int `yaml:"clock_skew_seconds"`
+
+	// NonceCacheSize Nonce 缓存大小
+	NonceCacheSize int `yaml:"nonce_cache_size"`
+
+	// RequireSignature 是否要求 RESP 请求签名
+	RequireSignature bool `yaml:"require_signature"`
+
+	// SignatureAlgorithm 默认签名算法：hmac-sha256 | hmac-sm3
+	SignatureAlgorithm string `yaml:"signature_algorithm"`
+
+	// SignatureMode 签名方式：handshake（连接握手时签名一次）| command（每条命令签名）
+	SignatureMode string `yaml:"signature_mode"`
+
+	// EnableSequence 是否要求已认证客户端的每条命令携带序列号
+	EnableSequence bool `yaml:"enable_sequence"`
+
+	// SequenceTolerance 序列号乱序接受窗口
+	SequenceTolerance int `yaml:"sequence_tolerance"`
+}
+
+// KeyHashingConfig 键哈希存储配置
+type KeyHashingConfig struct {
+	// Enabled 启用键哈希
+	Enabled bool `yaml:"enabled"`
+
+	// Algorithm 哈希算法：hmac-sha256 | hmac-sm3
+	Algorithm string `yaml:"algorithm"`
+
+	// Secret HMAC 密钥
+	Secret string `yaml:"secret"`
+
+	// Prefixes 需要哈希的键前缀
+	Prefixes []string `yaml:"prefixes"`
+}
+
+// SecretsConfig 密钥配置
+type SecretsConfig struct {
+	// Clients 客户端密钥列表
+	Clients []ClientSecret `yaml:"clients"`
+}
+
+// ClientSecret 单个客户端的签名密钥
+type ClientSecret struct {
+	// ClientID 客户端 ID
+	ClientID string `yaml:"client_id"`
+
+	// SecretKey 签名密钥
+	SecretKey string `yaml:"secret_key"`
+
+	// Algorithm 该客户端使用的签名算法，为空时使用 signature_algorithm
+	Algorithm string `yaml:"algorithm"`
+}
+
+// Default 返回默认配置
+//
+// 默认值与 config.example.yaml 保持一致。
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			TCPAddr: ":6380",
+		},
+		Storage: StorageConfig{
+			InitialCapacity: 4096,
+			Databases:       16,
+			DataDir:         "/var/lib/tokenginx",
+		},
+		TTL: TTLConfig{
+			CleanupInterval:  1,
+			CleanupBatchSize: 100,
+		},
+		Security: SecurityConfig{
+			AntiReplay: AntiReplayConfig{
+				WindowSeconds:      300,
+				ClockSkewSeconds:   30,
+				NonceCacheSize:     100000,
+				SignatureAlgorithm: "hmac-sha256",
+				SignatureMode:      "handshake",
+				SequenceTolerance:  10,
+			},
+			KeyHashing: KeyHashingConfig{
+				Algorithm: "hmac-sha256",
+			},
+		},
+	}
+}
+
+// Load 从 YAML 文件加载配置
+//
+// 参数说明：
+//   - path: 配置文件路径
+//
+// 返回值：
+//   - *Config: 配置（未出现在文件中的配置项使用默认值）
+//   - error: 读取、解析或校验失败时的错误信息
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+
+	return Parse(data)
+}
+
+// Parse 解析 YAML 格式的配置内容
+func Parse(data []byte) (*Config, error) {
+	cfg := Default()
+	if err := yaml.Unmarshal(expandEnv(data), cfg); err != nil {
+		return nil, fmt.Errorf("解析配置文件失败: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// envRef 匹配 ${ENV} 形式的环境变量引用
+var envRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv 将 ${ENV} 引用展开为环境变量的值
+//
+// 与 os.ExpandEnv 不同，不展开 $ENV 形式，其他 $ 原样保留，
+// 避免 "pa$word1" 这样的密钥被截断。未设置的环境变量展开为空字符串。
+func expandEnv(data []byte) []byte {
+	return envRef.ReplaceAllFunc(data, func(ref []byte) []byte {
+		return []byte(os.Getenv(string(ref[2 : len(ref)-1])))
+	})
+}
+
+// Validate 校验配置的合法性
+func (c *Config) Validate() error {
+	if c.Storage.Databases < 0 {
+		return fmt.Errorf("storage.databases 不能为负数")
+	}
+
+	for _, flag := range c.Server.NotifyKeyspaceEvents {
+		if !strings.ContainsRune("KEg$xeA", flag) {
+			return fmt.Errorf("server.notify_keyspace_events 中存在不支持的字符: %q", flag)
+		}
+	}
+
+	ar := c.Security.AntiReplay
+
+	if ar.WindowSeconds <= 0 {
+		return fmt.Errorf("security.anti_replay.window_seconds 必须大于 0")
+	}
+	if ar.ClockSkewSeconds < 0 {
+		return fmt.Errorf("security.anti_replay.clock_skew_seconds 不能为负数")
+	}
+
+	if ar.SequenceTolerance < 1 || ar.SequenceTolerance > 64 {
+		return fmt.Errorf("security.anti_replay.sequence_tolerance 必须在 1-64 之间")
+	}
+	if ar.Enabled && ar.EnableSequence && !ar.RequireSignature {
+		return fmt.Errorf("序列号按已认证的客户端跟踪，启用 enable_sequence 时必须同时启用 require_signature")
+	}
+
+	switch ar.SignatureMode {
+	case "handshake", "command":
+	default:
+		return fmt.Errorf("未知的签名方式: %s", ar.SignatureMode)
+	}
+
+	seen := make(map[string]bool, len(c.Secrets.Clients))
+	for _, client := range c.Secrets.Clients {
+		if client.ClientID == "" {
+			return fmt.Errorf("secrets.clients 中存在空的 client_id")
+		}
+		if client.SecretKey == "" {
+			return fmt.Errorf("客户端 %s 的 secret_key 为空", client.ClientID)
+		}
+		if seen[client.ClientID] {
+			return fmt.Errorf("客户端 %s 重复配置", client.ClientID)
+		}
+		seen[client.ClientID] = true
+	}
+
+	if ar.Enabled && ar.RequireSignature && len(c.Secrets.Clients) == 0 {
+		return fmt.Errorf("启用签名验证时必须在 secrets.clients 中配置至少一个客户端")
+	}
+
+	kh := c.Security.KeyHashing
+	if kh.Enabled {
+		switch kh.Algorithm {
+		case "hmac-sha256", "hmac-sm3":
+		default:
+			return fmt.Errorf("未知的键哈希算法: %s", kh.Algorithm)
+		}
+		if len(kh.Secret) < 16 {
+			return fmt.Errorf("security.key_hashing.secret 至少需要 16 字节")
+		}
+		if len(kh.Prefixes) == 0 {
+			return fmt.Errorf("启用键哈希时必须在 security.key_hashing.prefixes 中配置至少一个前缀")
+		}
+		for _, prefix := range kh.Prefixes {
+			if prefix == "" {
+				return fmt.Errorf("security.key_hashing.prefixes 中存在空前缀")
+			}
+		}
+	}
+
+	return nil
+}
+
+// Window 返回防重放时间窗口
+func (c *AntiReplayConfig) Window() time.Duration {
+	return time.Duration(c.WindowSeconds) * time.Second
+}
+
+// ClockSkew 返回时钟偏移容忍
+func (c *AntiReplayConfig) ClockSkew() time.Duration {
+	return time.Duration(c.ClockSkewSeconds) * time.Second
+}
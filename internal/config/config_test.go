@@ -0,0 +1,130 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestLoad_ExampleConfig 测试加载仓库中的示例配置文件
+func TestLoad_ExampleConfig(t *testing.T) {
+	cfg, err := Load(filepath.Join("..", "..", "config", "config.example.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to load example config: %v", err)
+	}
+
+	if cfg.Server.TCPAddr != "0.0.0.0:6380" {
+		t.Errorf("Expected tcp_addr 0.0.0.0:6380, got %s", cfg.Server.TCPAddr)
+	}
+
+	ar := cfg.Security.AntiReplay
+	if ar.Window() != 300*time.Second || ar.ClockSkew() != 30*time.Second {
+		t.Errorf("Unexpected anti-replay window: %v / %v", ar.Window(), ar.ClockSkew())
+	}
+	if ar.SignatureAlgorithm != "hmac-sha256" || ar.SignatureMode != "handshake" {
+		t.Errorf("Unexpected signature config: %s / %s", ar.SignatureAlgorithm, ar.SignatureMode)
+	}
+
+	if cfg.Storage.Databases != 16 {
+		t.Errorf("Expected 16 databases, got %d", cfg.Storage.Databases)
+	}
+}
+
+// TestParse_Secrets 测试解析客户端密钥和环境变量展开
+func TestParse_Secrets(t *testing.T) {
+	os.Setenv("TOKENGINX_TEST_SECRET", "from-env")
+	defer os.Unsetenv("TOKENGINX_TEST_SECRET")
+
+	data := []byte(`
+security:
+  anti_replay:
+    enabled: true
+    require_signature: true
+    signature_mode: command
+secrets:
+  clients:
+    - client_id: app1
+      secret_key: "${TOKENGINX_TEST_SECRET}"
+    - client_id: app2
+      secret_key: plain
+      algorithm: hmac-sm3
+`)
+
+	cfg, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	if len(cfg.Secrets.Clients) != 2 {
+		t.Fatalf("Expected 2 clients, got %d", len(cfg.Secrets.Clients))
+	}
+	if cfg.Secrets.Clients[0].SecretKey != "from-env" {
+		t.Errorf("Expected secret from env, got %q", cfg.Secrets.Clients[0].SecretKey)
+	}
+	if cfg.Secrets.Clients[1].Algorithm != "hmac-sm3" {
+		t.Errorf("Expected hmac-sm3, got %q", cfg.Secrets.Clients[1].Algorithm)
+	}
+
+	// 未出现在文件中的配置项使用默认值
+	if cfg.Security.AntiReplay.WindowSeconds != 300 {
+		t.Errorf("Expected default window 300, got %d", cfg.Security.AntiReplay.WindowSeconds)
+	}
+}
+
+// TestParse_LiteralDollar 测试非 ${ENV} 形式的 $ 按字面保留
+func TestParse_LiteralDollar(t *testing.T) {
+	os.Setenv("word1", "expanded")
+	defer os.Unsetenv("word1")
+
+	data := []byte(`
+server:
+  notify_keyspace_events: "E$x"
+secrets:
+  clients:
+    - client_id: app1
+      secret_key: "pa$word1"
+    - client_id: app2
+      secret_key: '$HOME:${'
+`)
+
+	cfg, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	if cfg.Secrets.Clients[0].SecretKey != "pa$word1" {
+		t.Errorf("Expected pa$word1, got %q", cfg.Secrets.Clients[0].SecretKey)
+	}
+	if cfg.Secrets.Clients[1].SecretKey != "$HOME:${" {
+		t.Errorf("Expected $HOME:${, got %q", cfg.Secrets.Clients[1].SecretKey)
+	}
+	if cfg.Server.NotifyKeyspaceEvents != "E$x" {
+		t.Errorf("Expected E$x, got %q", cfg.Server.NotifyKeyspaceEvents)
+	}
+}
+
+// TestParse_Invalid 测试非法配置
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"invalid yaml", "server: [\n"},
+		{"negative databases", "storage:\n  databases: -1\n"},
+		{"unknown keyspace event class", "server:\n  notify_keyspace_events: Ez\n"},
+		{"unknown mode", "security:\n  anti_replay:\n    signature_mode: sometimes\n"},
+		{"empty secret", "secrets:\n  clients:\n    - client_id: app1\n"},
+		{"duplicate client", "secrets:\n  clients:\n    - {client_id: a, secret_key: x}\n    - {client_id: a, secret_key: y}\n"},
+		{"signature without clients", "security:\n  anti_replay:\n    enabled: true\n    require_signature: true\n"},
+		{"short key hash secret", "security:\n  key_hashing:\n    enabled: true\n    secret: short\n    prefixes: [\"oauth:token:\"]\n"},
+		{"key hashing without prefixes", "security:\n  key_hashing:\n    enabled: true\n    secret: 0123456789abcdef\n"},
+		{"unknown key hash algorithm", "security:\n  key_hashing:\n    enabled: true\n    algorithm: md5\n    secret: 0123456789abcdef\n    prefixes: [\"oauth:token:\"]\n"},
+	}
+
+	for _, tt := range tests {
+		if _, err := Parse([]byte(tt.data)); err == nil {
+			t.Errorf("%s: expected error, got nil", tt.name)
+		}
+	}
+}
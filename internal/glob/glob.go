@@ -0,0 +1,213 @@
+// Package glob 实现与 Redis 兼容的 glob 模式匹配
+//
+// 支持的语法与 Redis KEYS / SCAN MATCH 相同：
+//   - *      匹配任意长度（包括 0）的任意字节
+//   - ?      匹配任意单个字节
+//   - [abc]  匹配括号内的任意一个字节，支持范围 [a-z]
+//   - [^abc] 匹配不在括号内的任意一个字节
+//   - \x     按字面匹配 x（用于匹配 *、?、[ 等特殊字符）
+//
+// 匹配按字节进行，区分大小写。
+//
+// 示例：
+//
+//	p := glob.Compile("oauth:token:*")
+//	p.Match("oauth:token:abc") // true
+//
+//	glob.Match("session:[0-9]?", "session:42") // true
+package glob
+
+import "strings"
+
+// kind 模式的匹配方式
+type kind int
+
+const (
+	kindAll     kind = iota // "*"：匹配所有字符串
+	kindExact               // 不含通配符：精确匹配
+	kindPrefix              // "前缀*" 且前缀不含通配符：前缀匹配
+	kindGeneral             // 其他：通用匹配
+)
+
+// Pattern 编译后的 glob 模式
+//
+// Pattern 是不可变的，可以在多个 goroutine 中并发使用。
+type Pattern struct {
+	raw     string
+	literal string // kindExact 为完整字符串，kindPrefix 为前缀
+	kind    kind
+}
+
+// Compile 编译 glob 模式
+//
+// 参数说明：
+//   - pattern: glob 模式
+//
+// 返回值：
+//   - *Pattern: 编译后的模式
+//
+// 注意事项：
+//   - 与 Redis 相同，任何字符串都是合法的模式：未闭合的 [ 视为延伸到模式末尾，
+//     末尾单独的 \ 按字面匹配
+//   - "*"、不含通配符的模式和 "前缀*" 形式的模式使用快速路径，不逐字节回溯
+func Compile(pattern string) *Pattern {
+	p := &Pattern{raw: pattern, kind: kindGeneral}
+
+	switch {
+	case pattern == "*":
+		p.kind = kindAll
+	case !hasSpecial(pattern):
+		p.kind = kindExact
+		p.literal = pattern
+	case strings.HasSuffix(pattern, "*") && !hasSpecial(pattern[:len(pattern)-1]):
+		p.kind = kindPrefix
+		p.literal = pattern[:len(pattern)-1]
+	}
+
+	return p
+}
+
+// Match 检查字符串是否匹配 glob 模式
+//
+// 只使用一次的模式可以直接调用 Match；对大量字符串匹配同一模式时应先 Compile。
+func Match(pattern, s string) bool {
+	return Compile(pattern).Match(s)
+}
+
+// String 返回原始模式
+func (p *Pattern) String() string {
+	return p.raw
+}
+
+// Match 检查字符串是否匹配模式
+func (p *Pattern) Match(s string) bool {
+	switch p.kind {
+	case kindAll:
+		return true
+	case kindExact:
+		return s == p.literal
+	case kindPrefix:
+		return strings.HasPrefix(s, p.literal)
+	default:
+		return match(p.raw, s)
+	}
+}
+
+// specialChars glob 模式中的特殊字符
+const specialChars = "*?[\\"
+
+// hasSpecial 检查字符串是否包含特殊字符
+func hasSpecial(s string) bool {
+	return strings.ContainsAny(s, specialChars)
+}
+
+// match 通用匹配
+//
+// 只有 * 能匹配可变长度，因此回溯时只需记住最近一个 * 的位置，
+// 最坏情况为 O(len(pattern) * len(s))，不会出现指数级回溯。
+func match(pattern, s string) bool {
+	px, sx := 0, 0
+	starPx, starSx := -1, -1
+
+	for sx < len(s) {
+		if px < len(pattern) && pattern[px] == '*' {
+			// 连续的 * 等价于一个
+			for px < len(pattern) && pattern[px] == '*' {
+				px++
+			}
+			if px == len(pattern) {
+				return true
+			}
+			starPx, starSx = px, sx
+			continue
+		}
+
+		if px < len(pattern) {
+			if next, ok := matchOne(pattern, px, s[sx]); ok {
+				px = next
+				sx++
+				continue
+			}
+		}
+
+		// 当前位置不匹配：让最近的 * 多吞一个字节后重试
+		if starPx < 0 {
+			return false
+		}
+		starSx++
+		px, sx = starPx, starSx
+	}
+
+	for px < len(pattern) && pattern[px] == '*' {
+		px++
+	}
+	return px == len(pattern)
+}
+
+// matchOne 匹配模式中从 px 开始的一个元素（* 以外）与字节 c
+//
+// 返回值：
+//   - int: 下一个元素在模式中的位置
+//   - bool: 是否匹配
+func matchOne(pattern string, px int, c byte) (int, bool) {
+	switch pattern[px] {
+	case '?':
+		return px + 1, true
+
+	case '\\':
+		if px+1 < len(pattern) {
+			return px + 2, pattern[px+1] == c
+		}
+		// 末尾单独的 \ 按字面匹配
+		return px + 1, c == '\\'
+
+	case '[':
+		return matchClass(pattern, px, c)
+
+	default:
+		return px + 1, pattern[px] == c
+	}
+}
+
+// matchClass 匹配从 px（指向 [）开始的字符类
+func matchClass(pattern string, px int, c byte) (int, bool) {
+	i := px + 1
+	negate := i < len(pattern) && pattern[i] == '^'
+	if negate {
+		i++
+	}
+
+	matched := false
+	for i < len(pattern) && pattern[i] != ']' {
+		switch {
+		case pattern[i] == '\\' && i+1 < len(pattern):
+			i++
+			if pattern[i] == c {
+				matched = true
+			}
+		case i+2 < len(pattern) && pattern[i+1] == '-':
+			start, end := pattern[i], pattern[i+2]
+			if start > end {
+				start, end = end, start
+			}
+			if c >= start && c <= end {
+				matched = true
+			}
+			i += 2
+		case pattern[i] == c:
+			matched = true
+		}
+		i++
+	}
+
+	// 未闭合的 [ 视为延伸到模式末尾
+	next := len(pattern)
+	if i < len(pattern) {
+		next = i + 1
+	}
+
+	if negate {
+		matched = !matched
+	}
+	return next, matched
+}
@@ -0,0 +1,95 @@
+package glob
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestMatch 测试 glob 匹配规则
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		s       string
+		want    bool
+	}{
+		// *
+		{"*", "", true},
+		{"*", "anything", true},
+		{"oauth:token:*", "oauth:token:abc", true},
+		{"oauth:token:*", "oauth:token:", true},
+		{"oauth:token:*", "oauth:refresh:abc", false},
+		{"*:abc", "oauth:token:abc", true},
+		{"oauth:*:abc", "oauth:token:abc", true},
+		{"oauth:*:abc", "oauth:token:abd", false},
+		{"a**b", "axxb", true},
+		{"a*b*c", "abbbc", true},
+		{"a*b*c", "acb", false},
+
+		// ?
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"??", "ab", true},
+		{"??", "abc", false},
+
+		// 字符类
+		{"h[ae]llo", "hello", true},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-b]llo", "hbllo", true},
+		{"h[a-b]llo", "hcllo", false},
+		{"h[b-a]llo", "hallo", true},
+		{"session:[0-9][0-9]", "session:42", true},
+		{"session:[0-9][0-9]", "session:4x", false},
+		{"[\\]]", "]", true},
+		{"[]", "a", false},
+		{"[abc", "b", true},
+		{"[abc", "d", false},
+
+		// 转义
+		{"h\\*llo", "h*llo", true},
+		{"h\\*llo", "hello", false},
+		{"\\?", "?", true},
+		{"\\[a]", "[a]", true},
+		{"a\\", "a\\", true},
+
+		// 精确匹配
+		{"session:1", "session:1", true},
+		{"session:1", "session:10", false},
+		{"", "", true},
+		{"", "a", false},
+	}
+
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
+
+// TestMatch_NoExponentialBacktracking 测试病态模式不会指数级回溯
+func TestMatch_NoExponentialBacktracking(t *testing.T) {
+	pattern := strings.Repeat("a*", 30) + "b"
+	s := strings.Repeat("a", 1000)
+
+	if Match(pattern, s) {
+		t.Error("Expected no match")
+	}
+}
+
+// BenchmarkPattern_Prefix 测试前缀模式的快速路径
+func BenchmarkPattern_Prefix(b *testing.B) {
+	p := Compile("oauth:token:*")
+	for i := 0; i < b.N; i++ {
+		p.Match("oauth:token:eyJhbGciOiJIUzI1NiJ9")
+	}
+}
+
+// BenchmarkPattern_General 测试通用匹配
+func BenchmarkPattern_General(b *testing.B) {
+	p := Compile("oauth:*:eyJ[a-z]*")
+	for i := 0; i < b.N; i++ {
+		p.Match("oauth:token:eyJhbGciOiJIUzI1NiJ9")
+	}
+}
@@ -0,0 +1,322 @@
+package antireplay
+
+import (
+	"errors"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// NonceShardCount 是 Nonce 缓存的分片数量
+	//
+	// 与存储引擎保持一致，使用 256 个分片降低高并发下的锁竞争。
+	NonceShardCount = 256
+
+	// DefaultNonceWindow 是 Nonce 的默认保留窗口（5 分钟）
+	DefaultNonceWindow = 5 * time.Minute
+
+	// DefaultNonceCacheSize 是 Nonce 缓存的默认最大容量
+	DefaultNonceCacheSize = 100000
+
+	// MaxNonceLength 是单个 Nonce 的最大长度（字节）
+	MaxNonceLength = 256
+)
+
+var (
+	// ErrEmptyNonce Nonce 为空
+	ErrEmptyNonce = errors.New("nonce is empty")
+
+	// ErrNonceTooLong Nonce 超过最大长度
+	ErrNonceTooLong = errors.New("nonce too long")
+
+	// ErrNonceReused Nonce 已在保留窗口内使用过
+	ErrNonceReused = errors.New("nonce reused")
+
+	// ErrNonceCacheFull Nonce 缓存已满
+	ErrNonceCacheFull = errors.New("nonce cache full")
+)
+
+// nonceShard 表示 Nonce 缓存的单个分片
+type nonceShard struct {
+	mu   sync.Mutex
+	seen map[string]int64 // Nonce -> 过期时间（Unix 纳秒）
+}
+
+// NonceStoreConfig Nonce 缓存配置
+type NonceStoreConfig struct {
+	// Window Nonce 保留窗口，默认 5 分钟
+	// 窗口内重复出现的 Nonce 会被拒绝，窗口外的 Nonce 会被清理
+	Window time.Duration
+
+	// MaxSize 缓存的最大 Nonce 数量，默认 100000，0 表示不限制
+	// 缓存已满时新 Nonce 会被拒绝（宁可拒绝也不遗忘已使用的 Nonce）
+	MaxSize int
+
+	// CleanupInterval 清理间隔，默认 1 秒
+	CleanupInterval time.Duration
+}
+
+// DefaultNonceStoreConfig 返回默认的 Nonce 缓存配置
+func DefaultNonceStoreConfig() *NonceStoreConfig {
+	return &NonceStoreConfig{
+		Window:          DefaultNonceWindow,
+		MaxSize:         DefaultNonceCacheSize,
+		CleanupInterval: 1 * time.Second,
+	}
+}
+
+// NonceStore 是一个分片的 Nonce 缓存，用于防重放校验
+//
+// NonceStore 记录保留窗口内出现过的所有 Nonce，CheckAndInsert 以原子方式
+// 完成"检查是否已使用 + 记录"两个步骤，保证同一个 Nonce 在窗口内只会被接受一次。
+// 过期的 Nonce 在访问时惰性删除，并由后台 Goroutine 定期清理。
+//
+// 示例：
+//
+//	ns := NewNonceStore(nil) // 使用默认配置
+//	ns.Start()
+//	defer ns.Stop()
+//
+//	if err := ns.CheckAndInsert(nonce); err != nil {
+//	    // 拒绝请求
+//	}
+type NonceStore struct {
+	shards          [NonceShardCount]*nonceShard
+	window          time.Duration
+	maxPerShard     int
+	cleanupInterval time.Duration
+
+	// 统计信息
+	accepted atomic.Int64 // 接受的 Nonce 数
+	rejected atomic.Int64 // 拒绝的 Nonce 数
+	size     atomic.Int64 // 当前缓存的 Nonce 数
+
+	// 后台清理
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+	running bool
+	mu      sync.Mutex
+}
+
+// NewNonceStore 创建一个新的 Nonce 缓存
+//
+// 参数说明：
+//   - config: Nonce 缓存配置，如果为 nil 则使用默认配置
+//
+// 返回值：
+//   - *NonceStore: Nonce 缓存实例
+//
+// 注意事项：
+//   - 需要调用 Start() 启动后台清理任务
+//   - MaxSize 按分片平均分配，单个分片的容量至少为 1
+func NewNonceStore(config *NonceStoreConfig) *NonceStore {
+	if config == nil {
+		config = DefaultNonceStoreConfig()
+	}
+
+	window := config.Window
+	if window <= 0 {
+		window = DefaultNonceWindow
+	}
+
+	cleanupInterval := config.CleanupInterval
+	if cleanupInterval <= 0 {
+		cleanupInterval = 1 * time.Second
+	}
+
+	maxPerShard := 0
+	if config.MaxSize > 0 {
+		maxPerShard = config.MaxSize / NonceShardCount
+		if maxPerShard < 1 {
+			maxPerShard = 1
+		}
+	}
+
+	ns := &NonceStore{
+		window:          window,
+		maxPerShard:     maxPerShard,
+		cleanupInterval: cleanupInterval,
+		stopCh:          make(chan struct{}),
+	}
+	for i := 0; i < NonceShardCount; i++ {
+		ns.shards[i] = &nonceShard{
+			seen: make(map[string]int64),
+		}
+	}
+
+	return ns
+}
+
+// getShard 根据 Nonce 的哈希值返回对应的分片
+func (ns *NonceStore) getShard(nonce string) *nonceShard {
+	h := fnv.New32a()
+	h.Write([]byte(nonce))
+	return ns.shards[h.Sum32()%NonceShardCount]
+}
+
+// Window 返回 Nonce 的保留窗口
+func (ns *NonceStore) Window() time.Duration {
+	return ns.window
+}
+
+// CheckAndInsert 检查 Nonce 是否已使用，未使用则记录
+//
+// 参数说明：
+//   - nonce: 客户端提供的一次性随机数
+//
+// 返回值：
+//   - error: nil 表示 Nonce 首次出现并已记录；
+//     ErrNonceReused 表示窗口内已使用过；
+//     ErrNonceCacheFull 表示缓存已满；
+//     ErrEmptyNonce / ErrNonceTooLong 表示 Nonce 格式无效
+//
+// 注意事项：
+//   - 该方法是并发安全的，检查和记录在同一把分片锁内完成
+//   - 同一个 Nonce 并发调用时只有一次会返回 nil
+func (ns *NonceStore) CheckAndInsert(nonce string) error {
+	if nonce == "" {
+		ns.rejected.Add(1)
+		return ErrEmptyNonce
+	}
+	if len(nonce) > MaxNonceLength {
+		ns.rejected.Add(1)
+		return ErrNonceTooLong
+	}
+
+	shard := ns.getShard(nonce)
+	now := time.Now().UnixNano()
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if expiresAt, exists := shard.seen[nonce]; exists {
+		if now < expiresAt {
+			ns.rejected.Add(1)
+			return ErrNonceReused
+		}
+		// 已超出保留窗口（惰性删除）
+		delete(shard.seen, nonce)
+		ns.size.Add(-1)
+	}
+
+	if ns.maxPerShard > 0 && len(shard.seen) >= ns.maxPerShard {
+		ns.evictExpired(shard, now)
+		if len(shard.seen) >= ns.maxPerShard {
+			ns.rejected.Add(1)
+			return ErrNonceCacheFull
+		}
+	}
+
+	shard.seen[nonce] = now + int64(ns.window)
+	ns.size.Add(1)
+	ns.accepted.Add(1)
+
+	return nil
+}
+
+// Seen 检查 Nonce 是否在保留窗口内出现过（不记录、不计入统计）
+func (ns *NonceStore) Seen(nonce string) bool {
+	shard := ns.getShard(nonce)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	expiresAt, exists := shard.seen[nonce]
+	return exists && time.Now().UnixNano() < expiresAt
+}
+
+// evictExpired 删除分片中所有已过期的 Nonce（调用方需持有分片锁）
+func (ns *NonceStore) evictExpired(shard *nonceShard, now int64) {
+	for nonce, expiresAt := range shard.seen {
+		if now >= expiresAt {
+			delete(shard.seen, nonce)
+			ns.size.Add(-1)
+		}
+	}
+}
+
+// Start 启动后台清理任务
+//
+// 注意事项：
+//   - 重复调用 Start 不会启动多个清理任务
+//   - 非阻塞调用
+func (ns *NonceStore) Start() {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	if ns.running {
+		return
+	}
+
+	ns.running = true
+	ns.stopCh = make(chan struct{})
+
+	ns.wg.Add(1)
+	go ns.cleanupLoop()
+}
+
+// Stop 停止后台清理任务并等待其退出
+//
+// 注意事项：
+//   - 多次调用 Stop 是安全的
+func (ns *NonceStore) Stop() {
+	ns.mu.Lock()
+	if !ns.running {
+		ns.mu.Unlock()
+		return
+	}
+
+	close(ns.stopCh)
+	ns.running = false
+	ns.mu.Unlock()
+
+	ns.wg.Wait()
+}
+
+// cleanupLoop 清理循环
+func (ns *NonceStore) cleanupLoop() {
+	defer ns.wg.Done()
+
+	ticker := time.NewTicker(ns.cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ns.stopCh:
+			return
+		case <-ticker.C:
+			ns.cleanup()
+		}
+	}
+}
+
+// cleanup 遍历所有分片，删除超出保留窗口的 Nonce
+func (ns *NonceStore) cleanup() {
+	now := time.Now().UnixNano()
+	for i := 0; i < NonceShardCount; i++ {
+		shard := ns.shards[i]
+		shard.mu.Lock()
+		ns.evictExpired(shard, now)
+		shard.mu.Unlock()
+	}
+}
+
+// NonceStats Nonce 缓存的统计信息
+type NonceStats struct {
+	Accepted int64         // 接受的 Nonce 总数
+	Rejected int64         // 拒绝的 Nonce 总数（重复、缓存已满或格式无效）
+	Size     int64         // 当前缓存的 Nonce 数（包括已过期但未清理的）
+	Window   time.Duration // 保留窗口
+}
+
+// GetStats 返回 Nonce 缓存的统计信息
+func (ns *NonceStore) GetStats() NonceStats {
+	return NonceStats{
+		Accepted: ns.accepted.Load(),
+		Rejected: ns.rejected.Load(),
+		Size:     ns.size.Load(),
+		Window:   ns.window,
+	}
+}
@@ -0,0 +1,167 @@
+package antireplay
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestNonceStore_CheckAndInsert 测试首次接受、重复拒绝
+func TestNonceStore_CheckAndInsert(t *testing.T) {
+	ns := NewNonceStore(nil)
+
+	if err := ns.CheckAndInsert("nonce-1"); err != nil {
+		t.Fatalf("First use should be accepted, got %v", err)
+	}
+
+	if err := ns.CheckAndInsert("nonce-1"); err != ErrNonceReused {
+		t.Errorf("Expected ErrNonceReused, got %v", err)
+	}
+
+	if err := ns.CheckAndInsert("nonce-2"); err != nil {
+		t.Errorf("Different nonce should be accepted, got %v", err)
+	}
+
+	if !ns.Seen("nonce-1") {
+		t.Error("nonce-1 should be seen")
+	}
+	if ns.Seen("nonce-3") {
+		t.Error("nonce-3 should not be seen")
+	}
+}
+
+// TestNonceStore_InvalidNonce 测试无效 Nonce
+func TestNonceStore_InvalidNonce(t *testing.T) {
+	ns := NewNonceStore(nil)
+
+	if err := ns.CheckAndInsert(""); err != ErrEmptyNonce {
+		t.Errorf("Expected ErrEmptyNonce, got %v", err)
+	}
+
+	if err := ns.CheckAndInsert(strings.Repeat("a", MaxNonceLength+1)); err != ErrNonceTooLong {
+		t.Errorf("Expected ErrNonceTooLong, got %v", err)
+	}
+
+	stats := ns.GetStats()
+	if stats.Rejected != 2 || stats.Accepted != 0 {
+		t.Errorf("Expected 0 accepted / 2 rejected, got %d / %d", stats.Accepted, stats.Rejected)
+	}
+}
+
+// TestNonceStore_WindowExpiry 测试超出保留窗口后 Nonce 可再次使用
+func TestNonceStore_WindowExpiry(t *testing.T) {
+	ns := NewNonceStore(&NonceStoreConfig{
+		Window: 100 * time.Millisecond,
+	})
+
+	if err := ns.CheckAndInsert("nonce-1"); err != nil {
+		t.Fatalf("First use should be accepted, got %v", err)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+
+	if err := ns.CheckAndInsert("nonce-1"); err != nil {
+		t.Errorf("Nonce outside window should be accepted, got %v", err)
+	}
+}
+
+// TestNonceStore_CacheFull 测试缓存已满时拒绝新 Nonce
+func TestNonceStore_CacheFull(t *testing.T) {
+	ns := NewNonceStore(&NonceStoreConfig{
+		Window:  time.Minute,
+		MaxSize: 1, // 每个分片 1 个
+	})
+
+	// 找到落在同一分片的两个 Nonce
+	first := "nonce-0"
+	var second string
+	for i := 1; i < 100000; i++ {
+		candidate := fmt.Sprintf("nonce-%d", i)
+		if ns.getShard(candidate) == ns.getShard(first) {
+			second = candidate
+			break
+		}
+	}
+
+	if err := ns.CheckAndInsert(first); err != nil {
+		t.Fatalf("First nonce should be accepted, got %v", err)
+	}
+
+	if err := ns.CheckAndInsert(second); err != ErrNonceCacheFull {
+		t.Errorf("Expected ErrNonceCacheFull, got %v", err)
+	}
+}
+
+// TestNonceStore_Cleanup 测试后台清理
+func TestNonceStore_Cleanup(t *testing.T) {
+	ns := NewNonceStore(&NonceStoreConfig{
+		Window:          50 * time.Millisecond,
+		CleanupInterval: 20 * time.Millisecond,
+	})
+	ns.Start()
+	defer ns.Stop()
+
+	for i := 0; i < 100; i++ {
+		ns.CheckAndInsert(fmt.Sprintf("nonce-%d", i))
+	}
+
+	if size := ns.GetStats().Size; size != 100 {
+		t.Errorf("Expected size 100, got %d", size)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	if size := ns.GetStats().Size; size != 0 {
+		t.Errorf("Expected size 0 after cleanup, got %d", size)
+	}
+}
+
+// TestNonceStore_StartStop 测试重复启动和停止
+func TestNonceStore_StartStop(t *testing.T) {
+	ns := NewNonceStore(nil)
+
+	ns.Start()
+	ns.Start() // 重复 Start 应该是安全的
+	ns.Stop()
+	ns.Stop() // 重复 Stop 应该是安全的
+}
+
+// TestNonceStore_Concurrent 测试并发使用同一个 Nonce 时只接受一次
+func TestNonceStore_Concurrent(t *testing.T) {
+	ns := NewNonceStore(nil)
+
+	var accepted atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ns.CheckAndInsert("shared-nonce") == nil {
+				accepted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if accepted.Load() != 1 {
+		t.Errorf("Expected exactly 1 acceptance, got %d", accepted.Load())
+	}
+
+	stats := ns.GetStats()
+	if stats.Accepted != 1 || stats.Rejected != 99 {
+		t.Errorf("Expected 1 accepted / 99 rejected, got %d / %d", stats.Accepted, stats.Rejected)
+	}
+}
+
+// BenchmarkNonceStore_CheckAndInsert 基准测试 Nonce 校验
+func BenchmarkNonceStore_CheckAndInsert(b *testing.B) {
+	ns := NewNonceStore(&NonceStoreConfig{Window: time.Minute})
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ns.CheckAndInsert(fmt.Sprintf("nonce-%d", i))
+	}
+}
@@ -0,0 +1,370 @@
+package antireplay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultSequenceWindow 是默认的乱序接受窗口大小
+	DefaultSequenceWindow = 10
+
+	// MaxSequenceWindow 是乱序接受窗口的最大值（由 64 位位图决定）
+	MaxSequenceWindow = 64
+)
+
+var (
+	// ErrInvalidSequence 序列号无效（必须是正整数）
+	ErrInvalidSequence = errors.New("invalid sequence number")
+
+	// ErrSequenceStale 序列号落在接受窗口之前
+	ErrSequenceStale = errors.New("stale sequence number")
+
+	// ErrSequenceDuplicate 序列号已使用过
+	ErrSequenceDuplicate = errors.New("duplicate sequence number")
+)
+
+// sequenceState 单个客户端的序列号状态
+//
+// 采用与 IPsec (RFC 4303) 相同的滑动窗口：Highest 是已接受的最大序列号，
+// Bitmap 的第 i 位表示序列号 Highest-i 是否已接受。
+type sequenceState struct {
+	Highest uint64 `json:"highest"`
+	Bitmap  uint64 `json:"bitmap"`
+}
+
+// SequenceTrackerConfig 序列号跟踪器配置
+type SequenceTrackerConfig struct {
+	// Window 乱序接受窗口，默认 10，最大 64
+	// 小于等于 Highest-Window 的序列号会被视为过期
+	Window int
+
+	// Path 持久化文件路径，为空表示不持久化
+	Path string
+
+	// SaveInterval 持久化间隔，默认 1 秒
+	SaveInterval time.Duration
+}
+
+// SequenceTracker 跟踪每个已认证客户端的序列号，拒绝过期和重复的序列号
+//
+// 客户端的序列号应单调递增，允许在 Window 范围内乱序到达（例如多个连接并发发送）。
+// 向前跳跃任意距离都会被接受，以容忍客户端侧的请求失败。
+//
+// 示例：
+//
+//	st := NewSequenceTracker(&SequenceTrackerConfig{Window: 10})
+//	if err := st.Check("app1", 42); err != nil {
+//	    // 拒绝请求
+//	}
+//
+// 注意事项：
+//   - 配置了 Path 时，状态在 Start() 时加载，运行期间定期保存，Stop() 时再保存一次；
+//     进程崩溃会丢失最近一个保存间隔内的状态
+type SequenceTracker struct {
+	mu      sync.Mutex
+	clients map[string]*sequenceState
+	window  uint64
+	dirty   bool
+
+	path         string
+	saveInterval time.Duration
+
+	// 统计信息
+	accepted atomic.Int64
+	rejected atomic.Int64
+
+	// 后台保存
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+	running bool
+	runMu   sync.Mutex
+}
+
+// NewSequenceTracker 创建一个新的序列号跟踪器
+//
+// 参数说明：
+//   - config: 跟踪器配置，如果为 nil 则使用默认配置（不持久化）
+//
+// 返回值：
+//   - *SequenceTracker: 跟踪器实例
+func NewSequenceTracker(config *SequenceTrackerConfig) *SequenceTracker {
+	if config == nil {
+		config = &SequenceTrackerConfig{}
+	}
+
+	window := config.Window
+	if window <= 0 {
+		window = DefaultSequenceWindow
+	}
+	if window > MaxSequenceWindow {
+		window = MaxSequenceWindow
+	}
+
+	saveInterval := config.SaveInterval
+	if saveInterval <= 0 {
+		saveInterval = 1 * time.Second
+	}
+
+	return &SequenceTracker{
+		clients:      make(map[string]*sequenceState),
+		window:       uint64(window),
+		path:         config.Path,
+		saveInterval: saveInterval,
+		stopCh:       make(chan struct{}),
+	}
+}
+
+// Check 检查并记录客户端的序列号
+//
+// 参数说明：
+//   - clientID: 已认证的客户端 ID
+//   - seq: 序列号（从 1 开始）
+//
+// 返回值：
+//   - error: nil 表示接受；ErrSequenceStale 表示早于接受窗口；
+//     ErrSequenceDuplicate 表示已使用过；ErrInvalidSequence 表示序列号为 0
+//
+// 注意事项：
+//   - 该方法是并发安全的
+func (st *SequenceTracker) Check(clientID string, seq uint64) error {
+	if err := st.check(clientID, seq); err != nil {
+		st.rejected.Add(1)
+		return err
+	}
+
+	st.accepted.Add(1)
+	return nil
+}
+
+// check 执行实际的滑动窗口检查
+func (st *SequenceTracker) check(clientID string, seq uint64) error {
+	if seq == 0 {
+		return ErrInvalidSequence
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	state, exists := st.clients[clientID]
+	if !exists {
+		st.clients[clientID] = &sequenceState{Highest: seq, Bitmap: 1}
+		st.dirty = true
+		return nil
+	}
+
+	if seq > state.Highest {
+		shift := seq - state.Highest
+		if shift >= 64 {
+			state.Bitmap = 0
+		} else {
+			state.Bitmap <<= shift
+		}
+		state.Bitmap |= 1
+		state.Highest = seq
+		st.dirty = true
+		return nil
+	}
+
+	offset := state.Highest - seq
+	if offset >= st.window {
+		return ErrSequenceStale
+	}
+
+	mask := uint64(1) << offset
+	if state.Bitmap&mask != 0 {
+		return ErrSequenceDuplicate
+	}
+
+	state.Bitmap |= mask
+	st.dirty = true
+	return nil
+}
+
+// Highest 返回客户端已接受的最大序列号，0 表示尚未收到过
+func (st *SequenceTracker) Highest(clientID string) uint64 {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if state, exists := st.clients[clientID]; exists {
+		return state.Highest
+	}
+	return 0
+}
+
+// Load 从持久化文件加载序列号状态
+//
+// 注意事项：
+//   - 文件不存在时不返回错误
+//   - 未配置 Path 时直接返回
+func (st *SequenceTracker) Load() error {
+	if st.path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(st.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("读取序列号状态失败: %w", err)
+	}
+
+	clients := make(map[string]*sequenceState)
+	if err := json.Unmarshal(data, &clients); err != nil {
+		return fmt.Errorf("解析序列号状态失败: %w", err)
+	}
+
+	st.mu.Lock()
+	st.clients = clients
+	st.dirty = false
+	st.mu.Unlock()
+
+	return nil
+}
+
+// Save 将序列号状态写入持久化文件
+//
+// 先写入临时文件再重命名，保证文件内容始终完整。
+//
+// 注意事项：
+//   - 状态未变化时不写文件
+//   - 未配置 Path 时直接返回
+func (st *SequenceTracker) Save() error {
+	if st.path == "" {
+		return nil
+	}
+
+	st.mu.Lock()
+	if !st.dirty {
+		st.mu.Unlock()
+		return nil
+	}
+	data, err := json.Marshal(st.clients)
+	st.dirty = false
+	st.mu.Unlock()
+
+	if err == nil {
+		err = st.writeFile(data)
+	}
+	if err != nil {
+		// 保存失败，下次重试
+		st.mu.Lock()
+		st.dirty = true
+		st.mu.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+// writeFile 原子地写入持久化文件
+func (st *SequenceTracker) writeFile(data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(st.path), 0o700); err != nil {
+		return fmt.Errorf("创建数据目录失败: %w", err)
+	}
+
+	tmp := st.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("写入序列号状态失败: %w", err)
+	}
+	if err := os.Rename(tmp, st.path); err != nil {
+		return fmt.Errorf("写入序列号状态失败: %w", err)
+	}
+
+	return nil
+}
+
+// Start 加载持久化状态并启动后台保存任务
+//
+// 注意事项：
+//   - 未配置 Path 时不会启动后台任务
+//   - 重复调用 Start 不会启动多个保存任务
+func (st *SequenceTracker) Start() error {
+	st.runMu.Lock()
+	defer st.runMu.Unlock()
+
+	if st.running || st.path == "" {
+		return nil
+	}
+
+	if err := st.Load(); err != nil {
+		return err
+	}
+
+	st.running = true
+	st.stopCh = make(chan struct{})
+
+	st.wg.Add(1)
+	go st.saveLoop()
+
+	return nil
+}
+
+// Stop 停止后台保存任务，并保存最终状态
+//
+// 注意事项：
+//   - 多次调用 Stop 是安全的
+func (st *SequenceTracker) Stop() {
+	st.runMu.Lock()
+	if !st.running {
+		st.runMu.Unlock()
+		return
+	}
+
+	close(st.stopCh)
+	st.running = false
+	st.runMu.Unlock()
+
+	st.wg.Wait()
+
+	if err := st.Save(); err != nil {
+		log.Printf("[ERROR] %v", err)
+	}
+}
+
+// saveLoop 定期保存序列号状态
+func (st *SequenceTracker) saveLoop() {
+	defer st.wg.Done()
+
+	ticker := time.NewTicker(st.saveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-st.stopCh:
+			return
+		case <-ticker.C:
+			if err := st.Save(); err != nil {
+				log.Printf("[ERROR] %v", err)
+			}
+		}
+	}
+}
+
+// SequenceStats 序列号跟踪器的统计信息
+type SequenceStats struct {
+	Accepted int64 // 接受的序列号总数
+	Rejected int64 // 拒绝的序列号总数
+	Clients  int   // 跟踪的客户端数
+}
+
+// GetStats 返回序列号跟踪器的统计信息
+func (st *SequenceTracker) GetStats() SequenceStats {
+	st.mu.Lock()
+	clients := len(st.clients)
+	st.mu.Unlock()
+
+	return SequenceStats{
+		Accepted: st.accepted.Load(),
+		Rejected: st.rejected.Load(),
+		Clients:  clients,
+	}
+}
@@ -0,0 +1,117 @@
+package antireplay
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestSequenceTracker_InOrder 测试顺序递增的序列号
+func TestSequenceTracker_InOrder(t *testing.T) {
+	st := NewSequenceTracker(nil)
+
+	for seq := uint64(1); seq <= 100; seq++ {
+		if err := st.Check("app1", seq); err != nil {
+			t.Fatalf("seq %d should be accepted, got %v", seq, err)
+		}
+	}
+
+	if st.Highest("app1") != 100 {
+		t.Errorf("Expected highest 100, got %d", st.Highest("app1"))
+	}
+}
+
+// TestSequenceTracker_Window 测试乱序窗口、重复和过期
+func TestSequenceTracker_Window(t *testing.T) {
+	st := NewSequenceTracker(&SequenceTrackerConfig{Window: 4})
+
+	for _, seq := range []uint64{10, 12, 11, 13} {
+		if err := st.Check("app1", seq); err != nil {
+			t.Errorf("seq %d should be accepted, got %v", seq, err)
+		}
+	}
+
+	// 重复
+	if err := st.Check("app1", 12); err != ErrSequenceDuplicate {
+		t.Errorf("Expected ErrSequenceDuplicate, got %v", err)
+	}
+	if err := st.Check("app1", 13); err != ErrSequenceDuplicate {
+		t.Errorf("Expected ErrSequenceDuplicate for highest, got %v", err)
+	}
+
+	// 窗口之前（13-4=9 及以前）
+	if err := st.Check("app1", 9); err != ErrSequenceStale {
+		t.Errorf("Expected ErrSequenceStale, got %v", err)
+	}
+
+	// 向前跳跃后，之前窗口内未使用的序列号仍可接受
+	if err := st.Check("app1", 15); err != nil {
+		t.Errorf("seq 15 should be accepted, got %v", err)
+	}
+	if err := st.Check("app1", 14); err != nil {
+		t.Errorf("seq 14 should be accepted, got %v", err)
+	}
+
+	// 大幅跳跃
+	if err := st.Check("app1", 1000); err != nil {
+		t.Errorf("seq 1000 should be accepted, got %v", err)
+	}
+	if err := st.Check("app1", 15); err != ErrSequenceStale {
+		t.Errorf("Expected ErrSequenceStale after jump, got %v", err)
+	}
+
+	// 无效序列号
+	if err := st.Check("app1", 0); err != ErrInvalidSequence {
+		t.Errorf("Expected ErrInvalidSequence, got %v", err)
+	}
+
+	stats := st.GetStats()
+	if stats.Accepted != 7 || stats.Rejected != 5 || stats.Clients != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+// TestSequenceTracker_PerClient 测试不同客户端的序列号互不影响
+func TestSequenceTracker_PerClient(t *testing.T) {
+	st := NewSequenceTracker(nil)
+
+	if err := st.Check("app1", 5); err != nil {
+		t.Fatalf("app1 seq 5 should be accepted, got %v", err)
+	}
+	if err := st.Check("app2", 5); err != nil {
+		t.Errorf("app2 seq 5 should be accepted, got %v", err)
+	}
+}
+
+// TestSequenceTracker_Persistence 测试重启后状态保持
+func TestSequenceTracker_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "sequences.json")
+
+	st := NewSequenceTracker(&SequenceTrackerConfig{Path: path})
+	if err := st.Start(); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	for seq := uint64(1); seq <= 20; seq++ {
+		st.Check("app1", seq)
+	}
+	st.Stop()
+
+	// 重启
+	restarted := NewSequenceTracker(&SequenceTrackerConfig{Path: path})
+	if err := restarted.Start(); err != nil {
+		t.Fatalf("Failed to restart: %v", err)
+	}
+	defer restarted.Stop()
+
+	if restarted.Highest("app1") != 20 {
+		t.Errorf("Expected highest 20 after restart, got %d", restarted.Highest("app1"))
+	}
+	if err := restarted.Check("app1", 20); err != ErrSequenceDuplicate {
+		t.Errorf("Expected ErrSequenceDuplicate after restart, got %v", err)
+	}
+	if err := restarted.Check("app1", 3); err != ErrSequenceStale {
+		t.Errorf("Expected ErrSequenceStale after restart, got %v", err)
+	}
+	if err := restarted.Check("app1", 21); err != nil {
+		t.Errorf("seq 21 should be accepted after restart, got %v", err)
+	}
+}
@@ -0,0 +1,314 @@
+package antireplay
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/tjfoc/gmsm/sm3"
+)
+
+// 支持的签名算法
+const (
+	// AlgorithmHMACSHA256 HMAC-SHA256（商密）
+	AlgorithmHMACSHA256 = "hmac-sha256"
+
+	// AlgorithmHMACSM3 HMAC-SM3（国密）
+	AlgorithmHMACSM3 = "hmac-sm3"
+)
+
+var (
+	// ErrUnknownClient 未配置密钥的客户端
+	ErrUnknownClient = errors.New("unknown client")
+
+	// ErrInvalidTimestamp 时间戳格式无效
+	ErrInvalidTimestamp = errors.New("invalid timestamp")
+
+	// ErrTimestampExpired 时间戳超出允许的时间窗口
+	ErrTimestampExpired = errors.New("timestamp expired")
+
+	// ErrInvalidSignature 签名验证失败
+	ErrInvalidSignature = errors.New("invalid signature")
+
+	// ErrUnsupportedAlgorithm 不支持的签名算法
+	ErrUnsupportedAlgorithm = errors.New("unsupported signature algorithm")
+)
+
+// hashFunc 返回签名算法对应的哈希构造函数
+func hashFunc(algorithm string) (func() hash.Hash, error) {
+	switch algorithm {
+	case AlgorithmHMACSHA256:
+		return sha256.New, nil
+	case AlgorithmHMACSM3:
+		return sm3.New, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, algorithm)
+	}
+}
+
+// SigningString 构造签名字符串
+//
+// 签名字符串格式与 HTTP 接口保持一致，以换行分隔：
+//
+//	clientID + "\n" + timestamp + "\n" + nonce + "\n" + payload
+//
+// RESP 连接握手时 payload 为空；逐条命令签名时 payload 为被签名命令
+// 按 RESP Array 编码后的字节（见 CommandPayload）。
+func SigningString(clientID, timestamp, nonce string, payload []byte) []byte {
+	buf := make([]byte, 0, len(clientID)+len(timestamp)+len(nonce)+len(payload)+3)
+	buf = append(buf, clientID...)
+	buf = append(buf, '\n')
+	buf = append(buf, timestamp...)
+	buf = append(buf, '\n')
+	buf = append(buf, nonce...)
+	buf = append(buf, '\n')
+	buf = append(buf, payload...)
+	return buf
+}
+
+// CommandPayload 将命令参数编码为 RESP Array，作为逐条命令签名的 payload
+//
+// 使用 RESP 编码而不是简单拼接，避免参数中包含分隔符时产生歧义。
+//
+// 示例：
+//
+//	CommandPayload([][]byte{[]byte("GET"), []byte("k")})
+//	// "*2\r\n$3\r\nGET\r\n$1\r\nk\r\n"
+func CommandPayload(args [][]byte) []byte {
+	buf := make([]byte, 0, 64)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+	return buf
+}
+
+// Sign 计算签名（十六进制小写）
+//
+// 参数说明：
+//   - algorithm: 签名算法（hmac-sha256 | hmac-sm3）
+//   - secret: 客户端密钥
+//   - clientID、timestamp、nonce、payload: 参与签名的字段，见 SigningString
+//
+// 返回值：
+//   - string: 十六进制编码的签名
+//   - error: 算法不支持时返回 ErrUnsupportedAlgorithm
+//
+// 示例：
+//
+//	ts := strconv.FormatInt(time.Now().Unix(), 10)
+//	sig, _ := Sign(AlgorithmHMACSHA256, secret, "app1", ts, nonce, nil)
+func Sign(algorithm string, secret []byte, clientID, timestamp, nonce string, payload []byte) (string, error) {
+	newHash, err := hashFunc(algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(newHash, secret)
+	mac.Write(SigningString(clientID, timestamp, nonce, payload))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// SignedRequest 表示一个待验证的签名请求
+type SignedRequest struct {
+	ClientID  string // 客户端 ID
+	Timestamp string // Unix 时间戳（秒）
+	Nonce     string // 一次性随机数
+	Signature string // 十六进制签名
+	Payload   []byte // 被签名的内容
+}
+
+// ClientKey 单个客户端的签名密钥
+type ClientKey struct {
+	ClientID  string // 客户端 ID
+	Secret    []byte // 签名密钥
+	Algorithm string // 签名算法，为空时使用 VerifierConfig.Algorithm
+}
+
+// VerifierConfig 签名验证器配置
+type VerifierConfig struct {
+	// Window 时间窗口，早于 now-Window-ClockSkew 的请求被拒绝，默认 5 分钟
+	Window time.Duration
+
+	// ClockSkew 时钟偏移容忍，晚于 now+ClockSkew 的请求被拒绝
+	ClockSkew time.Duration
+
+	// Algorithm 默认签名算法，默认 hmac-sha256
+	Algorithm string
+
+	// Clients 客户端密钥列表
+	Clients []ClientKey
+}
+
+// verifierClient 验证器内部使用的客户端密钥
+type verifierClient struct {
+	secret  []byte
+	newHash func() hash.Hash
+}
+
+// Verifier 签名验证器
+//
+// Verifier 对每个请求依次校验：客户端是否已配置、时间戳是否在窗口内、
+// 签名是否正确、Nonce 是否已使用。Nonce 在签名通过之后才写入缓存，
+// 避免伪造请求消耗合法客户端的 Nonce。
+//
+// 示例：
+//
+//	v, err := NewVerifier(&VerifierConfig{
+//	    Window:  5 * time.Minute,
+//	    Clients: []ClientKey{{ClientID: "app1", Secret: []byte("secret")}},
+//	}, nonceStore)
+//	if err := v.Verify(req); err != nil {
+//	    // 拒绝请求
+//	}
+type Verifier struct {
+	window    time.Duration
+	clockSkew time.Duration
+	clients   map[string]*verifierClient
+	nonces    *NonceStore
+
+	// 统计信息
+	verified atomic.Int64 // 验证通过数
+	failed   atomic.Int64 // 验证失败数
+}
+
+// NewVerifier 创建一个新的签名验证器
+//
+// 参数说明：
+//   - config: 验证器配置
+//   - nonces: 用于 Nonce 去重的缓存，其保留窗口应不小于 Window+ClockSkew；
+//     必须是验证器专用的实例，不能与 NONCE.CHECK 命令共用，
+//     否则任意连接都能提前消耗其他客户端的 Nonce 或写满缓存
+//
+// 返回值：
+//   - *Verifier: 验证器实例
+//   - error: 配置的签名算法不支持时返回错误
+func NewVerifier(config *VerifierConfig, nonces *NonceStore) (*Verifier, error) {
+	window := config.Window
+	if window <= 0 {
+		window = DefaultNonceWindow
+	}
+
+	defaultAlgorithm := config.Algorithm
+	if defaultAlgorithm == "" {
+		defaultAlgorithm = AlgorithmHMACSHA256
+	}
+
+	clients := make(map[string]*verifierClient, len(config.Clients))
+	for _, key := range config.Clients {
+		algorithm := key.Algorithm
+		if algorithm == "" {
+			algorithm = defaultAlgorithm
+		}
+
+		newHash, err := hashFunc(algorithm)
+		if err != nil {
+			return nil, fmt.Errorf("客户端 %s: %w", key.ClientID, err)
+		}
+
+		clients[key.ClientID] = &verifierClient{
+			secret:  key.Secret,
+			newHash: newHash,
+		}
+	}
+
+	return &Verifier{
+		window:    window,
+		clockSkew: config.ClockSkew,
+		clients:   clients,
+		nonces:    nonces,
+	}, nil
+}
+
+// Verify 验证签名请求
+//
+// 返回值：
+//   - error: nil 表示验证通过；否则为 ErrUnknownClient、ErrInvalidTimestamp、
+//     ErrTimestampExpired、ErrInvalidSignature、ErrNonceReused 等错误
+//
+// 注意事项：
+//   - 该方法是并发安全的
+//   - Nonce 按客户端隔离，不同客户端使用相同的 Nonce 互不影响
+func (v *Verifier) Verify(req *SignedRequest) error {
+	if err := v.verify(req); err != nil {
+		v.failed.Add(1)
+		return err
+	}
+
+	v.verified.Add(1)
+	return nil
+}
+
+// verify 执行实际的验证逻辑
+func (v *Verifier) verify(req *SignedRequest) error {
+	client, exists := v.clients[req.ClientID]
+	if !exists {
+		return ErrUnknownClient
+	}
+
+	ts, err := strconv.ParseInt(req.Timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidTimestamp
+	}
+
+	now := time.Now()
+	requestTime := time.Unix(ts, 0)
+	if requestTime.Before(now.Add(-v.window-v.clockSkew)) || requestTime.After(now.Add(v.clockSkew)) {
+		return ErrTimestampExpired
+	}
+
+	signature, err := hex.DecodeString(req.Signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(client.newHash, client.secret)
+	mac.Write(SigningString(req.ClientID, req.Timestamp, req.Nonce, req.Payload))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return ErrInvalidSignature
+	}
+
+	if v.nonces != nil {
+		if err := v.nonces.CheckAndInsert(nonceKey(req.ClientID, req.Nonce)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// nonceKey 构造签名 Nonce 在缓存中的键
+//
+// 键为 "客户端 ID 的长度:客户端 ID" 加 Nonce，客户端 ID 或 Nonce 中含有 ':' 时也不会产生歧义
+// （例如客户端 "a:b" 的 Nonce "c" 与客户端 "a" 的 Nonce "b:c"）。
+func nonceKey(clientID, nonce string) string {
+	return strconv.Itoa(len(clientID)) + ":" + clientID + nonce
+}
+
+// VerifierStats 签名验证器的统计信息
+type VerifierStats struct {
+	Verified int64 // 验证通过数
+	Failed   int64 // 验证失败数
+	Clients  int   // 已配置的客户端数
+}
+
+// GetStats 返回签名验证器的统计信息
+func (v *Verifier) GetStats() VerifierStats {
+	return VerifierStats{
+		Verified: v.verified.Load(),
+		Failed:   v.failed.Load(),
+		Clients:  len(v.clients),
+	}
+}
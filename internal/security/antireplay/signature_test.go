@@ -0,0 +1,182 @@
+package antireplay
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+// newTestVerifier 创建测试用的签名验证器
+func newTestVerifier(t *testing.T) *Verifier {
+	v, err := NewVerifier(&VerifierConfig{
+		Window:    5 * time.Minute,
+		ClockSkew: 30 * time.Second,
+		Clients: []ClientKey{
+			{ClientID: "app1", Secret: []byte("secret-1")},
+			{ClientID: "app2", Secret: []byte("secret-2"), Algorithm: AlgorithmHMACSM3},
+		},
+	}, NewNonceStore(nil))
+	if err != nil {
+		t.Fatalf("Failed to create verifier: %v", err)
+	}
+	return v
+}
+
+// signedRequest 构造一个签名正确的请求
+func signedRequest(t *testing.T, algorithm, clientID, secret string, ts time.Time, nonce string, payload []byte) *SignedRequest {
+	timestamp := strconv.FormatInt(ts.Unix(), 10)
+	sig, err := Sign(algorithm, []byte(secret), clientID, timestamp, nonce, payload)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	return &SignedRequest{
+		ClientID:  clientID,
+		Timestamp: timestamp,
+		Nonce:     nonce,
+		Signature: sig,
+		Payload:   payload,
+	}
+}
+
+// TestVerifier_Valid 测试 HMAC-SHA256 和 HMAC-SM3 签名验证通过
+func TestVerifier_Valid(t *testing.T) {
+	v := newTestVerifier(t)
+
+	req := signedRequest(t, AlgorithmHMACSHA256, "app1", "secret-1", time.Now(), "n1", []byte("payload"))
+	if err := v.Verify(req); err != nil {
+		t.Errorf("HMAC-SHA256 request should be valid, got %v", err)
+	}
+
+	req = signedRequest(t, AlgorithmHMACSM3, "app2", "secret-2", time.Now(), "n1", nil)
+	if err := v.Verify(req); err != nil {
+		t.Errorf("HMAC-SM3 request should be valid, got %v", err)
+	}
+
+	stats := v.GetStats()
+	if stats.Verified != 2 || stats.Failed != 0 || stats.Clients != 2 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+// TestVerifier_Errors 测试各类验证失败返回不同的错误
+func TestVerifier_Errors(t *testing.T) {
+	v := newTestVerifier(t)
+	now := time.Now()
+
+	// 未知客户端
+	req := signedRequest(t, AlgorithmHMACSHA256, "app3", "secret-3", now, "n1", nil)
+	if err := v.Verify(req); err != ErrUnknownClient {
+		t.Errorf("Expected ErrUnknownClient, got %v", err)
+	}
+
+	// 时间戳格式错误
+	req = signedRequest(t, AlgorithmHMACSHA256, "app1", "secret-1", now, "n2", nil)
+	req.Timestamp = "yesterday"
+	if err := v.Verify(req); err != ErrInvalidTimestamp {
+		t.Errorf("Expected ErrInvalidTimestamp, got %v", err)
+	}
+
+	// 时间戳过旧
+	req = signedRequest(t, AlgorithmHMACSHA256, "app1", "secret-1", now.Add(-10*time.Minute), "n3", nil)
+	if err := v.Verify(req); err != ErrTimestampExpired {
+		t.Errorf("Expected ErrTimestampExpired for old timestamp, got %v", err)
+	}
+
+	// 时间戳超前（超出时钟偏移容忍）
+	req = signedRequest(t, AlgorithmHMACSHA256, "app1", "secret-1", now.Add(2*time.Minute), "n4", nil)
+	if err := v.Verify(req); err != ErrTimestampExpired {
+		t.Errorf("Expected ErrTimestampExpired for future timestamp, got %v", err)
+	}
+
+	// 密钥错误
+	req = signedRequest(t, AlgorithmHMACSHA256, "app1", "wrong", now, "n5", nil)
+	if err := v.Verify(req); err != ErrInvalidSignature {
+		t.Errorf("Expected ErrInvalidSignature, got %v", err)
+	}
+
+	// 算法不匹配
+	req = signedRequest(t, AlgorithmHMACSHA256, "app2", "secret-2", now, "n6", nil)
+	if err := v.Verify(req); err != ErrInvalidSignature {
+		t.Errorf("Expected ErrInvalidSignature for algorithm mismatch, got %v", err)
+	}
+
+	// payload 被篡改
+	req = signedRequest(t, AlgorithmHMACSHA256, "app1", "secret-1", now, "n7", []byte("SET a 1"))
+	req.Payload = []byte("SET a 2")
+	if err := v.Verify(req); err != ErrInvalidSignature {
+		t.Errorf("Expected ErrInvalidSignature for tampered payload, got %v", err)
+	}
+
+	// Nonce 重放
+	req = signedRequest(t, AlgorithmHMACSHA256, "app1", "secret-1", now, "n8", nil)
+	if err := v.Verify(req); err != nil {
+		t.Fatalf("First request should be valid, got %v", err)
+	}
+	if err := v.Verify(req); err != ErrNonceReused {
+		t.Errorf("Expected ErrNonceReused, got %v", err)
+	}
+}
+
+// TestVerifier_NoncePerClient 测试 Nonce 按客户端隔离
+func TestVerifier_NoncePerClient(t *testing.T) {
+	v := newTestVerifier(t)
+
+	req := signedRequest(t, AlgorithmHMACSHA256, "app1", "secret-1", time.Now(), "same", nil)
+	if err := v.Verify(req); err != nil {
+		t.Errorf("app1 request should be valid, got %v", err)
+	}
+
+	req = signedRequest(t, AlgorithmHMACSM3, "app2", "secret-2", time.Now(), "same", nil)
+	if err := v.Verify(req); err != nil {
+		t.Errorf("app2 request with same nonce should be valid, got %v", err)
+	}
+}
+
+// TestVerifier_NonceKeyUnambiguous 测试客户端 ID 含 ':' 时 Nonce 不会串到其他客户端
+func TestVerifier_NonceKeyUnambiguous(t *testing.T) {
+	v, err := NewVerifier(&VerifierConfig{
+		Window: 5 * time.Minute,
+		Clients: []ClientKey{
+			{ClientID: "a", Secret: []byte("secret-a")},
+			{ClientID: "a:b", Secret: []byte("secret-ab")},
+		},
+	}, NewNonceStore(nil))
+	if err != nil {
+		t.Fatalf("Failed to create verifier: %v", err)
+	}
+
+	req := signedRequest(t, AlgorithmHMACSHA256, "a:b", "secret-ab", time.Now(), "c", nil)
+	if err := v.Verify(req); err != nil {
+		t.Errorf("a:b request should be valid, got %v", err)
+	}
+
+	req = signedRequest(t, AlgorithmHMACSHA256, "a", "secret-a", time.Now(), "b:c", nil)
+	if err := v.Verify(req); err != nil {
+		t.Errorf("a request with nonce b:c should be valid, got %v", err)
+	}
+}
+
+// TestNewVerifier_UnsupportedAlgorithm 测试不支持的签名算法
+func TestNewVerifier_UnsupportedAlgorithm(t *testing.T) {
+	_, err := NewVerifier(&VerifierConfig{
+		Clients: []ClientKey{{ClientID: "app1", Secret: []byte("s"), Algorithm: "md5"}},
+	}, nil)
+	if err == nil {
+		t.Error("Expected error for unsupported algorithm")
+	}
+}
+
+// TestCommandPayload 测试命令 payload 编码
+func TestCommandPayload(t *testing.T) {
+	payload := CommandPayload([][]byte{[]byte("GET"), []byte("k")})
+	if string(payload) != "*2\r\n$3\r\nGET\r\n$1\r\nk\r\n" {
+		t.Errorf("Unexpected payload %q", payload)
+	}
+
+	// 分隔符出现在参数中时不会产生歧义
+	a := CommandPayload([][]byte{[]byte("SET"), []byte("a\nb"), []byte("c")})
+	b := CommandPayload([][]byte{[]byte("SET"), []byte("a"), []byte("b\nc")})
+	if string(a) == string(b) {
+		t.Error("Payloads of different commands should differ")
+	}
+}
@@ -0,0 +1,138 @@
+package token
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"strings"
+)
+
+const (
+	// DefaultLength 是随机部分的默认长度（32 个字符，192 位熵）
+	DefaultLength = 32
+
+	// MinLength 是随机部分的最小长度（22 个字符，132 位熵）
+	MinLength = 22
+
+	// MaxLength 是随机部分的最大长度
+	MaxLength = 256
+
+	// MaxPrefixLength 是前缀的最大长度
+	MaxPrefixLength = 64
+
+	// ChecksumLength 是校验和后缀的长度（CRC32 编码为 6 个字符）
+	ChecksumLength = 6
+)
+
+// alphabet 是 URL 安全的 Base64 字符表（RFC 4648 §5）
+//
+// 字符表长度为 64，256 能被 64 整除，因此对随机字节取低 6 位即可得到均匀分布的字符。
+const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+
+var (
+	// ErrInvalidLength 随机部分长度超出范围
+	ErrInvalidLength = errors.New("invalid token length")
+
+	// ErrInvalidPrefix 前缀过长或包含 URL 非保留字符以外的字符
+	ErrInvalidPrefix = errors.New("invalid token prefix")
+)
+
+// Options 令牌生成选项
+type Options struct {
+	// Length 随机部分的字符数，0 表示使用 DefaultLength
+	Length int
+
+	// Prefix 令牌前缀（如 "tgx_at_"），只能包含 URL 非保留字符（A-Z a-z 0-9 - . _ ~）
+	Prefix string
+
+	// Checksum 是否追加 CRC32 校验和后缀，便于在不查询服务器的情况下识别拼写错误或截断的令牌
+	Checksum bool
+}
+
+// Generate 生成一个密码学安全的随机令牌
+//
+// 令牌格式：Prefix + 随机部分 + [校验和]，全部由 URL 安全字符组成。
+//
+// 参数说明：
+//   - opts: 生成选项，为 nil 时使用默认选项
+//
+// 返回值：
+//   - string: 生成的令牌
+//   - error: 选项无效或随机数源不可用时的错误
+//
+// 示例：
+//
+//	tok, err := token.Generate(&token.Options{Prefix: "tgx_", Checksum: true})
+//	// tgx_Jd8s...Qw1kB9z
+func Generate(opts *Options) (string, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	length := opts.Length
+	if length == 0 {
+		length = DefaultLength
+	}
+	if length < MinLength || length > MaxLength {
+		return "", fmt.Errorf("%w: %d (允许范围 %d-%d)", ErrInvalidLength, length, MinLength, MaxLength)
+	}
+
+	if len(opts.Prefix) > MaxPrefixLength || !isURLSafe(opts.Prefix) {
+		return "", ErrInvalidPrefix
+	}
+
+	random := make([]byte, length)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("读取随机数失败: %w", err)
+	}
+
+	var b strings.Builder
+	b.Grow(len(opts.Prefix) + length + ChecksumLength)
+	b.WriteString(opts.Prefix)
+	for _, r := range random {
+		b.WriteByte(alphabet[r&63])
+	}
+
+	if opts.Checksum {
+		b.WriteString(checksum(b.String()))
+	}
+
+	return b.String(), nil
+}
+
+// ValidChecksum 检查带校验和的令牌是否完整
+//
+// 注意事项：
+//   - 校验和只用于发现拼写错误和截断，不能代替服务器端的令牌查询
+func ValidChecksum(token string) bool {
+	if len(token) <= ChecksumLength {
+		return false
+	}
+
+	body := token[:len(token)-ChecksumLength]
+	return checksum(body) == token[len(token)-ChecksumLength:]
+}
+
+// checksum 计算 CRC32 校验和并编码为 6 个 URL 安全字符
+func checksum(s string) string {
+	sum := crc32.ChecksumIEEE([]byte(s))
+
+	var out [ChecksumLength]byte
+	for i := ChecksumLength - 1; i >= 0; i-- {
+		out[i] = alphabet[sum&63]
+		sum >>= 6
+	}
+	return string(out[:])
+}
+
+// isURLSafe 检查字符串是否只包含 URL 非保留字符（RFC 3986 §2.3）
+func isURLSafe(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if strings.IndexByte(alphabet, c) < 0 && c != '.' && c != '~' {
+			return false
+		}
+	}
+	return true
+}
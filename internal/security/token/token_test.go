@@ -0,0 +1,94 @@
+package token
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestGenerate_Default 测试默认选项
+func TestGenerate_Default(t *testing.T) {
+	tok, err := Generate(nil)
+	if err != nil {
+		t.Fatalf("Failed to generate: %v", err)
+	}
+
+	if len(tok) != DefaultLength {
+		t.Errorf("Expected length %d, got %d", DefaultLength, len(tok))
+	}
+	if !isURLSafe(tok) {
+		t.Errorf("Token %q is not URL safe", tok)
+	}
+}
+
+// TestGenerate_Unique 测试生成的令牌不重复
+func TestGenerate_Unique(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 10000; i++ {
+		tok, err := Generate(&Options{Length: MinLength})
+		if err != nil {
+			t.Fatalf("Failed to generate: %v", err)
+		}
+		if seen[tok] {
+			t.Fatalf("Duplicate token %q", tok)
+		}
+		seen[tok] = true
+	}
+}
+
+// TestGenerate_PrefixAndChecksum 测试前缀和校验和
+func TestGenerate_PrefixAndChecksum(t *testing.T) {
+	tok, err := Generate(&Options{Length: 40, Prefix: "tgx_at_", Checksum: true})
+	if err != nil {
+		t.Fatalf("Failed to generate: %v", err)
+	}
+
+	if !strings.HasPrefix(tok, "tgx_at_") {
+		t.Errorf("Expected prefix tgx_at_, got %q", tok)
+	}
+	if len(tok) != len("tgx_at_")+40+ChecksumLength {
+		t.Errorf("Unexpected length %d", len(tok))
+	}
+	if !ValidChecksum(tok) {
+		t.Errorf("Checksum of %q should be valid", tok)
+	}
+
+	// 修改一个字符后校验和失效
+	tampered := []byte(tok)
+	if tampered[10] == 'A' {
+		tampered[10] = 'B'
+	} else {
+		tampered[10] = 'A'
+	}
+	if ValidChecksum(string(tampered)) {
+		t.Error("Checksum of tampered token should be invalid")
+	}
+
+	// 截断
+	if ValidChecksum(tok[:len(tok)-1]) {
+		t.Error("Checksum of truncated token should be invalid")
+	}
+}
+
+// TestGenerate_InvalidOptions 测试非法选项
+func TestGenerate_InvalidOptions(t *testing.T) {
+	if _, err := Generate(&Options{Length: MinLength - 1}); err == nil {
+		t.Error("Expected error for short length")
+	}
+	if _, err := Generate(&Options{Length: MaxLength + 1}); err == nil {
+		t.Error("Expected error for long length")
+	}
+	if _, err := Generate(&Options{Prefix: "oauth:token:"}); err != ErrInvalidPrefix {
+		t.Errorf("Expected ErrInvalidPrefix, got %v", err)
+	}
+	if _, err := Generate(&Options{Prefix: strings.Repeat("a", MaxPrefixLength+1)}); err != ErrInvalidPrefix {
+		t.Errorf("Expected ErrInvalidPrefix, got %v", err)
+	}
+}
+
+// BenchmarkGenerate 基准测试令牌生成
+func BenchmarkGenerate(b *testing.B) {
+	opts := &Options{Prefix: "tgx_", Checksum: true}
+	for i := 0; i < b.N; i++ {
+		Generate(opts)
+	}
+}
@@ -0,0 +1,204 @@
+package storage
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrNotInteger 键的值不是整数或超出 int64 范围
+	ErrNotInteger = errors.New("value is not an integer or out of range")
+
+	// ErrNotFloat 键的值不是有效的浮点数
+	ErrNotFloat = errors.New("value is not a valid float")
+
+	// ErrOverflow 增减后超出 int64 范围，或浮点数结果为 NaN/Infinity
+	ErrOverflow = errors.New("increment or decrement would overflow")
+)
+
+// IncrBy 将键的整数值增加 delta
+//
+// 参数说明：
+//   - key: 计数器键，不存在时视为 0
+//   - delta: 增量，可以为负数
+//
+// 返回值：
+//   - int64: 增加后的值
+//   - error: 值不是整数时返回 ErrNotInteger，结果溢出时返回 ErrOverflow
+//
+// 示例：
+//
+//	// 登录失败计数，窗口为 15 分钟
+//	attempts, err := sm.IncrBy("login:fail:alice", 1)
+//	if err == nil && attempts == 1 {
+//	    Expire(sm, "login:fail:alice", 900)
+//	}
+//
+// 注意事项：
+//   - 读取、计算和写入在同一把分片锁内完成
+//   - 保留键原有的过期时间
+//   - 结果以 int64 存储，后续递增不再解析字符串；首次递增时会解析字符串形式的整数
+func (sm *ShardedMap) IncrBy(key string, delta int64) (int64, error) {
+	key = sm.storageKey(key)
+	shard := sm.getShard(key)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	existing := liveItem(shard, key)
+
+	var current int64
+	if existing != nil {
+		n, ok := integerValue(existing.value)
+		if !ok {
+			return 0, ErrNotInteger
+		}
+		current = n
+	}
+
+	if (delta > 0 && current > math.MaxInt64-delta) || (delta < 0 && current < math.MinInt64-delta) {
+		return 0, ErrOverflow
+	}
+	result := current + delta
+
+	if existing != nil {
+		existing.value = result
+		existing.version = nextVersion()
+	} else {
+		shard.put(key, &item{
+			value:     result,
+			createdAt: time.Now().UnixMilli(),
+			version:   nextVersion(),
+		})
+	}
+	shard.events.notify(KeyEventSet, key)
+
+	return result, nil
+}
+
+// IncrByFloat 将键的数值增加 delta（浮点数）
+//
+// 参数说明：
+//   - key: 计数器键，不存在时视为 0
+//   - delta: 增量，可以为负数
+//
+// 返回值：
+//   - float64: 增加后的值
+//   - error: 值不是数字时返回 ErrNotFloat，结果为 NaN 或 Infinity 时返回 ErrOverflow
+//
+// 注意事项：
+//   - 读取、计算和写入在同一把分片锁内完成
+//   - 保留键原有的过期时间
+//   - 结果以 float64 存储
+func (sm *ShardedMap) IncrByFloat(key string, delta float64) (float64, error) {
+	key = sm.storageKey(key)
+	shard := sm.getShard(key)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	existing := liveItem(shard, key)
+
+	var current float64
+	if existing != nil {
+		f, ok := floatValue(existing.value)
+		if !ok {
+			return 0, ErrNotFloat
+		}
+		current = f
+	}
+
+	result := current + delta
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		return 0, ErrOverflow
+	}
+
+	if existing != nil {
+		existing.value = result
+		existing.version = nextVersion()
+	} else {
+		shard.put(key, &item{
+			value:     result,
+			createdAt: time.Now().UnixMilli(),
+			version:   nextVersion(),
+		})
+	}
+	shard.events.notify(KeyEventSet, key)
+
+	return result, nil
+}
+
+// FormatFloat 按 INCRBYFLOAT 的格式将浮点数转换为字符串（不使用科学计数法，去掉多余的 0）
+func FormatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// liveItem 返回未过期的数据项，已过期的数据项会被删除
+//
+// 调用方必须持有分片的写锁。
+func liveItem(shard *mapShard, key string) *item {
+	existing, exists := shard.items[key]
+	if !exists {
+		return nil
+	}
+	if existing.expiresAt > 0 && time.Now().UnixMilli() >= existing.expiresAt {
+		shard.remove(key)
+		shard.events.notify(KeyEventExpired, key)
+		return nil
+	}
+	return existing
+}
+
+// integerValue 将存储的值转换为 int64
+//
+// 支持 int64、int、整数值的 float64，以及字符串形式的十进制整数。
+func integerValue(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return v, true
+	case int:
+		return int64(v), true
+	case float64:
+		if v != math.Trunc(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+			return 0, false
+		}
+		return int64(v), true
+	case []byte:
+		n, err := strconv.ParseInt(string(v), 10, 64)
+		return n, err == nil
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		return n, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// floatValue 将存储的值转换为 float64
+//
+// 支持 float64、int64、int，以及字符串形式的数字（不接受 NaN 和 Infinity）。
+func floatValue(value interface{}) (float64, bool) {
+	var s string
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return 0, false
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false
+	}
+	return f, true
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"testing"
+)
+
+// TestShardedMap_IncrBy 测试整数递增
+func TestShardedMap_IncrBy(t *testing.T) {
+	sm := NewShardedMap(1024)
+
+	if n, err := sm.IncrBy("counter", 1); err != nil || n != 1 {
+		t.Errorf("IncrBy on missing key = %d, %v; want 1, nil", n, err)
+	}
+	if n, err := sm.IncrBy("counter", -5); err != nil || n != -4 {
+		t.Errorf("IncrBy = %d, %v; want -4, nil", n, err)
+	}
+
+	// 值以 int64 存储
+	if value, _ := sm.Get("counter"); value != int64(-4) {
+		t.Errorf("Expected int64(-4) stored, got %#v", value)
+	}
+
+	// 字符串形式的整数在首次递增时解析
+	sm.Set("str", []byte("10"), 0)
+	if n, err := sm.IncrBy("str", 5); err != nil || n != 15 {
+		t.Errorf("IncrBy on string integer = %d, %v; want 15, nil", n, err)
+	}
+
+	sm.Set("text", []byte("abc"), 0)
+	if _, err := sm.IncrBy("text", 1); !errors.Is(err, ErrNotInteger) {
+		t.Errorf("Expected ErrNotInteger, got %v", err)
+	}
+
+	sm.Set("max", int64(math.MaxInt64), 0)
+	if _, err := sm.IncrBy("max", 1); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Expected ErrOverflow, got %v", err)
+	}
+	sm.Set("min", int64(math.MinInt64), 0)
+	if _, err := sm.IncrBy("min", -1); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Expected ErrOverflow, got %v", err)
+	}
+}
+
+// TestShardedMap_IncrByKeepsTTL 测试递增保留过期时间
+func TestShardedMap_IncrByKeepsTTL(t *testing.T) {
+	sm := NewShardedMap(1024)
+	sm.Set("login:fail:alice", []byte("1"), 900)
+
+	if _, err := sm.IncrBy("login:fail:alice", 1); err != nil {
+		t.Fatalf("IncrBy failed: %v", err)
+	}
+	if ttl := TTL(sm, "login:fail:alice"); ttl <= 0 || ttl > 900 {
+		t.Errorf("Expected TTL kept around 900, got %d", ttl)
+	}
+
+	if _, err := sm.IncrByFloat("login:fail:alice", 0.5); err != nil {
+		t.Fatalf("IncrByFloat failed: %v", err)
+	}
+	if ttl := TTL(sm, "login:fail:alice"); ttl <= 0 || ttl > 900 {
+		t.Errorf("Expected TTL kept around 900 after IncrByFloat, got %d", ttl)
+	}
+}
+
+// TestShardedMap_IncrByConcurrent 测试并发递增不丢失更新
+func TestShardedMap_IncrByConcurrent(t *testing.T) {
+	sm := NewShardedMap(1024)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 100; n++ {
+				sm.IncrBy("quota:app1", 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if value, _ := sm.Get("quota:app1"); value != int64(5000) {
+		t.Errorf("Expected 5000, got %v", value)
+	}
+}
+
+// TestShardedMap_IncrByFloat 测试浮点数递增
+func TestShardedMap_IncrByFloat(t *testing.T) {
+	sm := NewShardedMap(1024)
+
+	if f, err := sm.IncrByFloat("score", 10.5); err != nil || f != 10.5 {
+		t.Errorf("IncrByFloat = %v, %v; want 10.5, nil", f, err)
+	}
+
+	sm.Set("int", int64(3), 0)
+	if f, err := sm.IncrByFloat("int", 0.25); err != nil || f != 3.25 {
+		t.Errorf("IncrByFloat on int64 = %v, %v; want 3.25, nil", f, err)
+	}
+
+	// 整数值的浮点数可以继续 INCR
+	sm.IncrByFloat("whole", 2)
+	if n, err := sm.IncrBy("whole", 1); err != nil || n != 3 {
+		t.Errorf("IncrBy after IncrByFloat = %d, %v; want 3, nil", n, err)
+	}
+	if _, err := sm.IncrBy("score", 1); !errors.Is(err, ErrNotInteger) {
+		t.Errorf("Expected ErrNotInteger for 10.5, got %v", err)
+	}
+
+	sm.Set("text", []byte("abc"), 0)
+	if _, err := sm.IncrByFloat("text", 1); !errors.Is(err, ErrNotFloat) {
+		t.Errorf("Expected ErrNotFloat, got %v", err)
+	}
+
+	sm.Set("big", math.MaxFloat64, 0)
+	if _, err := sm.IncrByFloat("big", math.MaxFloat64); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Expected ErrOverflow, got %v", err)
+	}
+}
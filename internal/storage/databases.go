@@ -0,0 +1,254 @@
+package storage
+
+import (
+	"fmt"
+	"sync"
+)
+
+const (
+	// DefaultDatabaseCount 默认的逻辑数据库数量，与 Redis 相同
+	DefaultDatabaseCount = 16
+
+	// lazyDatabaseCapacity 按需创建的逻辑数据库每个分片的初始容量
+	//
+	// 除 0 号数据库外的逻辑数据库在第一次使用时才创建，使用较小的初始容量，
+	// 避免未使用的数据库占用内存。
+	lazyDatabaseCapacity = 16
+)
+
+// Databases 一组编号的逻辑数据库
+//
+// 每个逻辑数据库是一个独立的 ShardedMap，拥有独立的键空间。
+// 0 号数据库由调用方传入，其余数据库在第一次访问时创建，并共享 0 号数据库的键哈希器。
+//
+// 示例：
+//
+//	dbs := NewDatabases(NewShardedMap(4096), 16)
+//	dbs.DB(1).Set("saml:assertion:abc", data, 300)
+//	moved := dbs.Move("saml:assertion:abc", 1, 2)
+//
+// 注意事项：
+//   - 该类型是并发安全的
+//   - Swap 交换的是两个下标对应的 ShardedMap，已取得的 *ShardedMap 仍指向原来的数据
+type Databases struct {
+	mu     sync.RWMutex
+	dbs    []*ShardedMap // 下标即数据库编号，nil 表示尚未创建
+	hasher *KeyHasher    // 新建数据库使用的键哈希器
+
+	eventTypes   KeyEventType                 // 键空间事件回调选择的事件类型
+	eventHandler func(db int, event KeyEvent) // 键空间事件回调，nil 表示未设置
+}
+
+// NewDatabases 创建一组逻辑数据库
+//
+// 参数说明：
+//   - db0: 0 号数据库
+//   - count: 数据库数量，<= 0 时使用 DefaultDatabaseCount
+//
+// 返回值：
+//   - *Databases: 逻辑数据库集合
+//
+// 注意事项：
+//   - 应在 db0 设置键哈希器（SetKeyHasher）之后调用，其余数据库沿用同一个键哈希器
+func NewDatabases(db0 *ShardedMap, count int) *Databases {
+	if count <= 0 {
+		count = DefaultDatabaseCount
+	}
+
+	dbs := make([]*ShardedMap, count)
+	dbs[0] = db0
+
+	return &Databases{
+		dbs:    dbs,
+		hasher: db0.hasher,
+	}
+}
+
+// Count 返回逻辑数据库的数量
+func (d *Databases) Count() int {
+	return len(d.dbs)
+}
+
+// DB 返回指定编号的逻辑数据库
+//
+// 参数说明：
+//   - index: 数据库编号（0 到 Count()-1）
+//
+// 返回值：
+//   - *ShardedMap: 逻辑数据库，编号超出范围时返回 nil
+func (d *Databases) DB(index int) *ShardedMap {
+	if index < 0 || index >= len(d.dbs) {
+		return nil
+	}
+
+	d.mu.RLock()
+	sm := d.dbs[index]
+	d.mu.RUnlock()
+	if sm != nil {
+		return sm
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.getOrCreate(index)
+}
+
+// getOrCreate 返回指定编号的数据库，尚未创建时创建（调用方必须持有写锁）
+func (d *Databases) getOrCreate(index int) *ShardedMap {
+	if d.dbs[index] == nil {
+		sm := NewShardedMap(lazyDatabaseCapacity)
+		sm.SetKeyHasher(d.hasher)
+		d.dbs[index] = sm
+		d.installKeyEventHandler(index)
+	}
+	return d.dbs[index]
+}
+
+// SetKeyEventHandler 为全部逻辑数据库设置键空间事件回调
+//
+// 参数说明：
+//   - types: 要通知的事件类型
+//   - handler: 事件回调，db 为事件所在的数据库编号；nil 表示取消回调
+//
+// 示例：
+//
+//	dbs.SetKeyEventHandler(KeyEventExpired, func(db int, event KeyEvent) {
+//	    log.Printf("[INFO] 会话过期: db=%d key=%s", db, event.Key)
+//	})
+//
+// 注意事项：
+//   - 之后按需创建的数据库同样使用该回调
+//   - SWAPDB 之后事件按数据库的新编号通知
+//   - 回调的调用方式与 ShardedMap.SetKeyEventHandler 相同，不能访问事件所在的数据库
+func (d *Databases) SetKeyEventHandler(types KeyEventType, handler func(db int, event KeyEvent)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.eventTypes = types
+	d.eventHandler = handler
+	for index, sm := range d.dbs {
+		if sm != nil {
+			d.installKeyEventHandler(index)
+		}
+	}
+}
+
+// installKeyEventHandler 为指定编号的数据库设置事件回调（调用方必须持有写锁）
+func (d *Databases) installKeyEventHandler(index int) {
+	handler := d.eventHandler
+	if handler == nil {
+		d.dbs[index].SetKeyEventHandler(0, nil)
+		return
+	}
+
+	d.dbs[index].SetKeyEventHandler(d.eventTypes, func(event KeyEvent) {
+		handler(index, event)
+	})
+}
+
+// Each 按编号顺序遍历已创建的逻辑数据库
+//
+// 尚未创建的数据库一定是空的，不会被遍历。
+func (d *Databases) Each(fn func(index int, sm *ShardedMap)) {
+	d.mu.RLock()
+	dbs := make([]*ShardedMap, len(d.dbs))
+	copy(dbs, d.dbs)
+	d.mu.RUnlock()
+
+	for index, sm := range dbs {
+		if sm != nil {
+			fn(index, sm)
+		}
+	}
+}
+
+// Swap 交换两个逻辑数据库的内容
+//
+// 参数说明：
+//   - i, j: 要交换的数据库编号
+//
+// 返回值：
+//   - error: 编号超出范围时返回错误
+//
+// 注意事项：
+//   - 交换只是交换两个下标对应的 ShardedMap，时间复杂度 O(1)
+//   - 之后通过 DB(i) 访问的是原来 j 号数据库的数据
+func (d *Databases) Swap(i, j int) error {
+	if err := d.checkIndex(i); err != nil {
+		return err
+	}
+	if err := d.checkIndex(j); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.dbs[i], d.dbs[j] = d.dbs[j], d.dbs[i]
+	for _, index := range []int{i, j} {
+		if d.dbs[index] != nil {
+			d.installKeyEventHandler(index)
+		}
+	}
+	return nil
+}
+
+// Move 将键从一个逻辑数据库原子地移动到另一个逻辑数据库
+//
+// 参数说明：
+//   - key: 要移动的键
+//   - from: 源数据库编号
+//   - to: 目标数据库编号
+//
+// 返回值：
+//   - bool: 是否移动成功；源数据库中不存在该键或目标数据库中已存在该键时返回 false
+//
+// 注意事项：
+//   - 值和过期时间原样保留
+//   - 源数据库通知 KeyEventDel，目标数据库通知 KeyEventSet
+//   - 执行期间持有数据库集合的写锁，其他连接的命令会短暂等待
+//   - from 与 to 相同或编号超出范围时 panic，调用方应先校验
+func (d *Databases) Move(key string, from, to int) bool {
+	if from == to || d.checkIndex(from) != nil || d.checkIndex(to) != nil {
+		panic(fmt.Sprintf("storage: 无效的 Move 数据库编号 %d -> %d", from, to))
+	}
+
+	// 持有写锁期间不会发生 Swap，也不会有其他 Move 同时持有两个分片锁
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	src := d.getOrCreate(from)
+	dst := d.getOrCreate(to)
+
+	key = src.storageKey(key)
+	srcShard := src.getShard(key)
+	dstShard := dst.getShard(key)
+
+	srcShard.mu.Lock()
+	defer srcShard.mu.Unlock()
+	dstShard.mu.Lock()
+	defer dstShard.mu.Unlock()
+
+	it := liveItem(srcShard, key)
+	if it == nil {
+		return false
+	}
+	if liveItem(dstShard, key) != nil {
+		return false
+	}
+
+	srcShard.remove(key)
+	srcShard.events.notify(KeyEventDel, key)
+	it.version = nextVersion()
+	dstShard.put(key, it)
+	dstShard.events.notify(KeyEventSet, key)
+	return true
+}
+
+// checkIndex 校验数据库编号
+func (d *Databases) checkIndex(index int) error {
+	if index < 0 || index >= len(d.dbs) {
+		return fmt.Errorf("数据库编号 %d 超出范围 [0, %d)", index, len(d.dbs))
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestDatabases_DB 测试逻辑数据库相互隔离和编号校验
+func TestDatabases_DB(t *testing.T) {
+	db0 := NewShardedMap(1024)
+	dbs := NewDatabases(db0, 4)
+
+	if dbs.Count() != 4 {
+		t.Errorf("Expected 4 databases, got %d", dbs.Count())
+	}
+	if dbs.DB(0) != db0 {
+		t.Error("Expected DB(0) to be the map passed to NewDatabases")
+	}
+	if dbs.DB(-1) != nil || dbs.DB(4) != nil {
+		t.Error("Expected nil for out of range index")
+	}
+	if dbs.DB(1) != dbs.DB(1) {
+		t.Error("Expected DB(1) to return the same map on every call")
+	}
+
+	dbs.DB(1).Set("oauth:token:1", "a", 0)
+	if _, found := dbs.DB(0).Get("oauth:token:1"); found {
+		t.Error("Expected key set in db1 to be invisible in db0")
+	}
+	if value, _ := dbs.DB(1).Get("oauth:token:1"); value != "a" {
+		t.Errorf("Expected 'a' in db1, got %v", value)
+	}
+
+	if NewDatabases(db0, 0).Count() != DefaultDatabaseCount {
+		t.Errorf("Expected default database count %d", DefaultDatabaseCount)
+	}
+}
+
+// TestDatabases_Each 测试只遍历已创建的逻辑数据库
+func TestDatabases_Each(t *testing.T) {
+	dbs := NewDatabases(NewShardedMap(1024), 16)
+	dbs.DB(3)
+
+	var indexes []int
+	dbs.Each(func(index int, sm *ShardedMap) {
+		indexes = append(indexes, index)
+	})
+
+	if len(indexes) != 2 || indexes[0] != 0 || indexes[1] != 3 {
+		t.Errorf("Expected [0 3], got %v", indexes)
+	}
+}
+
+// TestDatabases_KeyHasher 测试按需创建的逻辑数据库沿用 0 号数据库的键哈希器
+func TestDatabases_KeyHasher(t *testing.T) {
+	hasher, err := NewKeyHasher(KeyHashHMACSHA256, testKeyHashSecret, []string{"oauth:token:"})
+	if err != nil {
+		t.Fatalf("NewKeyHasher failed: %v", err)
+	}
+
+	db0 := NewShardedMap(1024)
+	db0.SetKeyHasher(hasher)
+	dbs := NewDatabases(db0, 4)
+
+	db2 := dbs.DB(2)
+	db2.Set("oauth:token:abc", "a", 0)
+	for _, key := range db2.GetShardForIndex(int(shardIndex(hasher.Hash("oauth:token:abc")))).GetAllKeys() {
+		if key == "oauth:token:abc" {
+			t.Error("Expected key to be stored hashed in db2")
+		}
+	}
+	if value, _ := db2.Get("oauth:token:abc"); value != "a" {
+		t.Errorf("Expected 'a', got %v", value)
+	}
+}
+
+// TestDatabases_Swap 测试交换逻辑数据库
+func TestDatabases_Swap(t *testing.T) {
+	dbs := NewDatabases(NewShardedMap(1024), 4)
+	dbs.DB(0).Set("k", "zero", 0)
+	dbs.DB(1).Set("k", "one", 0)
+
+	if err := dbs.Swap(0, 1); err != nil {
+		t.Fatalf("Swap failed: %v", err)
+	}
+
+	if value, _ := dbs.DB(0).Get("k"); value != "one" {
+		t.Errorf("Expected 'one' in db0 after swap, got %v", value)
+	}
+	if value, _ := dbs.DB(1).Get("k"); value != "zero" {
+		t.Errorf("Expected 'zero' in db1 after swap, got %v", value)
+	}
+
+	if err := dbs.Swap(0, 4); err == nil {
+		t.Error("Expected error for out of range index")
+	}
+	if err := dbs.Swap(2, 2); err != nil {
+		t.Errorf("Expected swapping a database with itself to succeed, got %v", err)
+	}
+}
+
+// TestDatabases_Move 测试在逻辑数据库之间移动键
+func TestDatabases_Move(t *testing.T) {
+	dbs := NewDatabases(NewShardedMap(1024), 4)
+	src, dst := dbs.DB(0), dbs.DB(1)
+
+	src.Set("session:1", "s1", 100)
+	if !dbs.Move("session:1", 0, 1) {
+		t.Fatal("Expected Move to succeed")
+	}
+	if src.Exists("session:1") {
+		t.Error("Expected key to be removed from source")
+	}
+	if value, _ := dst.Get("session:1"); value != "s1" {
+		t.Errorf("Expected 's1' in destination, got %v", value)
+	}
+	if ttl := TTL(dst, "session:1"); ttl < 99 || ttl > 100 {
+		t.Errorf("Expected TTL to be kept, got %d", ttl)
+	}
+
+	// 源数据库中不存在
+	if dbs.Move("session:1", 0, 1) {
+		t.Error("Expected Move of missing key to fail")
+	}
+
+	// 目标数据库中已存在
+	src.Set("session:2", "new", 0)
+	dst.Set("session:2", "old", 0)
+	if dbs.Move("session:2", 0, 1) {
+		t.Error("Expected Move to fail when destination has the key")
+	}
+	if value, _ := dst.Get("session:2"); value != "old" {
+		t.Errorf("Expected destination value to be unchanged, got %v", value)
+	}
+
+	// 目标数据库中的同名键已过期
+	src.Set("session:3", "new", 0)
+	dst.SetWithOptions("session:3", "expired", SetOptions{ExpiresAt: time.Now().UnixMilli() + 10})
+	time.Sleep(20 * time.Millisecond)
+	if !dbs.Move("session:3", 0, 1) {
+		t.Error("Expected Move to succeed when destination key has expired")
+	}
+}
+
+// TestDatabases_MoveConcurrent 测试并发的反向移动不会死锁，也不会丢失键
+func TestDatabases_MoveConcurrent(t *testing.T) {
+	dbs := NewDatabases(NewShardedMap(1024), 2)
+	dbs.DB(0).Set("k", "v", 0)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				dbs.Move("k", g%2, 1-g%2)
+				if i%100 == 0 {
+					dbs.Swap(0, 1)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if dbs.DB(0).Len()+dbs.DB(1).Len() != 1 {
+		t.Errorf("Expected exactly one copy of the key, got %d", dbs.DB(0).Len()+dbs.DB(1).Len())
+	}
+}
+
+// TestTTLManager_CleanupAllDatabases 测试 TTL 管理器清理全部逻辑数据库
+func TestTTLManager_CleanupAllDatabases(t *testing.T) {
+	dbs := NewDatabases(NewShardedMap(1024), 16)
+	ttlMgr := NewDatabasesTTLManager(dbs, &TTLManagerConfig{
+		CleanupInterval: time.Hour,
+		KeysPerScan:     DefaultShardCount * 10,
+	})
+
+	expiresAt := time.Now().UnixMilli() + 10
+	for _, index := range []int{0, 5, 15} {
+		dbs.DB(index).SetWithOptions("expiring", "v", SetOptions{ExpiresAt: expiresAt})
+		dbs.DB(index).Set("permanent", "v", 0)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	ttlMgr.cleanup()
+
+	for _, index := range []int{0, 5, 15} {
+		if n := dbs.DB(index).Len(); n != 1 {
+			t.Errorf("Expected 1 key left in db%d, got %d", index, n)
+		}
+	}
+}
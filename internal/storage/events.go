@@ -0,0 +1,106 @@
+package storage
+
+import "sync/atomic"
+
+// KeyEventType 键空间事件类型，可按位组合用于选择要通知的事件
+type KeyEventType uint32
+
+const (
+	// KeyEventSet 写入了键的值：SET 系列命令（SET、SETNX、SETEX、GETSET、MSET、MSETNX、CAS）、
+	// INCRBY、INCRBYFLOAT、APPEND，锁、失败记录、验证码、WebAuthn challenge 和限流状态的写入，
+	// 以及 MOVE 的目标数据库
+	KeyEventSet KeyEventType = 1 << iota
+
+	// KeyEventDel 键被删除：DEL、GETDEL、释放锁、清除失败记录、消费验证码或 challenge、
+	// MOVE 的源数据库、FLUSHDB/FLUSHALL，或被写入已经过去的过期时间
+	KeyEventDel
+
+	// KeyEventExpired 键因过期被删除（访问时的惰性删除或 TTLManager 的定期清理）
+	KeyEventExpired
+
+	// KeyEventEvicted 键因内存淘汰被删除
+	//
+	// 当前存储引擎没有淘汰策略，该事件为后续的淘汰实现保留，不会触发。
+	KeyEventEvicted
+
+	// KeyEventExpire 键的过期时间被 EXPIRE 修改
+	KeyEventExpire
+
+	// KeyEventAll 全部事件类型
+	KeyEventAll = KeyEventSet | KeyEventDel | KeyEventExpired | KeyEventEvicted | KeyEventExpire
+)
+
+// String 返回事件名，与 Redis 键空间通知中的事件名相同（如 "expired"）
+func (t KeyEventType) String() string {
+	switch t {
+	case KeyEventSet:
+		return "set"
+	case KeyEventDel:
+		return "del"
+	case KeyEventExpired:
+		return "expired"
+	case KeyEventEvicted:
+		return "evicted"
+	case KeyEventExpire:
+		return "expire"
+	default:
+		return "unknown"
+	}
+}
+
+// KeyEvent 一次键空间事件
+type KeyEvent struct {
+	Type KeyEventType // 事件类型（单个类型）
+	Key  string       // 存储键，启用键哈希（SetKeyHasher）且键匹配哈希前缀时为哈希后的键
+}
+
+// KeyEventHandler 键空间事件回调
+type KeyEventHandler func(event KeyEvent)
+
+// keyEventHook 已设置的事件回调及其选择的事件类型
+type keyEventHook struct {
+	types   KeyEventType
+	handler KeyEventHandler
+}
+
+// keyEvents 同一个 ShardedMap 的全部分片共享的事件回调
+type keyEvents struct {
+	hook atomic.Pointer[keyEventHook]
+}
+
+// notify 在回调选择了该事件类型时调用回调
+func (e *keyEvents) notify(eventType KeyEventType, key string) {
+	if hook := e.hook.Load(); hook != nil && hook.types&eventType != 0 {
+		hook.handler(KeyEvent{Type: eventType, Key: key})
+	}
+}
+
+// selects 返回当前回调是否选择了该事件类型
+func (e *keyEvents) selects(eventType KeyEventType) bool {
+	hook := e.hook.Load()
+	return hook != nil && hook.types&eventType != 0
+}
+
+// SetKeyEventHandler 设置键空间事件回调
+//
+// 参数说明：
+//   - types: 要通知的事件类型，如 KeyEventExpired | KeyEventDel
+//   - handler: 事件回调，nil 表示取消回调
+//
+// 示例：
+//
+//	sm.SetKeyEventHandler(KeyEventExpired, func(event KeyEvent) {
+//	    auditCh <- event // 在其他 Goroutine 中发送审计事件或 SAML LogoutRequest
+//	})
+//
+// 注意事项：
+//   - 回调在持有键所在分片的写锁时同步调用，同一个键的事件按发生顺序到达
+//   - 回调不能访问同一个 ShardedMap（会死锁），耗时操作应转交其他 Goroutine
+//   - 每个 ShardedMap 只有一个回调，重复调用会替换之前的回调
+func (sm *ShardedMap) SetKeyEventHandler(types KeyEventType, handler KeyEventHandler) {
+	if handler == nil || types&KeyEventAll == 0 {
+		sm.events.hook.Store(nil)
+		return
+	}
+	sm.events.hook.Store(&keyEventHook{types: types, handler: handler})
+}
@@ -0,0 +1,231 @@
+package storage
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// eventRecorder 记录收到的键空间事件
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *eventRecorder) record(db int, event KeyEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, fmt.Sprintf("%d:%s:%s", db, event.Type, event.Key))
+}
+
+func (r *eventRecorder) take() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := r.events
+	r.events = nil
+	return events
+}
+
+// expectEvents 比较记录的事件与期望值
+func expectEvents(t *testing.T, r *eventRecorder, expected ...string) {
+	t.Helper()
+	if got := r.take(); fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("Expected events %v, got %v", expected, got)
+	}
+}
+
+// TestShardedMap_KeyEvents 测试写入、删除和过期事件
+func TestShardedMap_KeyEvents(t *testing.T) {
+	sm := NewShardedMap(1024)
+	recorder := &eventRecorder{}
+	sm.SetKeyEventHandler(KeyEventAll, func(event KeyEvent) {
+		recorder.record(0, event)
+	})
+
+	sm.Set("session:1", "alice", 0)
+	sm.SetNX("session:1", "bob", 0)
+	sm.MSet([]KeyValue{{Key: "session:2", Value: "bob"}})
+	expectEvents(t, recorder, "0:set:session:1", "0:set:session:2")
+
+	sm.Delete("session:1")
+	sm.Delete("session:1")
+	sm.GetDel("session:2")
+	expectEvents(t, recorder, "0:del:session:1", "0:del:session:2")
+
+	// 访问时的惰性删除
+	sm.Set("session:3", "carol", 1)
+	recorder.take()
+	time.Sleep(1100 * time.Millisecond)
+	if _, found := sm.Get("session:3"); found {
+		t.Fatal("Expected session:3 to be expired")
+	}
+	sm.Get("session:3")
+	expectEvents(t, recorder, "0:expired:session:3")
+}
+
+// TestShardedMap_KeyEventMutators 测试每个写入或删除键的方法都会通知事件
+func TestShardedMap_KeyEventMutators(t *testing.T) {
+	policy := LockoutPolicy{Window: time.Minute, Tiers: []LockoutTier{{Threshold: 3, Duration: time.Minute}}}
+	challenge := WebAuthnChallenge{Challenge: "c", Ceremony: CeremonyAuthentication, RPID: "example.com"}
+
+	tests := []struct {
+		name     string
+		setup    func(sm *ShardedMap)
+		mutate   func(sm *ShardedMap)
+		expected []string
+	}{
+		{"IncrBy", nil, func(sm *ShardedMap) {
+			sm.IncrBy("k", 1)
+			sm.IncrBy("k", 1)
+		}, []string{"0:set:k", "0:set:k"}},
+		{"IncrByFloat", nil, func(sm *ShardedMap) {
+			sm.IncrByFloat("k", 1.5)
+			sm.IncrByFloat("k", 1.5)
+		}, []string{"0:set:k", "0:set:k"}},
+		{"Append", nil, func(sm *ShardedMap) {
+			sm.Append("k", []byte("a"))
+			sm.Append("k", []byte("b"))
+		}, []string{"0:set:k", "0:set:k"}},
+		{"Expire", func(sm *ShardedMap) { sm.Set("k", "v", 0) }, func(sm *ShardedMap) {
+			Expire(sm, "k", 60)
+			Expire(sm, "missing", 60)
+		}, []string{"0:expire:k"}},
+		{"AcquireLock", nil, func(sm *ShardedMap) {
+			sm.AcquireLock("k", "owner-a", 1000)
+			sm.AcquireLock("k", "owner-b", 1000)
+		}, []string{"0:set:k"}},
+		{"ExtendLock", func(sm *ShardedMap) { sm.AcquireLock("k", "owner-a", 1000) }, func(sm *ShardedMap) {
+			sm.ExtendLock("k", "owner-b", 1000)
+			sm.ExtendLock("k", "owner-a", 1000)
+		}, []string{"0:set:k"}},
+		{"ReleaseLock", func(sm *ShardedMap) { sm.AcquireLock("k", "owner-a", 1000) }, func(sm *ShardedMap) {
+			sm.ReleaseLock("k", "owner-b")
+			sm.ReleaseLock("k", "owner-a")
+		}, []string{"0:del:k"}},
+		{"RecordFailure", nil, func(sm *ShardedMap) {
+			sm.RecordFailure("k", policy)
+		}, []string{"0:set:k"}},
+		{"ResetLockout", func(sm *ShardedMap) { sm.RecordFailure("k", policy) }, func(sm *ShardedMap) {
+			sm.ResetLockout("k")
+			sm.ResetLockout("k")
+		}, []string{"0:del:k"}},
+		{"SetOTP", nil, func(sm *ShardedMap) {
+			sm.SetOTP("k", "123456", 60, 3)
+		}, []string{"0:set:k"}},
+		{"VerifyOTP", func(sm *ShardedMap) { sm.SetOTP("k", "123456", 60, 3) }, func(sm *ShardedMap) {
+			sm.VerifyOTP("k", "000000")
+			sm.VerifyOTP("k", "123456")
+		}, []string{"0:set:k", "0:del:k"}},
+		{"PutChallenge", nil, func(sm *ShardedMap) {
+			sm.PutChallenge("k", challenge, 60)
+			sm.PutChallenge("k", challenge, 60)
+		}, []string{"0:set:k"}},
+		{"ConsumeChallenge", func(sm *ShardedMap) { sm.PutChallenge("k", challenge, 60) }, func(sm *ShardedMap) {
+			sm.ConsumeChallenge("k", CeremonyAuthentication, "example.com")
+			sm.ConsumeChallenge("k", CeremonyAuthentication, "example.com")
+		}, []string{"0:del:k"}},
+		{"ThrottleGCRA", nil, func(sm *ShardedMap) {
+			sm.ThrottleGCRA("k", GCRALimit{Burst: 1, Count: 1, Period: time.Second}, 1)
+		}, []string{"0:set:k"}},
+		{"ThrottleSlidingLog", nil, func(sm *ShardedMap) {
+			sm.ThrottleSlidingLog("k", 10, time.Second, 1)
+		}, []string{"0:set:k"}},
+		{"ThrottleSlidingCounter", nil, func(sm *ShardedMap) {
+			sm.ThrottleSlidingCounter("k", 10, time.Second, 1)
+		}, []string{"0:set:k"}},
+		{"Clear", func(sm *ShardedMap) { sm.Set("k", "v", 0) }, func(sm *ShardedMap) {
+			sm.Clear()
+		}, []string{"0:del:k"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := NewShardedMap(1024)
+			if tt.setup != nil {
+				tt.setup(sm)
+			}
+			recorder := &eventRecorder{}
+			sm.SetKeyEventHandler(KeyEventAll, func(event KeyEvent) {
+				recorder.record(0, event)
+			})
+
+			tt.mutate(sm)
+			expectEvents(t, recorder, tt.expected...)
+		})
+	}
+}
+
+// TestShardedMap_KeyEventTypes 测试只通知选择的事件类型
+func TestShardedMap_KeyEventTypes(t *testing.T) {
+	sm := NewShardedMap(1024)
+	recorder := &eventRecorder{}
+	sm.SetKeyEventHandler(KeyEventExpired, func(event KeyEvent) {
+		recorder.record(0, event)
+	})
+
+	sm.Set("session:1", "alice", 0)
+	sm.Delete("session:1")
+	sm.SetWithOptions("session:2", "bob", SetOptions{ExpiresAt: time.Now().UnixMilli() - 1})
+	expectEvents(t, recorder)
+
+	// 取消回调后不再通知
+	sm.SetKeyEventHandler(KeyEventAll, nil)
+	sm.Set("session:3", "carol", 0)
+	expectEvents(t, recorder)
+}
+
+// TestTTLManager_ExpiredEvents 测试定期清理触发过期事件
+func TestTTLManager_ExpiredEvents(t *testing.T) {
+	sm := NewShardedMap(1024)
+	recorder := &eventRecorder{}
+	sm.SetKeyEventHandler(KeyEventExpired, func(event KeyEvent) {
+		recorder.record(0, event)
+	})
+
+	sm.Set("session:1", "alice", 1)
+
+	ttlMgr := NewTTLManager(sm, &TTLManagerConfig{
+		CleanupInterval: 100 * time.Millisecond,
+		KeysPerScan:     DefaultShardCount,
+	})
+	ttlMgr.Start()
+	defer ttlMgr.Stop()
+
+	time.Sleep(1300 * time.Millisecond)
+
+	expectEvents(t, recorder, "0:expired:session:1")
+	if sm.Len() != 0 {
+		t.Errorf("Expected expired key to be cleaned up, got %d keys", sm.Len())
+	}
+}
+
+// TestDatabases_KeyEvents 测试逻辑数据库的事件带有数据库编号，SWAPDB 后使用新编号
+func TestDatabases_KeyEvents(t *testing.T) {
+	dbs := NewDatabases(NewShardedMap(1024), 16)
+	recorder := &eventRecorder{}
+	dbs.SetKeyEventHandler(KeyEventSet|KeyEventDel, recorder.record)
+
+	dbs.DB(0).Set("session:1", "alice", 0)
+	dbs.DB(3).Set("session:1", "bob", 0)
+	expectEvents(t, recorder, "0:set:session:1", "3:set:session:1")
+
+	if err := dbs.Swap(0, 3); err != nil {
+		t.Fatalf("Swap failed: %v", err)
+	}
+	dbs.DB(0).Delete("session:1")
+	dbs.DB(3).Delete("session:1")
+	expectEvents(t, recorder, "0:del:session:1", "3:del:session:1")
+
+	// MOVE 在源数据库通知 del，在目标数据库通知 set
+	dbs.DB(0).Set("session:2", "dave", 0)
+	recorder.take()
+	if !dbs.Move("session:2", 0, 7) {
+		t.Fatal("Move failed")
+	}
+	expectEvents(t, recorder, "0:del:session:2", "7:set:session:2")
+
+	dbs.SetKeyEventHandler(0, nil)
+	dbs.DB(5).Set("session:1", "carol", 0)
+	expectEvents(t, recorder)
+}
@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"sort"
+	"strings"
+
+	"github.com/tjfoc/gmsm/sm3"
+)
+
+// 键哈希算法
+const (
+	// KeyHashHMACSHA256 使用 HMAC-SHA256 哈希键
+	KeyHashHMACSHA256 = "hmac-sha256"
+
+	// KeyHashHMACSM3 使用 HMAC-SM3 哈希键（国密）
+	KeyHashHMACSM3 = "hmac-sm3"
+)
+
+// MinKeyHashSecretLength 是键哈希密钥的最小长度（字节）
+const MinKeyHashSecretLength = 16
+
+var (
+	// ErrKeyHashSecretTooShort 键哈希密钥过短
+	ErrKeyHashSecretTooShort = errors.New("key hash secret too short")
+
+	// ErrKeyHashNoPrefix 未配置需要哈希的键前缀
+	ErrKeyHashNoPrefix = errors.New("key hash prefix required")
+)
+
+// KeyHasher 将指定前缀的键替换为带密钥的哈希值后再存储
+//
+// 对于 Bearer Token 一类的键，键本身就是凭证。启用 KeyHasher 后，
+// 分片中只保存 prefix + hex(HMAC(secret, key))，KEYS 列表、快照或内存转储
+// 中都不再出现原始令牌；由于使用带密钥的 HMAC，没有密钥也无法通过枚举还原令牌。
+// 查询时对传入的键做同样的哈希，因此对调用方透明。
+//
+// 示例：
+//
+//	kh, _ := NewKeyHasher(KeyHashHMACSHA256, secret, []string{"oauth:token:"})
+//	kh.Hash("oauth:token:abc123") // "oauth:token:9c56cc51..."
+//	kh.Hash("session:xyz")        // "session:xyz"（未匹配前缀，原样返回）
+//
+// 注意事项：
+//   - 更换密钥后，已存储的键将无法再被查询到
+//   - 哈希后的键不能反向用于查询（会被再次哈希）
+type KeyHasher struct {
+	prefixes []string
+	secret   []byte
+	newHash  func() hash.Hash
+}
+
+// NewKeyHasher 创建一个新的键哈希器
+//
+// 参数说明：
+//   - algorithm: 哈希算法（hmac-sha256 | hmac-sm3）
+//   - secret: HMAC 密钥，至少 16 字节
+//   - prefixes: 需要哈希的键前缀列表，不能为空字符串
+//
+// 返回值：
+//   - *KeyHasher: 键哈希器实例
+//   - error: 参数无效时的错误信息
+func NewKeyHasher(algorithm string, secret []byte, prefixes []string) (*KeyHasher, error) {
+	var newHash func() hash.Hash
+	switch algorithm {
+	case KeyHashHMACSHA256, "":
+		newHash = sha256.New
+	case KeyHashHMACSM3:
+		newHash = sm3.New
+	default:
+		return nil, fmt.Errorf("不支持的键哈希算法: %s", algorithm)
+	}
+
+	if len(secret) < MinKeyHashSecretLength {
+		return nil, ErrKeyHashSecretTooShort
+	}
+
+	if len(prefixes) == 0 {
+		return nil, ErrKeyHashNoPrefix
+	}
+	for _, prefix := range prefixes {
+		if prefix == "" {
+			return nil, ErrKeyHashNoPrefix
+		}
+	}
+
+	// 按长度降序排列，优先匹配最长的前缀
+	sorted := append([]string(nil), prefixes...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return len(sorted[i]) > len(sorted[j])
+	})
+
+	return &KeyHasher{
+		prefixes: sorted,
+		secret:   append([]byte(nil), secret...),
+		newHash:  newHash,
+	}, nil
+}
+
+// Hash 返回键在分片中实际存储的形式
+//
+// 匹配前缀的键返回 prefix + hex(HMAC(secret, key))，否则原样返回。
+func (kh *KeyHasher) Hash(key string) string {
+	for _, prefix := range kh.prefixes {
+		if strings.HasPrefix(key, prefix) {
+			mac := hmac.New(kh.newHash, kh.secret)
+			mac.Write([]byte(key))
+			return prefix + hex.EncodeToString(mac.Sum(nil))
+		}
+	}
+	return key
+}
+
+// mac 使用键哈希的算法和密钥计算 HMAC(secret, label || data...)
+//
+// label 区分不同用途（如 "otp"），使同一个密钥在不同用途中得到互不相关的结果。
+func (kh *KeyHasher) mac(label string, data ...[]byte) []byte {
+	m := hmac.New(kh.newHash, kh.secret)
+	m.Write([]byte(label))
+	m.Write([]byte{0})
+	for _, d := range data {
+		m.Write(d)
+	}
+	return m.Sum(nil)
+}
+
+// Prefixes 返回需要哈希的键前缀列表
+func (kh *KeyHasher) Prefixes() []string {
+	return append([]string(nil), kh.prefixes...)
+}
@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+var testKeyHashSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// TestNewKeyHasher_Invalid 测试无效的键哈希器参数
+func TestNewKeyHasher_Invalid(t *testing.T) {
+	if _, err := NewKeyHasher("md5", testKeyHashSecret, []string{"t:"}); err == nil {
+		t.Error("Expected error for unknown algorithm")
+	}
+	if _, err := NewKeyHasher(KeyHashHMACSHA256, []byte("short"), []string{"t:"}); err != ErrKeyHashSecretTooShort {
+		t.Errorf("Expected ErrKeyHashSecretTooShort, got %v", err)
+	}
+	if _, err := NewKeyHasher(KeyHashHMACSHA256, testKeyHashSecret, nil); err != ErrKeyHashNoPrefix {
+		t.Errorf("Expected ErrKeyHashNoPrefix, got %v", err)
+	}
+	if _, err := NewKeyHasher(KeyHashHMACSHA256, testKeyHashSecret, []string{""}); err != ErrKeyHashNoPrefix {
+		t.Errorf("Expected ErrKeyHashNoPrefix for empty prefix, got %v", err)
+	}
+}
+
+// TestKeyHasher_Hash 测试键哈希的格式和确定性
+func TestKeyHasher_Hash(t *testing.T) {
+	for _, algorithm := range []string{KeyHashHMACSHA256, KeyHashHMACSM3} {
+		kh, err := NewKeyHasher(algorithm, testKeyHashSecret, []string{"oauth:", "oauth:token:"})
+		if err != nil {
+			t.Fatalf("Failed to create key hasher (%s): %v", algorithm, err)
+		}
+
+		hashed := kh.Hash("oauth:token:abc123")
+		if !strings.HasPrefix(hashed, "oauth:token:") {
+			t.Errorf("%s: expected longest prefix to be kept, got %s", algorithm, hashed)
+		}
+		if len(hashed) != len("oauth:token:")+64 {
+			t.Errorf("%s: expected 64 hex chars after prefix, got %s", algorithm, hashed)
+		}
+		if strings.Contains(hashed, "abc123") {
+			t.Errorf("%s: hashed key must not contain the original token", algorithm)
+		}
+		if kh.Hash("oauth:token:abc123") != hashed {
+			t.Errorf("%s: hash is not deterministic", algorithm)
+		}
+		if kh.Hash("session:abc123") != "session:abc123" {
+			t.Errorf("%s: keys without a configured prefix must be stored verbatim", algorithm)
+		}
+	}
+
+	other, _ := NewKeyHasher(KeyHashHMACSHA256, []byte("another-secret-0123456789"), []string{"oauth:token:"})
+	sha, _ := NewKeyHasher(KeyHashHMACSHA256, testKeyHashSecret, []string{"oauth:token:"})
+	if other.Hash("oauth:token:abc123") == sha.Hash("oauth:token:abc123") {
+		t.Error("Expected different secrets to produce different hashes")
+	}
+}
+
+// TestShardedMap_KeyHasher 测试启用键哈希后的读写、删除、TTL 和键列表
+func TestShardedMap_KeyHasher(t *testing.T) {
+	kh, err := NewKeyHasher(KeyHashHMACSHA256, testKeyHashSecret, []string{"oauth:token:"})
+	if err != nil {
+		t.Fatalf("Failed to create key hasher: %v", err)
+	}
+
+	sm := NewShardedMap(16)
+	sm.SetKeyHasher(kh)
+
+	sm.Set("oauth:token:secret-bearer", "payload", 3600)
+	sm.Set("session:plain", "value", 0)
+
+	if value, found := sm.Get("oauth:token:secret-bearer"); !found || value != "payload" {
+		t.Errorf("Expected transparent lookup, got %v, %v", value, found)
+	}
+	if ttl := TTL(sm, "oauth:token:secret-bearer"); ttl <= 0 || ttl > 3600 {
+		t.Errorf("Expected TTL in (0, 3600], got %d", ttl)
+	}
+	if !Expire(sm, "oauth:token:secret-bearer", 60) || TTL(sm, "oauth:token:secret-bearer") > 60 {
+		t.Error("Expected Expire to update the hashed key")
+	}
+	if sm.SetNX("oauth:token:secret-bearer", "other", 0) {
+		t.Error("Expected SetNX to see the existing hashed key")
+	}
+
+	hashed := kh.Hash("oauth:token:secret-bearer")
+	var keys []string
+	for i := 0; i < DefaultShardCount; i++ {
+		keys = append(keys, sm.GetShardForIndex(i).GetAllKeys()...)
+	}
+	for _, key := range keys {
+		if strings.Contains(key, "secret-bearer") {
+			t.Errorf("Key listing leaked the bearer token: %s", key)
+		}
+	}
+	if len(keys) != 2 {
+		t.Errorf("Expected 2 keys, got %v", keys)
+	}
+
+	// 哈希后的标识不能再用于查询
+	if sm.Exists(hashed) {
+		t.Error("Hashed identifier must not be usable as a lookup key")
+	}
+
+	if !sm.Delete("oauth:token:secret-bearer") {
+		t.Error("Expected Delete to remove the hashed key")
+	}
+	if sm.Exists("oauth:token:secret-bearer") || sm.Len() != 1 {
+		t.Error("Expected only the plain key to remain")
+	}
+}
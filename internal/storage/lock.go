@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotLock 键存在但保存的不是分布式锁
+var ErrNotLock = errors.New("key does not hold a lock")
+
+// Lock 分布式锁的状态
+type Lock struct {
+	Owner     string // 持有者标识，由调用方生成（如实例 ID + 随机数）
+	Token     uint64 // fencing token，每次获取锁时分配，单调递增
+	ExpiresAt int64  // 过期时间（Unix 毫秒）
+}
+
+// lockValue 存储在键中的锁
+//
+// String 返回持有者标识，GET 锁的键时返回持有者。
+type lockValue struct {
+	owner string
+	token uint64
+}
+
+// AcquireLock 获取分布式锁
+//
+// 参数说明：
+//   - key: 锁的键
+//   - owner: 持有者标识，释放和续期时必须提供相同的标识
+//   - ttl: 锁的有效期（毫秒），必须大于 0
+//
+// 返回值：
+//   - uint64: 获取成功时为 fencing token，失败时为 0
+//   - bool: 是否获取成功，锁已被持有（包括被同一持有者持有）时返回 false
+//   - error: 键存在但不是锁时返回 ErrNotLock
+//
+// 示例：
+//
+//	token, ok, err := sm.AcquireLock("lock:refresh:alice", instanceID, 5000)
+//	if err == nil && ok {
+//	    defer sm.ReleaseLock("lock:refresh:alice", instanceID)
+//	    // 写入下游存储时携带 token，下游拒绝小于已见最大值的 token
+//	}
+//
+// 注意事项：
+//   - fencing token 即获取锁时分配的写入版本号，全局单调递增，
+//     锁过期后被其他持有者获取时得到的 token 一定更大
+//   - 计数器在进程启动时以当前 Unix 微秒数为起点，重启后的 token 同样大于重启前的 token
+//     （前提是系统时钟没有回拨，见 versionSeed）
+//   - 持有者在锁过期后（如长时间 GC 停顿）仍可能认为自己持有锁，
+//     下游必须校验 token 才能保证安全
+func (sm *ShardedMap) AcquireLock(key, owner string, ttl int64) (uint64, bool, error) {
+	key = sm.storageKey(key)
+	shard := sm.getShard(key)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if existing := liveItem(shard, key); existing != nil {
+		if _, ok := existing.value.(*lockValue); !ok {
+			return 0, false, ErrNotLock
+		}
+		return 0, false, nil
+	}
+
+	now := time.Now().UnixMilli()
+	version := nextVersion()
+	shard.put(key, &item{
+		value:     &lockValue{owner: owner, token: version},
+		expiresAt: now + ttl,
+		createdAt: now,
+		version:   version,
+	})
+	shard.events.notify(KeyEventSet, key)
+
+	return version, true, nil
+}
+
+// ReleaseLock 释放分布式锁
+//
+// 参数说明：
+//   - key: 锁的键
+//   - owner: 持有者标识
+//
+// 返回值：
+//   - bool: 是否释放，锁不存在、已过期或由其他持有者持有时返回 false
+//   - error: 键存在但不是锁时返回 ErrNotLock
+func (sm *ShardedMap) ReleaseLock(key, owner string) (bool, error) {
+	key = sm.storageKey(key)
+	shard := sm.getShard(key)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	lock, err := liveLock(shard, key)
+	if lock == nil || lock.owner != owner {
+		return false, err
+	}
+
+	shard.remove(key)
+	shard.events.notify(KeyEventDel, key)
+	return true, nil
+}
+
+// ExtendLock 延长分布式锁的有效期
+//
+// 参数说明：
+//   - key: 锁的键
+//   - owner: 持有者标识
+//   - ttl: 从当前时间起新的有效期（毫秒），必须大于 0
+//
+// 返回值：
+//   - bool: 是否续期，锁不存在、已过期或由其他持有者持有时返回 false
+//   - error: 键存在但不是锁时返回 ErrNotLock
+//
+// 注意事项：
+//   - 续期不改变 fencing token；锁已过期时不能续期，需要重新获取
+func (sm *ShardedMap) ExtendLock(key, owner string, ttl int64) (bool, error) {
+	key = sm.storageKey(key)
+	shard := sm.getShard(key)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	lock, err := liveLock(shard, key)
+	if lock == nil || lock.owner != owner {
+		return false, err
+	}
+
+	it := shard.items[key]
+	it.expiresAt = time.Now().UnixMilli() + ttl
+	it.version = nextVersion()
+	shard.events.notify(KeyEventSet, key)
+	return true, nil
+}
+
+// GetLock 查询分布式锁的状态
+//
+// 参数说明：
+//   - key: 锁的键
+//
+// 返回值：
+//   - Lock: 锁的持有者、fencing token 和过期时间
+//   - bool: 锁是否被持有
+//   - error: 键存在但不是锁时返回 ErrNotLock
+func (sm *ShardedMap) GetLock(key string) (Lock, bool, error) {
+	key = sm.storageKey(key)
+	shard := sm.getShard(key)
+
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	it, exists := shard.items[key]
+	if !exists || (it.expiresAt > 0 && time.Now().UnixMilli() >= it.expiresAt) {
+		return Lock{}, false, nil
+	}
+	lock, ok := it.value.(*lockValue)
+	if !ok {
+		return Lock{}, false, ErrNotLock
+	}
+
+	return Lock{Owner: lock.owner, Token: lock.token, ExpiresAt: it.expiresAt}, true, nil
+}
+
+// liveLock 返回未过期的锁
+//
+// 调用方必须持有分片的写锁。
+func liveLock(shard *mapShard, key string) (*lockValue, error) {
+	existing := liveItem(shard, key)
+	if existing == nil {
+		return nil, nil
+	}
+	lock, ok := existing.value.(*lockValue)
+	if !ok {
+		return nil, ErrNotLock
+	}
+	return lock, nil
+}
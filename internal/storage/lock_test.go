@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestShardedMap_AcquireLock 测试获取、释放和重新获取锁
+func TestShardedMap_AcquireLock(t *testing.T) {
+	sm := NewShardedMap(1024)
+
+	token1, ok, err := sm.AcquireLock("lock:refresh", "a", 1000)
+	if err != nil || !ok || token1 == 0 {
+		t.Fatalf("Expected acquire to succeed, got %d, %v, %v", token1, ok, err)
+	}
+
+	if _, ok, _ := sm.AcquireLock("lock:refresh", "b", 1000); ok {
+		t.Error("Expected acquire by another owner to fail")
+	}
+	if _, ok, _ := sm.AcquireLock("lock:refresh", "a", 1000); ok {
+		t.Error("Expected acquire by the same owner to fail while held")
+	}
+
+	if released, _ := sm.ReleaseLock("lock:refresh", "b"); released {
+		t.Error("Expected release by another owner to fail")
+	}
+	if released, _ := sm.ReleaseLock("lock:refresh", "a"); !released {
+		t.Error("Expected release by the owner to succeed")
+	}
+	if released, _ := sm.ReleaseLock("lock:refresh", "a"); released {
+		t.Error("Expected second release to fail")
+	}
+
+	token2, ok, _ := sm.AcquireLock("lock:refresh", "b", 1000)
+	if !ok || token2 <= token1 {
+		t.Errorf("Expected a larger fencing token, got %d after %d", token2, token1)
+	}
+
+	if lock, held, _ := sm.GetLock("lock:refresh"); !held || lock.Owner != "b" {
+		t.Errorf("Expected lock held by b, got %+v, %v", lock, held)
+	}
+}
+
+// TestShardedMap_LockExpiry 测试锁过期后可以被其他持有者获取
+func TestShardedMap_LockExpiry(t *testing.T) {
+	sm := NewShardedMap(1024)
+
+	token1, _, _ := sm.AcquireLock("lock:job", "a", 10)
+	time.Sleep(20 * time.Millisecond)
+
+	if extended, _ := sm.ExtendLock("lock:job", "a", 1000); extended {
+		t.Error("Expected extend of expired lock to fail")
+	}
+	if _, held, _ := sm.GetLock("lock:job"); held {
+		t.Error("Expected expired lock to be free")
+	}
+
+	token2, ok, _ := sm.AcquireLock("lock:job", "b", 1000)
+	if !ok || token2 <= token1 {
+		t.Fatalf("Expected acquire after expiry with a larger token, got %d, %v", token2, ok)
+	}
+
+	// 原持有者在锁过期后释放，不影响新的持有者
+	if released, _ := sm.ReleaseLock("lock:job", "a"); released {
+		t.Error("Expected release by the previous owner to fail")
+	}
+	if lock, held, _ := sm.GetLock("lock:job"); !held || lock.Owner != "b" {
+		t.Errorf("Expected lock held by b, got %+v, %v", lock, held)
+	}
+}
+
+// TestShardedMap_ExtendLock 测试续期只更新过期时间
+func TestShardedMap_ExtendLock(t *testing.T) {
+	sm := NewShardedMap(1024)
+
+	token, _, _ := sm.AcquireLock("lock:job", "a", 100)
+
+	if extended, _ := sm.ExtendLock("lock:job", "b", 10000); extended {
+		t.Error("Expected extend by another owner to fail")
+	}
+	if extended, _ := sm.ExtendLock("lock:job", "a", 10000); !extended {
+		t.Fatal("Expected extend by the owner to succeed")
+	}
+
+	lock, held, err := sm.GetLock("lock:job")
+	if err != nil || !held {
+		t.Fatalf("Expected lock to be held, got %v, %v", held, err)
+	}
+	if lock.Owner != "a" || lock.Token != token {
+		t.Errorf("Expected owner a and token %d, got %+v", token, lock)
+	}
+	if remaining := lock.ExpiresAt - time.Now().UnixMilli(); remaining < 9000 || remaining > 10000 {
+		t.Errorf("Expected about 10s remaining, got %dms", remaining)
+	}
+}
+
+// TestShardedMap_LockNotLock 测试对普通键执行锁操作
+func TestShardedMap_LockNotLock(t *testing.T) {
+	sm := NewShardedMap(1024)
+	sm.Set("session:1", "data", 0)
+
+	if _, _, err := sm.AcquireLock("session:1", "a", 1000); !errors.Is(err, ErrNotLock) {
+		t.Errorf("Expected ErrNotLock from AcquireLock, got %v", err)
+	}
+	if _, err := sm.ReleaseLock("session:1", "a"); !errors.Is(err, ErrNotLock) {
+		t.Errorf("Expected ErrNotLock from ReleaseLock, got %v", err)
+	}
+	if _, err := sm.ExtendLock("session:1", "a", 1000); !errors.Is(err, ErrNotLock) {
+		t.Errorf("Expected ErrNotLock from ExtendLock, got %v", err)
+	}
+	if _, _, err := sm.GetLock("session:1"); !errors.Is(err, ErrNotLock) {
+		t.Errorf("Expected ErrNotLock from GetLock, got %v", err)
+	}
+	if value, _ := sm.Get("session:1"); value != "data" {
+		t.Errorf("Expected value to be untouched, got %v", value)
+	}
+}
+
+// TestShardedMap_AcquireLockConcurrent 测试并发获取时只有一个持有者
+func TestShardedMap_AcquireLockConcurrent(t *testing.T) {
+	sm := NewShardedMap(1024)
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		acquired int
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, ok, _ := sm.AcquireLock("lock:refresh", "owner", 10000); ok {
+				mu.Lock()
+				acquired++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if acquired != 1 {
+		t.Errorf("Expected exactly one acquire to succeed, got %d", acquired)
+	}
+}
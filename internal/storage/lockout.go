@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotLockout 键存在但保存的不是失败记录
+var ErrNotLockout = errors.New("key does not hold a lockout record")
+
+// LockoutTier 一级锁定规则：连续失败达到 Threshold 次后锁定 Duration
+type LockoutTier struct {
+	Threshold int64
+	Duration  time.Duration
+}
+
+// LockoutPolicy 账户锁定策略
+//
+// 例如 [{5, 1m}, {10, 5m}, {20, 30m}] 表示连续失败 5 次锁定 1 分钟，
+// 10 次锁定 5 分钟，20 次及以上每次失败都锁定 30 分钟。
+type LockoutPolicy struct {
+	Window time.Duration // 失败计数的保留时间：距最后一次失败超过 Window 且未被锁定时，记录过期、计数清零
+	Tiers  []LockoutTier // 按 Threshold 严格递增
+}
+
+// LockoutStatus 失败记录的状态
+type LockoutStatus struct {
+	Failures    int64 // 连续失败次数
+	LockedUntil int64 // 锁定截止时间（Unix 毫秒），0 表示未锁定
+}
+
+// Locked 检查在 now（Unix 毫秒）时是否处于锁定状态
+func (s LockoutStatus) Locked(now int64) bool {
+	return s.LockedUntil > now
+}
+
+// lockoutState 存储在键中的失败记录
+type lockoutState struct {
+	failures    int64
+	lockedUntil int64
+}
+
+// RecordFailure 记录一次失败并返回锁定状态
+//
+// 参数说明：
+//   - key: 失败记录的键，如 "lockout:user:alice"
+//   - policy: 锁定策略
+//
+// 返回值：
+//   - LockoutStatus: 记录本次失败之后的状态
+//   - error: 键存在但不是失败记录时返回 ErrNotLockout
+//
+// 示例：
+//
+//	status, err := sm.RecordFailure("lockout:user:alice", policy)
+//	if err == nil && status.Locked(time.Now().UnixMilli()) {
+//	    // 通知用户账户已锁定
+//	}
+//
+// 注意事项：
+//   - 失败次数达到第一级阈值后，每次失败都按已达到的最高一级重新锁定，锁定时间逐级递增
+//   - 记录的过期时间为锁定截止时间和最后一次失败 + Window 中较晚的一个，由 TTL 机制清理
+//   - 锁定期间的失败同样计数
+func (sm *ShardedMap) RecordFailure(key string, policy LockoutPolicy) (LockoutStatus, error) {
+	key = sm.storageKey(key)
+	shard := sm.getShard(key)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	state := lockoutState{}
+	if existing := liveItem(shard, key); existing != nil {
+		stored, ok := existing.value.(*lockoutState)
+		if !ok {
+			return LockoutStatus{}, ErrNotLockout
+		}
+		state = *stored
+	}
+
+	now := time.Now().UnixMilli()
+	state.failures++
+
+	var duration time.Duration
+	for _, tier := range policy.Tiers {
+		if state.failures >= tier.Threshold {
+			duration = tier.Duration
+		}
+	}
+	if duration > 0 {
+		state.lockedUntil = now + duration.Milliseconds()
+	}
+
+	expiresAt := now + policy.Window.Milliseconds()
+	if state.lockedUntil > expiresAt {
+		expiresAt = state.lockedUntil
+	}
+	shard.put(key, &item{
+		value:     &state,
+		expiresAt: expiresAt,
+		createdAt: now,
+		version:   nextVersion(),
+	})
+	shard.events.notify(KeyEventSet, key)
+
+	return LockoutStatus{Failures: state.failures, LockedUntil: state.lockedUntil}, nil
+}
+
+// GetLockout 查询失败记录的状态
+//
+// 参数说明：
+//   - key: 失败记录的键
+//
+// 返回值：
+//   - LockoutStatus: 当前状态，记录不存在或已过期时为零值
+//   - error: 键存在但不是失败记录时返回 ErrNotLockout
+func (sm *ShardedMap) GetLockout(key string) (LockoutStatus, error) {
+	key = sm.storageKey(key)
+	shard := sm.getShard(key)
+
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	it, exists := shard.items[key]
+	if !exists || (it.expiresAt > 0 && time.Now().UnixMilli() >= it.expiresAt) {
+		return LockoutStatus{}, nil
+	}
+	state, ok := it.value.(*lockoutState)
+	if !ok {
+		return LockoutStatus{}, ErrNotLockout
+	}
+
+	return LockoutStatus{Failures: state.failures, LockedUntil: state.lockedUntil}, nil
+}
+
+// ResetLockout 清除失败记录（登录成功或管理员解锁）
+//
+// 参数说明：
+//   - key: 失败记录的键
+//
+// 返回值：
+//   - bool: 是否清除了记录
+//   - error: 键存在但不是失败记录时返回 ErrNotLockout，此时不删除该键
+func (sm *ShardedMap) ResetLockout(key string) (bool, error) {
+	key = sm.storageKey(key)
+	shard := sm.getShard(key)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	existing := liveItem(shard, key)
+	if existing == nil {
+		return false, nil
+	}
+	if _, ok := existing.value.(*lockoutState); !ok {
+		return false, ErrNotLockout
+	}
+
+	shard.remove(key)
+	shard.events.notify(KeyEventDel, key)
+	return true, nil
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// testLockoutPolicy 连续失败 3 次锁定 1 分钟，5 次锁定 5 分钟，7 次锁定 30 分钟
+var testLockoutPolicy = LockoutPolicy{
+	Window: time.Hour,
+	Tiers: []LockoutTier{
+		{Threshold: 3, Duration: time.Minute},
+		{Threshold: 5, Duration: 5 * time.Minute},
+		{Threshold: 7, Duration: 30 * time.Minute},
+	},
+}
+
+// TestShardedMap_RecordFailure 测试失败计数和逐级递增的锁定时间
+func TestShardedMap_RecordFailure(t *testing.T) {
+	sm := NewShardedMap(1024)
+
+	tests := []struct {
+		failures int64
+		locked   time.Duration
+	}{
+		{1, 0},
+		{2, 0},
+		{3, time.Minute},
+		{4, time.Minute},
+		{5, 5 * time.Minute},
+		{6, 5 * time.Minute},
+		{7, 30 * time.Minute},
+		{8, 30 * time.Minute},
+	}
+	for _, tt := range tests {
+		status, err := sm.RecordFailure("lockout:user:alice", testLockoutPolicy)
+		if err != nil {
+			t.Fatalf("RecordFailure failed: %v", err)
+		}
+		now := time.Now().UnixMilli()
+		if status.Failures != tt.failures {
+			t.Errorf("Expected %d failures, got %d", tt.failures, status.Failures)
+		}
+		if tt.locked == 0 {
+			if status.Locked(now) {
+				t.Errorf("Failure %d: expected not locked, got %+v", tt.failures, status)
+			}
+			continue
+		}
+		remaining := time.Duration(status.LockedUntil-now) * time.Millisecond
+		if remaining <= tt.locked-time.Second || remaining > tt.locked {
+			t.Errorf("Failure %d: expected lock of %v, got %v", tt.failures, tt.locked, remaining)
+		}
+	}
+
+	if status, _ := sm.GetLockout("lockout:user:alice"); status.Failures != 8 || !status.Locked(time.Now().UnixMilli()) {
+		t.Errorf("Unexpected status %+v", status)
+	}
+	if ttl := TTL(sm, "lockout:user:alice"); ttl < 3599 || ttl > 3600 {
+		t.Errorf("Expected record to expire one window after the last failure, got TTL %d", ttl)
+	}
+
+	if reset, _ := sm.ResetLockout("lockout:user:alice"); !reset {
+		t.Error("Expected reset to succeed")
+	}
+	if status, _ := sm.GetLockout("lockout:user:alice"); status.Failures != 0 || status.LockedUntil != 0 {
+		t.Errorf("Expected empty status after reset, got %+v", status)
+	}
+	if reset, _ := sm.ResetLockout("lockout:user:alice"); reset {
+		t.Error("Expected second reset to return false")
+	}
+}
+
+// TestShardedMap_RecordFailureWindow 测试超过保留时间后失败计数清零
+func TestShardedMap_RecordFailureWindow(t *testing.T) {
+	sm := NewShardedMap(1024)
+	policy := LockoutPolicy{
+		Window: 20 * time.Millisecond,
+		Tiers:  []LockoutTier{{Threshold: 3, Duration: time.Minute}},
+	}
+
+	sm.RecordFailure("lockout:ip:10.0.0.1", policy)
+	sm.RecordFailure("lockout:ip:10.0.0.1", policy)
+	time.Sleep(30 * time.Millisecond)
+
+	status, _ := sm.RecordFailure("lockout:ip:10.0.0.1", policy)
+	if status.Failures != 1 || status.Locked(time.Now().UnixMilli()) {
+		t.Errorf("Expected the count to restart after the window, got %+v", status)
+	}
+
+	// 锁定时间长于保留时间时，记录随锁定一起过期
+	sm.RecordFailure("lockout:ip:10.0.0.1", policy)
+	sm.RecordFailure("lockout:ip:10.0.0.1", policy)
+	if ttl := TTL(sm, "lockout:ip:10.0.0.1"); ttl < 59 || ttl > 60 {
+		t.Errorf("Expected record to expire with the lock, got TTL %d", ttl)
+	}
+}
+
+// TestShardedMap_LockoutWrongType 测试对其他类型的键执行锁定操作
+func TestShardedMap_LockoutWrongType(t *testing.T) {
+	sm := NewShardedMap(1024)
+	sm.Set("session:1", "data", 0)
+
+	if _, err := sm.RecordFailure("session:1", testLockoutPolicy); !errors.Is(err, ErrNotLockout) {
+		t.Errorf("Expected ErrNotLockout from RecordFailure, got %v", err)
+	}
+	if _, err := sm.GetLockout("session:1"); !errors.Is(err, ErrNotLockout) {
+		t.Errorf("Expected ErrNotLockout from GetLockout, got %v", err)
+	}
+	if _, err := sm.ResetLockout("session:1"); !errors.Is(err, ErrNotLockout) {
+		t.Errorf("Expected ErrNotLockout from ResetLockout, got %v", err)
+	}
+	if !sm.Exists("session:1") {
+		t.Error("Expected the key to be kept")
+	}
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"sort"
+	"time"
+)
+
+// KeyValue 键值对，用于 MSet 和 MSetNX
+type KeyValue struct {
+	Key   string
+	Value interface{}
+}
+
+// MSet 原子地设置多个键值对
+//
+// 参数说明：
+//   - pairs: 键值对列表，同一个键出现多次时以最后一次为准
+//
+// 示例：
+//
+//	sm.MSet([]KeyValue{
+//	    {Key: "session:a", Value: dataA},
+//	    {Key: "session:b", Value: dataB},
+//	})
+//
+// 注意事项：
+//   - 涉及的全部分片按下标顺序加锁后一次性写入，其他客户端不会看到只写入一部分的状态
+//   - 与 Redis MSET 相同，写入的键不带过期时间
+func (sm *ShardedMap) MSet(pairs []KeyValue) {
+	keys := sm.storageKeys(pairs)
+	unlock := sm.lockShards(keys)
+	defer unlock()
+
+	now := time.Now().UnixMilli()
+	for i, key := range keys {
+		shard := sm.getShard(key)
+		shard.put(key, &item{
+			value:     pairs[i].Value,
+			createdAt: now,
+			version:   nextVersion(),
+		})
+		shard.events.notify(KeyEventSet, key)
+	}
+}
+
+// MSetNX 仅当所有键都不存在时，原子地设置多个键值对
+//
+// 参数说明：
+//   - pairs: 键值对列表
+//
+// 返回值：
+//   - bool: 是否写入（任一键已存在时不写入任何键）
+//
+// 注意事项：
+//   - 检查和写入在涉及的全部分片锁内完成
+func (sm *ShardedMap) MSetNX(pairs []KeyValue) bool {
+	keys := sm.storageKeys(pairs)
+	unlock := sm.lockShards(keys)
+	defer unlock()
+
+	for _, key := range keys {
+		if liveItem(sm.getShard(key), key) != nil {
+			return false
+		}
+	}
+
+	now := time.Now().UnixMilli()
+	for i, key := range keys {
+		shard := sm.getShard(key)
+		shard.put(key, &item{
+			value:     pairs[i].Value,
+			createdAt: now,
+			version:   nextVersion(),
+		})
+		shard.events.notify(KeyEventSet, key)
+	}
+
+	return true
+}
+
+// storageKeys 返回键值对中每个键的存储键
+func (sm *ShardedMap) storageKeys(pairs []KeyValue) []string {
+	keys := make([]string, len(pairs))
+	for i, pair := range pairs {
+		keys[i] = sm.storageKey(pair.Key)
+	}
+	return keys
+}
+
+// lockShards 对存储键涉及的全部分片加写锁
+//
+// 分片按下标升序加锁，避免多个并发的多键操作之间死锁。
+//
+// 返回值：
+//   - func(): 释放全部分片锁
+func (sm *ShardedMap) lockShards(keys []string) func() {
+	seen := make(map[uint32]bool, len(keys))
+	indexes := make([]int, 0, len(keys))
+	for _, key := range keys {
+		index := shardIndex(key)
+		if !seen[index] {
+			seen[index] = true
+			indexes = append(indexes, int(index))
+		}
+	}
+	sort.Ints(indexes)
+
+	for _, index := range indexes {
+		sm.shards[index].mu.Lock()
+	}
+
+	return func() {
+		for i := len(indexes) - 1; i >= 0; i-- {
+			sm.shards[indexes[i]].mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// TestShardedMap_MSet 测试多键写入
+func TestShardedMap_MSet(t *testing.T) {
+	sm := NewShardedMap(1024)
+	sm.Set("a", "old", 60)
+
+	sm.MSet([]KeyValue{{Key: "a", Value: "1"}, {Key: "b", Value: "2"}, {Key: "a", Value: "3"}})
+
+	if value, _ := sm.Get("a"); value != "3" {
+		t.Errorf("Expected last value 3 for duplicate key, got %v", value)
+	}
+	if value, _ := sm.Get("b"); value != "2" {
+		t.Errorf("Expected 2, got %v", value)
+	}
+	if ttl := TTL(sm, "a"); ttl != -2 {
+		t.Errorf("Expected MSET to clear TTL, got %d", ttl)
+	}
+}
+
+// TestShardedMap_MSetNX 测试任一键存在时不写入任何键
+func TestShardedMap_MSetNX(t *testing.T) {
+	sm := NewShardedMap(1024)
+
+	if !sm.MSetNX([]KeyValue{{Key: "a", Value: "1"}, {Key: "b", Value: "2"}}) {
+		t.Fatal("Expected first MSETNX to succeed")
+	}
+	if sm.MSetNX([]KeyValue{{Key: "c", Value: "3"}, {Key: "b", Value: "x"}}) {
+		t.Error("Expected MSETNX to fail when b exists")
+	}
+	if sm.Exists("c") {
+		t.Error("Expected c not to be written by failed MSETNX")
+	}
+}
+
+// TestShardedMap_MSetAtomic 测试并发跨分片 MSET 不会死锁，且写入不会交错
+//
+// 多个写入方反复把同一组键全部写成自己的值，结束后所有键应来自同一次 MSET。
+func TestShardedMap_MSetAtomic(t *testing.T) {
+	sm := NewShardedMap(1024)
+
+	keys := make([]string, 50)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("session:%d", i)
+	}
+
+	var wg sync.WaitGroup
+	for writer := 0; writer < 8; writer++ {
+		wg.Add(1)
+		go func(writer int) {
+			defer wg.Done()
+			pairs := make([]KeyValue, len(keys))
+			for i, key := range keys {
+				pairs[i] = KeyValue{Key: key, Value: writer}
+			}
+			for n := 0; n < 100; n++ {
+				sm.MSet(pairs)
+			}
+		}(writer)
+	}
+	wg.Wait()
+
+	first, _ := sm.Get(keys[0])
+	for _, key := range keys {
+		if value, _ := sm.Get(key); value != first {
+			t.Fatalf("Expected all keys written by the same MSET, got %v and %v", first, value)
+		}
+	}
+}
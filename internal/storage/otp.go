@@ -0,0 +1,177 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotOTP 键存在但保存的不是一次性验证码
+var ErrNotOTP = errors.New("key does not hold a one-time code")
+
+// otpSaltLength 验证码哈希的随机盐长度（字节）
+const otpSaltLength = 16
+
+// otpHashLabel 验证码 HMAC 的用途标签，与键哈希的结果区分
+const otpHashLabel = "tokenginx-otp"
+
+// otpFallbackHasher 未设置键哈希器时计算验证码 HMAC 使用的密钥
+//
+// 进程启动时随机生成，只保存在内存中；验证码本身也只保存在内存中，重启后随之失效。
+var otpFallbackHasher = newOTPFallbackHasher()
+
+// newOTPFallbackHasher 生成随机密钥的 HMAC-SHA256 哈希器
+func newOTPFallbackHasher() *KeyHasher {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(fmt.Sprintf("storage: 生成验证码密钥失败: %v", err))
+	}
+	return &KeyHasher{secret: secret, newHash: sha256.New}
+}
+
+// OTPResult 验证一次性验证码的结果
+type OTPResult int
+
+const (
+	// OTPNotFound 验证码不存在或已过期
+	OTPNotFound OTPResult = iota
+
+	// OTPSuccess 验证成功，验证码已被消费
+	OTPSuccess
+
+	// OTPWrongCode 验证码错误，还可以继续尝试
+	OTPWrongCode
+
+	// OTPLocked 尝试次数已用完，验证码已作废
+	OTPLocked
+)
+
+// otpState 存储在键中的一次性验证码
+//
+// 只保存以服务端密钥计算的 HMAC(secret, salt || code)（见 otpHash），内存转储中不会出现验证码明文；
+// 6 位数字验证码的取值空间很小，没有密钥时无法通过枚举从转储中还原验证码。
+type otpState struct {
+	salt      []byte
+	hash      []byte
+	remaining int // 剩余尝试次数，0 表示已作废
+}
+
+// SetOTP 保存一次性验证码
+//
+// 参数说明：
+//   - key: 验证码的键，如 "otp:email:alice@example.com"
+//   - code: 验证码明文
+//   - ttl: 有效期（秒），必须大于 0
+//   - maxAttempts: 最大验证次数，必须大于 0
+//
+// 返回值：
+//   - error: 生成随机盐失败时返回错误
+//
+// 示例：
+//
+//	// 发送邮件验证码，10 分钟内有效，最多尝试 5 次
+//	if err := sm.SetOTP("otp:email:alice@example.com", "482913", 600, 5); err != nil {
+//	    return err
+//	}
+//
+// 注意事项：
+//   - 覆盖键原有的值，重新发送验证码时旧验证码立即失效，尝试次数重新计算
+//   - 设置了键哈希器（SetKeyHasher）时使用其密钥和算法计算 HMAC，否则使用进程启动时随机生成的密钥
+func (sm *ShardedMap) SetOTP(key, code string, ttl int, maxAttempts int) error {
+	salt := make([]byte, otpSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("generate otp salt: %w", err)
+	}
+
+	state := &otpState{
+		salt:      salt,
+		hash:      sm.otpHash(salt, code),
+		remaining: maxAttempts,
+	}
+
+	key = sm.storageKey(key)
+	shard := sm.getShard(key)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	now := time.Now().UnixMilli()
+	shard.put(key, &item{
+		value:     state,
+		expiresAt: now + int64(ttl)*1000,
+		createdAt: now,
+		version:   nextVersion(),
+	})
+	shard.events.notify(KeyEventSet, key)
+
+	return nil
+}
+
+// VerifyOTP 验证一次性验证码
+//
+// 参数说明：
+//   - key: 验证码的键
+//   - candidate: 用户输入的验证码
+//
+// 返回值：
+//   - OTPResult: 验证结果
+//   - int: 剩余尝试次数
+//   - error: 键存在但不是验证码时返回 ErrNotOTP
+//
+// 注意事项：
+//   - 比较、扣减尝试次数和消费在同一把分片锁内完成，并发验证不会多用尝试次数
+//   - 验证成功后删除验证码，同一个验证码只能成功一次
+//   - 尝试次数用完后验证码作废（不再保存哈希），之后的验证都返回 OTPLocked，直到记录过期
+//   - 使用常量时间比较哈希
+func (sm *ShardedMap) VerifyOTP(key, candidate string) (OTPResult, int, error) {
+	key = sm.storageKey(key)
+	shard := sm.getShard(key)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	existing := liveItem(shard, key)
+	if existing == nil {
+		return OTPNotFound, 0, nil
+	}
+	state, ok := existing.value.(*otpState)
+	if !ok {
+		return OTPNotFound, 0, ErrNotOTP
+	}
+	if state.remaining <= 0 {
+		return OTPLocked, 0, nil
+	}
+
+	if hmac.Equal(sm.otpHash(state.salt, candidate), state.hash) {
+		shard.remove(key)
+		shard.events.notify(KeyEventDel, key)
+		return OTPSuccess, state.remaining - 1, nil
+	}
+
+	// 写入新的状态而不是原地修改，避免影响其他调用方持有的旧值
+	updated := &otpState{salt: state.salt, hash: state.hash, remaining: state.remaining - 1}
+	result := OTPWrongCode
+	if updated.remaining == 0 {
+		updated.salt, updated.hash = nil, nil
+		result = OTPLocked
+	}
+	existing.value = updated
+	existing.version = nextVersion()
+	shard.events.notify(KeyEventSet, key)
+
+	return result, updated.remaining, nil
+}
+
+// otpHash 计算验证码的 HMAC(secret, salt || code)
+//
+// 密钥为键哈希器的密钥（未设置时为 otpFallbackHasher），并以 otpHashLabel 与键哈希区分用途。
+func (sm *ShardedMap) otpHash(salt []byte, code string) []byte {
+	hasher := sm.hasher
+	if hasher == nil {
+		hasher = otpFallbackHasher
+	}
+	return hasher.mac(otpHashLabel, salt, []byte(code))
+}
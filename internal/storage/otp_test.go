@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestShardedMap_VerifyOTP 测试验证成功后验证码被消费
+func TestShardedMap_VerifyOTP(t *testing.T) {
+	sm := NewShardedMap(1024)
+
+	if err := sm.SetOTP("otp:email:alice", "482913", 600, 3); err != nil {
+		t.Fatalf("SetOTP failed: %v", err)
+	}
+
+	result, remaining, err := sm.VerifyOTP("otp:email:alice", "000000")
+	if err != nil || result != OTPWrongCode || remaining != 2 {
+		t.Errorf("Expected wrong code with 2 attempts left, got %v, %d, %v", result, remaining, err)
+	}
+
+	result, _, _ = sm.VerifyOTP("otp:email:alice", "482913")
+	if result != OTPSuccess {
+		t.Errorf("Expected success, got %v", result)
+	}
+
+	result, _, _ = sm.VerifyOTP("otp:email:alice", "482913")
+	if result != OTPNotFound {
+		t.Errorf("Expected consumed code to be gone, got %v", result)
+	}
+}
+
+// TestShardedMap_VerifyOTPLocked 测试尝试次数用完后验证码作废
+func TestShardedMap_VerifyOTPLocked(t *testing.T) {
+	sm := NewShardedMap(1024)
+	sm.SetOTP("otp:sms:13800000000", "1234", 600, 2)
+
+	if result, remaining, _ := sm.VerifyOTP("otp:sms:13800000000", "0000"); result != OTPWrongCode || remaining != 1 {
+		t.Errorf("Expected wrong code with 1 attempt left, got %v, %d", result, remaining)
+	}
+	if result, remaining, _ := sm.VerifyOTP("otp:sms:13800000000", "1111"); result != OTPLocked || remaining != 0 {
+		t.Errorf("Expected last wrong attempt to lock, got %v, %d", result, remaining)
+	}
+	if result, _, _ := sm.VerifyOTP("otp:sms:13800000000", "1234"); result != OTPLocked {
+		t.Errorf("Expected correct code to be rejected after lock, got %v", result)
+	}
+	if ttl := TTL(sm, "otp:sms:13800000000"); ttl < 599 || ttl > 600 {
+		t.Errorf("Expected locked record to keep its TTL, got %d", ttl)
+	}
+
+	// 重新发送后可以继续验证
+	sm.SetOTP("otp:sms:13800000000", "5678", 600, 2)
+	if result, _, _ := sm.VerifyOTP("otp:sms:13800000000", "5678"); result != OTPSuccess {
+		t.Errorf("Expected success after resend, got %v", result)
+	}
+}
+
+// TestShardedMap_VerifyOTPExpired 测试验证码过期
+func TestShardedMap_VerifyOTPExpired(t *testing.T) {
+	sm := NewShardedMap(1024)
+	sm.SetOTP("otp:email:bob", "482913", 1, 3)
+
+	time.Sleep(1100 * time.Millisecond)
+
+	if result, _, _ := sm.VerifyOTP("otp:email:bob", "482913"); result != OTPNotFound {
+		t.Errorf("Expected expired code to be not found, got %v", result)
+	}
+}
+
+// TestShardedMap_OTPNotStoredInPlaintext 测试验证码不以明文保存
+func TestShardedMap_OTPNotStoredInPlaintext(t *testing.T) {
+	sm := NewShardedMap(1024)
+	sm.SetOTP("otp:email:carol", "739182", 600, 3)
+
+	value, _ := sm.Get("otp:email:carol")
+	if strings.Contains(fmt.Sprintf("%v", value), "739182") {
+		t.Errorf("Expected code not to appear in the stored value, got %v", value)
+	}
+}
+
+// TestShardedMap_OTPKeyedHash 测试验证码哈希是以服务端密钥计算的 HMAC，而不是可离线枚举的加盐哈希
+func TestShardedMap_OTPKeyedHash(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	hasher, err := NewKeyHasher(KeyHashHMACSHA256, secret, []string{"oauth:token:"})
+	if err != nil {
+		t.Fatalf("NewKeyHasher failed: %v", err)
+	}
+
+	sm := NewShardedMap(1024)
+	sm.SetKeyHasher(hasher)
+	sm.SetOTP("otp:email:dave", "482913", 600, 3)
+
+	value, _ := sm.Get("otp:email:dave")
+	state := value.(*otpState)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(otpHashLabel + "\x00"))
+	mac.Write(state.salt)
+	mac.Write([]byte("482913"))
+	if !hmac.Equal(state.hash, mac.Sum(nil)) {
+		t.Error("Expected the stored hash to be HMAC keyed by the key hasher secret")
+	}
+
+	plain := sha256.Sum256(append(append([]byte(nil), state.salt...), "482913"...))
+	if hmac.Equal(state.hash, plain[:]) {
+		t.Error("Expected the stored hash not to be a plain salted SHA-256")
+	}
+
+	// 密钥不同时同一个验证码无法通过验证
+	other, _ := NewKeyHasher(KeyHashHMACSHA256, []byte("fedcba9876543210fedcba9876543210"), []string{"oauth:token:"})
+	sm.SetKeyHasher(other)
+	if result, _, _ := sm.VerifyOTP("otp:email:dave", "482913"); result != OTPWrongCode {
+		t.Errorf("Expected a different secret to reject the code, got %v", result)
+	}
+	sm.SetKeyHasher(hasher)
+	if result, _, _ := sm.VerifyOTP("otp:email:dave", "482913"); result != OTPSuccess {
+		t.Errorf("Expected the original secret to accept the code, got %v", result)
+	}
+}
+
+// TestShardedMap_VerifyOTPConcurrent 测试并发验证不会超出尝试次数
+func TestShardedMap_VerifyOTPConcurrent(t *testing.T) {
+	sm := NewShardedMap(1024)
+	sm.SetOTP("otp:email:dave", "482913", 600, 5)
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		counts = make(map[OTPResult]int)
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result, _, _ := sm.VerifyOTP("otp:email:dave", fmt.Sprintf("%06d", i))
+			mu.Lock()
+			counts[result]++
+			mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+
+	if counts[OTPWrongCode] != 4 || counts[OTPLocked] != 46 {
+		t.Errorf("Expected 4 wrong-code and 46 locked replies, got %v", counts)
+	}
+}
+
+// TestShardedMap_OTPWrongType 测试对其他类型的键验证
+func TestShardedMap_OTPWrongType(t *testing.T) {
+	sm := NewShardedMap(1024)
+	sm.Set("session:1", "482913", 0)
+
+	if _, _, err := sm.VerifyOTP("session:1", "482913"); !errors.Is(err, ErrNotOTP) {
+		t.Errorf("Expected ErrNotOTP, got %v", err)
+	}
+	if value, _ := sm.Get("session:1"); value != "482913" {
+		t.Errorf("Expected value to be untouched, got %v", value)
+	}
+}
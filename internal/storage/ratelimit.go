@@ -0,0 +1,292 @@
+package storage
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// ErrNotRateLimit 键存在但保存的不是该算法的限流状态
+var ErrNotRateLimit = errors.New("key does not hold rate limit state")
+
+// RateLimitResult 一次限流判定的结果
+type RateLimitResult struct {
+	Allowed    bool          // 是否允许本次请求
+	Limit      int64         // 允许的最大请求数
+	Remaining  int64         // 本次判定之后还能立即通过的请求数
+	RetryAfter time.Duration // 被拒绝时距离可以重试的时间；允许时为 0，请求量超过上限永远不会被允许时为 -1
+	ResetAfter time.Duration // 距离限流状态完全恢复（可以通过 Limit 个请求）的时间
+}
+
+// GCRALimit GCRA（generic cell rate algorithm）的限流参数
+//
+// 每 Period 时间补充 Count 个请求的额度，允许的突发请求数为 Burst + 1，
+// 与 redis-cell 的 CL.THROTTLE 参数含义相同。
+type GCRALimit struct {
+	Burst  int64         // 最大突发请求数，必须大于等于 0
+	Count  int64         // 每个周期补充的请求数，必须大于 0
+	Period time.Duration // 周期，必须大于 0
+}
+
+// gcraState GCRA 的状态：理论到达时间（Unix 微秒）
+type gcraState struct {
+	tat int64
+}
+
+// slidingLogState 滑动窗口日志的状态：窗口内已通过的请求时间（Unix 微秒，升序）
+type slidingLogState struct {
+	entries []int64
+}
+
+// slidingCounterState 滑动窗口计数器的状态：当前和上一个固定窗口的请求数
+type slidingCounterState struct {
+	start    int64 // 当前固定窗口的起始时间（Unix 微秒）
+	current  int64
+	previous int64
+}
+
+// ThrottleGCRA 使用 GCRA 判定请求是否允许通过
+//
+// 参数说明：
+//   - key: 限流键，如 "rl:login:user:alice"
+//   - limit: 限流参数
+//   - quantity: 本次请求消耗的额度，0 表示只查询不消耗
+//
+// 返回值：
+//   - RateLimitResult: 判定结果，Limit 为 Burst + 1
+//   - error: 键存在但不是 GCRA 状态时返回 ErrNotRateLimit
+//
+// 示例：
+//
+//	// 每个用户每分钟 5 次登录，允许连续 10 次突发
+//	result, err := sm.ThrottleGCRA("rl:login:user:alice", GCRALimit{Burst: 9, Count: 5, Period: time.Minute}, 1)
+//	if err == nil && !result.Allowed {
+//	    // 拒绝，result.RetryAfter 之后重试
+//	}
+//
+// 注意事项：
+//   - 读取、判定和写入在同一把分片锁内完成
+//   - 被拒绝的请求不消耗额度；状态的过期时间设置为完全恢复的时间，不会长期占用内存
+//   - 状态只保存一个时间戳，内存占用与限流参数无关
+func (sm *ShardedMap) ThrottleGCRA(key string, limit GCRALimit, quantity int64) (RateLimitResult, error) {
+	key = sm.storageKey(key)
+	shard := sm.getShard(key)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	now := time.Now().UnixMicro()
+
+	tat := now
+	if existing := liveItem(shard, key); existing != nil {
+		state, ok := existing.value.(*gcraState)
+		if !ok {
+			return RateLimitResult{}, ErrNotRateLimit
+		}
+		if state.tat > tat {
+			tat = state.tat
+		}
+	}
+
+	emission := limit.Period.Microseconds() / limit.Count
+	if emission < 1 {
+		emission = 1
+	}
+	tolerance := emission * (limit.Burst + 1)
+	increment := emission * quantity
+
+	result := RateLimitResult{Limit: limit.Burst + 1}
+
+	newTAT := tat + increment
+	if diff := now - (newTAT - tolerance); diff < 0 {
+		result.RetryAfter = time.Duration(-diff) * time.Microsecond
+		if increment > tolerance {
+			result.RetryAfter = -1
+		}
+		newTAT = tat
+	} else {
+		result.Allowed = true
+		if increment > 0 {
+			putRateLimitState(shard, key, &gcraState{tat: newTAT}, newTAT, now)
+		}
+	}
+
+	ttl := newTAT - now
+	if next := tolerance - ttl; next > 0 {
+		result.Remaining = next / emission
+	}
+	result.ResetAfter = time.Duration(ttl) * time.Microsecond
+
+	return result, nil
+}
+
+// ThrottleSlidingLog 使用滑动窗口日志判定请求是否允许通过
+//
+// 参数说明：
+//   - key: 限流键
+//   - limit: 任意 window 时间内允许的最大请求数，必须大于 0
+//   - window: 窗口大小，必须大于 0
+//   - quantity: 本次请求消耗的额度，0 表示只查询不消耗
+//
+// 返回值：
+//   - RateLimitResult: 判定结果
+//   - error: 键存在但不是滑动窗口日志状态时返回 ErrNotRateLimit
+//
+// 注意事项：
+//   - 记录窗口内每个通过的请求，判定是精确的，但内存占用与 limit 成正比，
+//     limit 较大时应使用 ThrottleSlidingCounter
+//   - 被拒绝的请求不记录
+func (sm *ShardedMap) ThrottleSlidingLog(key string, limit int64, window time.Duration, quantity int64) (RateLimitResult, error) {
+	key = sm.storageKey(key)
+	shard := sm.getShard(key)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	now := time.Now().UnixMicro()
+	windowMicros := window.Microseconds()
+
+	var entries []int64
+	if existing := liveItem(shard, key); existing != nil {
+		state, ok := existing.value.(*slidingLogState)
+		if !ok {
+			return RateLimitResult{}, ErrNotRateLimit
+		}
+		entries = state.entries
+	}
+
+	// 丢弃已滑出窗口的请求
+	start := 0
+	for start < len(entries) && entries[start] <= now-windowMicros {
+		start++
+	}
+	entries = entries[start:]
+
+	result := RateLimitResult{Limit: limit}
+	n := int64(len(entries))
+
+	if n+quantity <= limit {
+		result.Allowed = true
+		if quantity > 0 {
+			// 复制一份，避免修改其他调用方持有的旧状态
+			updated := make([]int64, 0, n+quantity)
+			updated = append(updated, entries...)
+			for i := int64(0); i < quantity; i++ {
+				updated = append(updated, now)
+			}
+			entries = updated
+			putRateLimitState(shard, key, &slidingLogState{entries: entries}, now+windowMicros, now)
+		}
+		n = int64(len(entries))
+	} else if quantity > limit {
+		result.RetryAfter = -1
+	} else {
+		// 需要等最早的 n+quantity-limit 个请求滑出窗口
+		result.RetryAfter = time.Duration(entries[n+quantity-limit-1]+windowMicros-now) * time.Microsecond
+	}
+
+	result.Remaining = limit - n
+	if n > 0 {
+		result.ResetAfter = time.Duration(entries[n-1]+windowMicros-now) * time.Microsecond
+	}
+
+	return result, nil
+}
+
+// ThrottleSlidingCounter 使用滑动窗口计数器判定请求是否允许通过
+//
+// 参数说明：
+//   - key: 限流键
+//   - limit: 窗口内允许的最大请求数，必须大于 0
+//   - window: 窗口大小，必须大于 0
+//   - quantity: 本次请求消耗的额度，0 表示只查询不消耗
+//
+// 返回值：
+//   - RateLimitResult: 判定结果
+//   - error: 键存在但不是滑动窗口计数器状态时返回 ErrNotRateLimit
+//
+// 注意事项：
+//   - 只保存当前和上一个固定窗口的计数，按上一个窗口与滑动窗口重叠的比例估算请求数，
+//     内存占用固定，但结果是近似值
+//   - 被拒绝的请求不计数
+func (sm *ShardedMap) ThrottleSlidingCounter(key string, limit int64, window time.Duration, quantity int64) (RateLimitResult, error) {
+	key = sm.storageKey(key)
+	shard := sm.getShard(key)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	now := time.Now().UnixMicro()
+	windowMicros := window.Microseconds()
+	windowStart := now - now%windowMicros
+
+	state := slidingCounterState{start: windowStart}
+	if existing := liveItem(shard, key); existing != nil {
+		stored, ok := existing.value.(*slidingCounterState)
+		if !ok {
+			return RateLimitResult{}, ErrNotRateLimit
+		}
+		switch stored.start {
+		case windowStart:
+			state = *stored
+		case windowStart - windowMicros:
+			state.previous = stored.current
+		}
+	}
+
+	elapsed := now - windowStart
+	weight := float64(windowMicros-elapsed) / float64(windowMicros)
+	estimated := func() float64 {
+		return float64(state.previous)*weight + float64(state.current)
+	}
+
+	result := RateLimitResult{Limit: limit}
+
+	switch {
+	case estimated()+float64(quantity) <= float64(limit):
+		result.Allowed = true
+		if quantity > 0 {
+			state.current += quantity
+			stored := state
+			putRateLimitState(shard, key, &stored, windowStart+2*windowMicros, now)
+		}
+
+	case quantity > limit:
+		result.RetryAfter = -1
+
+	case state.current+quantity <= limit:
+		// 当前窗口内等上一个窗口的权重继续下降
+		wait := float64(windowMicros-elapsed) - float64(limit-state.current-quantity)*float64(windowMicros)/float64(state.previous)
+		result.RetryAfter = time.Duration(math.Ceil(wait)) * time.Microsecond
+
+	default:
+		// 下一个窗口中当前窗口的计数成为上一个窗口，等它的权重下降
+		wait := float64(windowMicros-elapsed) + float64(windowMicros)*(1-float64(limit-quantity)/float64(state.current))
+		result.RetryAfter = time.Duration(math.Ceil(wait)) * time.Microsecond
+	}
+
+	if remaining := float64(limit) - estimated(); remaining > 0 {
+		result.Remaining = int64(remaining)
+	}
+	switch {
+	case state.current > 0:
+		result.ResetAfter = time.Duration(windowStart+2*windowMicros-now) * time.Microsecond
+	case state.previous > 0:
+		result.ResetAfter = time.Duration(windowStart+windowMicros-now) * time.Microsecond
+	}
+
+	return result, nil
+}
+
+// putRateLimitState 写入限流状态，过期时间为状态完全恢复的时间（Unix 微秒）
+//
+// 调用方必须持有分片的写锁。
+func putRateLimitState(shard *mapShard, key string, state interface{}, resetAt, now int64) {
+	shard.put(key, &item{
+		value:     state,
+		expiresAt: (resetAt + 999) / 1000,
+		createdAt: now / 1000,
+		version:   nextVersion(),
+	})
+	shard.events.notify(KeyEventSet, key)
+}
@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestShardedMap_ThrottleGCRA 测试 GCRA 的突发额度、拒绝和重试时间
+func TestShardedMap_ThrottleGCRA(t *testing.T) {
+	sm := NewShardedMap(1024)
+	limit := GCRALimit{Burst: 2, Count: 1, Period: time.Hour}
+
+	for i, want := range []int64{2, 1, 0} {
+		result, err := sm.ThrottleGCRA("rl:login:alice", limit, 1)
+		if err != nil {
+			t.Fatalf("ThrottleGCRA failed: %v", err)
+		}
+		if !result.Allowed || result.Limit != 3 || result.Remaining != want || result.RetryAfter != 0 {
+			t.Errorf("Request %d: unexpected result %+v", i+1, result)
+		}
+	}
+
+	result, _ := sm.ThrottleGCRA("rl:login:alice", limit, 1)
+	if result.Allowed || result.Remaining != 0 {
+		t.Fatalf("Expected 4th request to be denied, got %+v", result)
+	}
+	if result.RetryAfter <= 59*time.Minute || result.RetryAfter > time.Hour {
+		t.Errorf("Expected retry after about 1h, got %v", result.RetryAfter)
+	}
+	if result.ResetAfter <= 2*time.Hour+59*time.Minute || result.ResetAfter > 3*time.Hour {
+		t.Errorf("Expected reset after about 3h, got %v", result.ResetAfter)
+	}
+
+	// 被拒绝的请求不消耗额度，只查询也不消耗
+	peek, _ := sm.ThrottleGCRA("rl:login:alice", limit, 0)
+	if !peek.Allowed || peek.Remaining != 0 || peek.ResetAfter > result.ResetAfter || peek.ResetAfter <= 2*time.Hour+59*time.Minute {
+		t.Errorf("Expected peek to leave the state unchanged, got %+v", peek)
+	}
+
+	result, _ = sm.ThrottleGCRA("rl:login:bob", limit, 4)
+	if result.Allowed || result.RetryAfter != -1 {
+		t.Errorf("Expected quantity above the limit to be denied with -1, got %+v", result)
+	}
+	if sm.Exists("rl:login:bob") {
+		t.Error("Expected denied request not to create state")
+	}
+}
+
+// TestShardedMap_ThrottleGCRAExpiry 测试 GCRA 状态在完全恢复后过期
+func TestShardedMap_ThrottleGCRAExpiry(t *testing.T) {
+	sm := NewShardedMap(1024)
+	limit := GCRALimit{Burst: 0, Count: 1, Period: 20 * time.Millisecond}
+
+	if result, _ := sm.ThrottleGCRA("rl:ip:10.0.0.1", limit, 1); !result.Allowed {
+		t.Fatalf("Expected first request to be allowed, got %+v", result)
+	}
+	if result, _ := sm.ThrottleGCRA("rl:ip:10.0.0.1", limit, 1); result.Allowed {
+		t.Fatalf("Expected second request to be denied, got %+v", result)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	if sm.Exists("rl:ip:10.0.0.1") {
+		t.Error("Expected state to expire after reset")
+	}
+	if result, _ := sm.ThrottleGCRA("rl:ip:10.0.0.1", limit, 1); !result.Allowed {
+		t.Errorf("Expected request to be allowed after reset, got %+v", result)
+	}
+}
+
+// TestShardedMap_ThrottleSlidingLog 测试滑动窗口日志
+func TestShardedMap_ThrottleSlidingLog(t *testing.T) {
+	sm := NewShardedMap(1024)
+
+	for i, want := range []int64{2, 1, 0} {
+		result, err := sm.ThrottleSlidingLog("rl:login:alice", 3, time.Hour, 1)
+		if err != nil {
+			t.Fatalf("ThrottleSlidingLog failed: %v", err)
+		}
+		if !result.Allowed || result.Limit != 3 || result.Remaining != want {
+			t.Errorf("Request %d: unexpected result %+v", i+1, result)
+		}
+	}
+
+	result, _ := sm.ThrottleSlidingLog("rl:login:alice", 3, time.Hour, 1)
+	if result.Allowed || result.Remaining != 0 {
+		t.Fatalf("Expected 4th request to be denied, got %+v", result)
+	}
+	if result.RetryAfter <= 59*time.Minute || result.RetryAfter > time.Hour {
+		t.Errorf("Expected retry after about 1h, got %v", result.RetryAfter)
+	}
+
+	result, _ = sm.ThrottleSlidingLog("rl:login:alice", 3, time.Hour, 4)
+	if result.RetryAfter != -1 {
+		t.Errorf("Expected -1 for quantity above the limit, got %v", result.RetryAfter)
+	}
+
+	// 窗口滑过之后恢复
+	sm.ThrottleSlidingLog("rl:login:bob", 2, 20*time.Millisecond, 2)
+	if result, _ := sm.ThrottleSlidingLog("rl:login:bob", 2, 20*time.Millisecond, 1); result.Allowed {
+		t.Fatalf("Expected request to be denied, got %+v", result)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if result, _ := sm.ThrottleSlidingLog("rl:login:bob", 2, 20*time.Millisecond, 1); !result.Allowed || result.Remaining != 1 {
+		t.Errorf("Expected request to be allowed after the window, got %+v", result)
+	}
+}
+
+// TestShardedMap_ThrottleSlidingCounter 测试滑动窗口计数器
+func TestShardedMap_ThrottleSlidingCounter(t *testing.T) {
+	sm := NewShardedMap(1024)
+
+	// 窗口足够大，测试期间不会跨越固定窗口
+	window := 24 * time.Hour
+	for i := 0; i < 3; i++ {
+		if result, err := sm.ThrottleSlidingCounter("rl:login:alice", 3, window, 1); err != nil || !result.Allowed {
+			t.Fatalf("Request %d: expected allowed, got %+v, %v", i+1, result, err)
+		}
+	}
+
+	result, _ := sm.ThrottleSlidingCounter("rl:login:alice", 3, window, 1)
+	if result.Allowed || result.Remaining != 0 || result.RetryAfter <= 0 {
+		t.Errorf("Expected 4th request to be denied with a retry time, got %+v", result)
+	}
+	if result.ResetAfter <= result.RetryAfter {
+		t.Errorf("Expected reset after retry, got %+v", result)
+	}
+
+	result, _ = sm.ThrottleSlidingCounter("rl:login:alice", 3, window, 4)
+	if result.RetryAfter != -1 {
+		t.Errorf("Expected -1 for quantity above the limit, got %v", result.RetryAfter)
+	}
+}
+
+// TestShardedMap_ThrottleWrongType 测试对其他类型的键执行限流
+func TestShardedMap_ThrottleWrongType(t *testing.T) {
+	sm := NewShardedMap(1024)
+	sm.Set("session:1", "data", 0)
+	sm.ThrottleGCRA("rl:gcra", GCRALimit{Burst: 1, Count: 1, Period: time.Second}, 1)
+
+	if _, err := sm.ThrottleGCRA("session:1", GCRALimit{Burst: 1, Count: 1, Period: time.Second}, 1); !errors.Is(err, ErrNotRateLimit) {
+		t.Errorf("Expected ErrNotRateLimit, got %v", err)
+	}
+	if _, err := sm.ThrottleSlidingLog("rl:gcra", 1, time.Second, 1); !errors.Is(err, ErrNotRateLimit) {
+		t.Errorf("Expected ErrNotRateLimit for a different algorithm, got %v", err)
+	}
+	if _, err := sm.ThrottleSlidingCounter("rl:gcra", 1, time.Second, 1); !errors.Is(err, ErrNotRateLimit) {
+		t.Errorf("Expected ErrNotRateLimit for a different algorithm, got %v", err)
+	}
+}
@@ -0,0 +1,252 @@
+package storage
+
+import "time"
+
+const (
+	// scanPositionBits 游标中分片内哈希位置所占的位数
+	//
+	// 分片下标取键 FNV-1a 哈希的低 8 位，分片内按剩余的高 24 位排序遍历。
+	scanPositionBits = 24
+
+	// scanPositions 分片内哈希位置的数量
+	scanPositions = 1 << scanPositionBits
+
+	// DefaultScanCount SCAN 每次迭代的默认键数量
+	DefaultScanCount = 10
+
+	// scanMaxIterations 每次迭代最多访问的桶和键数量为 count 的倍数（与 Redis 相同）
+	scanMaxIterations = 10
+
+	// scanMinIndexBits 分片 SCAN 索引缩小时保留的最少桶数量为 1 << scanMinIndexBits
+	scanMinIndexBits = 4
+)
+
+// ScanEntry Scan 返回的一个键
+type ScanEntry struct {
+	Key  string // 存储键（启用键哈希时为哈希后的键）
+	Type string // 值类型，见 TypeOf
+}
+
+// Scan 基于游标遍历全部键
+//
+// 参数说明：
+//   - cursor: 游标，第一次调用传 0，之后传上一次返回的游标
+//   - count: 每次迭代期望返回的键数量（提示值，实际数量可能更多或更少）
+//
+// 返回值：
+//   - []ScanEntry: 本次迭代的键
+//   - uint64: 下一次迭代的游标，0 表示遍历结束
+//
+// 示例：
+//
+//	var cursor uint64
+//	for {
+//	    entries, next := sm.Scan(cursor, 100)
+//	    for _, entry := range entries {
+//	        fmt.Println(entry.Key)
+//	    }
+//	    if next == 0 {
+//	        break
+//	    }
+//	    cursor = next
+//	}
+//
+// 注意事项：
+//   - 游标编码了分片下标和分片内的哈希位置，服务端不保存任何迭代状态
+//   - 从遍历开始到结束一直存在的键恰好返回一次；遍历期间新增或删除的键可能返回，也可能不返回
+//   - 每次迭代只对一个分片加读锁，不会阻塞其他分片的读写
+//   - 每次迭代访问的桶和键约为 count*scanMaxIterations，耗时与 count 相关而与键总数无关，
+//     键稀疏时可能返回少于 count 个键（甚至 0 个）和非 0 游标
+//   - 已过期但未清理的键不会返回
+func (sm *ShardedMap) Scan(cursor uint64, count int) ([]ScanEntry, uint64) {
+	entries, next, _ := sm.scan(cursor, count)
+	return entries, next
+}
+
+// scan 实现 Scan，额外返回本次迭代访问的桶和键的数量（用于测试每次迭代的工作量）
+func (sm *ShardedMap) scan(cursor uint64, count int) ([]ScanEntry, uint64, int) {
+	if count <= 0 {
+		count = DefaultScanCount
+	}
+
+	shard := cursor >> scanPositionBits
+	position := cursor & (scanPositions - 1)
+	budget := count * scanMaxIterations
+	work := 0
+
+	var entries []ScanEntry
+	for shard < DefaultShardCount && len(entries) < count && work < budget {
+		var end uint64
+		var visited int
+		entries, end, visited = sm.scanShard(int(shard), position, count-len(entries), budget-work, entries)
+		work += visited
+
+		if end >= scanPositions {
+			shard++
+			position = 0
+		} else {
+			position = end
+		}
+	}
+
+	if shard >= DefaultShardCount {
+		return entries, 0, work
+	}
+	return entries, shard<<scanPositionBits | position, work
+}
+
+// scanShard 从哈希位置 start 开始按桶遍历分片
+//
+// 遍历完整的桶，直到返回了 count 个键或访问的桶和键达到 budget。
+//
+// 返回值：
+//   - []ScanEntry: 追加了遍历到的键的结果
+//   - uint64: 下一个未遍历的哈希位置，>= scanPositions 表示分片已遍历完
+//   - int: 访问的桶和键的数量，空分片为 0
+func (sm *ShardedMap) scanShard(index int, start uint64, count, budget int, entries []ScanEntry) ([]ScanEntry, uint64, int) {
+	shard := sm.shards[index]
+
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	idx := &shard.scan
+	if idx.size == 0 {
+		return entries, scanPositions, 0
+	}
+
+	shift := scanPositionBits - idx.bits
+	bucket := start >> shift
+	now := time.Now().UnixMilli()
+	found := 0
+	work := 0
+
+	for bucket < uint64(len(idx.buckets)) && found < count && work < budget {
+		work++
+		for _, key := range idx.buckets[bucket] {
+			work++
+			// 索引缩小后，游标可能位于桶的中间，跳过已经遍历过的位置
+			if scanPosition(key) < start {
+				continue
+			}
+			item := shard.items[key]
+			if item.expiresAt > 0 && now >= item.expiresAt {
+				continue
+			}
+			entries = append(entries, ScanEntry{Key: key, Type: TypeOf(item.value)})
+			found++
+		}
+		bucket++
+	}
+
+	return entries, bucket << shift, work
+}
+
+// scanIndex 分片内按哈希位置分桶的键索引
+//
+// 第 i 个桶保存哈希位置的高 bits 位等于 i 的键，桶按下标顺序即按哈希位置顺序排列，
+// 因此 SCAN 只需从游标所在的桶开始依次遍历，无需遍历整个分片。
+// 桶的数量随键数量加倍或减半，平均每个桶保持 1 到 2 个键；
+// 桶的边界始终是哈希位置上的 2 的幂次对齐点，调整桶数量不会使已返回的游标失效。
+type scanIndex struct {
+	bits    uint       // 桶数量为 1 << bits
+	buckets [][]string // 每个桶中的键
+	size    int        // 键数量
+}
+
+// add 将新键加入索引（调用方保证键不在索引中）
+func (idx *scanIndex) add(key string) {
+	if idx.buckets == nil {
+		idx.buckets = make([][]string, 1)
+	}
+	idx.size++
+	if idx.size > 2*len(idx.buckets) && idx.bits < scanPositionBits {
+		idx.resize(idx.bits + 1)
+	}
+	b := idx.bucketOf(key)
+	idx.buckets[b] = append(idx.buckets[b], key)
+}
+
+// remove 将键从索引中删除（调用方保证键在索引中）
+func (idx *scanIndex) remove(key string) {
+	b := idx.bucketOf(key)
+	bucket := idx.buckets[b]
+	for i, k := range bucket {
+		if k == key {
+			last := len(bucket) - 1
+			bucket[i] = bucket[last]
+			bucket[last] = ""
+			idx.buckets[b] = bucket[:last]
+			break
+		}
+	}
+	idx.size--
+	if idx.size < len(idx.buckets)/8 && idx.bits > scanMinIndexBits {
+		idx.resize(idx.bits - 1)
+	}
+}
+
+// bucketOf 返回键所在的桶
+func (idx *scanIndex) bucketOf(key string) uint64 {
+	return scanPosition(key) >> (scanPositionBits - idx.bits)
+}
+
+// resize 将桶数量调整为 1 << bits 并重新分配全部键
+func (idx *scanIndex) resize(bits uint) {
+	old := idx.buckets
+	idx.bits = bits
+	idx.buckets = make([][]string, 1<<bits)
+	for _, bucket := range old {
+		for _, key := range bucket {
+			b := idx.bucketOf(key)
+			idx.buckets[b] = append(idx.buckets[b], key)
+		}
+	}
+}
+
+// put 写入键的值，键不存在时加入 SCAN 索引
+//
+// 分片中所有新增键都必须通过 put 写入，调用方持有写锁。
+func (s *mapShard) put(key string, it *item) {
+	if _, exists := s.items[key]; !exists {
+		s.scan.add(key)
+	}
+	s.items[key] = it
+}
+
+// remove 删除键并将其移出 SCAN 索引
+//
+// 分片中所有键的删除都必须通过 remove，调用方持有写锁。
+func (s *mapShard) remove(key string) {
+	if _, exists := s.items[key]; exists {
+		delete(s.items, key)
+		s.scan.remove(key)
+	}
+}
+
+// scanPosition 返回键在分片内的哈希位置（FNV-1a 哈希的高 24 位）
+//
+// 与 shardIndex 使用同一个哈希，低 8 位决定分片，高 24 位决定分片内的位置。
+func scanPosition(key string) uint64 {
+	return uint64(fnv32a(key) >> (32 - scanPositionBits))
+}
+
+// TypeOf 返回值的类型名称，与 Redis TYPE 命令的返回值一致
+//
+// 字符串和计数器为 "string"；锁、失败记录、验证码、限流状态和 WebAuthn challenge
+// 各有独立的类型名称，SCAN TYPE string 不会返回这些键。
+func TypeOf(value interface{}) string {
+	switch value.(type) {
+	case *lockValue:
+		return "lock"
+	case *lockoutState:
+		return "lockout"
+	case *otpState:
+		return "otp"
+	case *gcraState, *slidingLogState, *slidingCounterState:
+		return "ratelimit"
+	case *WebAuthnChallenge:
+		return "webauthn"
+	default:
+		return "string"
+	}
+}
@@ -0,0 +1,194 @@
+package storage
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// scanAll 使用 Scan 遍历全部键，返回每个键出现的次数和迭代次数
+func scanAll(sm *ShardedMap, count int) (map[string]int, int) {
+	seen := make(map[string]int)
+	var cursor uint64
+	calls := 0
+	for {
+		entries, next := sm.Scan(cursor, count)
+		calls++
+		for _, entry := range entries {
+			seen[entry.Key]++
+		}
+		if next == 0 {
+			return seen, calls
+		}
+		cursor = next
+	}
+}
+
+// TestShardedMap_Scan 测试完整遍历恰好返回每个键一次
+func TestShardedMap_Scan(t *testing.T) {
+	sm := NewShardedMap(1024)
+	for i := 0; i < 5000; i++ {
+		sm.Set(fmt.Sprintf("key:%d", i), i, 0)
+	}
+
+	for _, count := range []int{1, 10, 100, 10000} {
+		seen, calls := scanAll(sm, count)
+		if len(seen) != 5000 {
+			t.Errorf("count=%d: expected 5000 keys, got %d", count, len(seen))
+		}
+		for key, n := range seen {
+			if n != 1 {
+				t.Errorf("count=%d: key %s returned %d times", count, key, n)
+			}
+		}
+		if count == 100 && (calls < 20 || calls > 200) {
+			t.Errorf("count=100: expected about 50 calls, got %d", calls)
+		}
+	}
+
+	if entries, next := NewShardedMap(16).Scan(0, 10); len(entries) != 0 || next != 0 {
+		t.Errorf("Expected empty scan to finish immediately, got %d entries, cursor %d", len(entries), next)
+	}
+}
+
+// TestShardedMap_ScanSkipsExpired 测试不返回已过期的键
+func TestShardedMap_ScanSkipsExpired(t *testing.T) {
+	sm := NewShardedMap(1024)
+	sm.Set("live", "v", 0)
+	sm.SetWithOptions("expired", "v", SetOptions{ExpiresAt: time.Now().UnixMilli() + 20})
+
+	time.Sleep(50 * time.Millisecond)
+
+	seen, _ := scanAll(sm, 10)
+	if seen["live"] != 1 || seen["expired"] != 0 {
+		t.Errorf("Unexpected scan result: %v", seen)
+	}
+}
+
+// TestShardedMap_ScanConcurrentMutation 测试遍历期间的并发写入不影响一直存在的键
+func TestShardedMap_ScanConcurrentMutation(t *testing.T) {
+	sm := NewShardedMap(1024)
+	for i := 0; i < 2000; i++ {
+		sm.Set(fmt.Sprintf("stable:%d", i), i, 0)
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			key := fmt.Sprintf("churn:%d", i%500)
+			if i%2 == 0 {
+				sm.Set(key, i, 0)
+			} else {
+				sm.Delete(key)
+			}
+		}
+	}()
+
+	seen, _ := scanAll(sm, 20)
+	close(stop)
+	wg.Wait()
+
+	for i := 0; i < 2000; i++ {
+		if n := seen[fmt.Sprintf("stable:%d", i)]; n != 1 {
+			t.Fatalf("stable:%d returned %d times, want 1", i, n)
+		}
+	}
+}
+
+// TestShardedMap_ScanTypes 测试锁、失败记录、验证码、限流和 WebAuthn 记录的类型名称
+func TestShardedMap_ScanTypes(t *testing.T) {
+	sm := NewShardedMap(1024)
+	sm.Set("string", "v", 0)
+	sm.IncrBy("counter", 1)
+	sm.AcquireLock("lock", "owner", 10000)
+	sm.RecordFailure("lockout", LockoutPolicy{Window: time.Minute})
+	sm.SetOTP("otp", "123456", 60, 3)
+	sm.ThrottleGCRA("gcra", GCRALimit{Burst: 1, Count: 1, Period: time.Second}, 1)
+	sm.ThrottleSlidingLog("log", 10, time.Second, 1)
+	sm.ThrottleSlidingCounter("counter-window", 10, time.Second, 1)
+	sm.PutChallenge("webauthn", WebAuthnChallenge{Challenge: "c", Ceremony: CeremonyRegistration, RPID: "example.com"}, 60)
+
+	expected := map[string]string{
+		"string":         "string",
+		"counter":        "string",
+		"lock":           "lock",
+		"lockout":        "lockout",
+		"otp":            "otp",
+		"gcra":           "ratelimit",
+		"log":            "ratelimit",
+		"counter-window": "ratelimit",
+		"webauthn":       "webauthn",
+	}
+
+	entries, _ := sm.Scan(0, 1000)
+	if len(entries) != len(expected) {
+		t.Fatalf("Expected %d entries, got %d", len(expected), len(entries))
+	}
+	for _, entry := range entries {
+		if entry.Type != expected[entry.Key] {
+			t.Errorf("TypeOf(%s) = %q, want %q", entry.Key, entry.Type, expected[entry.Key])
+		}
+	}
+}
+
+// TestShardedMap_ScanBoundedWork 测试每次迭代的工作量与 count 相关而与键总数无关
+func TestShardedMap_ScanBoundedWork(t *testing.T) {
+	const count = 10
+	limit := count*scanMaxIterations + 64
+
+	for _, size := range []int{1000, 200000} {
+		sm := NewShardedMap(1024)
+		for i := 0; i < size; i++ {
+			sm.Set(fmt.Sprintf("session:%d", i), i, 0)
+		}
+		// 删除大部分键，使桶变得稀疏
+		for i := 0; i < size; i++ {
+			if i%50 != 0 {
+				sm.Delete(fmt.Sprintf("session:%d", i))
+			}
+		}
+
+		seen := make(map[string]int)
+		var cursor uint64
+		for {
+			entries, next, work := sm.scan(cursor, count)
+			if work > limit {
+				t.Fatalf("size=%d: one iteration did %d units of work, want <= %d", size, work, limit)
+			}
+			for _, entry := range entries {
+				seen[entry.Key]++
+			}
+			if next == 0 {
+				break
+			}
+			cursor = next
+		}
+
+		if len(seen) != size/50 {
+			t.Errorf("size=%d: expected %d keys, got %d", size, size/50, len(seen))
+		}
+	}
+}
+
+// BenchmarkShardedMap_Scan 测试 100 万个键时单次 SCAN 迭代的耗时
+func BenchmarkShardedMap_Scan(b *testing.B) {
+	sm := NewShardedMap(4096)
+	for i := 0; i < 1000000; i++ {
+		sm.Set(fmt.Sprintf("session:%d", i), i, 0)
+	}
+
+	var cursor uint64
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, cursor = sm.Scan(cursor, 100)
+	}
+}
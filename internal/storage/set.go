@@ -0,0 +1,113 @@
+package storage
+
+import "time"
+
+// SetOptions SetWithOptions 的写入条件和过期方式
+//
+// 与 Redis SET 命令的选项一一对应：NX、XX、KEEPTTL，以及由 EX/PX/EXAT/PXAT
+// 换算得到的绝对过期时间。
+type SetOptions struct {
+	// NX 仅当键不存在时写入
+	NX bool
+
+	// XX 仅当键已存在时写入
+	XX bool
+
+	// KeepTTL 保留已有键的过期时间
+	KeepTTL bool
+
+	// ExpiresAt 绝对过期时间（Unix 毫秒），0 表示永不过期
+	ExpiresAt int64
+
+	// Get 需要返回旧值（SET ... GET、GETSET）：旧值不是字符串时不写入，SetResult.WrongType 为 true
+	Get bool
+}
+
+// SetResult SetWithOptions 的执行结果
+type SetResult struct {
+	// Written 是否写入（NX/XX 条件不满足时为 false）
+	Written bool
+
+	// Existed 写入前键是否存在（未过期）
+	Existed bool
+
+	// Old 写入前的值，键不存在时为 nil
+	Old interface{}
+
+	// WrongType 设置了 Get 且旧值不是字符串（如锁、验证码），此时不写入，Old 为 nil
+	WrongType bool
+}
+
+// SetWithOptions 按条件设置键值对
+//
+// 参数说明：
+//   - key: 要设置的键
+//   - value: 要设置的值
+//   - opts: 写入条件和过期方式
+//
+// 返回值：
+//   - SetResult: 是否写入，以及写入前的值（用于 SET ... GET）
+//
+// 示例：
+//
+//	// SET lock:order:42 owner NX PX 30000
+//	result := sm.SetWithOptions("lock:order:42", "owner", SetOptions{
+//	    NX:        true,
+//	    ExpiresAt: time.Now().UnixMilli() + 30000,
+//	})
+//	if !result.Written {
+//	    log.Println("锁已被占用")
+//	}
+//
+// 注意事项：
+//   - 条件检查、读取旧值和写入在同一把分片锁内完成
+//   - ExpiresAt 早于当前时间时删除该键（与 Redis 对过去时间的 EXAT/PXAT 处理一致）
+//   - 由调用方保证 NX 与 XX、KeepTTL 与 ExpiresAt 不同时设置
+func (sm *ShardedMap) SetWithOptions(key string, value interface{}, opts SetOptions) SetResult {
+	key = sm.storageKey(key)
+	shard := sm.getShard(key)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	now := time.Now().UnixMilli()
+
+	var result SetResult
+	existing := liveItem(shard, key)
+	exists := existing != nil
+	if exists {
+		result.Existed = true
+		if opts.Get && TypeOf(existing.value) != "string" {
+			result.WrongType = true
+			return result
+		}
+		result.Old = existing.value
+	}
+
+	if (opts.NX && exists) || (opts.XX && !exists) {
+		return result
+	}
+	result.Written = true
+
+	expiresAt := opts.ExpiresAt
+	if opts.KeepTTL && exists {
+		expiresAt = existing.expiresAt
+	}
+	if expiresAt > 0 && expiresAt <= now {
+		if exists {
+			shard.remove(key)
+			shard.events.notify(KeyEventDel, key)
+		}
+		return result
+	}
+
+	shard.put(key, &item{
+		value:     value,
+		expiresAt: expiresAt,
+		createdAt: now,
+		version:   nextVersion(),
+	})
+	shard.events.notify(KeyEventSet, key)
+
+	return result
+}
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestShardedMap_SetWithOptions 测试条件写入和过期时间
+func TestShardedMap_SetWithOptions(t *testing.T) {
+	sm := NewShardedMap(1024)
+	future := time.Now().UnixMilli() + 60000
+
+	result := sm.SetWithOptions("k", "v1", SetOptions{NX: true, ExpiresAt: future})
+	if !result.Written || result.Existed {
+		t.Fatalf("Expected first NX write, got %+v", result)
+	}
+
+	result = sm.SetWithOptions("k", "v2", SetOptions{NX: true})
+	if result.Written || !result.Existed || result.Old != "v1" {
+		t.Errorf("Expected NX to fail with old value v1, got %+v", result)
+	}
+
+	result = sm.SetWithOptions("k", "v3", SetOptions{XX: true, KeepTTL: true})
+	if !result.Written || result.Old != "v1" {
+		t.Errorf("Expected XX write with old value v1, got %+v", result)
+	}
+	if ttl := TTL(sm, "k"); ttl < 59 || ttl > 60 {
+		t.Errorf("Expected KEEPTTL to keep TTL around 60, got %d", ttl)
+	}
+
+	result = sm.SetWithOptions("missing", "v", SetOptions{XX: true})
+	if result.Written || sm.Exists("missing") {
+		t.Errorf("Expected XX on missing key to do nothing, got %+v", result)
+	}
+
+	result = sm.SetWithOptions("k", "v4", SetOptions{ExpiresAt: time.Now().UnixMilli() - 1})
+	if !result.Written || sm.Exists("k") {
+		t.Errorf("Expected expiry in the past to delete the key, got %+v", result)
+	}
+}
+
+// TestShardedMap_SetWithOptionsExpired 测试已过期的键视为不存在
+func TestShardedMap_SetWithOptionsExpired(t *testing.T) {
+	sm := NewShardedMap(1024)
+	sm.SetWithOptions("k", "old", SetOptions{ExpiresAt: time.Now().UnixMilli() + 50})
+
+	time.Sleep(100 * time.Millisecond)
+
+	result := sm.SetWithOptions("k", "new", SetOptions{NX: true})
+	if !result.Written || result.Existed || result.Old != nil {
+		t.Errorf("Expected expired key to be treated as missing, got %+v", result)
+	}
+}
+
+// TestShardedMap_SetWithOptionsConcurrentNX 测试并发 NX 只有一个成功
+func TestShardedMap_SetWithOptionsConcurrentNX(t *testing.T) {
+	sm := NewShardedMap(1024)
+
+	var wg sync.WaitGroup
+	var acquired atomic.Int32
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(owner int) {
+			defer wg.Done()
+			if sm.SetWithOptions("lock", owner, SetOptions{NX: true}).Written {
+				acquired.Add(1)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if acquired.Load() != 1 {
+		t.Errorf("Expected exactly 1 NX winner, got %d", acquired.Load())
+	}
+}
@@ -1,7 +1,6 @@
 package storage
 
 import (
-	"hash/fnv"
 	"sync"
 	"time"
 )
@@ -20,8 +19,9 @@ const (
 // item 表示存储的单个数据项
 type item struct {
 	value     interface{} // 存储的值
-	expiresAt int64       // 过期时间戳（Unix 秒），0 表示永不过期
-	createdAt int64       // 创建时间戳
+	expiresAt int64       // 过期时间戳（Unix 毫秒），0 表示永不过期
+	createdAt int64       // 创建时间戳（Unix 毫秒）
+	version   uint64      // 写入版本号（WATCH），每次修改都会更新
 }
 
 // mapShard 表示单个分片
@@ -30,6 +30,10 @@ type item struct {
 type mapShard struct {
 	mu    sync.RWMutex       // 读写锁，保证并发安全
 	items map[string]*item   // 存储的键值对
+	txMu  sync.RWMutex       // 事务锁（见 LockKeys），与 mu 相互独立
+	scan  scanIndex          // SCAN 使用的按哈希位置排序的桶索引（见 scan.go）
+
+	events *keyEvents // 所属 ShardedMap 的键空间事件回调
 }
 
 // ShardedMap 是一个线程安全的分片哈希表，用于高并发场景下的键值存储
@@ -47,6 +51,9 @@ type mapShard struct {
 //	}
 type ShardedMap struct {
 	shards [DefaultShardCount]*mapShard // 256 个分片
+	hasher *KeyHasher                   // 键哈希器，为 nil 表示按原样存储键
+	id     uint64                       // 实例编号，用于多个实例之间的加锁顺序
+	events keyEvents                    // 键空间事件回调（SetKeyEventHandler）
 }
 
 // NewShardedMap 创建一个新的分片哈希表实例
@@ -70,10 +77,11 @@ func NewShardedMap(initialCapacity int) *ShardedMap {
 		initialCapacity = DefaultInitialCapacity
 	}
 
-	sm := &ShardedMap{}
+	sm := &ShardedMap{id: nextMapID()}
 	for i := 0; i < DefaultShardCount; i++ {
 		sm.shards[i] = &mapShard{
-			items: make(map[string]*item, initialCapacity),
+			items:  make(map[string]*item, initialCapacity),
+			events: &sm.events,
 		}
 	}
 
@@ -84,10 +92,49 @@ func NewShardedMap(initialCapacity int) *ShardedMap {
 //
 // 使用 FNV-1a 哈希算法将键映射到 0-255 的分片索引。
 func (sm *ShardedMap) getShard(key string) *mapShard {
-	h := fnv.New32a()
-	h.Write([]byte(key))
-	index := h.Sum32() % DefaultShardCount
-	return sm.shards[index]
+	return sm.shards[shardIndex(key)]
+}
+
+// shardIndex 计算键所在分片的下标
+func shardIndex(key string) uint32 {
+	return fnv32a(key) % DefaultShardCount
+}
+
+// fnv32a 计算键的 FNV-1a 哈希
+//
+// 与 hash/fnv 的结果相同，内联计算以避免为每个键分配哈希器。
+func fnv32a(key string) uint32 {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+
+	h := uint32(offset32)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= prime32
+	}
+	return h
+}
+
+// SetKeyHasher 设置键哈希器，匹配前缀的键将以哈希形式存储
+//
+// 设置后 Set、Get、Delete、TTL 等按键访问的方法会先对键做哈希，
+// 对调用方透明；GetAllKeys 返回的是哈希后的键。
+//
+// 注意事项：
+//   - 必须在写入任何数据之前调用，该方法不是并发安全的
+//   - 传入 nil 表示关闭键哈希
+func (sm *ShardedMap) SetKeyHasher(hasher *KeyHasher) {
+	sm.hasher = hasher
+}
+
+// storageKey 返回键在分片中实际存储的形式
+func (sm *ShardedMap) storageKey(key string) string {
+	if sm.hasher == nil {
+		return key
+	}
+	return sm.hasher.Hash(key)
 }
 
 // Set 在分片哈希表中设置键值对，并指定过期时间
@@ -113,26 +160,75 @@ func (sm *ShardedMap) getShard(key string) *mapShard {
 //   - 如果 key 已存在，将覆盖旧值
 //   - ttl 使用惰性删除 + 定期清理策略
 func (sm *ShardedMap) Set(key string, value interface{}, ttl int) error {
+	key = sm.storageKey(key)
 	shard := sm.getShard(key)
 
 	shard.mu.Lock()
 	defer shard.mu.Unlock()
 
-	now := time.Now().Unix()
+	now := time.Now().UnixMilli()
 	var expiresAt int64
 	if ttl > 0 {
-		expiresAt = now + int64(ttl)
+		expiresAt = now + int64(ttl)*1000
 	}
 
-	shard.items[key] = &item{
+	shard.put(key, &item{
 		value:     value,
 		expiresAt: expiresAt,
 		createdAt: now,
-	}
+		version:   nextVersion(),
+	})
+	shard.events.notify(KeyEventSet, key)
 
 	return nil
 }
 
+// SetNX 仅当键不存在（或已过期）时设置键值对
+//
+// 参数说明：
+//   - key: 要设置的键
+//   - value: 要设置的值
+//   - ttl: 生存时间（秒），0 表示永不过期
+//
+// 返回值：
+//   - bool: 是否设置成功（false 表示键已存在）
+//
+// 示例：
+//
+//	if !sm.SetNX("oauth:code:xyz", codeData, 300) {
+//	    log.Println("授权码已存在")
+//	}
+//
+// 注意事项：
+//   - 该方法是并发安全的，检查和设置在同一把分片锁内完成
+func (sm *ShardedMap) SetNX(key string, value interface{}, ttl int) bool {
+	key = sm.storageKey(key)
+	shard := sm.getShard(key)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if liveItem(shard, key) != nil {
+		return false
+	}
+
+	now := time.Now().UnixMilli()
+	var expiresAt int64
+	if ttl > 0 {
+		expiresAt = now + int64(ttl)*1000
+	}
+
+	shard.put(key, &item{
+		value:     value,
+		expiresAt: expiresAt,
+		createdAt: now,
+		version:   nextVersion(),
+	})
+	shard.events.notify(KeyEventSet, key)
+
+	return true
+}
+
 // Get 从分片哈希表中获取指定键的值
 //
 // 参数说明：
@@ -156,6 +252,7 @@ func (sm *ShardedMap) Set(key string, value interface{}, ttl int) error {
 //   - 访问时会检查 TTL，如果已过期则删除并返回 false（惰性删除）
 //   - 类型断言需要调用方自行处理
 func (sm *ShardedMap) Get(key string) (interface{}, bool) {
+	key = sm.storageKey(key)
 	shard := sm.getShard(key)
 
 	shard.mu.Lock()
@@ -167,9 +264,10 @@ func (sm *ShardedMap) Get(key string) (interface{}, bool) {
 	}
 
 	// 检查是否过期（惰性删除）
-	if item.expiresAt > 0 && time.Now().Unix() >= item.expiresAt {
+	if item.expiresAt > 0 && time.Now().UnixMilli() >= item.expiresAt {
 		// 删除过期的键
-		delete(shard.items, key)
+		shard.remove(key)
+		shard.events.notify(KeyEventExpired, key)
 		return nil, false
 	}
 
@@ -195,6 +293,7 @@ func (sm *ShardedMap) Get(key string) (interface{}, bool) {
 //   - 该方法是并发安全的
 //   - 如果键不存在，返回 false
 func (sm *ShardedMap) Delete(key string) bool {
+	key = sm.storageKey(key)
 	shard := sm.getShard(key)
 
 	shard.mu.Lock()
@@ -205,7 +304,8 @@ func (sm *ShardedMap) Delete(key string) bool {
 		return false
 	}
 
-	delete(shard.items, key)
+	shard.remove(key)
+	shard.events.notify(KeyEventDel, key)
 	return true
 }
 
@@ -260,11 +360,21 @@ func (sm *ShardedMap) Len() int {
 // 注意事项：
 //   - 该方法是并发安全的
 //   - 会删除所有键值对，包括未过期的
+//   - 设置了 KeyEventDel 回调时，每个被删除的键都会通知一次
 func (sm *ShardedMap) Clear() {
+	notify := sm.events.selects(KeyEventDel)
 	for i := 0; i < DefaultShardCount; i++ {
-		sm.shards[i].mu.Lock()
-		sm.shards[i].items = make(map[string]*item, DefaultInitialCapacity)
-		sm.shards[i].mu.Unlock()
+		shard := sm.shards[i]
+		shard.mu.Lock()
+		old := shard.items
+		shard.items = make(map[string]*item, DefaultInitialCapacity)
+		shard.scan = scanIndex{}
+		if notify {
+			for key := range old {
+				shard.events.notify(KeyEventDel, key)
+			}
+		}
+		shard.mu.Unlock()
 	}
 }
 
@@ -60,6 +60,30 @@ func TestShardedMap_SetOverwrite(t *testing.T) {
 	}
 }
 
+// TestShardedMap_SetNX 测试仅当键不存在时设置
+func TestShardedMap_SetNX(t *testing.T) {
+	sm := NewShardedMap(1024)
+
+	if !sm.SetNX("key1", "value1", 0) {
+		t.Error("SetNX on missing key should succeed")
+	}
+	if sm.SetNX("key1", "value2", 0) {
+		t.Error("SetNX on existing key should fail")
+	}
+
+	value, _ := sm.Get("key1")
+	if value != "value1" {
+		t.Errorf("Expected 'value1', got '%v'", value)
+	}
+
+	// 已过期的键视为不存在
+	sm.Set("key2", "old", 1)
+	time.Sleep(1100 * time.Millisecond)
+	if !sm.SetNX("key2", "new", 0) {
+		t.Error("SetNX on expired key should succeed")
+	}
+}
+
 // TestShardedMap_GetNonExistent 测试获取不存在的键
 func TestShardedMap_GetNonExistent(t *testing.T) {
 	sm := NewShardedMap(1024)
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+// ErrWrongType 键的值不是字符串，无法执行字符串操作
+var ErrWrongType = errors.New("value is not a string")
+
+// GetDel 获取键的值并删除该键
+//
+// 参数说明：
+//   - key: 要获取并删除的键
+//
+// 返回值：
+//   - interface{}: 键原来的值
+//   - bool: 键是否存在（未过期）
+//   - error: 键的值不是字符串（如锁、验证码）时返回 ErrWrongType，此时不删除该键
+//
+// 示例：
+//
+//	// 一次性授权码：读取后立即失效
+//	code, found, err := sm.GetDel("oauth:code:xyz")
+//	if err != nil || !found {
+//	    return errors.New("授权码无效或已使用")
+//	}
+//
+// 注意事项：
+//   - 读取和删除在同一把分片锁内完成，并发调用时只有一个调用方能取到值
+func (sm *ShardedMap) GetDel(key string) (interface{}, bool, error) {
+	key = sm.storageKey(key)
+	shard := sm.getShard(key)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	item := liveItem(shard, key)
+	if item == nil {
+		return nil, false, nil
+	}
+	if TypeOf(item.value) != "string" {
+		return nil, true, ErrWrongType
+	}
+	shard.remove(key)
+	shard.events.notify(KeyEventDel, key)
+
+	return item.value, true, nil
+}
+
+// Append 将数据追加到键的值末尾
+//
+// 参数说明：
+//   - key: 要追加的键，不存在时等同于设置为 data
+//   - data: 要追加的数据
+//
+// 返回值：
+//   - int: 追加后值的长度（字节）
+//   - error: 键的值不是字符串时返回 ErrWrongType
+//
+// 注意事项：
+//   - 保留键原有的过期时间
+//   - 追加后的值以 []byte 存储（计数器的值先转换为十进制字符串）
+func (sm *ShardedMap) Append(key string, data []byte) (int, error) {
+	key = sm.storageKey(key)
+	shard := sm.getShard(key)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	now := time.Now().UnixMilli()
+	existing := liveItem(shard, key)
+
+	if existing == nil {
+		value := append([]byte(nil), data...)
+		shard.put(key, &item{
+			value:     value,
+			createdAt: now,
+			version:   nextVersion(),
+		})
+		shard.events.notify(KeyEventSet, key)
+		return len(value), nil
+	}
+
+	var current []byte
+	switch v := existing.value.(type) {
+	case []byte:
+		current = v
+	case string:
+		current = []byte(v)
+	case int64:
+		current = strconv.AppendInt(nil, v, 10)
+	case float64:
+		current = []byte(FormatFloat(v))
+	default:
+		return 0, ErrWrongType
+	}
+
+	// 复制一份，避免修改其他调用方持有的旧值
+	value := make([]byte, 0, len(current)+len(data))
+	value = append(value, current...)
+	value = append(value, data...)
+	existing.value = value
+	existing.version = nextVersion()
+	shard.events.notify(KeyEventSet, key)
+
+	return len(value), nil
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// TestShardedMap_GetDel 测试读取后删除
+func TestShardedMap_GetDel(t *testing.T) {
+	sm := NewShardedMap(1024)
+	sm.Set("code", "xyz", 60)
+
+	value, found, _ := sm.GetDel("code")
+	if !found || value != "xyz" {
+		t.Errorf("Expected xyz, got %v, %v", value, found)
+	}
+	if sm.Exists("code") {
+		t.Error("Expected key deleted after GetDel")
+	}
+	if _, found, _ := sm.GetDel("code"); found {
+		t.Error("Expected second GetDel to miss")
+	}
+}
+
+// TestShardedMap_GetDelWrongType 测试 GetDel 不读取、不删除非字符串记录
+func TestShardedMap_GetDelWrongType(t *testing.T) {
+	sm := NewShardedMap(1024)
+	sm.AcquireLock("lock", "owner", 10000)
+
+	if _, found, err := sm.GetDel("lock"); !found || !errors.Is(err, ErrWrongType) {
+		t.Errorf("Expected ErrWrongType, got %v, %v", found, err)
+	}
+	if !sm.Exists("lock") {
+		t.Error("Expected lock to survive GetDel")
+	}
+
+	result := sm.SetWithOptions("lock", "v", SetOptions{Get: true})
+	if !result.WrongType || result.Written || result.Old != nil {
+		t.Errorf("Expected SET GET on a lock to be rejected, got %+v", result)
+	}
+	if lock, held, _ := sm.GetLock("lock"); !held || lock.Owner != "owner" {
+		t.Error("Expected lock to survive SET GET")
+	}
+}
+
+// TestShardedMap_GetDelConcurrent 测试并发 GetDel 只有一个调用方取到值
+func TestShardedMap_GetDelConcurrent(t *testing.T) {
+	sm := NewShardedMap(1024)
+	sm.Set("code", "xyz", 60)
+
+	var wg sync.WaitGroup
+	var hits atomic.Int32
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, found, _ := sm.GetDel("code"); found {
+				hits.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if hits.Load() != 1 {
+		t.Errorf("Expected exactly 1 GetDel hit, got %d", hits.Load())
+	}
+}
+
+// TestShardedMap_Append 测试追加数据
+func TestShardedMap_Append(t *testing.T) {
+	sm := NewShardedMap(1024)
+
+	if n, err := sm.Append("log", []byte("ab")); err != nil || n != 2 {
+		t.Errorf("Append to missing key = %d, %v; want 2, nil", n, err)
+	}
+
+	sm.Set("greeting", "hello", 60)
+	if n, err := sm.Append("greeting", []byte(" world")); err != nil || n != 11 {
+		t.Errorf("Append = %d, %v; want 11, nil", n, err)
+	}
+	if value, _ := sm.Get("greeting"); string(value.([]byte)) != "hello world" {
+		t.Errorf("Expected hello world, got %v", value)
+	}
+	if ttl := TTL(sm, "greeting"); ttl <= 0 {
+		t.Errorf("Expected TTL kept after Append, got %d", ttl)
+	}
+
+	sm.Set("number", 42, 0)
+	if _, err := sm.Append("number", []byte("x")); !errors.Is(err, ErrWrongType) {
+		t.Errorf("Expected ErrWrongType, got %v", err)
+	}
+}
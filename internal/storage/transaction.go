@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"sort"
+	"sync/atomic"
+)
+
+// mapIDCounter ShardedMap 实例编号
+var mapIDCounter atomic.Uint64
+
+// nextMapID 分配一个新的 ShardedMap 实例编号
+func nextMapID() uint64 {
+	return mapIDCounter.Add(1)
+}
+
+// KeyRef 指定逻辑数据库中的一个键
+type KeyRef struct {
+	DB  *ShardedMap
+	Key string
+}
+
+// shardRef 一个分片的事务锁
+type shardRef struct {
+	mapID uint64
+	index uint32
+	shard *mapShard
+}
+
+// LockKeys 对键所在分片的事务锁加写锁
+//
+// 事务锁与分片的数据锁相互独立：持有事务锁期间仍可以正常调用 Get、Set 等方法，
+// 而其他调用 RLockKeys 的调用方会等待事务锁释放。MULTI/EXEC 通过它让整个事务原子执行。
+//
+// 参数说明：
+//   - refs: 要锁定的键，可以分属多个逻辑数据库
+//
+// 返回值：
+//   - func(): 释放全部事务锁
+//
+// 注意事项：
+//   - 分片按（实例编号，分片下标）升序加锁，LockKeys 与 RLockKeys 之间不会死锁
+//   - 同一个分片只加一次锁
+//   - 持有事务锁时不能再对同一分片调用 LockKeys 或 RLockKeys
+func LockKeys(refs []KeyRef) func() {
+	shards := make([]shardRef, 0, len(refs))
+	for _, ref := range refs {
+		shards = append(shards, ref.DB.shardRef(ref.Key))
+	}
+	return lockShardRefs(shards, false)
+}
+
+// LockDatabases 对逻辑数据库全部分片的事务锁加写锁
+//
+// 用于包含 FLUSHDB、SWAPDB 等不带键的命令的事务：这类命令影响整个数据库，
+// 需要与其他连接的全部键命令互斥。
+//
+// 参数说明：
+//   - dbs: 要锁定的逻辑数据库
+//
+// 返回值：
+//   - func(): 释放全部事务锁
+//
+// 注意事项：
+//   - 与 LockKeys 使用相同的加锁顺序，两者之间不会死锁
+func LockDatabases(dbs []*ShardedMap) func() {
+	return lockShardRefs(databaseShards(dbs), false)
+}
+
+// RLockDatabases 对逻辑数据库全部分片的事务锁加读锁
+//
+// 用于 KEYS、SCAN、DBSIZE 等读取整个数据库的命令：多个 RLockDatabases 之间、
+// 与 RLockKeys 之间互不阻塞，但会等待正在执行的事务（LockKeys）完成，不会读到事务的部分结果。
+//
+// 参数说明：
+//   - dbs: 要锁定的逻辑数据库
+//
+// 返回值：
+//   - func(): 释放全部事务锁
+func RLockDatabases(dbs []*ShardedMap) func() {
+	return lockShardRefs(databaseShards(dbs), true)
+}
+
+// databaseShards 返回逻辑数据库的全部分片
+func databaseShards(dbs []*ShardedMap) []shardRef {
+	shards := make([]shardRef, 0, len(dbs)*DefaultShardCount)
+	for _, sm := range dbs {
+		for i, shard := range sm.shards {
+			shards = append(shards, shardRef{mapID: sm.id, index: uint32(i), shard: shard})
+		}
+	}
+	return shards
+}
+
+// RLockKeys 对键所在分片的事务锁加读锁
+//
+// 普通命令在执行前调用，使其不会与正在执行的事务（LockKeys）交错。
+// 多个 RLockKeys 之间互不阻塞。
+//
+// 参数说明：
+//   - keys: 要锁定的键
+//
+// 返回值：
+//   - func(): 释放全部事务锁
+func (sm *ShardedMap) RLockKeys(keys []string) func() {
+	if len(keys) == 1 {
+		shard := sm.getShard(sm.storageKey(keys[0]))
+		shard.txMu.RLock()
+		return shard.txMu.RUnlock
+	}
+
+	shards := make([]shardRef, 0, len(keys))
+	for _, key := range keys {
+		shards = append(shards, sm.shardRef(key))
+	}
+	return lockShardRefs(shards, true)
+}
+
+// shardRef 返回键所在分片的引用
+func (sm *ShardedMap) shardRef(key string) shardRef {
+	index := shardIndex(sm.storageKey(key))
+	return shardRef{mapID: sm.id, index: index, shard: sm.shards[index]}
+}
+
+// lockShardRefs 按固定顺序对分片的事务锁加锁并去重
+func lockShardRefs(shards []shardRef, shared bool) func() {
+	sort.Slice(shards, func(i, j int) bool {
+		if shards[i].mapID != shards[j].mapID {
+			return shards[i].mapID < shards[j].mapID
+		}
+		return shards[i].index < shards[j].index
+	})
+
+	locked := make([]*mapShard, 0, len(shards))
+	for _, ref := range shards {
+		if len(locked) > 0 && locked[len(locked)-1] == ref.shard {
+			continue
+		}
+		if shared {
+			ref.shard.txMu.RLock()
+		} else {
+			ref.shard.txMu.Lock()
+		}
+		locked = append(locked, ref.shard)
+	}
+
+	return func() {
+		for i := len(locked) - 1; i >= 0; i-- {
+			if shared {
+				locked[i].txMu.RUnlock()
+			} else {
+				locked[i].txMu.Unlock()
+			}
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+// TestShardedMap_Version 测试写入版本号在每次修改后变化
+func TestShardedMap_Version(t *testing.T) {
+	sm := NewShardedMap(1024)
+
+	if v := sm.Version("k"); v != 0 {
+		t.Errorf("Expected version 0 for missing key, got %d", v)
+	}
+
+	sm.Set("k", "a", 0)
+	v1 := sm.Version("k")
+	if v1 == 0 {
+		t.Fatal("Expected non-zero version after Set")
+	}
+	if sm.Version("k") != v1 {
+		t.Error("Expected version to be stable without writes")
+	}
+	sm.Get("k")
+	if sm.Version("k") != v1 {
+		t.Error("Expected Get not to change version")
+	}
+
+	writes := []struct {
+		name string
+		fn   func()
+	}{
+		{"Set", func() { sm.Set("k", "b", 0) }},
+		{"Expire", func() { Expire(sm, "k", 100) }},
+		{"Append", func() { sm.Append("k", []byte("c")) }},
+		{"SetWithOptions", func() { sm.SetWithOptions("k", "1", SetOptions{KeepTTL: true}) }},
+		{"IncrBy", func() { sm.IncrBy("k", 1) }},
+		{"IncrByFloat", func() { sm.IncrByFloat("k", 0.5) }},
+		{"MSet", func() { sm.MSet([]KeyValue{{Key: "k", Value: "d"}}) }},
+	}
+	last := v1
+	for _, w := range writes {
+		w.fn()
+		v := sm.Version("k")
+		if v == last {
+			t.Errorf("%s: expected version to change", w.name)
+		}
+		last = v
+	}
+
+	// 删除后重新创建的键版本号与原来不同
+	sm.Delete("k")
+	if v := sm.Version("k"); v != 0 {
+		t.Errorf("Expected version 0 after Delete, got %d", v)
+	}
+	sm.Set("k", "d", 0)
+	if v := sm.Version("k"); v == 0 || v == last {
+		t.Errorf("Expected new version after re-create, got %d", v)
+	}
+
+	// 已过期的键版本号为 0
+	sm.SetWithOptions("e", "v", SetOptions{ExpiresAt: time.Now().UnixMilli() + 10})
+	time.Sleep(20 * time.Millisecond)
+	if v := sm.Version("e"); v != 0 {
+		t.Errorf("Expected version 0 for expired key, got %d", v)
+	}
+}
+
+// TestLockKeys 测试事务锁阻塞 RLockKeys 但不阻塞普通读写
+func TestLockKeys(t *testing.T) {
+	db0, db1 := NewShardedMap(1024), NewShardedMap(1024)
+	unlock := LockKeys([]KeyRef{{DB: db0, Key: "a"}, {DB: db1, Key: "b"}, {DB: db0, Key: "a"}})
+
+	// 持有事务锁时仍可正常读写
+	db0.Set("a", "1", 0)
+	if value, _ := db0.Get("a"); value != "1" {
+		t.Errorf("Expected '1', got %v", value)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		release := db0.RLockKeys([]string{"x", "a"})
+		close(acquired)
+		release()
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("Expected RLockKeys to wait for LockKeys")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unlock()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("Expected RLockKeys to proceed after unlock")
+	}
+
+	// 共享锁之间互不阻塞
+	r1 := db1.RLockKeys([]string{"b"})
+	r2 := db1.RLockKeys([]string{"b", "c"})
+	r1()
+	r2()
+}
@@ -9,8 +9,10 @@ import (
 //
 // TTLManager 运行一个后台 Goroutine，定期扫描并清理过期的键。
 // 这与 Get 方法中的惰性删除配合使用，确保过期键能够及时清理。
+// 清理的每个键都会触发 KeyEventExpired 事件（见 ShardedMap.SetKeyEventHandler）。
+// 管理一组逻辑数据库时，每次清理会依次处理每个已创建的数据库。
 type TTLManager struct {
-	sm              *ShardedMap   // 要管理的 ShardedMap
+	dbs             *Databases    // 要管理的逻辑数据库
 	cleanupInterval time.Duration // 清理间隔
 	keysPerScan     int           // 每次扫描清理的键数
 	stopCh          chan struct{} // 停止信号
@@ -57,12 +59,35 @@ func DefaultTTLManagerConfig() *TTLManagerConfig {
 //   - 需要调用 Start() 启动清理任务
 //   - 使用完毕后应调用 Stop() 停止清理任务
 func NewTTLManager(sm *ShardedMap, config *TTLManagerConfig) *TTLManager {
+	return NewDatabasesTTLManager(NewDatabases(sm, 1), config)
+}
+
+// NewDatabasesTTLManager 创建一个清理全部逻辑数据库的 TTL 管理器
+//
+// 参数说明：
+//   - dbs: 要管理的逻辑数据库
+//   - config: TTL 管理器配置，如果为 nil 则使用默认配置
+//
+// 返回值：
+//   - *TTLManager: TTL 管理器实例
+//
+// 示例：
+//
+//	dbs := NewDatabases(NewShardedMap(4096), 16)
+//	ttlMgr := NewDatabasesTTLManager(dbs, nil)
+//	ttlMgr.Start()
+//	defer ttlMgr.Stop()
+//
+// 注意事项：
+//   - KeysPerScan 对每个逻辑数据库分别生效
+//   - 尚未创建的逻辑数据库不会被扫描
+func NewDatabasesTTLManager(dbs *Databases, config *TTLManagerConfig) *TTLManager {
 	if config == nil {
 		config = DefaultTTLManagerConfig()
 	}
 
 	return &TTLManager{
-		sm:              sm,
+		dbs:             dbs,
 		cleanupInterval: config.CleanupInterval,
 		keysPerScan:     config.KeysPerScan,
 		stopCh:          make(chan struct{}),
@@ -140,19 +165,21 @@ func (tm *TTLManager) cleanupLoop() {
 
 // cleanup 执行一次清理操作
 //
-// 遍历所有分片，从每个分片中随机选择一些键检查是否过期，
+// 遍历所有逻辑数据库的所有分片，从每个分片中随机选择一些键检查是否过期，
 // 如果过期则删除。这种采样方式既能及时清理过期键，
 // 又不会因为扫描所有键而影响性能。
 func (tm *TTLManager) cleanup() {
-	now := time.Now().Unix()
+	now := time.Now().UnixMilli()
 	keysPerShard := tm.keysPerScan / DefaultShardCount
 	if keysPerShard < 1 {
 		keysPerShard = 1
 	}
 
-	for i := 0; i < DefaultShardCount; i++ {
-		tm.cleanupShard(tm.sm.shards[i], keysPerShard, now)
-	}
+	tm.dbs.Each(func(_ int, sm *ShardedMap) {
+		for i := 0; i < DefaultShardCount; i++ {
+			tm.cleanupShard(sm.shards[i], keysPerShard, now)
+		}
+	})
 }
 
 // cleanupShard 清理单个分片中的过期键
@@ -184,7 +211,8 @@ func (tm *TTLManager) cleanupShard(shard *mapShard, maxKeys int, now int64) {
 
 	// 删除过期的键
 	for _, key := range expiredKeys {
-		delete(shard.items, key)
+		shard.remove(key)
+		shard.events.notify(KeyEventExpired, key)
 	}
 }
 
@@ -226,6 +254,7 @@ func (tm *TTLManager) GetStats() TTLStats {
 //	    log.Printf("剩余 %d 秒", ttl)
 //	}
 func TTL(sm *ShardedMap, key string) int64 {
+	key = sm.storageKey(key)
 	shard := sm.getShard(key)
 
 	shard.mu.RLock()
@@ -240,14 +269,13 @@ func TTL(sm *ShardedMap, key string) int64 {
 		return -2 // 永不过期
 	}
 
-	now := time.Now().Unix()
-	remaining := item.expiresAt - now
-
+	remaining := item.expiresAt - time.Now().UnixMilli()
 	if remaining <= 0 {
 		return 0 // 已过期或即将过期
 	}
 
-	return remaining
+	// 与 Redis 相同，按四舍五入换算为秒
+	return (remaining + 500) / 1000
 }
 
 // Expire 更新键的过期时间
@@ -267,6 +295,7 @@ func TTL(sm *ShardedMap, key string) int64 {
 //	    log.Println("会话不存在")
 //	}
 func Expire(sm *ShardedMap, key string, ttl int) bool {
+	key = sm.storageKey(key)
 	shard := sm.getShard(key)
 
 	shard.mu.Lock()
@@ -278,10 +307,12 @@ func Expire(sm *ShardedMap, key string, ttl int) bool {
 	}
 
 	if ttl > 0 {
-		item.expiresAt = time.Now().Unix() + int64(ttl)
+		item.expiresAt = time.Now().UnixMilli() + int64(ttl)*1000
 	} else {
 		item.expiresAt = 0 // 永不过期
 	}
+	item.version = nextVersion()
+	shard.events.notify(KeyEventExpire, key)
 
 	return true
 }
@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// versionCounter 全局递增的写入版本号，同时作为分布式锁的 fencing token
+//
+// 每次写入都分配一个新的版本号，因此同一个键的版本号单调递增，
+// 删除后重新创建的键也不会与旧版本号相同。
+//
+// 进程启动时以当前时间的 Unix 微秒数为起点（见 versionSeed），而不是从 1 开始，
+// 因此重启后分配的 fencing token 大于重启前分配的全部 token，下游不会拒绝新持有者。
+var versionCounter atomic.Uint64
+
+func init() {
+	versionCounter.Store(versionSeed(time.Now()))
+}
+
+// versionSeed 返回进程启动时版本号计数器的起点：now 的 Unix 微秒数
+//
+// 注意事项：
+//   - 只要上次运行期间平均每微秒分配的版本号不超过 1 个（即每秒不超过 100 万次写入），
+//     重启后的起点就大于上次运行分配的最大版本号
+//   - 依赖系统时钟不回拨；时钟回拨超过上次运行的时长时，重启后的 token 可能小于之前的 token
+func versionSeed(now time.Time) uint64 {
+	return uint64(now.UnixMicro())
+}
+
+// nextVersion 分配一个新的写入版本号
+func nextVersion() uint64 {
+	return versionCounter.Add(1)
+}
+
+// Version 返回键当前的写入版本号
+//
+// 参数说明：
+//   - key: 要查询的键
+//
+// 返回值：
+//   - uint64: 写入版本号，键不存在或已过期时返回 0
+//
+// 示例：
+//
+//	// WATCH：记录版本号，EXEC 前比较
+//	watched := sm.Version("session:abc")
+//	...
+//	if sm.Version("session:abc") != watched {
+//	    // 键在此期间被修改、删除或已过期
+//	}
+//
+// 注意事项：
+//   - 任何修改（包括 EXPIRE、INCR、APPEND）都会更新版本号
+//   - 版本号全局唯一，删除后重新创建的键的版本号与原来不同
+func (sm *ShardedMap) Version(key string) uint64 {
+	key = sm.storageKey(key)
+	shard := sm.getShard(key)
+
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	it, exists := shard.items[key]
+	if !exists {
+		return 0
+	}
+	if it.expiresAt > 0 && time.Now().UnixMilli() >= it.expiresAt {
+		return 0
+	}
+	return it.version
+}
+
+// GetWithVersion 获取键的值和写入版本号
+//
+// 参数说明：
+//   - key: 要获取的键
+//
+// 返回值：
+//   - interface{}: 键对应的值
+//   - uint64: 写入版本号
+//   - bool: 是否找到该键（true 表示找到且未过期）
+//
+// 示例：
+//
+//	value, version, found := sm.GetWithVersion("session:abc")
+//	if found {
+//	    updated := update(value)
+//	    if _, ok := sm.CompareAndSet("session:abc", version, updated, SetOptions{KeepTTL: true}); !ok {
+//	        // 期间被其他调用方修改，重新读取后重试
+//	    }
+//	}
+func (sm *ShardedMap) GetWithVersion(key string) (interface{}, uint64, bool) {
+	key = sm.storageKey(key)
+	shard := sm.getShard(key)
+
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	it, exists := shard.items[key]
+	if !exists {
+		return nil, 0, false
+	}
+	if it.expiresAt > 0 && time.Now().UnixMilli() >= it.expiresAt {
+		return nil, 0, false
+	}
+	return it.value, it.version, true
+}
+
+// CompareAndSet 仅当键的写入版本号仍等于 expected 时写入新值
+//
+// 参数说明：
+//   - key: 要设置的键
+//   - expected: 期望的写入版本号，0 表示仅当键不存在时写入
+//   - value: 要设置的值
+//   - opts: 过期方式，只使用 KeepTTL 和 ExpiresAt
+//
+// 返回值：
+//   - uint64: 写入成功时为新的版本号，失败时为键当前的版本号（不存在时为 0）
+//   - bool: 是否写入
+//
+// 注意事项：
+//   - 版本号比较和写入在同一把分片锁内完成
+//   - ExpiresAt 早于当前时间时删除该键，返回 0 和 true
+func (sm *ShardedMap) CompareAndSet(key string, expected uint64, value interface{}, opts SetOptions) (uint64, bool) {
+	key = sm.storageKey(key)
+	shard := sm.getShard(key)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	now := time.Now().UnixMilli()
+
+	existing := liveItem(shard, key)
+	var current uint64
+	if existing != nil {
+		current = existing.version
+	}
+	if current != expected {
+		return current, false
+	}
+
+	expiresAt := opts.ExpiresAt
+	if opts.KeepTTL && existing != nil {
+		expiresAt = existing.expiresAt
+	}
+	if expiresAt > 0 && expiresAt <= now {
+		if existing != nil {
+			shard.remove(key)
+			shard.events.notify(KeyEventDel, key)
+		}
+		return 0, true
+	}
+
+	version := nextVersion()
+	shard.put(key, &item{
+		value:     value,
+		expiresAt: expiresAt,
+		createdAt: now,
+		version:   version,
+	})
+	shard.events.notify(KeyEventSet, key)
+
+	return version, true
+}
@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestVersionSeed 测试版本号计数器以启动时间为起点，模拟重启后 fencing token 仍然递增
+func TestVersionSeed(t *testing.T) {
+	before := uint64(time.Now().UnixMicro())
+	if v := nextVersion(); v < before-uint64(time.Hour.Microseconds()) {
+		t.Fatalf("Expected version seeded from wall clock, got %d", v)
+	}
+
+	sm := NewShardedMap(16)
+	token, acquired, _ := sm.AcquireLock("lock:1", "replica-1", 1000)
+	if !acquired {
+		t.Fatal("AcquireLock failed")
+	}
+
+	// 模拟进程重启：计数器按新的启动时间重新设置
+	time.Sleep(time.Millisecond)
+	versionCounter.Store(versionSeed(time.Now()))
+
+	restarted := NewShardedMap(16)
+	newToken, acquired, _ := restarted.AcquireLock("lock:1", "replica-2", 1000)
+	if !acquired || newToken <= token {
+		t.Errorf("Expected token after restart > %d, got %d", token, newToken)
+	}
+}
+
+// TestShardedMap_GetWithVersion 测试读取值和版本号
+func TestShardedMap_GetWithVersion(t *testing.T) {
+	sm := NewShardedMap(1024)
+
+	if _, _, found := sm.GetWithVersion("k"); found {
+		t.Error("Expected missing key")
+	}
+
+	sm.Set("k", "a", 0)
+	value, v1, found := sm.GetWithVersion("k")
+	if !found || value != "a" || v1 == 0 {
+		t.Fatalf("Unexpected result: %v, %d, %v", value, v1, found)
+	}
+	if v1 != sm.Version("k") {
+		t.Errorf("Expected GetWithVersion and Version to agree")
+	}
+
+	sm.Set("k", "b", 0)
+	if _, v2, _ := sm.GetWithVersion("k"); v2 <= v1 {
+		t.Errorf("Expected version to increase, got %d after %d", v2, v1)
+	}
+}
+
+// TestShardedMap_CompareAndSet 测试按版本号条件写入
+func TestShardedMap_CompareAndSet(t *testing.T) {
+	sm := NewShardedMap(1024)
+
+	// 0 表示仅当键不存在时写入
+	v1, ok := sm.CompareAndSet("session:1", 0, "a", SetOptions{ExpiresAt: time.Now().UnixMilli() + 100000})
+	if !ok || v1 == 0 {
+		t.Fatalf("Expected create to succeed, got %d, %v", v1, ok)
+	}
+	if current, ok := sm.CompareAndSet("session:1", 0, "x", SetOptions{}); ok || current != v1 {
+		t.Errorf("Expected create on existing key to fail with current version, got %d, %v", current, ok)
+	}
+
+	// 版本号匹配，保留过期时间
+	v2, ok := sm.CompareAndSet("session:1", v1, "b", SetOptions{KeepTTL: true})
+	if !ok || v2 <= v1 {
+		t.Fatalf("Expected update to succeed, got %d, %v", v2, ok)
+	}
+	if ttl := TTL(sm, "session:1"); ttl < 99 || ttl > 100 {
+		t.Errorf("Expected TTL to be kept, got %d", ttl)
+	}
+
+	// 旧版本号
+	if current, ok := sm.CompareAndSet("session:1", v1, "c", SetOptions{}); ok || current != v2 {
+		t.Errorf("Expected stale version to fail, got %d, %v", current, ok)
+	}
+	if value, _ := sm.Get("session:1"); value != "b" {
+		t.Errorf("Expected 'b', got %v", value)
+	}
+
+	// 不带过期选项时清除过期时间
+	if _, ok := sm.CompareAndSet("session:1", v2, "d", SetOptions{}); !ok {
+		t.Fatal("Expected update to succeed")
+	}
+	if ttl := TTL(sm, "session:1"); ttl != -2 {
+		t.Errorf("Expected no expiry, got %d", ttl)
+	}
+
+	// 已过期的键视为不存在
+	sm.SetWithOptions("expired", "v", SetOptions{ExpiresAt: time.Now().UnixMilli() + 10})
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := sm.CompareAndSet("expired", 0, "new", SetOptions{}); !ok {
+		t.Error("Expected expired key to be treated as missing")
+	}
+}
+
+// TestShardedMap_CompareAndSetConcurrent 测试并发 CAS 递增不会丢失更新
+func TestShardedMap_CompareAndSetConcurrent(t *testing.T) {
+	sm := NewShardedMap(1024)
+	sm.Set("counter", 0, 0)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				for {
+					value, version, _ := sm.GetWithVersion("counter")
+					if _, ok := sm.CompareAndSet("counter", version, value.(int)+1, SetOptions{}); ok {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if value, _ := sm.Get("counter"); value != 800 {
+		t.Errorf("Expected 800, got %v", value)
+	}
+}
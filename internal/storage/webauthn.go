@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"errors"
+	"time"
+)
+
+// WebAuthn 仪式类型
+const (
+	// CeremonyRegistration 注册仪式（navigator.credentials.create）
+	CeremonyRegistration = "registration"
+
+	// CeremonyAuthentication 认证仪式（navigator.credentials.get）
+	CeremonyAuthentication = "authentication"
+)
+
+var (
+	// ErrNotChallenge 键存在但保存的不是 WebAuthn challenge
+	ErrNotChallenge = errors.New("key does not hold a webauthn challenge")
+
+	// ErrCeremonyMismatch challenge 的仪式类型与请求不符
+	ErrCeremonyMismatch = errors.New("webauthn ceremony type mismatch")
+
+	// ErrRPIDMismatch challenge 的依赖方 ID 与请求不符
+	ErrRPIDMismatch = errors.New("webauthn relying party id mismatch")
+)
+
+// WebAuthnChallenge 一次 WebAuthn 仪式的 challenge
+type WebAuthnChallenge struct {
+	Challenge  string // challenge（base64url）
+	Ceremony   string // 仪式类型：CeremonyRegistration 或 CeremonyAuthentication
+	RPID       string // 依赖方 ID，如 "example.com"
+	UserHandle string // 用户句柄，可发现凭据的认证仪式中可以为空
+}
+
+// PutChallenge 保存 WebAuthn challenge
+//
+// 参数说明：
+//   - key: 仪式 ID 对应的键，如 "webauthn:ceremony:<id>"
+//   - challenge: 要保存的 challenge
+//   - ttl: 有效期（秒），必须大于 0
+//
+// 返回值：
+//   - bool: 是否保存，键已存在（包括其他类型的值）时返回 false
+//
+// 注意事项：
+//   - 与 SetNX 相同，不会覆盖同一个仪式 ID 上尚未消费的 challenge
+func (sm *ShardedMap) PutChallenge(key string, challenge WebAuthnChallenge, ttl int) bool {
+	key = sm.storageKey(key)
+	shard := sm.getShard(key)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if liveItem(shard, key) != nil {
+		return false
+	}
+
+	now := time.Now().UnixMilli()
+	shard.put(key, &item{
+		value:     &challenge,
+		expiresAt: now + int64(ttl)*1000,
+		createdAt: now,
+		version:   nextVersion(),
+	})
+	shard.events.notify(KeyEventSet, key)
+
+	return true
+}
+
+// ConsumeChallenge 取出并删除 WebAuthn challenge
+//
+// 参数说明：
+//   - key: 仪式 ID 对应的键
+//   - ceremony: 期望的仪式类型
+//   - rpID: 期望的依赖方 ID
+//
+// 返回值：
+//   - WebAuthnChallenge: 保存的 challenge
+//   - bool: 是否找到（false 表示不存在、已过期或已被消费）
+//   - error: 仪式类型不符返回 ErrCeremonyMismatch，依赖方 ID 不符返回 ErrRPIDMismatch，
+//     键保存的不是 challenge 时返回 ErrNotChallenge
+//
+// 示例：
+//
+//	ch, found, err := sm.ConsumeChallenge("webauthn:ceremony:"+id, CeremonyAuthentication, "example.com")
+//	if err != nil || !found {
+//	    // 拒绝本次断言
+//	}
+//	// 校验 clientDataJSON.challenge == ch.Challenge
+//
+// 注意事项：
+//   - 取出和删除在同一把分片锁内完成，并发调用时只有一个调用方能取到 challenge
+//   - 类型或依赖方 ID 不符时 challenge 同样被删除，仪式需要重新开始
+func (sm *ShardedMap) ConsumeChallenge(key, ceremony, rpID string) (WebAuthnChallenge, bool, error) {
+	key = sm.storageKey(key)
+	shard := sm.getShard(key)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	existing := liveItem(shard, key)
+	if existing == nil {
+		return WebAuthnChallenge{}, false, nil
+	}
+	challenge, ok := existing.value.(*WebAuthnChallenge)
+	if !ok {
+		return WebAuthnChallenge{}, false, ErrNotChallenge
+	}
+
+	shard.remove(key)
+	shard.events.notify(KeyEventDel, key)
+
+	if challenge.Ceremony != ceremony {
+		return WebAuthnChallenge{}, true, ErrCeremonyMismatch
+	}
+	if challenge.RPID != rpID {
+		return WebAuthnChallenge{}, true, ErrRPIDMismatch
+	}
+
+	return *challenge, true, nil
+}
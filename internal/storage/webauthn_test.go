@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+// testChallenge 测试用的注册仪式 challenge
+var testChallenge = WebAuthnChallenge{
+	Challenge:  "q2Xw3bVQm1c8x7KpAB3fZkQm9a1cLx0r",
+	Ceremony:   CeremonyRegistration,
+	RPID:       "example.com",
+	UserHandle: "dXNlcjAwMQ",
+}
+
+// TestShardedMap_ConsumeChallenge 测试 challenge 只能被消费一次
+func TestShardedMap_ConsumeChallenge(t *testing.T) {
+	sm := NewShardedMap(1024)
+
+	if !sm.PutChallenge("webauthn:ceremony:1", testChallenge, 300) {
+		t.Fatal("Expected PutChallenge to succeed")
+	}
+	if sm.PutChallenge("webauthn:ceremony:1", testChallenge, 300) {
+		t.Error("Expected PutChallenge not to overwrite a pending challenge")
+	}
+
+	ch, found, err := sm.ConsumeChallenge("webauthn:ceremony:1", CeremonyRegistration, "example.com")
+	if err != nil || !found {
+		t.Fatalf("Expected challenge, got %v, %v", found, err)
+	}
+	if ch != testChallenge {
+		t.Errorf("Expected %+v, got %+v", testChallenge, ch)
+	}
+
+	if _, found, _ := sm.ConsumeChallenge("webauthn:ceremony:1", CeremonyRegistration, "example.com"); found {
+		t.Error("Expected second consume to find nothing")
+	}
+}
+
+// TestShardedMap_ConsumeChallengeMismatch 测试类型或依赖方 ID 不符时拒绝并删除 challenge
+func TestShardedMap_ConsumeChallengeMismatch(t *testing.T) {
+	sm := NewShardedMap(1024)
+
+	sm.PutChallenge("webauthn:ceremony:1", testChallenge, 300)
+	if _, _, err := sm.ConsumeChallenge("webauthn:ceremony:1", CeremonyAuthentication, "example.com"); !errors.Is(err, ErrCeremonyMismatch) {
+		t.Errorf("Expected ErrCeremonyMismatch, got %v", err)
+	}
+	if sm.Exists("webauthn:ceremony:1") {
+		t.Error("Expected mismatched consume to delete the challenge")
+	}
+
+	sm.PutChallenge("webauthn:ceremony:2", testChallenge, 300)
+	if _, _, err := sm.ConsumeChallenge("webauthn:ceremony:2", CeremonyRegistration, "evil.example"); !errors.Is(err, ErrRPIDMismatch) {
+		t.Errorf("Expected ErrRPIDMismatch, got %v", err)
+	}
+
+	sm.Set("session:1", "data", 0)
+	if _, _, err := sm.ConsumeChallenge("session:1", CeremonyRegistration, "example.com"); !errors.Is(err, ErrNotChallenge) {
+		t.Errorf("Expected ErrNotChallenge, got %v", err)
+	}
+	if !sm.Exists("session:1") {
+		t.Error("Expected other values to be kept")
+	}
+}
+
+// TestShardedMap_ConsumeChallengeConcurrent 测试并发消费时只有一个调用方成功
+func TestShardedMap_ConsumeChallengeConcurrent(t *testing.T) {
+	sm := NewShardedMap(1024)
+	sm.PutChallenge("webauthn:ceremony:1", testChallenge, 300)
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		consumed int
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, found, err := sm.ConsumeChallenge("webauthn:ceremony:1", CeremonyRegistration, "example.com"); found && err == nil {
+				mu.Lock()
+				consumed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if consumed != 1 {
+		t.Errorf("Expected exactly one consume to succeed, got %d", consumed)
+	}
+}
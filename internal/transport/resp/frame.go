@@ -0,0 +1,117 @@
+package resp
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// CommandBuffered 报告缓冲区中是否已有一条完整的客户端命令
+//
+// 服务端处理完一条命令后，据此决定是继续处理管道中的下一条命令，还是先刷新响应：
+// 缓冲区中只剩半条命令时，下一次 ParseCommand 会阻塞等待剩余数据，
+// 此时必须先把已生成的响应发出去，否则等待响应的客户端会一直收不到回复。
+//
+// 返回值：
+//   - bool: true 表示下一次 ParseCommand 无需再从数据源读取即可返回（包括格式错误的输入）
+//
+// 示例：
+//
+//	value, err := parser.ParseCommand()
+//	// ... 处理命令并写入响应
+//	if !parser.CommandBuffered() {
+//	    writer.Flush()
+//	}
+//
+// 注意事项：
+//   - 只检查缓冲区中第一条命令，不会触发读取，开销与该命令的长度成正比
+func (p *Parser) CommandBuffered() bool {
+	buf, _ := p.reader.Peek(p.reader.Buffered())
+	if len(buf) == 0 {
+		return false
+	}
+
+	if buf[0] != Array {
+		return inlineBuffered(buf)
+	}
+
+	_, ok := frameEnd(buf, 0)
+	return ok
+}
+
+// inlineBuffered 报告 buf 中是否有一行完整的非空内联命令
+func inlineBuffered(buf []byte) bool {
+	for len(buf) > 0 {
+		n := bytes.IndexByte(buf, '\n')
+		if n < 0 {
+			// 超长的行会立即返回 ErrTooLarge
+			return len(buf) > MaxInlineSize+2
+		}
+
+		args, err := splitInlineArgs(bytes.TrimSuffix(buf[:n], []byte("\r")))
+		if err != nil || len(args) > 0 {
+			return true
+		}
+
+		// 空行会被跳过，继续检查下一行
+		buf = buf[n+1:]
+	}
+
+	return false
+}
+
+// frameEnd 返回从 buf[pos] 开始的 RESP 值的结束位置
+//
+// 值不完整时 ok 为 false；值格式错误时返回 (-1, true)，因为解析器遇到格式错误会立即返回，不会阻塞。
+func frameEnd(buf []byte, pos int) (end int, ok bool) {
+	if pos >= len(buf) {
+		return 0, false
+	}
+
+	n := bytes.IndexByte(buf[pos:], '\n')
+	if n < 0 {
+		return 0, false
+	}
+	line := bytes.TrimSuffix(buf[pos+1:pos+n], []byte("\r"))
+	next := pos + n + 1
+
+	switch buf[pos] {
+	case SimpleString, Error, Integer, Null, Boolean, Double, BigNumber:
+		return next, true
+
+	case BulkString, BlobError, VerbatimString:
+		length, err := strconv.ParseInt(string(line), 10, 64)
+		if err != nil || length < -1 || length > MaxBulkSize {
+			return -1, true
+		}
+		if length == -1 {
+			return next, true
+		}
+		end := next + int(length) + 2
+		if end > len(buf) {
+			return 0, false
+		}
+		return end, true
+
+	case Array, Map, Attribute, Set, Push:
+		count, err := strconv.ParseInt(string(line), 10, 64)
+		if err != nil || count < -1 {
+			return -1, true
+		}
+		if buf[pos] == Map || buf[pos] == Attribute {
+			count *= 2
+		}
+		if count > MaxArrayLength {
+			return -1, true
+		}
+		for i := int64(0); i < count; i++ {
+			next, ok = frameEnd(buf, next)
+			if !ok || next < 0 {
+				return next, ok
+			}
+		}
+		return next, true
+
+	default:
+		return -1, true
+	}
+}
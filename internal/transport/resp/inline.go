@@ -0,0 +1,220 @@
+package resp
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+)
+
+const (
+	// MaxInlineSize 是内联命令单行的最大长度 (64KB)，与 Redis 的 PROTO_INLINE_MAX_SIZE 一致
+	MaxInlineSize = 64 * 1024
+)
+
+// parseInline 解析内联命令
+//
+// 内联命令是以换行结尾的一行纯文本，参数以空白字符分隔，例如：
+//
+//	PING\r\n
+//	SET greeting "hello world"\r\n
+//
+// 参数拆分规则与 Redis 的 sdssplitargs 相同：
+//   - 双引号内支持 \n \r \t \b \a \\ \" 和 \xHH 转义
+//   - 单引号内只支持 \' 转义
+//   - 闭合引号后必须是空白字符或行尾
+//
+// 返回值：
+//   - *Value: 由 Bulk String 组成的 Array，与客户端发送的 RESP 命令格式一致
+//   - error: 行过长（ErrTooLarge）或引号不匹配（ErrInvalidFormat）时的错误；
+//     命令名为 POST 或 Host:（不区分大小写）时返回 ErrCrossProtocol
+//
+// 注意事项：
+//   - 行尾可以是 \r\n 或单独的 \n
+//   - 空行会被跳过
+//   - 与 Redis 相同，HTTP 请求行（POST ...）和请求头（Host: ...）不会被当作命令执行，
+//     调用方应关闭连接，防止网页通过浏览器向服务端口发送命令
+func (p *Parser) parseInline() (*Value, error) {
+	for {
+		line, err := p.readInlineLine()
+		if err != nil {
+			return nil, err
+		}
+
+		args, err := splitInlineArgs(line)
+		if err != nil {
+			return nil, err
+		}
+		if len(args) == 0 {
+			// 空行
+			continue
+		}
+		if bytes.EqualFold(args[0], []byte("post")) || bytes.EqualFold(args[0], []byte("host:")) {
+			return nil, ErrCrossProtocol
+		}
+
+		array := make([]Value, len(args))
+		for i, arg := range args {
+			array[i] = Value{Type: BulkString, Bulk: arg}
+		}
+
+		return &Value{
+			Type:  Array,
+			Array: array,
+		}, nil
+	}
+}
+
+// readInlineLine 读取一行内联命令（不含行尾），长度超过 MaxInlineSize 时返回错误
+func (p *Parser) readInlineLine() ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := p.reader.ReadSlice('\n')
+		if len(line)+len(chunk) > MaxInlineSize+2 {
+			return nil, fmt.Errorf("%w: inline command too long", ErrTooLarge)
+		}
+		line = append(line, chunk...)
+
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		break
+	}
+
+	// 移除行尾的 \n 和可选的 \r
+	line = line[:len(line)-1]
+	if len(line) > 0 && line[len(line)-1] == '\r' {
+		line = line[:len(line)-1]
+	}
+
+	return line, nil
+}
+
+// splitInlineArgs 按 Redis sdssplitargs 的规则拆分内联命令参数
+func splitInlineArgs(line []byte) ([][]byte, error) {
+	var args [][]byte
+	i := 0
+
+	for {
+		// 跳过空白字符
+		for i < len(line) && isInlineSpace(line[i]) {
+			i++
+		}
+		if i >= len(line) {
+			return args, nil
+		}
+
+		var (
+			arg      = []byte{}
+			inDouble bool
+			inSingle bool
+			done     bool
+		)
+
+		for !done {
+			if inDouble {
+				if i >= len(line) {
+					return nil, fmt.Errorf("%w: unbalanced quotes in inline command", ErrInvalidFormat)
+				}
+
+				c := line[i]
+				switch {
+				case c == '\\' && i+3 < len(line) && line[i+1] == 'x' && isHexDigit(line[i+2]) && isHexDigit(line[i+3]):
+					arg = append(arg, hexValue(line[i+2])<<4|hexValue(line[i+3]))
+					i += 3
+				case c == '\\' && i+1 < len(line):
+					i++
+					switch line[i] {
+					case 'n':
+						arg = append(arg, '\n')
+					case 'r':
+						arg = append(arg, '\r')
+					case 't':
+						arg = append(arg, '\t')
+					case 'b':
+						arg = append(arg, '\b')
+					case 'a':
+						arg = append(arg, '\a')
+					default:
+						arg = append(arg, line[i])
+					}
+				case c == '"':
+					// 闭合引号后必须是空白字符或行尾
+					if i+1 < len(line) && !isInlineSpace(line[i+1]) {
+						return nil, fmt.Errorf("%w: unbalanced quotes in inline command", ErrInvalidFormat)
+					}
+					done = true
+				default:
+					arg = append(arg, c)
+				}
+			} else if inSingle {
+				if i >= len(line) {
+					return nil, fmt.Errorf("%w: unbalanced quotes in inline command", ErrInvalidFormat)
+				}
+
+				c := line[i]
+				switch {
+				case c == '\\' && i+1 < len(line) && line[i+1] == '\'':
+					arg = append(arg, '\'')
+					i++
+				case c == '\'':
+					if i+1 < len(line) && !isInlineSpace(line[i+1]) {
+						return nil, fmt.Errorf("%w: unbalanced quotes in inline command", ErrInvalidFormat)
+					}
+					done = true
+				default:
+					arg = append(arg, c)
+				}
+			} else {
+				if i >= len(line) {
+					break
+				}
+
+				switch c := line[i]; {
+				case isInlineSpace(c):
+					done = true
+				case c == '"':
+					inDouble = true
+				case c == '\'':
+					inSingle = true
+				default:
+					arg = append(arg, c)
+				}
+			}
+
+			if i < len(line) {
+				i++
+			}
+		}
+
+		args = append(args, arg)
+	}
+}
+
+// isInlineSpace 判断是否为空白字符（与 C 的 isspace 一致）
+func isInlineSpace(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\r', '\v', '\f':
+		return true
+	}
+	return false
+}
+
+// isHexDigit 判断是否为十六进制数字
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// hexValue 返回十六进制数字的值
+func hexValue(c byte) byte {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0'
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
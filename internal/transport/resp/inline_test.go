@@ -0,0 +1,116 @@
+package resp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// inlineArgs 解析单条内联命令并返回参数字符串
+func inlineArgs(t *testing.T, input string) []string {
+	t.Helper()
+
+	value, err := NewParser(strings.NewReader(input)).ParseCommand()
+	if err != nil {
+		t.Fatalf("ParseCommand(%q) failed: %v", input, err)
+	}
+	if value.Type != Array {
+		t.Fatalf("Expected Array, got %c", value.Type)
+	}
+
+	args := make([]string, len(value.Array))
+	for i, v := range value.Array {
+		if v.Type != BulkString {
+			t.Fatalf("Expected BulkString argument, got %c", v.Type)
+		}
+		args[i] = string(v.Bulk)
+	}
+	return args
+}
+
+// TestParser_Inline 测试内联命令的参数拆分
+func TestParser_Inline(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"PING\r\n", []string{"PING"}},
+		{"PING\n", []string{"PING"}},
+		{"  SET   key\tvalue  \r\n", []string{"SET", "key", "value"}},
+		{"SET key \"hello world\"\r\n", []string{"SET", "key", "hello world"}},
+		{"SET key \"a\\\"b\\n\\x41\\\\\"\r\n", []string{"SET", "key", "a\"b\nA\\"}},
+		{"SET key 'it\\'s \\n raw'\r\n", []string{"SET", "key", "it's \\n raw"}},
+		{"SET key \"\"\r\n", []string{"SET", "key", ""}},
+		{"\r\n\r\nPING\r\n", []string{"PING"}},
+	}
+
+	for _, tt := range tests {
+		args := inlineArgs(t, tt.input)
+		if strings.Join(args, "|") != strings.Join(tt.expected, "|") || len(args) != len(tt.expected) {
+			t.Errorf("ParseCommand(%q): expected %q, got %q", tt.input, tt.expected, args)
+		}
+	}
+}
+
+// TestParser_InlineThenRESP 测试内联命令与 RESP 命令混合发送
+func TestParser_InlineThenRESP(t *testing.T) {
+	parser := NewParser(strings.NewReader("PING\r\n*1\r\n$4\r\nPING\r\n"))
+
+	for i := 0; i < 2; i++ {
+		value, err := parser.ParseCommand()
+		if err != nil {
+			t.Fatalf("ParseCommand #%d failed: %v", i, err)
+		}
+		if value.Type != Array || len(value.Array) != 1 || string(value.Array[0].Bulk) != "PING" {
+			t.Errorf("ParseCommand #%d: unexpected value %+v", i, value)
+		}
+	}
+}
+
+// TestParser_InlineInvalid 测试引号不匹配和行过长
+func TestParser_InlineInvalid(t *testing.T) {
+	for _, input := range []string{
+		"SET key \"unterminated\r\n",
+		"SET key 'unterminated\r\n",
+		"SET key \"closed\"trailing\r\n",
+	} {
+		_, err := NewParser(strings.NewReader(input)).ParseCommand()
+		if !errors.Is(err, ErrInvalidFormat) {
+			t.Errorf("ParseCommand(%q): expected ErrInvalidFormat, got %v", input, err)
+		}
+	}
+
+	long := "SET key " + strings.Repeat("x", MaxInlineSize) + "\r\n"
+	if _, err := NewParser(strings.NewReader(long)).ParseCommand(); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expected ErrTooLarge for long inline command, got %v", err)
+	}
+}
+
+// TestParser_InlineNonArrayType 测试只有 '*' 开头的输入按 RESP 解析，其他类型标识符开头的输入按内联命令解析
+func TestParser_InlineNonArrayType(t *testing.T) {
+	for _, input := range []string{"+PING\r\n", "$4\r\n", ":1\r\n", "%1\r\n"} {
+		args := inlineArgs(t, input)
+		if len(args) != 1 || args[0] != strings.TrimSuffix(input, "\r\n") {
+			t.Errorf("ParseCommand(%q): expected inline argument, got %q", input, args)
+		}
+	}
+}
+
+// TestParser_InlineCrossProtocol 测试 HTTP 请求行和 Host 头被识别为跨协议攻击
+func TestParser_InlineCrossProtocol(t *testing.T) {
+	for _, input := range []string{
+		"POST / HTTP/1.1\r\n",
+		"post /x HTTP/1.0\r\n",
+		"Host: 127.0.0.1:6380\r\n",
+		"\r\nHOST: localhost\r\n",
+	} {
+		if _, err := NewParser(strings.NewReader(input)).ParseCommand(); !errors.Is(err, ErrCrossProtocol) {
+			t.Errorf("ParseCommand(%q): expected ErrCrossProtocol, got %v", input, err)
+		}
+	}
+
+	// 只检查命令名，参数中出现 POST 不受影响
+	if args := inlineArgs(t, "SET post Host:\r\n"); len(args) != 3 {
+		t.Errorf("Expected SET with 3 arguments, got %q", args)
+	}
+}
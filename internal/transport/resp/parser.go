@@ -37,23 +37,35 @@ var (
 
 	// ErrUnexpectedEOF 意外的 EOF
 	ErrUnexpectedEOF = errors.New("unexpected EOF")
+
+	// ErrCrossProtocol 内联命令以 POST 或 Host: 开头，可能是跨协议攻击（如网页向本地端口发送 HTTP 请求）
+	ErrCrossProtocol = errors.New("possible cross protocol attack")
 )
 
 // Value 表示一个 RESP 值
 //
-// RESP 支持 5 种数据类型：
+// RESP2 支持 5 种数据类型：
 //   - Simple String: 简单字符串，如 +OK\r\n
 //   - Error: 错误信息，如 -ERR message\r\n
 //   - Integer: 整数，如 :1000\r\n
 //   - Bulk String: 二进制安全的字符串，如 $6\r\nfoobar\r\n
 //   - Array: 数组，可包含任意类型的元素
+//
+// RESP3 新增的类型见 resp3.go，其中：
+//   - Map / Attribute 的键值对按 键, 值, 键, 值 ... 的顺序保存在 Array 中
+//   - Set / Push 的元素保存在 Array 中
+//   - Double 保存在 Double 中，Boolean 保存在 Bool 中
+//   - Big Number 的十进制文本和 Blob Error 的内容保存在 Str 中
+//   - Verbatim String 的格式（如 "txt"）保存在 Str 中，内容保存在 Bulk 中
 type Value struct {
-	Type  byte        // RESP 类型标识符
-	Str   string      // Simple String 或 Error 的值
-	Int   int64       // Integer 的值
-	Bulk  []byte      // Bulk String 的值（二进制安全）
-	Array []Value     // Array 的值
-	Null  bool        // 是否为 Null Bulk String ($-1\r\n)
+	Type   byte    // RESP 类型标识符
+	Str    string  // Simple String、Error、Blob Error、Big Number 的值，或 Verbatim String 的格式
+	Int    int64   // Integer 的值
+	Bulk   []byte  // Bulk String 或 Verbatim String 的值（二进制安全）
+	Array  []Value // Array、Map、Set、Push、Attribute 的元素
+	Null   bool    // 是否为 Null（$-1\r\n、*-1\r\n 或 RESP3 的 _\r\n）
+	Double float64 // Double 的值
+	Bool   bool    // Boolean 的值
 }
 
 // Parser RESP 协议解析器
@@ -101,7 +113,46 @@ func NewParser(reader io.Reader) *Parser {
 //	case Array:
 //	    fmt.Println("Array with", len(value.Array), "elements")
 //	}
+//
+// 注意事项：
+//   - 服务端读取客户端命令时应使用 ParseCommand
 func (p *Parser) Parse() (*Value, error) {
+	return p.parseValue()
+}
+
+// ParseCommand 解析一条客户端命令
+//
+// 与 Redis 相同，只有以 '*' 开头的输入按 RESP 数组解析，其余输入一律按内联命令解析（见 parseInline），
+// 便于使用 telnet / nc 等纯文本工具调试。
+//
+// 返回值：
+//   - *Value: 由 Bulk String 组成的 Array
+//   - error: 错误信息；内联命令以 POST 或 Host: 开头时返回 ErrCrossProtocol
+//
+// 示例：
+//
+//	value, err := parser.ParseCommand()
+//	if errors.Is(err, ErrCrossProtocol) {
+//	    conn.Close() // 可能是通过浏览器发起的跨协议攻击
+//	}
+func (p *Parser) ParseCommand() (*Value, error) {
+	first, err := p.reader.Peek(1)
+	if err != nil {
+		if err == io.EOF {
+			return nil, ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	if first[0] != Array {
+		return p.parseInline()
+	}
+
+	return p.parseValue()
+}
+
+// parseValue 解析一个以类型标识符开头的 RESP 值
+func (p *Parser) parseValue() (*Value, error) {
 	// 读取类型标识符
 	typeByte, err := p.reader.ReadByte()
 	if err != nil {
@@ -123,6 +174,20 @@ func (p *Parser) Parse() (*Value, error) {
 		return p.parseBulkString()
 	case Array:
 		return p.parseArray()
+	case Null:
+		return p.parseNull()
+	case Boolean:
+		return p.parseBoolean()
+	case Double:
+		return p.parseDouble()
+	case BigNumber:
+		return p.parseBigNumber()
+	case BlobError, VerbatimString:
+		return p.parseBlob(typeByte)
+	case Map, Attribute:
+		return p.parseAggregate(typeByte, 2)
+	case Set, Push:
+		return p.parseAggregate(typeByte, 1)
 	default:
 		return nil, fmt.Errorf("%w: unknown type '%c'", ErrInvalidType, typeByte)
 	}
@@ -250,7 +315,7 @@ func (p *Parser) parseArray() (*Value, error) {
 	// 解析数组元素
 	array := make([]Value, length)
 	for i := int64(0); i < length; i++ {
-		value, err := p.Parse()
+		value, err := p.parseValue()
 		if err != nil {
 			return nil, err
 		}
@@ -263,6 +328,14 @@ func (p *Parser) parseArray() (*Value, error) {
 	}, nil
 }
 
+// Buffered 返回已从数据源读入、尚未被解析的字节数
+//
+// 客户端使用管道（pipelining）一次发送多条命令时，解析一条命令后缓冲区中
+// 通常还有后续命令。服务端可以据此判断是否需要先刷新响应再阻塞读取。
+func (p *Parser) Buffered() int {
+	return p.reader.Buffered()
+}
+
 // readLine 读取一行（直到 \r\n）
 func (p *Parser) readLine() ([]byte, error) {
 	line, err := p.reader.ReadBytes('\n')
@@ -300,8 +373,12 @@ func (p *Parser) expectCRLF() error {
 }
 
 // Writer RESP 协议写入器
+//
+// 默认按 RESP2 写入。通过 SetProtocol(RESP3) 切换后可以写入 RESP3 的全部类型；
+// 在 RESP2 模式下，RESP3 类型会被降级为最接近的 RESP2 类型（见 resp3.go）。
 type Writer struct {
-	writer *bufio.Writer
+	writer   *bufio.Writer
+	protocol int
 }
 
 // NewWriter 创建一个新的 RESP 写入器
@@ -320,7 +397,8 @@ type Writer struct {
 //	writer.Flush()
 func NewWriter(writer io.Writer) *Writer {
 	return &Writer{
-		writer: bufio.NewWriter(writer),
+		writer:   bufio.NewWriter(writer),
+		protocol: RESP2,
 	}
 }
 
@@ -343,6 +421,24 @@ func (w *Writer) WriteValue(value *Value) error {
 			return w.WriteNullArray()
 		}
 		return w.WriteArray(value.Array)
+	case Null:
+		return w.WriteNull()
+	case Boolean:
+		return w.WriteBoolean(value.Bool)
+	case Double:
+		return w.WriteDouble(value.Double)
+	case BigNumber:
+		return w.WriteBigNumber(value.Str)
+	case BlobError:
+		return w.WriteBlobError(value.Str)
+	case VerbatimString:
+		return w.WriteVerbatimString(value.Str, value.Bulk)
+	case Map:
+		return w.WriteMap(value.Array)
+	case Set:
+		return w.WriteSet(value.Array)
+	case Push:
+		return w.WritePush(value.Array)
 	default:
 		return fmt.Errorf("%w: unknown type '%c'", ErrInvalidType, value.Type)
 	}
@@ -399,7 +495,12 @@ func (w *Writer) WriteBulkString(b []byte) error {
 }
 
 // WriteNull 写入 Null Bulk String
+//
+// RESP3 模式下写入 Null（_\r\n）。
 func (w *Writer) WriteNull() error {
+	if w.protocol >= RESP3 {
+		return w.writeNull3()
+	}
 	if err := w.writer.WriteByte(BulkString); err != nil {
 		return err
 	}
@@ -411,27 +512,16 @@ func (w *Writer) WriteNull() error {
 
 // WriteArray 写入 Array
 func (w *Writer) WriteArray(values []Value) error {
-	if err := w.writer.WriteByte(Array); err != nil {
-		return err
-	}
-	if _, err := w.writer.WriteString(strconv.Itoa(len(values))); err != nil {
-		return err
-	}
-	if err := w.writeCRLF(); err != nil {
-		return err
-	}
-
-	for _, value := range values {
-		if err := w.WriteValue(&value); err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return w.writeAggregate(Array, values)
 }
 
 // WriteNullArray 写入 Null Array
+//
+// RESP3 模式下写入 Null（_\r\n）。
 func (w *Writer) WriteNullArray() error {
+	if w.protocol >= RESP3 {
+		return w.writeNull3()
+	}
 	if err := w.writer.WriteByte(Array); err != nil {
 		return err
 	}
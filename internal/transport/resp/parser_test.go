@@ -25,6 +25,78 @@ func TestParser_SimpleString(t *testing.T) {
 	}
 }
 
+// TestParser_Buffered 测试管道输入时缓冲区中剩余的字节数
+func TestParser_Buffered(t *testing.T) {
+	input := "*1\r\n$4\r\nPING\r\n*1\r\n$4\r\nPING\r\n"
+	parser := NewParser(strings.NewReader(input))
+
+	if _, err := parser.Parse(); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if n := parser.Buffered(); n != len(input)/2 {
+		t.Errorf("Expected %d buffered bytes after first command, got %d", len(input)/2, n)
+	}
+
+	if _, err := parser.Parse(); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if n := parser.Buffered(); n != 0 {
+		t.Errorf("Expected empty buffer after last command, got %d", n)
+	}
+}
+
+// TestParser_CommandBuffered 测试缓冲区中是否已有完整命令的判断
+func TestParser_CommandBuffered(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  bool
+	}{
+		{"empty", "", false},
+		{"complete command", "*1\r\n$4\r\nPING\r\n", true},
+		{"partial header", "*2\r\n$3\r", false},
+		{"partial bulk", "*2\r\n$3\r\nGET\r\n$3\r\nke", false},
+		{"missing element", "*2\r\n$3\r\nGET\r\n", false},
+		{"null bulk", "*1\r\n$-1\r\n", true},
+		{"nested aggregate", "*2\r\n%1\r\n+a\r\n:1\r\n_\r\n", true},
+		{"partial map", "*1\r\n%1\r\n+a\r\n", false},
+		{"invalid length", "*x\r\n", true},
+		{"unknown type", "*1\r\n?\r\n", true},
+		{"inline complete", "PING\r\n", true},
+		{"inline partial", "PIN", false},
+		{"inline blank lines", "\r\n  \r\nGE", false},
+		{"inline after blank lines", "\r\n\r\nPING\n", true},
+		{"inline unbalanced quotes", "SET k \"v\r\n", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewParser(strings.NewReader(tt.input))
+			// 通过 Peek 把输入读入缓冲区
+			parser.reader.Peek(len(tt.input))
+
+			if got := parser.CommandBuffered(); got != tt.want {
+				t.Errorf("CommandBuffered() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestParser_CommandBufferedPipeline 测试管道输入中最后一条命令不完整时的判断
+func TestParser_CommandBufferedPipeline(t *testing.T) {
+	input := "*1\r\n$4\r\nPING\r\n*2\r\n$3\r\nGET\r\n$1\r\nk\r\n*2\r\n$3\r\nGET\r\n$1"
+	parser := NewParser(strings.NewReader(input))
+
+	for i, want := range []bool{true, false} {
+		if _, err := parser.ParseCommand(); err != nil {
+			t.Fatalf("ParseCommand %d failed: %v", i, err)
+		}
+		if got := parser.CommandBuffered(); got != want {
+			t.Errorf("CommandBuffered() after command %d = %v, want %v", i, got, want)
+		}
+	}
+}
+
 // TestParser_Error 测试解析 Error
 func TestParser_Error(t *testing.T) {
 	input := "-ERR unknown command\r\n"
@@ -297,7 +369,7 @@ func TestParser_InvalidFormat(t *testing.T) {
 		input string
 	}{
 		{"Missing CRLF", "+OK"},
-		{"Invalid type", "?invalid\r\n"},
+		{"Invalid element type", "*1\r\n?invalid\r\n"},
 		{"Invalid integer", ":abc\r\n"},
 		{"Invalid bulk length", "$abc\r\ndata\r\n"},
 		{"Negative bulk length", "$-2\r\n"},
@@ -0,0 +1,365 @@
+package resp
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// 协议版本
+const (
+	// RESP2 是 Redis 6 之前的默认协议版本
+	RESP2 = 2
+
+	// RESP3 是通过 HELLO 3 协商的协议版本
+	RESP3 = 3
+)
+
+// RESP3 新增的类型标识符
+const (
+	Null           = '_' // Null: _\r\n
+	Boolean        = '#' // Boolean: #t\r\n
+	Double         = ',' // Double: ,3.14\r\n
+	BigNumber      = '(' // Big Number: (3492890328409238509324850943850943825024385\r\n
+	BlobError      = '!' // Blob Error: !21\r\nSYNTAX invalid syntax\r\n
+	VerbatimString = '=' // Verbatim String: =15\r\ntxt:Some string\r\n
+	Map            = '%' // Map: %2\r\n+first\r\n:1\r\n+second\r\n:2\r\n
+	Set            = '~' // Set: ~2\r\n+a\r\n+b\r\n
+	Attribute      = '|' // Attribute: |1\r\n+key\r\n+value\r\n
+	Push           = '>' // Push: >2\r\n+message\r\n+hello\r\n
+)
+
+// parseNull 解析 Null (_\r\n)
+func (p *Parser) parseNull() (*Value, error) {
+	line, err := p.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if len(line) != 0 {
+		return nil, fmt.Errorf("%w: invalid null", ErrInvalidFormat)
+	}
+
+	return &Value{
+		Type: Null,
+		Null: true,
+	}, nil
+}
+
+// parseBoolean 解析 Boolean (#t\r\n 或 #f\r\n)
+func (p *Parser) parseBoolean() (*Value, error) {
+	line, err := p.readLine()
+	if err != nil {
+		return nil, err
+	}
+
+	switch string(line) {
+	case "t":
+		return &Value{Type: Boolean, Bool: true}, nil
+	case "f":
+		return &Value{Type: Boolean, Bool: false}, nil
+	default:
+		return nil, fmt.Errorf("%w: invalid boolean", ErrInvalidFormat)
+	}
+}
+
+// parseDouble 解析 Double (,3.14\r\n、,inf\r\n、,-inf\r\n、,nan\r\n)
+func (p *Parser) parseDouble() (*Value, error) {
+	line, err := p.readLine()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := strconv.ParseFloat(string(line), 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid double", ErrInvalidFormat)
+	}
+
+	return &Value{
+		Type:   Double,
+		Double: f,
+	}, nil
+}
+
+// parseBigNumber 解析 Big Number ((12345678901234567890\r\n)
+func (p *Parser) parseBigNumber() (*Value, error) {
+	line, err := p.readLine()
+	if err != nil {
+		return nil, err
+	}
+
+	digits := line
+	if len(digits) > 0 && (digits[0] == '-' || digits[0] == '+') {
+		digits = digits[1:]
+	}
+	if len(digits) == 0 {
+		return nil, fmt.Errorf("%w: invalid big number", ErrInvalidFormat)
+	}
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return nil, fmt.Errorf("%w: invalid big number", ErrInvalidFormat)
+		}
+	}
+
+	return &Value{
+		Type: BigNumber,
+		Str:  string(line),
+	}, nil
+}
+
+// parseBlob 解析 Blob Error (!<len>\r\n<msg>\r\n) 和 Verbatim String (=<len>\r\n<fmt>:<data>\r\n)
+func (p *Parser) parseBlob(typeByte byte) (*Value, error) {
+	line, err := p.readLine()
+	if err != nil {
+		return nil, err
+	}
+
+	length, err := strconv.ParseInt(string(line), 10, 64)
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("%w: invalid blob length", ErrInvalidFormat)
+	}
+	if length > MaxBulkSize {
+		return nil, fmt.Errorf("%w: blob too large (%d bytes)", ErrTooLarge, length)
+	}
+
+	blob := make([]byte, length)
+	if _, err := io.ReadFull(p.reader, blob); err != nil {
+		return nil, err
+	}
+	if err := p.expectCRLF(); err != nil {
+		return nil, err
+	}
+
+	if typeByte == BlobError {
+		return &Value{
+			Type: BlobError,
+			Str:  string(blob),
+		}, nil
+	}
+
+	// Verbatim String 以 3 个字符的格式和冒号开头
+	if len(blob) < 4 || blob[3] != ':' {
+		return nil, fmt.Errorf("%w: invalid verbatim string", ErrInvalidFormat)
+	}
+
+	return &Value{
+		Type: VerbatimString,
+		Str:  string(blob[:3]),
+		Bulk: blob[4:],
+	}, nil
+}
+
+// parseAggregate 解析 Map、Set、Attribute、Push 等聚合类型
+//
+// 参数说明：
+//   - typeByte: 类型标识符
+//   - multiplier: 每个条目包含的值的个数（Map / Attribute 为 2，其余为 1）
+func (p *Parser) parseAggregate(typeByte byte, multiplier int64) (*Value, error) {
+	line, err := p.readLine()
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := strconv.ParseInt(string(line), 10, 64)
+	if err != nil || count < 0 {
+		return nil, fmt.Errorf("%w: invalid aggregate length", ErrInvalidFormat)
+	}
+	if count*multiplier > MaxArrayLength {
+		return nil, fmt.Errorf("%w: aggregate too large (%d elements)", ErrTooLarge, count)
+	}
+
+	array := make([]Value, count*multiplier)
+	for i := range array {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		array[i] = *value
+	}
+
+	return &Value{
+		Type:  typeByte,
+		Array: array,
+	}, nil
+}
+
+// SetProtocol 设置写入时使用的协议版本（RESP2 或 RESP3）
+func (w *Writer) SetProtocol(version int) {
+	w.protocol = version
+}
+
+// Protocol 返回写入时使用的协议版本
+func (w *Writer) Protocol() int {
+	return w.protocol
+}
+
+// WriteBoolean 写入 Boolean
+//
+// RESP2 模式下降级为 Integer（1 或 0）。
+func (w *Writer) WriteBoolean(b bool) error {
+	if w.protocol < RESP3 {
+		if b {
+			return w.WriteInteger(1)
+		}
+		return w.WriteInteger(0)
+	}
+
+	s := "#f"
+	if b {
+		s = "#t"
+	}
+	if _, err := w.writer.WriteString(s); err != nil {
+		return err
+	}
+	return w.writeCRLF()
+}
+
+// WriteDouble 写入 Double
+//
+// RESP2 模式下降级为 Bulk String。
+func (w *Writer) WriteDouble(f float64) error {
+	var s string
+	switch {
+	case math.IsInf(f, 1):
+		s = "inf"
+	case math.IsInf(f, -1):
+		s = "-inf"
+	case math.IsNaN(f):
+		s = "nan"
+	default:
+		s = strconv.FormatFloat(f, 'g', -1, 64)
+	}
+
+	if w.protocol < RESP3 {
+		return w.WriteBulkString([]byte(s))
+	}
+	return w.writeLine(Double, s)
+}
+
+// WriteBigNumber 写入 Big Number（十进制文本）
+//
+// RESP2 模式下降级为 Bulk String。
+func (w *Writer) WriteBigNumber(n string) error {
+	if w.protocol < RESP3 {
+		return w.WriteBulkString([]byte(n))
+	}
+	return w.writeLine(BigNumber, n)
+}
+
+// WriteBlobError 写入 Blob Error
+//
+// RESP2 模式下降级为 Error。
+func (w *Writer) WriteBlobError(msg string) error {
+	if w.protocol < RESP3 {
+		return w.WriteError(msg)
+	}
+	return w.writeBlob(BlobError, []byte(msg))
+}
+
+// WriteVerbatimString 写入 Verbatim String
+//
+// 参数说明：
+//   - format: 3 个字符的格式，如 "txt" 或 "mkd"
+//   - b: 内容
+//
+// RESP2 模式下降级为 Bulk String（只包含内容）。
+func (w *Writer) WriteVerbatimString(format string, b []byte) error {
+	if w.protocol < RESP3 {
+		return w.WriteBulkString(b)
+	}
+	if len(format) != 3 {
+		return fmt.Errorf("%w: verbatim format must be 3 characters", ErrInvalidFormat)
+	}
+
+	blob := make([]byte, 0, len(b)+4)
+	blob = append(blob, format...)
+	blob = append(blob, ':')
+	blob = append(blob, b...)
+	return w.writeBlob(VerbatimString, blob)
+}
+
+// WriteMap 写入 Map
+//
+// 参数说明：
+//   - pairs: 按 键, 值, 键, 值 ... 顺序排列的键值对，长度必须是偶数
+//
+// RESP2 模式下降级为扁平的 Array（与 Redis 的 HGETALL 等命令一致）。
+func (w *Writer) WriteMap(pairs []Value) error {
+	if len(pairs)%2 != 0 {
+		return fmt.Errorf("%w: map requires an even number of elements", ErrInvalidFormat)
+	}
+	if w.protocol < RESP3 {
+		return w.writeAggregate(Array, pairs)
+	}
+	return w.writeAggregateCount(Map, len(pairs)/2, pairs)
+}
+
+// WriteSet 写入 Set
+//
+// RESP2 模式下降级为 Array。
+func (w *Writer) WriteSet(values []Value) error {
+	if w.protocol < RESP3 {
+		return w.writeAggregate(Array, values)
+	}
+	return w.writeAggregate(Set, values)
+}
+
+// WritePush 写入 Push（服务端主动推送的消息）
+//
+// RESP2 模式下降级为 Array（与 RESP2 的 Pub/Sub 消息格式一致）。
+func (w *Writer) WritePush(values []Value) error {
+	if w.protocol < RESP3 {
+		return w.writeAggregate(Array, values)
+	}
+	return w.writeAggregate(Push, values)
+}
+
+// writeNull3 写入 RESP3 Null
+func (w *Writer) writeNull3() error {
+	if err := w.writer.WriteByte(Null); err != nil {
+		return err
+	}
+	return w.writeCRLF()
+}
+
+// writeLine 写入 类型标识符 + 文本 + \r\n
+func (w *Writer) writeLine(typeByte byte, s string) error {
+	if err := w.writer.WriteByte(typeByte); err != nil {
+		return err
+	}
+	if _, err := w.writer.WriteString(s); err != nil {
+		return err
+	}
+	return w.writeCRLF()
+}
+
+// writeBlob 写入 类型标识符 + 长度 + \r\n + 内容 + \r\n
+func (w *Writer) writeBlob(typeByte byte, b []byte) error {
+	if err := w.writeLine(typeByte, strconv.Itoa(len(b))); err != nil {
+		return err
+	}
+	if _, err := w.writer.Write(b); err != nil {
+		return err
+	}
+	return w.writeCRLF()
+}
+
+// writeAggregate 写入元素个数等于 len(values) 的聚合类型
+func (w *Writer) writeAggregate(typeByte byte, values []Value) error {
+	return w.writeAggregateCount(typeByte, len(values), values)
+}
+
+// writeAggregateCount 写入 类型标识符 + 条目数 + \r\n，再依次写入全部元素
+func (w *Writer) writeAggregateCount(typeByte byte, count int, values []Value) error {
+	if err := w.writeLine(typeByte, strconv.Itoa(count)); err != nil {
+		return err
+	}
+
+	for i := range values {
+		if err := w.WriteValue(&values[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
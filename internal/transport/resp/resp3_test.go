@@ -0,0 +1,161 @@
+package resp
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+// TestParser_RESP3 测试解析 RESP3 类型
+func TestParser_RESP3(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		check func(v *Value) bool
+	}{
+		{"Null", "_\r\n", func(v *Value) bool { return v.Type == Null && v.Null }},
+		{"Boolean true", "#t\r\n", func(v *Value) bool { return v.Type == Boolean && v.Bool }},
+		{"Boolean false", "#f\r\n", func(v *Value) bool { return v.Type == Boolean && !v.Bool }},
+		{"Double", ",3.25\r\n", func(v *Value) bool { return v.Type == Double && v.Double == 3.25 }},
+		{"Double inf", ",-inf\r\n", func(v *Value) bool { return v.Type == Double && math.IsInf(v.Double, -1) }},
+		{"Big Number", "(-123456789012345678901234567890\r\n", func(v *Value) bool {
+			return v.Type == BigNumber && v.Str == "-123456789012345678901234567890"
+		}},
+		{"Blob Error", "!21\r\nSYNTAX invalid syntax\r\n", func(v *Value) bool {
+			return v.Type == BlobError && v.Str == "SYNTAX invalid syntax"
+		}},
+		{"Verbatim String", "=15\r\ntxt:Some string\r\n", func(v *Value) bool {
+			return v.Type == VerbatimString && v.Str == "txt" && string(v.Bulk) == "Some string"
+		}},
+		{"Map", "%2\r\n+first\r\n:1\r\n+second\r\n:2\r\n", func(v *Value) bool {
+			return v.Type == Map && len(v.Array) == 4 && v.Array[2].Str == "second" && v.Array[3].Int == 2
+		}},
+		{"Set", "~2\r\n+a\r\n+b\r\n", func(v *Value) bool { return v.Type == Set && len(v.Array) == 2 }},
+		{"Push", ">2\r\n+message\r\n+hello\r\n", func(v *Value) bool { return v.Type == Push && len(v.Array) == 2 }},
+		{"Nested Map", "%1\r\n+k\r\n~1\r\n#t\r\n", func(v *Value) bool {
+			return v.Type == Map && v.Array[1].Type == Set && v.Array[1].Array[0].Bool
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := NewParser(strings.NewReader(tt.input)).Parse()
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			if !tt.check(value) {
+				t.Errorf("Unexpected value: %+v", value)
+			}
+		})
+	}
+}
+
+// TestParser_RESP3Invalid 测试无效的 RESP3 数据
+func TestParser_RESP3Invalid(t *testing.T) {
+	for _, input := range []string{
+		"_x\r\n",
+		"#x\r\n",
+		",abc\r\n",
+		"(12a\r\n",
+		"=3\r\ntxt\r\n",
+		"%-1\r\n",
+	} {
+		if _, err := NewParser(strings.NewReader(input)).Parse(); err == nil {
+			t.Errorf("Parse(%q): expected error, got nil", input)
+		}
+	}
+}
+
+// TestWriter_RESP3 测试 RESP3 模式下写入的格式
+func TestWriter_RESP3(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    Value
+		expected string
+	}{
+		{"Null Bulk", Value{Type: BulkString, Null: true}, "_\r\n"},
+		{"Null Array", Value{Type: Array, Null: true}, "_\r\n"},
+		{"Boolean", Value{Type: Boolean, Bool: true}, "#t\r\n"},
+		{"Double", Value{Type: Double, Double: 1.5}, ",1.5\r\n"},
+		{"Double inf", Value{Type: Double, Double: math.Inf(1)}, ",inf\r\n"},
+		{"Big Number", Value{Type: BigNumber, Str: "12345678901234567890"}, "(12345678901234567890\r\n"},
+		{"Blob Error", Value{Type: BlobError, Str: "ERR x"}, "!5\r\nERR x\r\n"},
+		{"Verbatim String", Value{Type: VerbatimString, Str: "txt", Bulk: []byte("hi")}, "=6\r\ntxt:hi\r\n"},
+		{"Map", Value{Type: Map, Array: []Value{
+			{Type: SimpleString, Str: "a"}, {Type: Integer, Int: 1},
+		}}, "%1\r\n+a\r\n:1\r\n"},
+		{"Set", Value{Type: Set, Array: []Value{{Type: Integer, Int: 1}}}, "~1\r\n:1\r\n"},
+		{"Push", Value{Type: Push, Array: []Value{{Type: SimpleString, Str: "msg"}}}, ">1\r\n+msg\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			writer := NewWriter(&buf)
+			writer.SetProtocol(RESP3)
+
+			if err := writer.WriteValue(&tt.value); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+			writer.Flush()
+
+			if buf.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, buf.String())
+			}
+
+			// 写入的内容应能被解析回来
+			if _, err := NewParser(&buf).Parse(); err != nil {
+				t.Errorf("Failed to parse written value: %v", err)
+			}
+		})
+	}
+}
+
+// TestWriter_RESP2Downgrade 测试 RESP2 模式下 RESP3 类型的降级
+func TestWriter_RESP2Downgrade(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    Value
+		expected string
+	}{
+		{"Null", Value{Type: Null, Null: true}, "$-1\r\n"},
+		{"Boolean", Value{Type: Boolean, Bool: true}, ":1\r\n"},
+		{"Double", Value{Type: Double, Double: 1.5}, "$3\r\n1.5\r\n"},
+		{"Big Number", Value{Type: BigNumber, Str: "123"}, "$3\r\n123\r\n"},
+		{"Blob Error", Value{Type: BlobError, Str: "ERR x"}, "-ERR x\r\n"},
+		{"Verbatim String", Value{Type: VerbatimString, Str: "txt", Bulk: []byte("hi")}, "$2\r\nhi\r\n"},
+		{"Map", Value{Type: Map, Array: []Value{
+			{Type: SimpleString, Str: "a"}, {Type: Integer, Int: 1},
+		}}, "*2\r\n+a\r\n:1\r\n"},
+		{"Set", Value{Type: Set, Array: []Value{{Type: Integer, Int: 1}}}, "*1\r\n:1\r\n"},
+		{"Push", Value{Type: Push, Array: []Value{{Type: SimpleString, Str: "msg"}}}, "*1\r\n+msg\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			writer := NewWriter(&buf)
+
+			if err := writer.WriteValue(&tt.value); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+			writer.Flush()
+
+			if buf.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+// TestWriter_MapOddElements 测试 Map 元素个数为奇数时返回错误
+func TestWriter_MapOddElements(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	writer.SetProtocol(RESP3)
+
+	if err := writer.WriteMap([]Value{{Type: Integer, Int: 1}}); err == nil {
+		t.Error("Expected error for odd map elements, got nil")
+	}
+}
@@ -0,0 +1,46 @@
+package tcp
+
+import "github.com/yndnr/tokenginx/internal/transport/resp"
+
+// Client 表示一个客户端连接的状态
+//
+// 每个 TCP 连接对应一个 Client，由 handleConnection 创建并在连接的整个生命周期内复用。
+// Client 只会被所属连接的 Goroutine 访问，因此不需要加锁。
+// 进入推送模式后，订阅者的输出队列由写 Goroutine 和 PUBLISH 共享，其同步由 subscriber 负责。
+type Client struct {
+	ID       int64  // 连接 ID（服务器内递增）
+	Addr     string // 客户端地址
+	Identity string // 通过签名认证的客户端 ID，空表示未认证
+	Name     string // 客户端名称（HELLO ... SETNAME）
+	Protocol int    // 协商的 RESP 协议版本（HELLO），默认 RESP2
+	DB       int    // 当前选择的逻辑数据库（SELECT），默认 0
+
+	multi   *transaction // MULTI 之后排队的命令，nil 表示不在事务中
+	watched []watchedKey // WATCH 的键
+
+	extraReplies []*resp.Value // 命令在响应之后还要依次写出的响应（未订阅时 UNSUBSCRIBE 多个频道），写出后清空
+
+	sub           *subscriber       // 订阅者，nil 表示未进入推送模式
+	enterPushMode func(*subscriber) // 进入推送模式时由 Server 启动写 Goroutine，nil 时由调用方读取输出队列
+}
+
+// subscribed 返回连接当前是否订阅了频道或模式
+func (c *Client) subscribed() bool {
+	return c.sub != nil && c.sub.count() > 0
+}
+
+// newClient 创建一个新的客户端连接状态
+func newClient(id int64, addr string) *Client {
+	return &Client{
+		ID:       id,
+		Addr:     addr,
+		Protocol: resp.RESP2,
+	}
+}
+
+// abortTransaction 标记当前事务在 EXEC 时放弃执行（不在事务中时无操作）
+func (c *Client) abortTransaction() {
+	if c.multi != nil {
+		c.multi.aborted = true
+	}
+}
@@ -0,0 +1,380 @@
+package tcp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/yndnr/tokenginx/internal/transport/resp"
+)
+
+// CommandFlag 命令标志，可按位组合
+type CommandFlag uint32
+
+const (
+	// FlagWrite 命令会修改数据
+	FlagWrite CommandFlag = 1 << iota
+
+	// FlagReadonly 命令只读取数据
+	FlagReadonly
+
+	// FlagAdmin 管理命令（如 FLUSHALL），ACL 应只授予管理员
+	FlagAdmin
+
+	// FlagFast 命令的时间复杂度为 O(1) 或 O(log N)
+	FlagFast
+)
+
+// flagNames 命令标志在 COMMAND 响应中的名称，顺序与标志位一致
+var flagNames = []struct {
+	flag CommandFlag
+	name string
+}{
+	{FlagWrite, "write"},
+	{FlagReadonly, "readonly"},
+	{FlagAdmin, "admin"},
+	{FlagFast, "fast"},
+}
+
+// Names 返回标志的名称列表（如 ["write", "fast"]）
+func (f CommandFlag) Names() []string {
+	names := make([]string, 0, len(flagNames))
+	for _, fn := range flagNames {
+		if f&fn.flag != 0 {
+			names = append(names, fn.name)
+		}
+	}
+	return names
+}
+
+// CommandFunc 命令处理函数
+//
+// 参数说明：
+//   - c: 发送命令的客户端连接
+//   - args: 命令参数（不包括命令名），参数个数已按 Arity 校验
+//
+// 返回值：
+//   - *resp.Value: RESP 响应
+type CommandFunc func(c *Client, args [][]byte) *resp.Value
+
+// Command 命令表中的一个命令
+//
+// 键位置与 Redis COMMAND 的约定相同：下标从命令名之后的第一个参数开始计为 1，
+// LastKey 为 -1 表示直到最后一个参数，FirstKey 为 0 表示命令不涉及键。
+//
+// 示例：
+//
+//	&Command{
+//	    Name:     "get",
+//	    Arity:    2,
+//	    Flags:    FlagReadonly | FlagFast,
+//	    FirstKey: 1, LastKey: 1, Step: 1,
+//	    Group:    "string",
+//	    Summary:  "获取键的值",
+//	    Handler:  h.handleGet,
+//	}
+type Command struct {
+	// Name 命令名（小写），如 "get"、"token.mint"
+	Name string
+
+	// Arity 参数个数（包括命令名）；负数表示至少 -Arity 个
+	Arity int
+
+	// Flags 命令标志
+	Flags CommandFlag
+
+	// FirstKey 第一个键的位置，0 表示没有键
+	FirstKey int
+
+	// LastKey 最后一个键的位置，-1 表示最后一个参数
+	LastKey int
+
+	// Step 相邻两个键之间的间隔
+	Step int
+
+	// Group 命令分组（如 "string"、"connection"、"security"），用于 COMMAND DOCS
+	Group string
+
+	// Since 引入该命令的版本，用于 COMMAND DOCS
+	Since string
+
+	// Summary 命令说明，用于 COMMAND DOCS
+	Summary string
+
+	// Handler 命令处理函数
+	Handler CommandFunc
+}
+
+// CheckArity 检查参数个数（包括命令名）是否符合 Arity
+func (cmd *Command) CheckArity(argc int) bool {
+	if cmd.Arity >= 0 {
+		return argc == cmd.Arity
+	}
+	return argc >= -cmd.Arity
+}
+
+// Keys 根据键位置提取命令涉及的键
+//
+// 参数说明：
+//   - argv: 完整的命令参数（包括命令名）
+//
+// 返回值：
+//   - [][]byte: 键列表，命令不涉及键时返回 nil
+func (cmd *Command) Keys(argv [][]byte) [][]byte {
+	if cmd.FirstKey <= 0 || cmd.FirstKey >= len(argv) {
+		return nil
+	}
+
+	last := cmd.LastKey
+	if last < 0 {
+		last = len(argv) + last
+	}
+	if last >= len(argv) {
+		last = len(argv) - 1
+	}
+
+	step := cmd.Step
+	if step <= 0 {
+		step = 1
+	}
+
+	var keys [][]byte
+	for i := cmd.FirstKey; i <= last; i += step {
+		keys = append(keys, argv[i])
+	}
+	return keys
+}
+
+// CommandTable 命令表
+//
+// 命令名不区分大小写。命令表是并发安全的，但通常只在服务器启动前注册命令。
+type CommandTable struct {
+	mu       sync.RWMutex
+	commands map[string]*Command
+}
+
+// NewCommandTable 创建一个空的命令表
+func NewCommandTable() *CommandTable {
+	return &CommandTable{
+		commands: make(map[string]*Command),
+	}
+}
+
+// Register 注册一个命令
+//
+// 返回值：
+//   - error: 命令定义无效或同名命令已存在时的错误信息
+func (t *CommandTable) Register(cmd *Command) error {
+	if cmd == nil || cmd.Name == "" {
+		return fmt.Errorf("命令名不能为空")
+	}
+	if cmd.Handler == nil {
+		return fmt.Errorf("命令 %s 缺少处理函数", cmd.Name)
+	}
+	if cmd.Arity == 0 {
+		return fmt.Errorf("命令 %s 的 arity 不能为 0", cmd.Name)
+	}
+	if cmd.Flags&FlagWrite != 0 && cmd.Flags&FlagReadonly != 0 {
+		return fmt.Errorf("命令 %s 不能同时是 write 和 readonly", cmd.Name)
+	}
+
+	name := strings.ToLower(cmd.Name)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, exists := t.commands[name]; exists {
+		return fmt.Errorf("命令 %s 已存在", name)
+	}
+
+	registered := *cmd
+	registered.Name = name
+	t.commands[name] = &registered
+
+	return nil
+}
+
+// Lookup 按名称查找命令（不区分大小写）
+func (t *CommandTable) Lookup(name string) (*Command, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	cmd, exists := t.commands[strings.ToLower(name)]
+	return cmd, exists
+}
+
+// Commands 返回全部命令，按名称排序
+func (t *CommandTable) Commands() []*Command {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	commands := make([]*Command, 0, len(t.commands))
+	for _, cmd := range t.commands {
+		commands = append(commands, cmd)
+	}
+	sort.Slice(commands, func(i, j int) bool {
+		return commands[i].Name < commands[j].Name
+	})
+
+	return commands
+}
+
+// Len 返回命令数量
+func (t *CommandTable) Len() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return len(t.commands)
+}
+
+// handleCommandInfo 处理 COMMAND 命令
+//
+// 格式：
+//   - COMMAND                  返回全部命令的信息
+//   - COMMAND COUNT            返回命令数量
+//   - COMMAND INFO name [...]  返回指定命令的信息，未知命令返回 Null
+//   - COMMAND DOCS [name ...]  返回命令文档 Map（name → Map(summary, since, group)）
+func (h *CommandHandler) handleCommandInfo(c *Client, args [][]byte) *resp.Value {
+	if len(args) == 0 {
+		commands := h.commands.Commands()
+		result := make([]resp.Value, len(commands))
+		for i, cmd := range commands {
+			result[i] = commandInfoReply(cmd)
+		}
+		return &resp.Value{
+			Type:  resp.Array,
+			Array: result,
+		}
+	}
+
+	sub := strings.ToUpper(string(args[0]))
+	switch sub {
+	case "COUNT":
+		if len(args) != 1 {
+			return &resp.Value{
+				Type: resp.Error,
+				Str:  "ERR COMMAND COUNT 命令不需要参数",
+			}
+		}
+		return &resp.Value{
+			Type: resp.Integer,
+			Int:  int64(h.commands.Len()),
+		}
+
+	case "INFO":
+		result := make([]resp.Value, 0, len(args)-1)
+		for _, name := range args[1:] {
+			cmd, exists := h.commands.Lookup(string(name))
+			if !exists {
+				result = append(result, resp.Value{Type: resp.Array, Null: true})
+				continue
+			}
+			result = append(result, commandInfoReply(cmd))
+		}
+		return &resp.Value{
+			Type:  resp.Array,
+			Array: result,
+		}
+
+	case "DOCS":
+		var commands []*Command
+		if len(args) == 1 {
+			commands = h.commands.Commands()
+		} else {
+			for _, name := range args[1:] {
+				if cmd, exists := h.commands.Lookup(string(name)); exists {
+					commands = append(commands, cmd)
+				}
+			}
+		}
+
+		docs := &resp.Value{
+			Type:  resp.Map,
+			Array: make([]resp.Value, 0, 2*len(commands)),
+		}
+		for _, cmd := range commands {
+			docs.Array = append(docs.Array,
+				resp.Value{Type: resp.BulkString, Bulk: []byte(cmd.Name)},
+				commandDocsReply(cmd),
+			)
+		}
+		return docs
+
+	default:
+		return &resp.Value{
+			Type: resp.Error,
+			Str:  fmt.Sprintf("ERR 未知的 COMMAND 子命令: %s", sub),
+		}
+	}
+}
+
+// commandInfoReply 构建单个命令的 COMMAND INFO 响应
+//
+// 格式与 Redis 6 相同：[name, arity, [flags], first_key, last_key, step, [acl_categories]]
+func commandInfoReply(cmd *Command) resp.Value {
+	flags := cmd.Flags.Names()
+	flagValues := make([]resp.Value, len(flags))
+	for i, flag := range flags {
+		flagValues[i] = resp.Value{Type: resp.SimpleString, Str: flag}
+	}
+
+	categories := commandCategories(cmd)
+	categoryValues := make([]resp.Value, len(categories))
+	for i, category := range categories {
+		categoryValues[i] = resp.Value{Type: resp.SimpleString, Str: category}
+	}
+
+	return resp.Value{
+		Type: resp.Array,
+		Array: []resp.Value{
+			{Type: resp.BulkString, Bulk: []byte(cmd.Name)},
+			{Type: resp.Integer, Int: int64(cmd.Arity)},
+			{Type: resp.Set, Array: flagValues},
+			{Type: resp.Integer, Int: int64(cmd.FirstKey)},
+			{Type: resp.Integer, Int: int64(cmd.LastKey)},
+			{Type: resp.Integer, Int: int64(cmd.Step)},
+			{Type: resp.Set, Array: categoryValues},
+		},
+	}
+}
+
+// commandCategories 根据命令标志和分组生成 ACL 分类（如 @write、@fast、@string）
+func commandCategories(cmd *Command) []string {
+	var categories []string
+
+	if cmd.Flags&FlagWrite != 0 {
+		categories = append(categories, "@write")
+	}
+	if cmd.Flags&FlagReadonly != 0 {
+		categories = append(categories, "@read")
+	}
+	if cmd.Flags&FlagAdmin != 0 {
+		categories = append(categories, "@admin", "@dangerous")
+	}
+	if cmd.Flags&FlagFast != 0 {
+		categories = append(categories, "@fast")
+	} else {
+		categories = append(categories, "@slow")
+	}
+	if cmd.Group != "" {
+		categories = append(categories, "@"+cmd.Group)
+	}
+
+	return categories
+}
+
+// commandDocsReply 构建单个命令的 COMMAND DOCS 响应
+func commandDocsReply(cmd *Command) resp.Value {
+	return resp.Value{
+		Type: resp.Map,
+		Array: []resp.Value{
+			{Type: resp.BulkString, Bulk: []byte("summary")},
+			{Type: resp.BulkString, Bulk: []byte(cmd.Summary)},
+			{Type: resp.BulkString, Bulk: []byte("since")},
+			{Type: resp.BulkString, Bulk: []byte(cmd.Since)},
+			{Type: resp.BulkString, Bulk: []byte("group")},
+			{Type: resp.BulkString, Bulk: []byte(cmd.Group)},
+		},
+	}
+}
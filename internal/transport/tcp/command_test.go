@@ -0,0 +1,184 @@
+package tcp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yndnr/tokenginx/internal/storage"
+	"github.com/yndnr/tokenginx/internal/transport/resp"
+)
+
+// okHandler 返回 +OK 的命令处理函数
+func okHandler(c *Client, args [][]byte) *resp.Value {
+	return &resp.Value{Type: resp.SimpleString, Str: "OK"}
+}
+
+// TestCommandTable_Register 测试命令注册和查找
+func TestCommandTable_Register(t *testing.T) {
+	table := NewCommandTable()
+
+	if err := table.Register(&Command{Name: "Tenant.Get", Arity: 2, Handler: okHandler}); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	cmd, exists := table.Lookup("TENANT.GET")
+	if !exists || cmd.Name != "tenant.get" {
+		t.Errorf("Expected case-insensitive lookup with lowercase name, got %v, %v", cmd, exists)
+	}
+
+	invalid := []*Command{
+		{Name: "tenant.get", Arity: 2, Handler: okHandler},
+		{Name: "", Arity: 1, Handler: okHandler},
+		{Name: "noop", Arity: 1},
+		{Name: "zero", Arity: 0, Handler: okHandler},
+		{Name: "both", Arity: 1, Flags: FlagWrite | FlagReadonly, Handler: okHandler},
+	}
+	for _, cmd := range invalid {
+		if err := table.Register(cmd); err == nil {
+			t.Errorf("Register(%q): expected error, got nil", cmd.Name)
+		}
+	}
+
+	if table.Len() != 1 {
+		t.Errorf("Expected 1 command, got %d", table.Len())
+	}
+}
+
+// TestCommand_CheckArity 测试固定和可变参数个数
+func TestCommand_CheckArity(t *testing.T) {
+	fixed := &Command{Arity: 2}
+	if !fixed.CheckArity(2) || fixed.CheckArity(1) || fixed.CheckArity(3) {
+		t.Error("Unexpected result for fixed arity 2")
+	}
+
+	variadic := &Command{Arity: -2}
+	if variadic.CheckArity(1) || !variadic.CheckArity(2) || !variadic.CheckArity(10) {
+		t.Error("Unexpected result for variadic arity -2")
+	}
+}
+
+// TestCommand_Keys 测试按键位置提取键
+func TestCommand_Keys(t *testing.T) {
+	argv := func(args ...string) [][]byte {
+		out := make([][]byte, len(args))
+		for i, arg := range args {
+			out[i] = []byte(arg)
+		}
+		return out
+	}
+	join := func(keys [][]byte) string {
+		parts := make([]string, len(keys))
+		for i, key := range keys {
+			parts[i] = string(key)
+		}
+		return strings.Join(parts, ",")
+	}
+
+	tests := []struct {
+		cmd      *Command
+		argv     [][]byte
+		expected string
+	}{
+		{&Command{FirstKey: 1, LastKey: 1, Step: 1}, argv("get", "a"), "a"},
+		{&Command{FirstKey: 1, LastKey: -1, Step: 1}, argv("del", "a", "b", "c"), "a,b,c"},
+		{&Command{FirstKey: 1, LastKey: -1, Step: 2}, argv("mset", "a", "1", "b", "2"), "a,b"},
+		{&Command{}, argv("ping"), ""},
+	}
+
+	for _, tt := range tests {
+		if got := join(tt.cmd.Keys(tt.argv)); got != tt.expected {
+			t.Errorf("Keys(%s): expected %q, got %q", tt.argv[0], tt.expected, got)
+		}
+	}
+}
+
+// TestCommandHandler_Arity 测试分发前统一的参数个数校验
+func TestCommandHandler_Arity(t *testing.T) {
+	handler := NewCommandHandler(storage.NewShardedMap(1024))
+
+	for _, args := range [][]string{
+		{"GET"},
+		{"GET", "a", "b"},
+		{"SET", "a"},
+		{"DEL"},
+		{"ECHO"},
+		{"DBSIZE", "x"},
+	} {
+		response := handler.HandleCommand(newCommand(args...))
+		if response.Type != resp.Error || !strings.Contains(response.Str, "参数数量错误") {
+			t.Errorf("%v: expected arity error, got %+v", args, response)
+		}
+	}
+
+	// 参数必须全部是 Bulk String
+	response := handler.HandleCommand(&resp.Value{Type: resp.Array, Array: []resp.Value{
+		{Type: resp.BulkString, Bulk: []byte("GET")},
+		{Type: resp.Integer, Int: 1},
+	}})
+	if response.Type != resp.Error {
+		t.Errorf("Expected error for non-bulk argument, got %+v", response)
+	}
+}
+
+// TestCommandHandler_Command 测试 COMMAND、COMMAND COUNT、COMMAND INFO 和 COMMAND DOCS
+func TestCommandHandler_Command(t *testing.T) {
+	handler := NewCommandHandler(storage.NewShardedMap(1024))
+	count := int64(handler.Commands().Len())
+
+	response := handler.HandleCommand(newCommand("COMMAND", "COUNT"))
+	if response.Type != resp.Integer || response.Int != count {
+		t.Errorf("Expected COMMAND COUNT %d, got %+v", count, response)
+	}
+
+	response = handler.HandleCommand(newCommand("COMMAND"))
+	if response.Type != resp.Array || int64(len(response.Array)) != count {
+		t.Errorf("Expected %d command entries, got %+v", count, response)
+	}
+
+	response = handler.HandleCommand(newCommand("COMMAND", "INFO", "get", "nosuch"))
+	if response.Type != resp.Array || len(response.Array) != 2 {
+		t.Fatalf("Expected 2 entries, got %+v", response)
+	}
+	get := response.Array[0]
+	if string(get.Array[0].Bulk) != "get" || get.Array[1].Int != 2 || get.Array[3].Int != 1 {
+		t.Errorf("Unexpected COMMAND INFO get: %+v", get)
+	}
+	if len(get.Array[2].Array) != 2 || get.Array[2].Array[0].Str != "readonly" || get.Array[2].Array[1].Str != "fast" {
+		t.Errorf("Expected readonly/fast flags, got %+v", get.Array[2])
+	}
+	if !response.Array[1].Null {
+		t.Errorf("Expected null for unknown command, got %+v", response.Array[1])
+	}
+
+	response = handler.HandleCommand(newCommand("COMMAND", "DOCS", "token.mint"))
+	if response.Type != resp.Map || len(response.Array) != 2 || string(response.Array[0].Bulk) != "token.mint" {
+		t.Fatalf("Unexpected COMMAND DOCS reply: %+v", response)
+	}
+	if string(response.Array[1].Array[5].Bulk) != "security" {
+		t.Errorf("Expected group security, got %+v", response.Array[1])
+	}
+
+	response = handler.HandleCommand(newCommand("COMMAND", "BOGUS"))
+	if response.Type != resp.Error {
+		t.Errorf("Expected error for unknown subcommand, got %+v", response)
+	}
+}
+
+// TestCommandHandler_AdminCommands 测试影响整个数据库或遍历全部键的命令带有 admin 标志
+func TestCommandHandler_AdminCommands(t *testing.T) {
+	handler := NewCommandHandler(storage.NewShardedMap(1024))
+
+	for _, name := range []string{"keys", "swapdb", "flushdb", "flushall"} {
+		cmd, ok := handler.Commands().Lookup(name)
+		if !ok {
+			t.Fatalf("Command %s not registered", name)
+		}
+		if cmd.Flags&FlagAdmin == 0 {
+			t.Errorf("Expected %s to be flagged admin", name)
+		}
+		categories := strings.Join(commandCategories(cmd), " ")
+		if !strings.Contains(categories, "@admin") || !strings.Contains(categories, "@dangerous") {
+			t.Errorf("Expected %s in @admin and @dangerous, got %s", name, categories)
+		}
+	}
+}
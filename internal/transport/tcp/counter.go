@@ -0,0 +1,107 @@
+package tcp
+
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	"github.com/yndnr/tokenginx/internal/storage"
+	"github.com/yndnr/tokenginx/internal/transport/resp"
+)
+
+// handleIncr 处理 INCR 命令
+//
+// 格式：INCR key
+// 返回：加 1 后的值
+func (h *CommandHandler) handleIncr(c *Client, args [][]byte) *resp.Value {
+	return h.incrBy(c, args[0], 1)
+}
+
+// handleDecr 处理 DECR 命令
+//
+// 格式：DECR key
+// 返回：减 1 后的值
+func (h *CommandHandler) handleDecr(c *Client, args [][]byte) *resp.Value {
+	return h.incrBy(c, args[0], -1)
+}
+
+// handleIncrBy 处理 INCRBY 命令
+//
+// 格式：INCRBY key increment
+// 返回：增加后的值
+func (h *CommandHandler) handleIncrBy(c *Client, args [][]byte) *resp.Value {
+	delta, err := strconv.ParseInt(string(args[1]), 10, 64)
+	if err != nil {
+		return counterError(storage.ErrNotInteger)
+	}
+	return h.incrBy(c, args[0], delta)
+}
+
+// handleDecrBy 处理 DECRBY 命令
+//
+// 格式：DECRBY key decrement
+// 返回：减少后的值
+func (h *CommandHandler) handleDecrBy(c *Client, args [][]byte) *resp.Value {
+	delta, err := strconv.ParseInt(string(args[1]), 10, 64)
+	if err != nil {
+		return counterError(storage.ErrNotInteger)
+	}
+	if delta == math.MinInt64 {
+		return counterError(storage.ErrOverflow)
+	}
+	return h.incrBy(c, args[0], -delta)
+}
+
+// incrBy 将键的整数值增加 delta 并构建响应
+func (h *CommandHandler) incrBy(c *Client, key []byte, delta int64) *resp.Value {
+	result, err := h.db(c).IncrBy(string(key), delta)
+	if err != nil {
+		return counterError(err)
+	}
+
+	return &resp.Value{
+		Type: resp.Integer,
+		Int:  result,
+	}
+}
+
+// handleIncrByFloat 处理 INCRBYFLOAT 命令
+//
+// 格式：INCRBYFLOAT key increment
+// 返回：增加后的值（Bulk String）
+func (h *CommandHandler) handleIncrByFloat(c *Client, args [][]byte) *resp.Value {
+	delta, err := strconv.ParseFloat(string(args[1]), 64)
+	if err != nil || math.IsNaN(delta) || math.IsInf(delta, 0) {
+		return counterError(storage.ErrNotFloat)
+	}
+
+	result, err := h.db(c).IncrByFloat(string(args[0]), delta)
+	if err != nil {
+		return counterError(err)
+	}
+
+	return &resp.Value{
+		Type: resp.BulkString,
+		Bulk: []byte(storage.FormatFloat(result)),
+	}
+}
+
+// counterError 将计数器错误转换为错误响应
+func counterError(err error) *resp.Value {
+	var msg string
+	switch {
+	case errors.Is(err, storage.ErrNotInteger):
+		msg = "ERR 值不是整数或超出范围"
+	case errors.Is(err, storage.ErrNotFloat):
+		msg = "ERR 值不是有效的浮点数"
+	case errors.Is(err, storage.ErrOverflow):
+		msg = "ERR 增减后溢出"
+	default:
+		msg = "ERR " + err.Error()
+	}
+
+	return &resp.Value{
+		Type: resp.Error,
+		Str:  msg,
+	}
+}
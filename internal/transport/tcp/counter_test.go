@@ -0,0 +1,50 @@
+package tcp
+
+import (
+	"testing"
+
+	"github.com/yndnr/tokenginx/internal/storage"
+	"github.com/yndnr/tokenginx/internal/transport/resp"
+)
+
+// TestCommandHandler_Counters 测试 INCR / DECR / INCRBY / DECRBY / INCRBYFLOAT
+func TestCommandHandler_Counters(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	handler := NewCommandHandler(sm)
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected resp.Value
+	}{
+		{"INCR new", []string{"INCR", "c"}, resp.Value{Type: resp.Integer, Int: 1}},
+		{"INCRBY", []string{"INCRBY", "c", "10"}, resp.Value{Type: resp.Integer, Int: 11}},
+		{"DECR", []string{"DECR", "c"}, resp.Value{Type: resp.Integer, Int: 10}},
+		{"DECRBY", []string{"DECRBY", "c", "15"}, resp.Value{Type: resp.Integer, Int: -5}},
+		{"GET counter", []string{"GET", "c"}, resp.Value{Type: resp.BulkString, Bulk: []byte("-5")}},
+		{"INCRBYFLOAT", []string{"INCRBYFLOAT", "f", "10.50"}, resp.Value{Type: resp.BulkString, Bulk: []byte("10.5")}},
+		{"INCRBYFLOAT exponent", []string{"INCRBYFLOAT", "f", "5.0e3"}, resp.Value{Type: resp.BulkString, Bulk: []byte("5010.5")}},
+		{"GET float", []string{"GET", "f"}, resp.Value{Type: resp.BulkString, Bulk: []byte("5010.5")}},
+		{"APPEND counter", []string{"APPEND", "c", "0"}, resp.Value{Type: resp.Integer, Int: 3}},
+		{"INCR after APPEND", []string{"INCR", "c"}, resp.Value{Type: resp.Integer, Int: -49}},
+		{"INCRBY not integer", []string{"INCRBY", "c", "1.5"}, resp.Value{Type: resp.Error, Str: "ERR 值不是整数或超出范围"}},
+		{"INCR float value", []string{"INCR", "f"}, resp.Value{Type: resp.Error, Str: "ERR 值不是整数或超出范围"}},
+		{"INCRBYFLOAT not float", []string{"INCRBYFLOAT", "f", "abc"}, resp.Value{Type: resp.Error, Str: "ERR 值不是有效的浮点数"}},
+		{"INCRBYFLOAT inf", []string{"INCRBYFLOAT", "f", "inf"}, resp.Value{Type: resp.Error, Str: "ERR 值不是有效的浮点数"}},
+		{"DECRBY min", []string{"DECRBY", "c", "-9223372036854775808"}, resp.Value{Type: resp.Error, Str: "ERR 增减后溢出"}},
+	}
+
+	for _, tt := range tests {
+		response := handler.HandleCommand(newCommand(tt.args...))
+		if response.Type != tt.expected.Type || response.Int != tt.expected.Int ||
+			string(response.Bulk) != string(tt.expected.Bulk) || response.Str != tt.expected.Str {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.expected, response)
+		}
+	}
+
+	handler.HandleCommand(newCommand("SET", "text", "abc"))
+	response := handler.HandleCommand(newCommand("INCR", "text"))
+	if response.Type != resp.Error {
+		t.Errorf("Expected error for INCR on non-numeric value, got %+v", response)
+	}
+}
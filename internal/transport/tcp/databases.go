@@ -0,0 +1,142 @@
+package tcp
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/yndnr/tokenginx/internal/storage"
+	"github.com/yndnr/tokenginx/internal/transport/resp"
+)
+
+// db 返回客户端当前选择的逻辑数据库
+func (h *CommandHandler) db(c *Client) *storage.ShardedMap {
+	return h.dbs.DB(c.DB)
+}
+
+// parseDBIndex 解析并校验逻辑数据库编号
+//
+// 返回值：
+//   - int: 数据库编号
+//   - *resp.Value: 解析失败时的错误响应，成功时为 nil
+func (h *CommandHandler) parseDBIndex(arg []byte) (int, *resp.Value) {
+	index, err := strconv.Atoi(string(arg))
+	if err != nil {
+		return 0, &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR 参数必须是整数",
+		}
+	}
+	if index < 0 || index >= h.dbs.Count() {
+		return 0, &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR 数据库编号超出范围",
+		}
+	}
+	return index, nil
+}
+
+// handleSelect 处理 SELECT 命令
+//
+// 格式：SELECT index
+// 返回：+OK
+// 注意：只影响当前连接，新连接默认使用 0 号数据库
+func (h *CommandHandler) handleSelect(c *Client, args [][]byte) *resp.Value {
+	index, errReply := h.parseDBIndex(args[0])
+	if errReply != nil {
+		return errReply
+	}
+
+	c.DB = index
+
+	return &resp.Value{
+		Type: resp.SimpleString,
+		Str:  "OK",
+	}
+}
+
+// handleSwapDB 处理 SWAPDB 命令
+//
+// 格式：SWAPDB index1 index2
+// 返回：+OK
+// 注意：对所有连接立即生效，选择了 index1 的连接之后看到的是原来 index2 的数据
+func (h *CommandHandler) handleSwapDB(c *Client, args [][]byte) *resp.Value {
+	i, errReply := h.parseDBIndex(args[0])
+	if errReply != nil {
+		return errReply
+	}
+	j, errReply := h.parseDBIndex(args[1])
+	if errReply != nil {
+		return errReply
+	}
+
+	if err := h.dbs.Swap(i, j); err != nil {
+		return &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR " + err.Error(),
+		}
+	}
+
+	return &resp.Value{
+		Type: resp.SimpleString,
+		Str:  "OK",
+	}
+}
+
+// handleMove 处理 MOVE 命令
+//
+// 格式：MOVE key db
+// 返回：1 表示已移动，0 表示键不存在或目标数据库中已存在同名键
+// 注意：值和过期时间原样保留
+func (h *CommandHandler) handleMove(c *Client, args [][]byte) *resp.Value {
+	to, errReply := h.parseDBIndex(args[1])
+	if errReply != nil {
+		return errReply
+	}
+	if to == c.DB {
+		return &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR 源数据库和目标数据库相同",
+		}
+	}
+
+	result := int64(0)
+	if h.dbs.Move(string(args[0]), c.DB, to) {
+		result = 1
+	}
+
+	return &resp.Value{
+		Type: resp.Integer,
+		Int:  result,
+	}
+}
+
+// handleFlushDB 处理 FLUSHDB 命令
+//
+// 格式：FLUSHDB [ASYNC|SYNC]
+// 返回：+OK
+// 注意：只清空当前逻辑数据库；ASYNC 与 SYNC 均同步执行
+func (h *CommandHandler) handleFlushDB(c *Client, args [][]byte) *resp.Value {
+	if !validFlushMode(args) {
+		return syntaxErrorReply()
+	}
+
+	h.db(c).Clear()
+
+	return &resp.Value{
+		Type: resp.SimpleString,
+		Str:  "OK",
+	}
+}
+
+// validFlushMode 校验 FLUSHDB / FLUSHALL 的可选参数
+func validFlushMode(args [][]byte) bool {
+	switch len(args) {
+	case 0:
+		return true
+	case 1:
+		mode := strings.ToUpper(string(args[0]))
+		return mode == "ASYNC" || mode == "SYNC"
+	default:
+		return false
+	}
+}
@@ -0,0 +1,189 @@
+package tcp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yndnr/tokenginx/internal/storage"
+	"github.com/yndnr/tokenginx/internal/transport/resp"
+)
+
+// TestCommandHandler_Select 测试 SELECT 只影响当前连接
+func TestCommandHandler_Select(t *testing.T) {
+	handler := NewCommandHandler(storage.NewShardedMap(1024))
+	c1 := newClient(1, "")
+	c2 := newClient(2, "")
+
+	response := handler.HandleClientCommand(c1, newCommand("SELECT", "2"))
+	if response.Type != resp.SimpleString || response.Str != "OK" {
+		t.Fatalf("Expected +OK, got %+v", response)
+	}
+	if c1.DB != 2 {
+		t.Errorf("Expected client DB 2, got %d", c1.DB)
+	}
+
+	handler.HandleClientCommand(c1, newCommand("SET", "saml:assertion:1", "a"))
+
+	response = handler.HandleClientCommand(c2, newCommand("GET", "saml:assertion:1"))
+	if !response.Null {
+		t.Errorf("Expected key in db2 to be invisible from db0, got %+v", response)
+	}
+	response = handler.HandleClientCommand(c1, newCommand("GET", "saml:assertion:1"))
+	if string(response.Bulk) != "a" {
+		t.Errorf("Expected 'a', got %+v", response)
+	}
+
+	response = handler.HandleClientCommand(c1, newCommand("DBSIZE"))
+	if response.Int != 1 {
+		t.Errorf("Expected DBSIZE 1 in db2, got %d", response.Int)
+	}
+	response = handler.HandleClientCommand(c2, newCommand("DBSIZE"))
+	if response.Int != 0 {
+		t.Errorf("Expected DBSIZE 0 in db0, got %d", response.Int)
+	}
+
+	tests := []struct {
+		arg  string
+		want string
+	}{
+		{"16", "ERR 数据库编号超出范围"},
+		{"-1", "ERR 数据库编号超出范围"},
+		{"abc", "ERR 参数必须是整数"},
+	}
+	for _, tt := range tests {
+		response := handler.HandleClientCommand(c1, newCommand("SELECT", tt.arg))
+		if response.Type != resp.Error || response.Str != tt.want {
+			t.Errorf("SELECT %s: expected %q, got %+v", tt.arg, tt.want, response)
+		}
+	}
+	if c1.DB != 2 {
+		t.Errorf("Expected failed SELECT to keep DB 2, got %d", c1.DB)
+	}
+}
+
+// TestCommandHandler_SwapDB 测试 SWAPDB 对所有连接生效
+func TestCommandHandler_SwapDB(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	handler := NewCommandHandler(sm)
+	c := newClient(1, "")
+	sm.Set("k", "zero", 0)
+
+	handler.HandleClientCommand(c, newCommand("SELECT", "1"))
+	handler.HandleClientCommand(c, newCommand("SET", "k", "one"))
+
+	response := handler.HandleCommand(newCommand("SWAPDB", "0", "1"))
+	if response.Type != resp.SimpleString || response.Str != "OK" {
+		t.Fatalf("Expected +OK, got %+v", response)
+	}
+
+	response = handler.HandleCommand(newCommand("GET", "k"))
+	if string(response.Bulk) != "one" {
+		t.Errorf("Expected 'one' in db0 after SWAPDB, got %+v", response)
+	}
+	response = handler.HandleClientCommand(c, newCommand("GET", "k"))
+	if string(response.Bulk) != "zero" {
+		t.Errorf("Expected 'zero' in db1 after SWAPDB, got %+v", response)
+	}
+
+	response = handler.HandleCommand(newCommand("SWAPDB", "0", "16"))
+	if response.Type != resp.Error {
+		t.Errorf("Expected error for out of range index, got %+v", response)
+	}
+}
+
+// TestCommandHandler_Move 测试 MOVE 命令
+func TestCommandHandler_Move(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	handler := NewCommandHandler(sm)
+	c := newClient(1, "")
+	sm.Set("cas:ticket:1", "t", 60)
+
+	response := handler.HandleClientCommand(c, newCommand("MOVE", "cas:ticket:1", "3"))
+	if response.Type != resp.Integer || response.Int != 1 {
+		t.Fatalf("Expected :1, got %+v", response)
+	}
+	if sm.Exists("cas:ticket:1") {
+		t.Error("Expected key to be removed from db0")
+	}
+
+	handler.HandleClientCommand(c, newCommand("SELECT", "3"))
+	response = handler.HandleClientCommand(c, newCommand("TTL", "cas:ticket:1"))
+	if response.Int < 59 || response.Int > 60 {
+		t.Errorf("Expected TTL to be kept after MOVE, got %d", response.Int)
+	}
+
+	// 目标数据库（0）中已存在同名键
+	sm.Set("cas:ticket:1", "other", 0)
+	response = handler.HandleClientCommand(c, newCommand("MOVE", "cas:ticket:1", "0"))
+	if response.Int != 0 {
+		t.Errorf("Expected :0 when destination has the key, got %+v", response)
+	}
+
+	response = handler.HandleClientCommand(c, newCommand("MOVE", "missing", "0"))
+	if response.Int != 0 {
+		t.Errorf("Expected :0 for missing key, got %+v", response)
+	}
+
+	response = handler.HandleClientCommand(c, newCommand("MOVE", "cas:ticket:1", "3"))
+	if response.Type != resp.Error || response.Str != "ERR 源数据库和目标数据库相同" {
+		t.Errorf("Expected same database error, got %+v", response)
+	}
+}
+
+// TestCommandHandler_FlushDB 测试 FLUSHDB 只清空当前数据库，FLUSHALL 清空全部数据库
+func TestCommandHandler_FlushDB(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	handler := NewCommandHandler(sm)
+	c := newClient(1, "")
+	sm.Set("a", "0", 0)
+
+	handler.HandleClientCommand(c, newCommand("SELECT", "1"))
+	handler.HandleClientCommand(c, newCommand("SET", "b", "1"))
+
+	response := handler.HandleClientCommand(c, newCommand("FLUSHDB"))
+	if response.Type != resp.SimpleString || response.Str != "OK" {
+		t.Fatalf("Expected +OK, got %+v", response)
+	}
+	if handler.HandleClientCommand(c, newCommand("DBSIZE")).Int != 0 {
+		t.Error("Expected db1 to be empty after FLUSHDB")
+	}
+	if sm.Len() != 1 {
+		t.Errorf("Expected db0 to be untouched, got %d keys", sm.Len())
+	}
+
+	handler.HandleClientCommand(c, newCommand("SET", "b", "1"))
+	response = handler.HandleClientCommand(c, newCommand("FLUSHALL", "ASYNC"))
+	if response.Type != resp.SimpleString {
+		t.Fatalf("Expected +OK, got %+v", response)
+	}
+	if sm.Len() != 0 || handler.HandleClientCommand(c, newCommand("DBSIZE")).Int != 0 {
+		t.Error("Expected all databases to be empty after FLUSHALL")
+	}
+
+	response = handler.HandleClientCommand(c, newCommand("FLUSHDB", "LATER"))
+	if response.Type != resp.Error {
+		t.Errorf("Expected syntax error, got %+v", response)
+	}
+}
+
+// TestCommandHandler_InfoKeyspace 测试 INFO keyspace 按数据库列出键数量
+func TestCommandHandler_InfoKeyspace(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	handler := NewCommandHandler(sm)
+	c := newClient(1, "")
+	sm.Set("a", "0", 0)
+
+	handler.HandleClientCommand(c, newCommand("SELECT", "5"))
+	handler.HandleClientCommand(c, newCommand("MSET", "b", "1", "c", "2"))
+	handler.HandleClientCommand(c, newCommand("SELECT", "3"))
+
+	info := string(handler.HandleCommand(newCommand("INFO", "keyspace")).Bulk)
+	for _, want := range []string{"db0:keys=1", "db5:keys=2"} {
+		if !strings.Contains(info, want) {
+			t.Errorf("Expected INFO keyspace to contain %q, got %q", want, info)
+		}
+	}
+	if strings.Contains(info, "db3") {
+		t.Errorf("Expected empty db3 to be omitted, got %q", info)
+	}
+}
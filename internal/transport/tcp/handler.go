@@ -2,24 +2,24 @@ package tcp
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/yndnr/tokenginx/internal/glob"
+	"github.com/yndnr/tokenginx/internal/security/antireplay"
+	"github.com/yndnr/tokenginx/internal/security/token"
 	"github.com/yndnr/tokenginx/internal/storage"
 	"github.com/yndnr/tokenginx/internal/transport/resp"
 )
 
 // CommandHandler 命令处理器
 //
-// CommandHandler 负责解析和执行 Redis 兼容的命令，包括：
-//   - GET key
-//   - SET key value [EX seconds]
-//   - DEL key [key ...]
-//   - EXISTS key [key ...]
-//   - TTL key
-//   - EXPIRE key seconds
-//   - PING [message]
-//   - ECHO message
+// CommandHandler 通过命令表（CommandTable）分发 Redis 兼容的命令。
+// 参数个数和参数类型在分发前统一校验，命令处理函数只需处理业务逻辑。
+// 内置命令见 registerBuiltinCommands，可使用 COMMAND 命令查询完整列表。
 //
 // 示例：
 //
@@ -27,20 +27,229 @@ import (
 //	handler := NewCommandHandler(sm)
 //	response := handler.HandleCommand(commandValue)
 type CommandHandler struct {
-	sm *storage.ShardedMap // 存储引擎
+	dbs      *storage.Databases     // 逻辑数据库，连接通过 SELECT 选择
+	nonces   *antireplay.NonceStore // Nonce 缓存（防重放），nil 表示未启用
+	commands *CommandTable          // 命令表
+	pubsub   *PubSub                // 频道订阅表
+	keyspace *keyspaceHook          // 键空间事件通知，nil 表示未启用
+
+	// txMu 数据库级事务锁：EXEC 和 KEYS 等读取整个数据库的命令加读锁，FLUSHDB 等修改整个数据库的命令（见 databaseWrite）加写锁
+	txMu sync.RWMutex
+
+	modulesMu sync.RWMutex
+	modules   []string // 已加载的扩展模块名
 }
 
 // NewCommandHandler 创建一个新的命令处理器
 //
 // 参数说明：
-//   - sm: 存储引擎实例
+//   - sm: 存储引擎实例，作为 0 号逻辑数据库
 //
 // 返回值：
-//   - *CommandHandler: 命令处理器实例
+//   - *CommandHandler: 命令处理器实例（已注册全部内置命令）
+//
+// 注意事项：
+//   - 默认提供 storage.DefaultDatabaseCount 个逻辑数据库，可通过 SetDatabases 替换
 func NewCommandHandler(sm *storage.ShardedMap) *CommandHandler {
-	return &CommandHandler{
-		sm: sm,
+	h := &CommandHandler{
+		dbs:      storage.NewDatabases(sm, storage.DefaultDatabaseCount),
+		commands: NewCommandTable(),
+		pubsub:   NewPubSub(),
 	}
+	h.registerBuiltinCommands()
+
+	return h
+}
+
+// registerBuiltinCommands 注册内置命令
+func (h *CommandHandler) registerBuiltinCommands() {
+	builtins := []*Command{
+		// 连接
+		{Name: "ping", Arity: -1, Flags: FlagFast, Group: "connection", Since: "0.1.0",
+			Summary: "测试连接，返回 PONG 或 message", Handler: h.handlePing},
+		{Name: "echo", Arity: 2, Flags: FlagFast, Group: "connection", Since: "0.1.0",
+			Summary: "回显消息", Handler: h.handleEcho},
+		{Name: "hello", Arity: -1, Flags: FlagFast, Group: "connection", Since: "0.1.0",
+			Summary: "协商 RESP 协议版本", Handler: h.handleHello},
+		{Name: "auth.sign", Arity: 5, Flags: FlagFast, Group: "connection", Since: "0.1.0",
+			Summary: "签名握手认证", Handler: connectionOnly("AUTH.SIGN")},
+		{Name: "signed", Arity: -6, Group: "connection", Since: "0.1.0",
+			Summary: "执行签名命令", Handler: connectionOnly("SIGNED")},
+		{Name: "seq", Arity: -3, Group: "connection", Since: "0.1.0",
+			Summary: "携带序列号执行命令", Handler: connectionOnly("SEQ")},
+
+		// 键值
+		{Name: "get", Arity: 2, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "string", Since: "0.1.0", Summary: "获取键的值", Handler: h.handleGet},
+		{Name: "set", Arity: -3, Flags: FlagWrite, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "string", Since: "0.1.0", Summary: "设置键的值，支持 NX/XX/GET 条件和 EX/PX/EXAT/PXAT/KEEPTTL 过期选项", Handler: h.handleSet},
+		{Name: "mget", Arity: -2, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: -1, Step: 1,
+			Group: "string", Since: "0.1.0", Summary: "获取多个键的值", Handler: h.handleMGet},
+		{Name: "mset", Arity: -3, Flags: FlagWrite, FirstKey: 1, LastKey: -1, Step: 2,
+			Group: "string", Since: "0.1.0", Summary: "原子地设置多个键的值", Handler: h.handleMSet},
+		{Name: "msetnx", Arity: -3, Flags: FlagWrite, FirstKey: 1, LastKey: -1, Step: 2,
+			Group: "string", Since: "0.1.0", Summary: "仅当所有键都不存在时原子地设置多个键的值", Handler: h.handleMSetNX},
+		{Name: "getdel", Arity: 2, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "string", Since: "0.1.0", Summary: "获取键的值并删除该键", Handler: h.handleGetDel},
+		{Name: "getset", Arity: 3, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "string", Since: "0.1.0", Summary: "设置键的值并返回旧值", Handler: h.handleGetSet},
+		{Name: "setnx", Arity: 3, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "string", Since: "0.1.0", Summary: "仅当键不存在时设置键的值", Handler: h.handleSetNX},
+		{Name: "setex", Arity: 4, Flags: FlagWrite, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "string", Since: "0.1.0", Summary: "设置键的值和过期时间（秒）", Handler: h.handleSetEX},
+		{Name: "append", Arity: 3, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "string", Since: "0.1.0", Summary: "将数据追加到键的值末尾", Handler: h.handleAppend},
+		{Name: "strlen", Arity: 2, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "string", Since: "0.1.0", Summary: "返回键的值的长度", Handler: h.handleStrlen},
+		{Name: "incr", Arity: 2, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "string", Since: "0.1.0", Summary: "将键的整数值加 1", Handler: h.handleIncr},
+		{Name: "decr", Arity: 2, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "string", Since: "0.1.0", Summary: "将键的整数值减 1", Handler: h.handleDecr},
+		{Name: "incrby", Arity: 3, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "string", Since: "0.1.0", Summary: "将键的整数值增加指定的整数", Handler: h.handleIncrBy},
+		{Name: "decrby", Arity: 3, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "string", Since: "0.1.0", Summary: "将键的整数值减少指定的整数", Handler: h.handleDecrBy},
+		{Name: "incrbyfloat", Arity: 3, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "string", Since: "0.1.0", Summary: "将键的数值增加指定的浮点数", Handler: h.handleIncrByFloat},
+		{Name: "getver", Arity: 2, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "string", Since: "0.1.0", Summary: "获取键的值和写入版本号", Handler: h.handleGetVer},
+		{Name: "cas", Arity: -4, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "string", Since: "0.1.0", Summary: "仅当写入版本号匹配时设置键的值", Handler: h.handleCAS},
+		{Name: "del", Arity: -2, Flags: FlagWrite, FirstKey: 1, LastKey: -1, Step: 1,
+			Group: "keyspace", Since: "0.1.0", Summary: "删除键", Handler: h.handleDel},
+		{Name: "exists", Arity: -2, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: -1, Step: 1,
+			Group: "keyspace", Since: "0.1.0", Summary: "检查键是否存在", Handler: h.handleExists},
+		{Name: "ttl", Arity: 2, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "keyspace", Since: "0.1.0", Summary: "获取键的剩余生存时间（秒）", Handler: h.handleTTL},
+		{Name: "expire", Arity: 3, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "keyspace", Since: "0.1.0", Summary: "设置键的过期时间（秒）", Handler: h.handleExpire},
+		{Name: "scan", Arity: -2, Flags: FlagReadonly, Group: "keyspace", Since: "0.1.0",
+			Summary: "基于游标遍历键，支持 MATCH/COUNT/TYPE", Handler: h.handleScan},
+		{Name: "keys", Arity: 2, Flags: FlagReadonly | FlagAdmin, Group: "keyspace", Since: "0.1.0",
+			Summary: "列出匹配模式的键", Handler: h.handleKeys},
+
+		// 事务
+		{Name: "multi", Arity: 1, Flags: FlagFast, Group: "transactions", Since: "0.1.0",
+			Summary: "开始事务，之后的命令排队直到 EXEC", Handler: h.handleMulti},
+		{Name: "exec", Arity: 1, Group: "transactions", Since: "0.1.0",
+			Summary: "原子地执行事务中排队的全部命令", Handler: h.handleExec},
+		{Name: "discard", Arity: 1, Flags: FlagFast, Group: "transactions", Since: "0.1.0",
+			Summary: "放弃事务中排队的命令", Handler: h.handleDiscard},
+		{Name: "watch", Arity: -2, Flags: FlagFast, FirstKey: 1, LastKey: -1, Step: 1,
+			Group: "transactions", Since: "0.1.0", Summary: "监视键，键被修改时 EXEC 不执行", Handler: h.handleWatch},
+		{Name: "unwatch", Arity: 1, Flags: FlagFast, Group: "transactions", Since: "0.1.0",
+			Summary: "取消全部监视的键", Handler: h.handleUnwatch},
+
+		// 发布订阅
+		{Name: "subscribe", Arity: -2, Group: "pubsub", Since: "0.1.0",
+			Summary: "订阅频道，连接进入推送模式", Handler: h.handleSubscribe},
+		{Name: "unsubscribe", Arity: -1, Group: "pubsub", Since: "0.1.0",
+			Summary: "退订频道，不指定频道时退订全部", Handler: h.handleUnsubscribe},
+		{Name: "psubscribe", Arity: -2, Group: "pubsub", Since: "0.1.0",
+			Summary: "按 glob 模式订阅频道", Handler: h.handlePSubscribe},
+		{Name: "punsubscribe", Arity: -1, Group: "pubsub", Since: "0.1.0",
+			Summary: "退订模式，不指定模式时退订全部", Handler: h.handlePUnsubscribe},
+		{Name: "publish", Arity: 3, Flags: FlagFast, Group: "pubsub", Since: "0.1.0",
+			Summary: "向频道发布消息，返回收到消息的订阅者数量", Handler: h.handlePublish},
+
+		// 服务器
+		{Name: "select", Arity: 2, Flags: FlagFast, Group: "connection", Since: "0.1.0",
+			Summary: "选择当前连接使用的逻辑数据库", Handler: h.handleSelect},
+		{Name: "swapdb", Arity: 3, Flags: FlagWrite | FlagAdmin | FlagFast, Group: "server", Since: "0.1.0",
+			Summary: "交换两个逻辑数据库", Handler: h.handleSwapDB},
+		{Name: "move", Arity: 3, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "keyspace", Since: "0.1.0", Summary: "将键移动到另一个逻辑数据库", Handler: h.handleMove},
+		{Name: "dbsize", Arity: 1, Flags: FlagReadonly | FlagFast, Group: "server", Since: "0.1.0",
+			Summary: "返回当前逻辑数据库中键的数量", Handler: h.handleDBSize},
+		{Name: "flushdb", Arity: -1, Flags: FlagWrite | FlagAdmin, Group: "server", Since: "0.1.0",
+			Summary: "删除当前逻辑数据库中的全部键", Handler: h.handleFlushDB},
+		{Name: "flushall", Arity: -1, Flags: FlagWrite | FlagAdmin, Group: "server", Since: "0.1.0",
+			Summary: "删除全部逻辑数据库中的键", Handler: h.handleFlushAll},
+		{Name: "info", Arity: -1, Group: "server", Since: "0.1.0",
+			Summary: "返回服务器信息和统计", Handler: h.handleInfo},
+		{Name: "command", Arity: -1, Group: "server", Since: "0.1.0",
+			Summary: "返回命令表信息（COUNT | INFO | DOCS）", Handler: h.handleCommandInfo},
+
+		// 安全扩展
+		{Name: "nonce.check", Arity: 2, Flags: FlagWrite | FlagFast, Group: "security", Since: "0.1.0",
+			Summary: "防重放 Nonce 校验", Handler: h.handleNonceCheck},
+		{Name: "token.mint", Arity: -3, Flags: FlagWrite, Group: "security", Since: "0.1.0",
+			Summary: "生成随机令牌并存储 payload", Handler: h.handleTokenMint},
+
+		// 分布式锁
+		{Name: "lock.acquire", Arity: 4, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "lock", Since: "0.1.0", Summary: "获取分布式锁，返回 fencing token", Handler: h.handleLockAcquire},
+		{Name: "lock.release", Arity: 3, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "lock", Since: "0.1.0", Summary: "由持有者释放分布式锁", Handler: h.handleLockRelease},
+		{Name: "lock.extend", Arity: 4, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "lock", Since: "0.1.0", Summary: "由持有者延长分布式锁的有效期", Handler: h.handleLockExtend},
+		{Name: "lock.info", Arity: 2, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "lock", Since: "0.1.0", Summary: "查询分布式锁的持有者、fencing token 和剩余有效期", Handler: h.handleLockInfo},
+
+		// 限流
+		{Name: "rl.throttle", Arity: -5, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "ratelimit", Since: "0.1.0", Summary: "GCRA 限流判定，回复格式与 CL.THROTTLE 相同", Handler: h.handleRLThrottle},
+		{Name: "rl.window", Arity: -5, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "ratelimit", Since: "0.1.0", Summary: "滑动窗口（LOG | COUNTER）限流判定", Handler: h.handleRLWindow},
+
+		// 账户锁定
+		{Name: "lockout.fail", Arity: -4, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "lockout", Since: "0.1.0", Summary: "记录一次失败并返回锁定状态，锁定时间逐级递增", Handler: h.handleLockoutFail},
+		{Name: "lockout.status", Arity: 2, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "lockout", Since: "0.1.0", Summary: "查询失败次数和锁定状态", Handler: h.handleLockoutStatus},
+		{Name: "lockout.reset", Arity: 2, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "lockout", Since: "0.1.0", Summary: "清除失败记录（登录成功或管理员解锁）", Handler: h.handleLockoutReset},
+
+		// 一次性验证码
+		{Name: "otp.set", Arity: -4, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "otp", Since: "0.1.0", Summary: "保存一次性验证码（加盐哈希），设置有效期和最大验证次数", Handler: h.handleOTPSet},
+		{Name: "verify", Arity: 3, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "otp", Since: "0.1.0", Summary: "验证一次性验证码，成功后消费，尝试次数用完后作废", Handler: h.handleVerify},
+
+		// WebAuthn
+		{Name: "webauthn.begin", Arity: -6, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "webauthn", Since: "0.1.0", Summary: "保存与仪式 ID 绑定的 WebAuthn challenge", Handler: h.handleWebAuthnBegin},
+		{Name: "webauthn.consume", Arity: 4, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "webauthn", Since: "0.1.0", Summary: "校验仪式类型和依赖方 ID 后取出并删除 challenge", Handler: h.handleWebAuthnConsume},
+	}
+
+	for _, cmd := range builtins {
+		if err := h.commands.Register(cmd); err != nil {
+			// 内置命令表是静态的，注册失败说明代码有误
+			panic(err)
+		}
+	}
+}
+
+// SetNonceStore 设置防重放使用的 Nonce 缓存
+//
+// 注意事项：
+//   - 未设置时 NONCE.CHECK 命令返回错误
+//   - 应在服务器开始处理连接之前调用
+func (h *CommandHandler) SetNonceStore(ns *antireplay.NonceStore) {
+	h.nonces = ns
+}
+
+// SetDatabases 设置逻辑数据库
+//
+// 注意事项：
+//   - 应在服务器开始处理连接之前调用
+//   - 过期键清理应使用同一组数据库（storage.NewDatabasesTTLManager）
+func (h *CommandHandler) SetDatabases(dbs *storage.Databases) {
+	h.dbs = dbs
+	h.installKeyspaceHook()
+}
+
+// Databases 返回逻辑数据库
+func (h *CommandHandler) Databases() *storage.Databases {
+	return h.dbs
+}
+
+// Commands 返回命令表
+//
+// 可以通过命令表注册新的命令，应在服务器开始处理连接之前完成注册。
+func (h *CommandHandler) Commands() *CommandTable {
+	return h.commands
 }
 
 // HandleCommand 处理 RESP 命令并返回响应
@@ -63,7 +272,26 @@ func NewCommandHandler(sm *storage.ShardedMap) *CommandHandler {
 //	    },
 //	}
 //	response := handler.HandleCommand(command)
+//
+// 注意事项：
+//   - 命令按 RESP2 执行，HELLO 协商的协议版本不会保留；
+//     需要保留连接状态时使用 HandleClientCommand
 func (h *CommandHandler) HandleCommand(value *resp.Value) *resp.Value {
+	return h.HandleClientCommand(newClient(0, ""), value)
+}
+
+// HandleClientCommand 处理来自指定客户端连接的 RESP 命令
+//
+// 与 HandleCommand 相同，但 HELLO 会修改连接的协议版本，
+// INFO 等命令会根据连接协商的协议版本选择 RESP2 或 RESP3 的响应格式。
+//
+// 参数说明：
+//   - c: 客户端连接状态
+//   - value: 解析后的 RESP 命令
+//
+// 返回值：
+//   - *resp.Value: RESP 响应；nil 表示响应已放入推送模式的输出队列（SUBSCRIBE 等）
+func (h *CommandHandler) HandleClientCommand(c *Client, value *resp.Value) *resp.Value {
 	// 命令必须是 Array 类型
 	if value.Type != resp.Array {
 		return &resp.Value{
@@ -80,48 +308,96 @@ func (h *CommandHandler) HandleCommand(value *resp.Value) *resp.Value {
 		}
 	}
 
-	// 提取命令名（第一个元素）
-	cmdValue := value.Array[0]
-	if cmdValue.Type != resp.BulkString {
+	argv, ok := commandArgs(value)
+	if !ok {
 		return &resp.Value{
 			Type: resp.Error,
-			Str:  "ERR 命令名必须是 Bulk String",
-		}
-	}
-
-	cmdName := strings.ToUpper(string(cmdValue.Bulk))
-	args := value.Array[1:]
-
-	// 根据命令名分发
-	switch cmdName {
-	case "PING":
-		return h.handlePing(args)
-	case "ECHO":
-		return h.handleEcho(args)
-	case "GET":
-		return h.handleGet(args)
-	case "SET":
-		return h.handleSet(args)
-	case "DEL":
-		return h.handleDel(args)
-	case "EXISTS":
-		return h.handleExists(args)
-	case "TTL":
-		return h.handleTTL(args)
-	case "EXPIRE":
-		return h.handleExpire(args)
-	case "DBSIZE":
-		return h.handleDBSize(args)
-	case "FLUSHALL":
-		return h.handleFlushAll(args)
-	case "KEYS":
-		return h.handleKeys(args)
-	case "INFO":
-		return h.handleInfo(args)
-	default:
+			Str:  "ERR 命令名和参数必须是 Bulk String",
+		}
+	}
+
+	return h.dispatch(c, argv)
+}
+
+// dispatch 查找命令、校验参数个数并执行
+//
+// 参数说明：
+//   - c: 客户端连接状态
+//   - argv: 完整的命令参数（包括命令名）
+//
+// 注意事项：
+//   - MULTI 之后除 EXEC、DISCARD 等事务命令外只排队不执行，
+//     排队时出现的错误会使 EXEC 放弃整个事务
+func (h *CommandHandler) dispatch(c *Client, argv [][]byte) *resp.Value {
+	cmd, exists := h.commands.Lookup(string(argv[0]))
+	if !exists {
+		c.abortTransaction()
+		return &resp.Value{
+			Type: resp.Error,
+			Str:  fmt.Sprintf("ERR 未知命令: %s", strings.ToUpper(string(argv[0]))),
+		}
+	}
+
+	if !cmd.CheckArity(len(argv)) {
+		c.abortTransaction()
 		return &resp.Value{
 			Type: resp.Error,
-			Str:  fmt.Sprintf("ERR 未知命令: %s", cmdName),
+			Str:  fmt.Sprintf("ERR %s 命令参数数量错误", strings.ToUpper(cmd.Name)),
+		}
+	}
+
+	// RESP2 连接订阅期间只能收发订阅相关的命令，响应和消息无法区分
+	if c.subscribed() && c.Protocol < resp.RESP3 && !subscribeCommand(cmd.Name) && cmd.Name != "ping" {
+		return &resp.Value{
+			Type: resp.Error,
+			Str:  fmt.Sprintf("ERR 订阅模式下不能执行 %s，只能执行 (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING", strings.ToUpper(cmd.Name)),
+		}
+	}
+
+	if c.multi != nil && subscribeCommand(cmd.Name) {
+		c.abortTransaction()
+		return &resp.Value{
+			Type: resp.Error,
+			Str:  fmt.Sprintf("ERR %s 不能在 MULTI 中使用", strings.ToUpper(cmd.Name)),
+		}
+	}
+
+	if c.multi != nil && !transactionControl(cmd.Name) {
+		return c.multi.queue(cmd, argv)
+	}
+
+	// 修改整个数据库的命令与 EXEC 互斥；读取整个数据库的命令对当前数据库全部分片加事务锁（读锁），
+	// 其他命令对涉及的键加事务锁（读锁），避免与正在执行的 EXEC 交错
+	if databaseWrite(cmd.Name) {
+		h.txMu.Lock()
+		defer h.txMu.Unlock()
+	} else if databaseRead(cmd.Name) {
+		h.txMu.RLock()
+		defer h.txMu.RUnlock()
+
+		unlock := storage.RLockDatabases([]*storage.ShardedMap{h.db(c)})
+		defer unlock()
+	} else if keys := cmd.Keys(argv); len(keys) > 0 {
+		names := make([]string, len(keys))
+		for i, key := range keys {
+			names[i] = string(key)
+		}
+		unlock := h.db(c).RLockKeys(names)
+		defer unlock()
+	}
+
+	return cmd.Handler(c, argv[1:])
+}
+
+// connectionOnly 返回只能由 Server 在连接层处理的命令的处理函数
+//
+// AUTH.SIGN、SIGNED、SEQ 由 Server.authorize 在命令分发之前处理，
+// 注册到命令表只是为了让 COMMAND 能够列出它们。
+func connectionOnly(name string) CommandFunc {
+	return func(c *Client, args [][]byte) *resp.Value {
+		return &resp.Value{
+			Type: resp.Error,
+			Str:  fmt.Sprintf("ERR %s 只能通过 TCP 连接使用", name),
 		}
 	}
 }
@@ -129,8 +405,13 @@ func (h *CommandHandler) HandleCommand(value *resp.Value) *resp.Value {
 // handlePing 处理 PING 命令
 //
 // 格式：PING [message]
-// 返回：如果没有 message，返回 "PONG"；否则返回 message
-func (h *CommandHandler) handlePing(args []resp.Value) *resp.Value {
+// 返回：如果没有 message，返回 "PONG"；否则返回 message。
+// RESP2 连接订阅期间返回 ["pong", message]
+func (h *CommandHandler) handlePing(c *Client, args [][]byte) *resp.Value {
+	if len(args) <= 1 && c.subscribed() && c.Protocol < resp.RESP3 {
+		return subscribedPing(args)
+	}
+
 	if len(args) == 0 {
 		return &resp.Value{
 			Type: resp.SimpleString,
@@ -138,16 +419,16 @@ func (h *CommandHandler) handlePing(args []resp.Value) *resp.Value {
 		}
 	}
 
-	if args[0].Type != resp.BulkString {
+	if len(args) > 1 {
 		return &resp.Value{
 			Type: resp.Error,
-			Str:  "ERR PING 参数必须是 Bulk String",
+			Str:  "ERR PING 命令最多需要 1 个参数",
 		}
 	}
 
 	return &resp.Value{
 		Type: resp.BulkString,
-		Bulk: args[0].Bulk,
+		Bulk: args[0],
 	}
 }
 
@@ -155,24 +436,10 @@ func (h *CommandHandler) handlePing(args []resp.Value) *resp.Value {
 //
 // 格式：ECHO message
 // 返回：message
-func (h *CommandHandler) handleEcho(args []resp.Value) *resp.Value {
-	if len(args) != 1 {
-		return &resp.Value{
-			Type: resp.Error,
-			Str:  "ERR ECHO 命令需要 1 个参数",
-		}
-	}
-
-	if args[0].Type != resp.BulkString {
-		return &resp.Value{
-			Type: resp.Error,
-			Str:  "ERR ECHO 参数必须是 Bulk String",
-		}
-	}
-
+func (h *CommandHandler) handleEcho(c *Client, args [][]byte) *resp.Value {
 	return &resp.Value{
 		Type: resp.BulkString,
-		Bulk: args[0].Bulk,
+		Bulk: args[0],
 	}
 }
 
@@ -180,23 +447,9 @@ func (h *CommandHandler) handleEcho(args []resp.Value) *resp.Value {
 //
 // 格式：GET key
 // 返回：键对应的值，或 Null Bulk String（如果不存在）
-func (h *CommandHandler) handleGet(args []resp.Value) *resp.Value {
-	if len(args) != 1 {
-		return &resp.Value{
-			Type: resp.Error,
-			Str:  "ERR GET 命令需要 1 个参数",
-		}
-	}
-
-	if args[0].Type != resp.BulkString {
-		return &resp.Value{
-			Type: resp.Error,
-			Str:  "ERR 键名必须是 Bulk String",
-		}
-	}
-
-	key := string(args[0].Bulk)
-	value, exists := h.sm.Get(key)
+func (h *CommandHandler) handleGet(c *Client, args [][]byte) *resp.Value {
+	key := string(args[0])
+	value, exists := h.db(c).Get(key)
 	if !exists {
 		return &resp.Value{
 			Type: resp.BulkString,
@@ -204,13 +457,26 @@ func (h *CommandHandler) handleGet(args []resp.Value) *resp.Value {
 		}
 	}
 
-	// 将值转换为字符串
+	return valueReply(value)
+}
+
+// valueReply 将存储的值转换为 Bulk String 响应
+//
+// 锁、失败记录、验证码、限流状态和 WebAuthn challenge 等非字符串记录返回 WRONGTYPE 错误，
+// 不会把记录的内部状态格式化后返回给客户端。
+func valueReply(value interface{}) *resp.Value {
+	if storage.TypeOf(value) != "string" {
+		return wrongTypeError
+	}
+
 	var strValue string
 	switch v := value.(type) {
 	case string:
 		strValue = v
 	case []byte:
 		strValue = string(v)
+	case float64:
+		strValue = storage.FormatFloat(v)
 	default:
 		strValue = fmt.Sprintf("%v", v)
 	}
@@ -223,60 +489,156 @@ func (h *CommandHandler) handleGet(args []resp.Value) *resp.Value {
 
 // handleSet 处理 SET 命令
 //
-// 格式：SET key value [EX seconds]
-// 返回：+OK 或错误
-func (h *CommandHandler) handleSet(args []resp.Value) *resp.Value {
-	if len(args) < 2 {
-		return &resp.Value{
-			Type: resp.Error,
-			Str:  "ERR SET 命令至少需要 2 个参数",
+// 格式：SET key value [NX | XX] [GET] [EX seconds | PX milliseconds |
+// EXAT unix-time-seconds | PXAT unix-time-milliseconds | KEEPTTL]
+// 返回：
+//   - 写入成功返回 +OK，NX/XX 条件不满足返回 Null
+//   - 带 GET 选项时返回写入前的值（键不存在时为 Null），无论是否写入
+//   - 带 GET 选项且旧值不是字符串时返回 WRONGTYPE 错误，不写入
+//
+// 注意事项：
+//   - 条件检查和写入在同一把分片锁内原子完成
+//   - 未知选项、重复或互相冲突的选项返回语法错误
+func (h *CommandHandler) handleSet(c *Client, args [][]byte) *resp.Value {
+	key := string(args[0])
+	value := args[1]
+
+	opts, get, errReply := parseSetOptions(args[2:])
+	if errReply != nil {
+		return errReply
+	}
+	opts.Get = get
+
+	result := h.db(c).SetWithOptions(key, value, opts)
+	if result.WrongType {
+		return wrongTypeError
+	}
+
+	if get {
+		if !result.Existed {
+			return &resp.Value{
+				Type: resp.BulkString,
+				Null: true,
+			}
 		}
+		return valueReply(result.Old)
 	}
 
-	if args[0].Type != resp.BulkString || args[1].Type != resp.BulkString {
+	if !result.Written {
 		return &resp.Value{
-			Type: resp.Error,
-			Str:  "ERR 键和值必须是 Bulk String",
+			Type: resp.BulkString,
+			Null: true,
 		}
 	}
 
-	key := string(args[0].Bulk)
-	value := args[1].Bulk
+	return &resp.Value{
+		Type: resp.SimpleString,
+		Str:  "OK",
+	}
+}
 
-	// 解析 TTL（如果有 EX 选项）
-	ttl := 0
-	if len(args) >= 4 {
-		option := strings.ToUpper(string(args[2].Bulk))
-		if option == "EX" {
-			if args[3].Type != resp.BulkString {
-				return &resp.Value{
+// parseSetOptions 解析 SET 命令 key value 之后的选项
+//
+// 返回值：
+//   - storage.SetOptions: 写入条件和绝对过期时间
+//   - bool: 是否带 GET 选项
+//   - *resp.Value: 选项无效时的错误响应，nil 表示解析成功
+func parseSetOptions(args [][]byte) (storage.SetOptions, bool, *resp.Value) {
+	var opts storage.SetOptions
+	var get bool
+	var expireOption string
+
+	syntaxError := &resp.Value{
+		Type: resp.Error,
+		Str:  "ERR 语法错误",
+	}
+
+	for i := 0; i < len(args); i++ {
+		option := strings.ToUpper(string(args[i]))
+		switch option {
+		case "NX":
+			if opts.NX || opts.XX {
+				return opts, false, syntaxError
+			}
+			opts.NX = true
+
+		case "XX":
+			if opts.NX || opts.XX {
+				return opts, false, syntaxError
+			}
+			opts.XX = true
+
+		case "GET":
+			if get {
+				return opts, false, syntaxError
+			}
+			get = true
+
+		case "KEEPTTL":
+			if expireOption != "" {
+				return opts, false, syntaxError
+			}
+			expireOption = option
+			opts.KeepTTL = true
+
+		case "EX", "PX", "EXAT", "PXAT":
+			if expireOption != "" || i+1 >= len(args) {
+				return opts, false, syntaxError
+			}
+			expireOption = option
+			i++
+
+			n, err := strconv.ParseInt(string(args[i]), 10, 64)
+			if err != nil {
+				return opts, false, &resp.Value{
 					Type: resp.Error,
-					Str:  "ERR EX 参数必须是数字",
+					Str:  "ERR 参数必须是整数",
 				}
 			}
 
-			seconds, err := strconv.Atoi(string(args[3].Bulk))
-			if err != nil || seconds < 0 {
-				return &resp.Value{
+			expiresAt, ok := absoluteExpiry(option, n, time.Now().UnixMilli())
+			if !ok {
+				return opts, false, &resp.Value{
 					Type: resp.Error,
-					Str:  "ERR EX 参数必须是非负整数",
+					Str:  "ERR SET 命令的过期时间无效",
 				}
 			}
-			ttl = seconds
+			opts.ExpiresAt = expiresAt
+
+		default:
+			return opts, false, syntaxError
 		}
 	}
 
-	// 存储键值对
-	if err := h.sm.Set(key, value, ttl); err != nil {
-		return &resp.Value{
-			Type: resp.Error,
-			Str:  fmt.Sprintf("ERR 设置失败: %v", err),
-		}
+	return opts, get, nil
+}
+
+// absoluteExpiry 将 EX/PX/EXAT/PXAT 的参数换算为绝对过期时间（Unix 毫秒）
+//
+// 参数必须为正数，且换算结果不能溢出。
+func absoluteExpiry(option string, n int64, nowMillis int64) (int64, bool) {
+	if n <= 0 {
+		return 0, false
 	}
 
-	return &resp.Value{
-		Type: resp.SimpleString,
-		Str:  "OK",
+	switch option {
+	case "EX":
+		if n > (math.MaxInt64-nowMillis)/1000 {
+			return 0, false
+		}
+		return nowMillis + n*1000, true
+	case "PX":
+		if n > math.MaxInt64-nowMillis {
+			return 0, false
+		}
+		return nowMillis + n, true
+	case "EXAT":
+		if n > math.MaxInt64/1000 {
+			return 0, false
+		}
+		return n * 1000, true
+	default: // PXAT
+		return n, true
 	}
 }
 
@@ -284,25 +646,11 @@ func (h *CommandHandler) handleSet(args []resp.Value) *resp.Value {
 //
 // 格式：DEL key [key ...]
 // 返回：删除的键数量
-func (h *CommandHandler) handleDel(args []resp.Value) *resp.Value {
-	if len(args) == 0 {
-		return &resp.Value{
-			Type: resp.Error,
-			Str:  "ERR DEL 命令至少需要 1 个参数",
-		}
-	}
-
+func (h *CommandHandler) handleDel(c *Client, args [][]byte) *resp.Value {
+	db := h.db(c)
 	count := 0
-	for _, arg := range args {
-		if arg.Type != resp.BulkString {
-			return &resp.Value{
-				Type: resp.Error,
-				Str:  "ERR 键名必须是 Bulk String",
-			}
-		}
-
-		key := string(arg.Bulk)
-		if h.sm.Delete(key) {
+	for _, key := range args {
+		if db.Delete(string(key)) {
 			count++
 		}
 	}
@@ -317,25 +665,11 @@ func (h *CommandHandler) handleDel(args []resp.Value) *resp.Value {
 //
 // 格式：EXISTS key [key ...]
 // 返回：存在的键数量
-func (h *CommandHandler) handleExists(args []resp.Value) *resp.Value {
-	if len(args) == 0 {
-		return &resp.Value{
-			Type: resp.Error,
-			Str:  "ERR EXISTS 命令至少需要 1 个参数",
-		}
-	}
-
+func (h *CommandHandler) handleExists(c *Client, args [][]byte) *resp.Value {
+	db := h.db(c)
 	count := 0
-	for _, arg := range args {
-		if arg.Type != resp.BulkString {
-			return &resp.Value{
-				Type: resp.Error,
-				Str:  "ERR 键名必须是 Bulk String",
-			}
-		}
-
-		key := string(arg.Bulk)
-		if h.sm.Exists(key) {
+	for _, key := range args {
+		if db.Exists(string(key)) {
 			count++
 		}
 	}
@@ -350,23 +684,8 @@ func (h *CommandHandler) handleExists(args []resp.Value) *resp.Value {
 //
 // 格式：TTL key
 // 返回：剩余 TTL（秒），-1 表示不存在，-2 表示永不过期
-func (h *CommandHandler) handleTTL(args []resp.Value) *resp.Value {
-	if len(args) != 1 {
-		return &resp.Value{
-			Type: resp.Error,
-			Str:  "ERR TTL 命令需要 1 个参数",
-		}
-	}
-
-	if args[0].Type != resp.BulkString {
-		return &resp.Value{
-			Type: resp.Error,
-			Str:  "ERR 键名必须是 Bulk String",
-		}
-	}
-
-	key := string(args[0].Bulk)
-	ttl := storage.TTL(h.sm, key)
+func (h *CommandHandler) handleTTL(c *Client, args [][]byte) *resp.Value {
+	ttl := storage.TTL(h.db(c), string(args[0]))
 
 	return &resp.Value{
 		Type: resp.Integer,
@@ -378,23 +697,9 @@ func (h *CommandHandler) handleTTL(args []resp.Value) *resp.Value {
 //
 // 格式：EXPIRE key seconds
 // 返回：1 表示成功，0 表示键不存在
-func (h *CommandHandler) handleExpire(args []resp.Value) *resp.Value {
-	if len(args) != 2 {
-		return &resp.Value{
-			Type: resp.Error,
-			Str:  "ERR EXPIRE 命令需要 2 个参数",
-		}
-	}
-
-	if args[0].Type != resp.BulkString || args[1].Type != resp.BulkString {
-		return &resp.Value{
-			Type: resp.Error,
-			Str:  "ERR 参数必须是 Bulk String",
-		}
-	}
-
-	key := string(args[0].Bulk)
-	seconds, err := strconv.Atoi(string(args[1].Bulk))
+func (h *CommandHandler) handleExpire(c *Client, args [][]byte) *resp.Value {
+	key := string(args[0])
+	seconds, err := strconv.Atoi(string(args[1]))
 	if err != nil || seconds < 0 {
 		return &resp.Value{
 			Type: resp.Error,
@@ -402,7 +707,7 @@ func (h *CommandHandler) handleExpire(args []resp.Value) *resp.Value {
 		}
 	}
 
-	updated := storage.Expire(h.sm, key, seconds)
+	updated := storage.Expire(h.db(c), key, seconds)
 	result := int64(0)
 	if updated {
 		result = 1
@@ -417,16 +722,9 @@ func (h *CommandHandler) handleExpire(args []resp.Value) *resp.Value {
 // handleDBSize 处理 DBSIZE 命令
 //
 // 格式：DBSIZE
-// 返回：数据库中键的数量
-func (h *CommandHandler) handleDBSize(args []resp.Value) *resp.Value {
-	if len(args) != 0 {
-		return &resp.Value{
-			Type: resp.Error,
-			Str:  "ERR DBSIZE 命令不需要参数",
-		}
-	}
-
-	size := h.sm.Len()
+// 返回：当前逻辑数据库中键的数量
+func (h *CommandHandler) handleDBSize(c *Client, args [][]byte) *resp.Value {
+	size := h.db(c).Len()
 
 	return &resp.Value{
 		Type: resp.Integer,
@@ -436,17 +734,17 @@ func (h *CommandHandler) handleDBSize(args []resp.Value) *resp.Value {
 
 // handleFlushAll 处理 FLUSHALL 命令
 //
-// 格式：FLUSHALL
+// 格式：FLUSHALL [ASYNC|SYNC]
 // 返回：+OK
-func (h *CommandHandler) handleFlushAll(args []resp.Value) *resp.Value {
-	if len(args) != 0 {
-		return &resp.Value{
-			Type: resp.Error,
-			Str:  "ERR FLUSHALL 命令不需要参数",
-		}
+// 注意：清空全部逻辑数据库；ASYNC 与 SYNC 均同步执行
+func (h *CommandHandler) handleFlushAll(c *Client, args [][]byte) *resp.Value {
+	if !validFlushMode(args) {
+		return syntaxErrorReply()
 	}
 
-	h.sm.Clear()
+	h.dbs.Each(func(_ int, sm *storage.ShardedMap) {
+		sm.Clear()
+	})
 
 	return &resp.Value{
 		Type: resp.SimpleString,
@@ -457,86 +755,254 @@ func (h *CommandHandler) handleFlushAll(args []resp.Value) *resp.Value {
 // handleKeys 处理 KEYS 命令
 //
 // 格式：KEYS pattern
-// 返回：匹配的键列表
-// 注意：这是一个简化实现，仅支持 * 通配符
-func (h *CommandHandler) handleKeys(args []resp.Value) *resp.Value {
-	if len(args) != 1 {
+// 返回：匹配 glob 模式（*、?、[a-z]、[^x]、\x）的键列表
+// 注意：O(n) 操作，会遍历全部键，生产环境应使用 SCAN
+func (h *CommandHandler) handleKeys(c *Client, args [][]byte) *resp.Value {
+	pattern := glob.Compile(string(args[0]))
+
+	// 获取所有键后按模式过滤
+	keys := h.getAllKeys(h.db(c))
+
+	// 构建响应数组
+	result := make([]resp.Value, 0, len(keys))
+	for _, key := range keys {
+		if !pattern.Match(key) {
+			continue
+		}
+		result = append(result, resp.Value{
+			Type: resp.BulkString,
+			Bulk: []byte(key),
+		})
+	}
+
+	return &resp.Value{
+		Type:  resp.Array,
+		Array: result,
+	}
+}
+
+// handleInfo 处理 INFO 命令
+//
+// 格式：INFO [section]
+// 返回：RESP2 连接返回 Bulk String；RESP3 连接返回 Map（section → Map(field → value)）
+func (h *CommandHandler) handleInfo(c *Client, args [][]byte) *resp.Value {
+	section := "all"
+	if len(args) > 0 {
+		section = strings.ToLower(string(args[0]))
+	}
+
+	if c.Protocol >= resp.RESP3 {
+		return h.buildInfoMap(section)
+	}
+
+	info := h.buildInfoString(section)
+
+	return &resp.Value{
+		Type: resp.BulkString,
+		Bulk: []byte(info),
+	}
+}
+
+// handleHello 处理 HELLO 命令
+//
+// 格式：HELLO [protover [SETNAME clientname]]
+// 返回：服务器信息 Map（RESP2 连接降级为扁平 Array），
+// 之后该连接的响应使用协商的协议版本
+func (h *CommandHandler) handleHello(c *Client, args [][]byte) *resp.Value {
+	protocol := c.Protocol
+	name := c.Name
+
+	if len(args) > 0 {
+		version, err := strconv.Atoi(string(args[0]))
+		if err != nil {
+			return &resp.Value{
+				Type: resp.Error,
+				Str:  "ERR 协议版本必须是整数",
+			}
+		}
+		if version != resp.RESP2 && version != resp.RESP3 {
+			return &resp.Value{
+				Type: resp.Error,
+				Str:  "NOPROTO 不支持的协议版本",
+			}
+		}
+		protocol = version
+
+		for i := 1; i < len(args); i++ {
+			option := strings.ToUpper(string(args[i]))
+			switch {
+			case option == "SETNAME" && i+1 < len(args):
+				name = string(args[i+1])
+				i++
+			case option == "AUTH":
+				return &resp.Value{
+					Type: resp.Error,
+					Str:  "ERR 不支持 HELLO AUTH，请使用 AUTH.SIGN 认证",
+				}
+			default:
+				return &resp.Value{
+					Type: resp.Error,
+					Str:  fmt.Sprintf("ERR HELLO 选项语法错误: %s", args[i]),
+				}
+			}
+		}
+	}
+
+	c.Protocol = protocol
+	c.Name = name
+
+	return &resp.Value{
+		Type: resp.Map,
+		Array: []resp.Value{
+			{Type: resp.BulkString, Bulk: []byte("server")},
+			{Type: resp.BulkString, Bulk: []byte("tokenginx")},
+			{Type: resp.BulkString, Bulk: []byte("version")},
+			{Type: resp.BulkString, Bulk: []byte("0.1.0-dev")},
+			{Type: resp.BulkString, Bulk: []byte("proto")},
+			{Type: resp.Integer, Int: int64(protocol)},
+			{Type: resp.BulkString, Bulk: []byte("id")},
+			{Type: resp.Integer, Int: c.ID},
+			{Type: resp.BulkString, Bulk: []byte("mode")},
+			{Type: resp.BulkString, Bulk: []byte("standalone")},
+			{Type: resp.BulkString, Bulk: []byte("role")},
+			{Type: resp.BulkString, Bulk: []byte("master")},
+			{Type: resp.BulkString, Bulk: []byte("modules")},
+			h.modulesReply(),
+		},
+	}
+}
+
+// handleNonceCheck 处理 NONCE.CHECK 命令
+//
+// 格式：NONCE.CHECK nonce
+// 返回：1 表示 Nonce 首次出现并已记录，0 表示保留窗口内已使用过（疑似重放）
+func (h *CommandHandler) handleNonceCheck(c *Client, args [][]byte) *resp.Value {
+	if h.nonces == nil {
 		return &resp.Value{
 			Type: resp.Error,
-			Str:  "ERR KEYS 命令需要 1 个参数",
+			Str:  "ERR 防重放未启用",
 		}
 	}
 
-	if args[0].Type != resp.BulkString {
+	err := h.nonces.CheckAndInsert(string(args[0]))
+	switch err {
+	case nil:
+		return &resp.Value{
+			Type: resp.Integer,
+			Int:  1,
+		}
+	case antireplay.ErrNonceReused:
+		return &resp.Value{
+			Type: resp.Integer,
+			Int:  0,
+		}
+	case antireplay.ErrNonceCacheFull:
 		return &resp.Value{
 			Type: resp.Error,
-			Str:  "ERR 模式必须是 Bulk String",
+			Str:  "ERR Nonce 缓存已满",
+		}
+	default:
+		return &resp.Value{
+			Type: resp.Error,
+			Str:  fmt.Sprintf("ERR 无效的 Nonce: %v", err),
 		}
 	}
+}
 
-	pattern := string(args[0].Bulk)
+// maxMintAttempts 是 TOKEN.MINT 遇到令牌冲突时的最大尝试次数
+//
+// 令牌至少有 132 位熵，冲突概率可以忽略，重试只是为了在理论上保证不覆盖已有数据。
+const maxMintAttempts = 3
 
-	// 简化实现：仅支持 "*" 模式（返回所有键）
-	if pattern != "*" {
+// handleTokenMint 处理 TOKEN.MINT 命令
+//
+// 格式：TOKEN.MINT payload seconds [LENGTH n] [PREFIX prefix] [CHECKSUM]
+// 返回：生成的令牌（payload 已以该令牌为键存储，TTL 为 seconds 秒）
+func (h *CommandHandler) handleTokenMint(c *Client, args [][]byte) *resp.Value {
+	payload := args[0]
+	ttl, err := strconv.Atoi(string(args[1]))
+	if err != nil {
 		return &resp.Value{
 			Type: resp.Error,
-			Str:  "ERR 当前仅支持 KEYS * 模式",
+			Str:  "ERR 参数必须是整数",
 		}
 	}
-
-	// 获取所有键（简化实现）
-	keys := h.getAllKeys()
-
-	// 构建响应数组
-	result := make([]resp.Value, len(keys))
-	for i, key := range keys {
-		result[i] = resp.Value{
-			Type: resp.BulkString,
-			Bulk: []byte(key),
+	// 令牌必须带有过期时间，避免生成永不过期的会话令牌
+	if _, ok := absoluteExpiry("EX", int64(ttl), time.Now().UnixMilli()); !ok {
+		return &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR 令牌有效期必须是正整数（秒）",
 		}
 	}
 
-	return &resp.Value{
-		Type:  resp.Array,
-		Array: result,
+	opts := &token.Options{}
+	for i := 2; i < len(args); i++ {
+		option := strings.ToUpper(string(args[i]))
+		switch option {
+		case "CHECKSUM":
+			opts.Checksum = true
+		case "LENGTH", "PREFIX":
+			if i+1 >= len(args) {
+				return &resp.Value{
+					Type: resp.Error,
+					Str:  fmt.Sprintf("ERR %s 选项缺少参数", option),
+				}
+			}
+			i++
+			if option == "PREFIX" {
+				opts.Prefix = string(args[i])
+				continue
+			}
+			length, err := strconv.Atoi(string(args[i]))
+			if err != nil {
+				return &resp.Value{
+					Type: resp.Error,
+					Str:  "ERR LENGTH 参数必须是整数",
+				}
+			}
+			opts.Length = length
+		default:
+			return &resp.Value{
+				Type: resp.Error,
+				Str:  fmt.Sprintf("ERR 未知选项: %s", option),
+			}
+		}
 	}
-}
 
-// handleInfo 处理 INFO 命令
-//
-// 格式：INFO [section]
-// 返回：服务器信息
-func (h *CommandHandler) handleInfo(args []resp.Value) *resp.Value {
-	section := "all"
-	if len(args) > 0 {
-		if args[0].Type != resp.BulkString {
+	for attempt := 0; attempt < maxMintAttempts; attempt++ {
+		tok, err := token.Generate(opts)
+		if err != nil {
 			return &resp.Value{
 				Type: resp.Error,
-				Str:  "ERR section 必须是 Bulk String",
+				Str:  fmt.Sprintf("ERR 生成令牌失败: %v", err),
 			}
 		}
-		section = strings.ToLower(string(args[0].Bulk))
-	}
 
-	info := h.buildInfoString(section)
+		if h.db(c).SetNX(tok, payload, ttl) {
+			return &resp.Value{
+				Type: resp.BulkString,
+				Bulk: []byte(tok),
+			}
+		}
+	}
 
 	return &resp.Value{
-		Type: resp.BulkString,
-		Bulk: []byte(info),
+		Type: resp.Error,
+		Str:  "ERR 生成令牌失败: 多次冲突",
 	}
 }
 
-// getAllKeys 获取所有键（简化实现，仅用于 KEYS 命令）
+// getAllKeys 获取逻辑数据库中的所有键（简化实现，仅用于 KEYS 命令）
 //
 // 注意：这是一个 O(n) 操作，在生产环境中应避免频繁使用
-func (h *CommandHandler) getAllKeys() []string {
+func (h *CommandHandler) getAllKeys(sm *storage.ShardedMap) []string {
 	keys := make([]string, 0, 100)
 
 	// 遍历所有分片收集键
 	// 注意：这里访问了 ShardedMap 的内部实现
 	// 在实际生产代码中，应该在 ShardedMap 中提供一个专门的方法
 	for i := 0; i < 256; i++ {
-		shard := h.sm.GetShardForIndex(i)
+		shard := sm.GetShardForIndex(i)
 		if shard == nil {
 			continue
 		}
@@ -548,36 +1014,83 @@ func (h *CommandHandler) getAllKeys() []string {
 	return keys
 }
 
-// buildInfoString 构建 INFO 命令的响应字符串
-func (h *CommandHandler) buildInfoString(section string) string {
-	var info strings.Builder
+// infoSection INFO 命令的一个信息段
+type infoSection struct {
+	name   string
+	fields [][2]string // 字段名和值
+}
+
+// buildInfo 收集 INFO 命令需要返回的信息段
+func (h *CommandHandler) buildInfo(section string) []infoSection {
+	var sections []infoSection
 
 	if section == "all" || section == "server" {
-		info.WriteString("# Server\r\n")
-		info.WriteString("tokenginx_version:0.1.0-dev\r\n")
-		info.WriteString("tokenginx_mode:standalone\r\n")
-		info.WriteString("os:Linux\r\n")
-		info.WriteString("arch_bits:64\r\n")
-		info.WriteString("\r\n")
+		sections = append(sections, infoSection{
+			name: "Server",
+			fields: [][2]string{
+				{"tokenginx_version", "0.1.0-dev"},
+				{"tokenginx_mode", "standalone"},
+				{"os", "Linux"},
+				{"arch_bits", "64"},
+			},
+		})
 	}
 
 	if section == "all" || section == "memory" {
-		info.WriteString("# Memory\r\n")
 		// 这里可以添加更详细的内存统计
-		info.WriteString("\r\n")
+		sections = append(sections, infoSection{name: "Memory"})
 	}
 
 	if section == "all" || section == "stats" {
-		info.WriteString("# Stats\r\n")
-		size := h.sm.Len()
-		info.WriteString(fmt.Sprintf("total_keys:%d\r\n", size))
-		info.WriteString("\r\n")
+		size := 0
+		h.dbs.Each(func(_ int, sm *storage.ShardedMap) {
+			size += sm.Len()
+		})
+		sections = append(sections, infoSection{
+			name:   "Stats",
+			fields: [][2]string{{"total_keys", strconv.Itoa(size)}},
+		})
 	}
 
 	if section == "all" || section == "keyspace" {
-		info.WriteString("# Keyspace\r\n")
-		size := h.sm.Len()
-		info.WriteString(fmt.Sprintf("db0:keys=%d\r\n", size))
+		// 与 Redis 相同，只列出非空的逻辑数据库
+		var fields [][2]string
+		h.dbs.Each(func(index int, sm *storage.ShardedMap) {
+			if size := sm.Len(); size > 0 {
+				fields = append(fields, [2]string{fmt.Sprintf("db%d", index), fmt.Sprintf("keys=%d", size)})
+			}
+		})
+		sections = append(sections, infoSection{
+			name:   "Keyspace",
+			fields: fields,
+		})
+	}
+
+	if (section == "all" || section == "antireplay") && h.nonces != nil {
+		stats := h.nonces.GetStats()
+		sections = append(sections, infoSection{
+			name: "AntiReplay",
+			fields: [][2]string{
+				{"nonce_accepted", strconv.FormatInt(stats.Accepted, 10)},
+				{"nonce_rejected", strconv.FormatInt(stats.Rejected, 10)},
+				{"nonce_cache_size", strconv.FormatInt(stats.Size, 10)},
+				{"nonce_window_seconds", strconv.FormatInt(int64(stats.Window.Seconds()), 10)},
+			},
+		})
+	}
+
+	return sections
+}
+
+// buildInfoString 构建 INFO 命令的响应字符串（RESP2）
+func (h *CommandHandler) buildInfoString(section string) string {
+	var info strings.Builder
+
+	for _, sec := range h.buildInfo(section) {
+		info.WriteString("# " + sec.name + "\r\n")
+		for _, field := range sec.fields {
+			info.WriteString(field[0] + ":" + field[1] + "\r\n")
+		}
 		info.WriteString("\r\n")
 	}
 
@@ -587,3 +1100,34 @@ func (h *CommandHandler) buildInfoString(section string) string {
 
 	return info.String()
 }
+
+// buildInfoMap 构建 INFO 命令的响应 Map（RESP3）
+//
+// 格式：{"Server": {"tokenginx_version": "0.1.0-dev", ...}, "Stats": {...}, ...}
+func (h *CommandHandler) buildInfoMap(section string) *resp.Value {
+	sections := h.buildInfo(section)
+
+	info := &resp.Value{
+		Type:  resp.Map,
+		Array: make([]resp.Value, 0, 2*len(sections)),
+	}
+	for _, sec := range sections {
+		fields := resp.Value{
+			Type:  resp.Map,
+			Array: make([]resp.Value, 0, 2*len(sec.fields)),
+		}
+		for _, field := range sec.fields {
+			fields.Array = append(fields.Array,
+				resp.Value{Type: resp.BulkString, Bulk: []byte(field[0])},
+				resp.Value{Type: resp.BulkString, Bulk: []byte(field[1])},
+			)
+		}
+
+		info.Array = append(info.Array,
+			resp.Value{Type: resp.BulkString, Bulk: []byte(sec.name)},
+			fields,
+		)
+	}
+
+	return info
+}
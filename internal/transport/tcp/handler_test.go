@@ -1,8 +1,13 @@
 package tcp
 
 import (
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/yndnr/tokenginx/internal/security/antireplay"
+	"github.com/yndnr/tokenginx/internal/security/token"
 	"github.com/yndnr/tokenginx/internal/storage"
 	"github.com/yndnr/tokenginx/internal/transport/resp"
 )
@@ -140,6 +145,95 @@ func TestCommandHandler_Set(t *testing.T) {
 	}
 }
 
+// TestCommandHandler_SetOptions 测试 SET 的 NX/XX/GET/KEEPTTL 和过期选项
+func TestCommandHandler_SetOptions(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	handler := NewCommandHandler(sm)
+
+	// NX 与 EX 组合：键已存在时不能覆盖（分布式锁）
+	response := handler.HandleCommand(newCommand("SET", "lock", "owner1", "NX", "EX", "10"))
+	if response.Type != resp.SimpleString || response.Str != "OK" {
+		t.Fatalf("Expected OK for first NX, got %+v", response)
+	}
+	response = handler.HandleCommand(newCommand("SET", "lock", "owner2", "EX", "10", "nx"))
+	if !response.Null {
+		t.Errorf("Expected Null for second NX, got %+v", response)
+	}
+	if value, _ := sm.Get("lock"); string(value.([]byte)) != "owner1" {
+		t.Errorf("Expected lock to stay owner1, got %v", value)
+	}
+
+	// XX：键不存在时不写入
+	response = handler.HandleCommand(newCommand("SET", "missing", "v", "XX"))
+	if !response.Null || sm.Exists("missing") {
+		t.Errorf("Expected Null and no key for XX on missing key, got %+v", response)
+	}
+
+	// GET：返回旧值，条件不满足时也返回旧值
+	response = handler.HandleCommand(newCommand("SET", "lock", "owner3", "XX", "GET"))
+	if string(response.Bulk) != "owner1" {
+		t.Errorf("Expected old value owner1, got %+v", response)
+	}
+	response = handler.HandleCommand(newCommand("SET", "lock", "owner4", "NX", "GET"))
+	if string(response.Bulk) != "owner3" {
+		t.Errorf("Expected old value owner3 for failed NX GET, got %+v", response)
+	}
+	response = handler.HandleCommand(newCommand("SET", "fresh", "v", "GET"))
+	if !response.Null {
+		t.Errorf("Expected Null for GET on missing key, got %+v", response)
+	}
+
+	// KEEPTTL：保留原有过期时间；不带过期选项的 SET 清除过期时间
+	handler.HandleCommand(newCommand("SET", "session", "a", "EX", "100"))
+	handler.HandleCommand(newCommand("SET", "session", "b", "KEEPTTL"))
+	if ttl := storage.TTL(sm, "session"); ttl <= 0 || ttl > 100 {
+		t.Errorf("Expected TTL kept around 100, got %d", ttl)
+	}
+	handler.HandleCommand(newCommand("SET", "session", "c"))
+	if ttl := storage.TTL(sm, "session"); ttl != -2 {
+		t.Errorf("Expected no expiry after plain SET, got %d", ttl)
+	}
+
+	// PX / EXAT / PXAT
+	handler.HandleCommand(newCommand("SET", "px", "v", "PX", "1500"))
+	if ttl := storage.TTL(sm, "px"); ttl != 2 && ttl != 1 {
+		t.Errorf("Expected TTL of about 1.5s, got %d", ttl)
+	}
+	exat := strconv.FormatInt(time.Now().Unix()+50, 10)
+	handler.HandleCommand(newCommand("SET", "exat", "v", "EXAT", exat))
+	if ttl := storage.TTL(sm, "exat"); ttl < 49 || ttl > 50 {
+		t.Errorf("Expected TTL around 50, got %d", ttl)
+	}
+	pxat := strconv.FormatInt(time.Now().UnixMilli()-1000, 10)
+	response = handler.HandleCommand(newCommand("SET", "session", "d", "PXAT", pxat))
+	if response.Str != "OK" || sm.Exists("session") {
+		t.Errorf("Expected OK and deleted key for PXAT in the past, got %+v", response)
+	}
+
+	// 语法错误和无效过期时间
+	invalid := [][]string{
+		{"SET", "k", "v", "NX", "XX"},
+		{"SET", "k", "v", "EX", "10", "PX", "100"},
+		{"SET", "k", "v", "EX", "10", "KEEPTTL"},
+		{"SET", "k", "v", "GET", "GET"},
+		{"SET", "k", "v", "EX"},
+		{"SET", "k", "v", "EX", "abc"},
+		{"SET", "k", "v", "EX", "0"},
+		{"SET", "k", "v", "PX", "-1"},
+		{"SET", "k", "v", "EX", "9223372036854775807"},
+		{"SET", "k", "v", "BOGUS"},
+	}
+	for _, args := range invalid {
+		response := handler.HandleCommand(newCommand(args...))
+		if response.Type != resp.Error {
+			t.Errorf("%v: expected error, got %+v", args, response)
+		}
+	}
+	if sm.Exists("k") {
+		t.Error("Invalid SET should not write the key")
+	}
+}
+
 // TestCommandHandler_Del 测试 DEL 命令
 func TestCommandHandler_Del(t *testing.T) {
 	sm := storage.NewShardedMap(1024)
@@ -297,6 +391,35 @@ func TestCommandHandler_Expire(t *testing.T) {
 	}
 }
 
+// TestCommandHandler_Keys 测试 KEYS 的 glob 模式
+func TestCommandHandler_Keys(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	handler := NewCommandHandler(sm)
+	for _, key := range []string{"oauth:token:a", "oauth:token:b", "oauth:refresh:a", "session:1", "session:22"} {
+		sm.Set(key, "v", 0)
+	}
+
+	tests := []struct {
+		pattern string
+		count   int
+	}{
+		{"*", 5},
+		{"oauth:token:*", 2},
+		{"oauth:*:a", 2},
+		{"session:?", 1},
+		{"session:[0-9][0-9]", 1},
+		{"session:[^1]*", 1},
+		{"missing:*", 0},
+	}
+
+	for _, tt := range tests {
+		response := handler.HandleCommand(newCommand("KEYS", tt.pattern))
+		if response.Type != resp.Array || len(response.Array) != tt.count {
+			t.Errorf("KEYS %s: expected %d keys, got %+v", tt.pattern, tt.count, response)
+		}
+	}
+}
+
 // TestCommandHandler_UnknownCommand 测试未知命令
 func TestCommandHandler_UnknownCommand(t *testing.T) {
 	sm := storage.NewShardedMap(1024)
@@ -342,3 +465,188 @@ func TestCommandHandler_InvalidFormat(t *testing.T) {
 		t.Errorf("Expected error, got %v", response)
 	}
 }
+
+// TestCommandHandler_NonceCheck 测试 NONCE.CHECK 命令
+func TestCommandHandler_NonceCheck(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	handler := NewCommandHandler(sm)
+
+	cmd := newCommand("NONCE.CHECK", "abc123")
+
+	// 未启用防重放
+	response := handler.HandleCommand(cmd)
+	if response.Type != resp.Error {
+		t.Errorf("Expected error when anti-replay disabled, got %v", response)
+	}
+
+	ns := antireplay.NewNonceStore(nil)
+	handler.SetNonceStore(ns)
+
+	// 首次使用
+	response = handler.HandleCommand(cmd)
+	if response.Type != resp.Integer || response.Int != 1 {
+		t.Errorf("Expected 1, got %v", response)
+	}
+
+	// 重放
+	response = handler.HandleCommand(cmd)
+	if response.Type != resp.Integer || response.Int != 0 {
+		t.Errorf("Expected 0, got %v", response)
+	}
+
+	// 参数错误
+	response = handler.HandleCommand(newCommand("NONCE.CHECK"))
+	if response.Type != resp.Error {
+		t.Errorf("Expected error, got %v", response)
+	}
+
+	stats := ns.GetStats()
+	if stats.Accepted != 1 || stats.Rejected != 1 {
+		t.Errorf("Expected 1 accepted / 1 rejected, got %d / %d", stats.Accepted, stats.Rejected)
+	}
+
+	// INFO 中包含防重放统计
+	response = handler.HandleCommand(newCommand("INFO", "antireplay"))
+	if !strings.Contains(string(response.Bulk), "nonce_accepted:1") {
+		t.Errorf("Expected nonce stats in INFO, got %q", response.Bulk)
+	}
+}
+
+// newCommand 根据参数构建 RESP 命令
+func newCommand(args ...string) *resp.Value {
+	array := make([]resp.Value, len(args))
+	for i, arg := range args {
+		array[i] = resp.Value{Type: resp.BulkString, Bulk: []byte(arg)}
+	}
+	return &resp.Value{
+		Type:  resp.Array,
+		Array: array,
+	}
+}
+
+// TestCommandHandler_TokenMint 测试 TOKEN.MINT 命令
+func TestCommandHandler_TokenMint(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	handler := NewCommandHandler(sm)
+
+	response := handler.HandleCommand(newCommand("TOKEN.MINT", "session-data", "60", "PREFIX", "tgx_", "CHECKSUM"))
+	if response.Type != resp.BulkString {
+		t.Fatalf("Expected bulk string, got %v", response)
+	}
+
+	tok := string(response.Bulk)
+	if !strings.HasPrefix(tok, "tgx_") || !token.ValidChecksum(tok) {
+		t.Errorf("Unexpected token %q", tok)
+	}
+
+	value, exists := sm.Get(tok)
+	if !exists || string(value.([]byte)) != "session-data" {
+		t.Errorf("Expected payload stored under token, got %v", value)
+	}
+	if ttl := storage.TTL(sm, tok); ttl <= 0 || ttl > 60 {
+		t.Errorf("Expected TTL around 60, got %d", ttl)
+	}
+
+	// LENGTH 选项
+	response = handler.HandleCommand(newCommand("TOKEN.MINT", "x", "60", "LENGTH", "48"))
+	if response.Type != resp.BulkString || len(response.Bulk) != 48 {
+		t.Errorf("Expected 48-char token, got %v", response)
+	}
+
+	// 非法参数
+	for _, cmd := range [][]string{
+		{"TOKEN.MINT", "x"},
+		{"TOKEN.MINT", "x", "-1"},
+		{"TOKEN.MINT", "x", "0"},
+		{"TOKEN.MINT", "x", "ten"},
+		{"TOKEN.MINT", "x", "60", "LENGTH", "8"},
+		{"TOKEN.MINT", "x", "60", "PREFIX"},
+		{"TOKEN.MINT", "x", "60", "PREFIX", "a:b"},
+		{"TOKEN.MINT", "x", "60", "UNKNOWN"},
+	} {
+		response = handler.HandleCommand(newCommand(cmd...))
+		if response.Type != resp.Error {
+			t.Errorf("%v: expected error, got %v", cmd, response)
+		}
+	}
+}
+
+// TestCommandHandler_Hello 测试 HELLO 协议版本协商
+func TestCommandHandler_Hello(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	handler := NewCommandHandler(sm)
+	client := newClient(7, "127.0.0.1:1")
+
+	response := handler.HandleClientCommand(client, newCommand("HELLO", "3", "SETNAME", "app1"))
+	if response.Type != resp.Map {
+		t.Fatalf("Expected Map, got %c (%s)", response.Type, response.Str)
+	}
+	if client.Protocol != resp.RESP3 || client.Name != "app1" {
+		t.Errorf("Expected protocol 3 and name app1, got %d / %q", client.Protocol, client.Name)
+	}
+
+	fields := make(map[string]resp.Value)
+	for i := 0; i+1 < len(response.Array); i += 2 {
+		fields[string(response.Array[i].Bulk)] = response.Array[i+1]
+	}
+	if fields["proto"].Int != 3 || fields["id"].Int != 7 || string(fields["server"].Bulk) != "tokenginx" {
+		t.Errorf("Unexpected HELLO reply: %+v", fields)
+	}
+
+	// 不带参数的 HELLO 保持当前协议版本
+	handler.HandleClientCommand(client, newCommand("HELLO"))
+	if client.Protocol != resp.RESP3 {
+		t.Errorf("Expected HELLO without arguments to keep protocol 3, got %d", client.Protocol)
+	}
+
+	// 切换回 RESP2
+	handler.HandleClientCommand(client, newCommand("HELLO", "2"))
+	if client.Protocol != resp.RESP2 {
+		t.Errorf("Expected protocol 2, got %d", client.Protocol)
+	}
+
+	tests := []struct {
+		args   []string
+		prefix string
+	}{
+		{[]string{"HELLO", "4"}, "NOPROTO"},
+		{[]string{"HELLO", "abc"}, "ERR"},
+		{[]string{"HELLO", "3", "AUTH", "user", "pass"}, "ERR"},
+		{[]string{"HELLO", "3", "SETNAME"}, "ERR"},
+	}
+	for _, tt := range tests {
+		response := handler.HandleClientCommand(client, newCommand(tt.args...))
+		if response.Type != resp.Error || !strings.HasPrefix(response.Str, tt.prefix) {
+			t.Errorf("%v: expected %s error, got %+v", tt.args, tt.prefix, response)
+		}
+	}
+	if client.Protocol != resp.RESP2 {
+		t.Errorf("Failed HELLO must not change the protocol, got %d", client.Protocol)
+	}
+}
+
+// TestCommandHandler_InfoRESP3 测试 RESP3 连接的 INFO 返回 Map
+func TestCommandHandler_InfoRESP3(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	sm.Set("key1", "value1", 0)
+	handler := NewCommandHandler(sm)
+
+	client := newClient(1, "127.0.0.1:1")
+	response := handler.HandleClientCommand(client, newCommand("INFO", "stats"))
+	if response.Type != resp.BulkString || !strings.Contains(string(response.Bulk), "total_keys:1") {
+		t.Errorf("Expected RESP2 bulk string INFO, got %+v", response)
+	}
+
+	client.Protocol = resp.RESP3
+	response = handler.HandleClientCommand(client, newCommand("INFO", "stats"))
+	if response.Type != resp.Map || len(response.Array) != 2 {
+		t.Fatalf("Expected map with one section, got %+v", response)
+	}
+	if string(response.Array[0].Bulk) != "Stats" {
+		t.Errorf("Expected section Stats, got %s", response.Array[0].Bulk)
+	}
+	stats := response.Array[1]
+	if stats.Type != resp.Map || string(stats.Array[0].Bulk) != "total_keys" || string(stats.Array[1].Bulk) != "1" {
+		t.Errorf("Unexpected Stats section: %+v", stats)
+	}
+}
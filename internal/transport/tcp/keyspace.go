@@ -0,0 +1,246 @@
+package tcp
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/yndnr/tokenginx/internal/storage"
+)
+
+// defaultKeyEventQueue 键空间事件回调队列的长度
+const defaultKeyEventQueue = 4096
+
+// KeyspaceEvents 键空间事件通知配置
+//
+// 与 Redis 的 notify-keyspace-events 相同，K、E 选择发布到哪类频道，
+// 其余字符选择事件类别，两者都设置时才会通过 Pub/Sub 发布。
+type KeyspaceEvents struct {
+	Keyspace bool                 // K：发布到 __keyspace@<db>__:<key>，消息为事件名
+	Keyevent bool                 // E：发布到 __keyevent@<db>__:<event>，消息为键
+	Types    storage.KeyEventType // 选择的事件类别
+}
+
+// keyspaceEventClasses 事件类别字符，顺序与 String 的输出一致
+var keyspaceEventClasses = []struct {
+	flag  byte
+	types storage.KeyEventType
+}{
+	{'g', storage.KeyEventDel | storage.KeyEventExpire},
+	{'$', storage.KeyEventSet},
+	{'x', storage.KeyEventExpired},
+	{'e', storage.KeyEventEvicted},
+}
+
+// ParseKeyspaceEvents 解析 notify-keyspace-events 格式的配置
+//
+// 参数说明：
+//   - flags: 配置字符串，空字符串表示不通知
+//
+// 支持的字符：
+//   - K: 键空间频道 __keyspace@<db>__:<key>
+//   - E: 键事件频道 __keyevent@<db>__:<event>
+//   - g: 通用事件（del、expire）
+//   - $: 字符串写入事件（set）
+//   - x: 过期事件（expired）
+//   - e: 淘汰事件（evicted，当前没有淘汰策略，不会触发）
+//   - A: g$xe 的别名
+//
+// 返回值：
+//   - KeyspaceEvents: 解析后的配置
+//   - error: 包含不支持的字符时返回错误
+//
+// 示例：
+//
+//	// 会话过期时发布到 __keyevent@0__:expired
+//	events, err := ParseKeyspaceEvents("Ex")
+func ParseKeyspaceEvents(flags string) (KeyspaceEvents, error) {
+	var events KeyspaceEvents
+
+	for i := 0; i < len(flags); i++ {
+		switch c := flags[i]; c {
+		case 'K':
+			events.Keyspace = true
+		case 'E':
+			events.Keyevent = true
+		case 'A':
+			events.Types |= storage.KeyEventAll
+		default:
+			found := false
+			for _, class := range keyspaceEventClasses {
+				if class.flag == c {
+					events.Types |= class.types
+					found = true
+				}
+			}
+			if !found {
+				return KeyspaceEvents{}, fmt.Errorf("不支持的键空间事件类别: %q", c)
+			}
+		}
+	}
+
+	return events, nil
+}
+
+// String 返回 notify-keyspace-events 格式的配置（如 "Ex"）
+func (e KeyspaceEvents) String() string {
+	var flags []byte
+
+	if e.Keyspace {
+		flags = append(flags, 'K')
+	}
+	if e.Keyevent {
+		flags = append(flags, 'E')
+	}
+	if e.Types&storage.KeyEventAll == storage.KeyEventAll {
+		return string(append(flags, 'A'))
+	}
+	for _, class := range keyspaceEventClasses {
+		if e.Types&class.types != 0 {
+			flags = append(flags, class.flag)
+		}
+	}
+
+	return string(flags)
+}
+
+// publishes 返回配置是否需要通过 Pub/Sub 发布
+func (e KeyspaceEvents) publishes() bool {
+	return (e.Keyspace || e.Keyevent) && e.Types != 0
+}
+
+// keyspaceHook 已设置的键空间事件通知
+type keyspaceHook struct {
+	events KeyspaceEvents
+	queue  *keyEventQueue // 嵌入方回调的事件队列，nil 表示不回调
+}
+
+// queuedKeyEvent 等待回调的键空间事件
+type queuedKeyEvent struct {
+	db    int
+	event storage.KeyEvent
+}
+
+// keyEventQueue 在独立的 Goroutine 中按顺序调用嵌入方回调
+//
+// 事件在持有分片锁时入队，回调在锁外执行，因此回调可以访问存储而不会死锁。
+type keyEventQueue struct {
+	events    chan queuedKeyEvent
+	callback  func(db int, event storage.KeyEvent)
+	done      chan struct{}
+	closeOnce sync.Once
+	dropped   atomic.Uint64 // 队列已满时丢弃的事件数量
+}
+
+// newKeyEventQueue 创建事件队列并启动回调 Goroutine
+func newKeyEventQueue(size int, callback func(db int, event storage.KeyEvent)) *keyEventQueue {
+	q := &keyEventQueue{
+		events:   make(chan queuedKeyEvent, size),
+		callback: callback,
+		done:     make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+// push 将事件加入队列，不会阻塞
+//
+// 队列已满（回调处理过慢）时丢弃事件并记录警告，避免阻塞持有分片锁的写入。
+func (q *keyEventQueue) push(db int, event storage.KeyEvent) {
+	select {
+	case <-q.done:
+		return
+	default:
+	}
+
+	select {
+	case q.events <- queuedKeyEvent{db: db, event: event}:
+	default:
+		if n := q.dropped.Add(1); n == 1 || n%1000 == 0 {
+			log.Printf("[WARN] 键空间事件回调处理过慢，队列已满，累计丢弃 %d 个事件", n)
+		}
+	}
+}
+
+// run 依次调用回调，直到队列关闭
+func (q *keyEventQueue) run() {
+	for {
+		select {
+		case e := <-q.events:
+			q.callback(e.db, e.event)
+		case <-q.done:
+			return
+		}
+	}
+}
+
+// close 停止回调 Goroutine，之后的事件被忽略；可以多次调用
+func (q *keyEventQueue) close() {
+	q.closeOnce.Do(func() { close(q.done) })
+}
+
+// SetKeyspaceEvents 启用键空间事件通知
+//
+// 参数说明：
+//   - events: 事件配置（见 ParseKeyspaceEvents），K、E 都未设置时不通过 Pub/Sub 发布
+//   - callback: 嵌入方的 Go 回调，接收 events.Types 选择的事件；nil 表示不回调
+//
+// 注意事项：
+//   - 应在服务器开始处理连接之前调用，SetDatabases 之后的数据库同样生效
+//   - 回调在独立的 Goroutine 中按事件发生的顺序调用，可以访问存储
+//   - 事件先进入长度为 4096 的队列，回调处理过慢导致队列已满时丢弃新事件并记录警告
+//   - 重复调用会停止之前的回调 Goroutine
+func (h *CommandHandler) SetKeyspaceEvents(events KeyspaceEvents, callback func(db int, event storage.KeyEvent)) {
+	h.closeKeyspaceEvents()
+
+	hook := &keyspaceHook{events: events}
+	if callback != nil {
+		hook.queue = newKeyEventQueue(defaultKeyEventQueue, callback)
+	}
+	h.keyspace = hook
+	h.installKeyspaceHook()
+}
+
+// closeKeyspaceEvents 停止嵌入方回调的 Goroutine
+func (h *CommandHandler) closeKeyspaceEvents() {
+	if h.keyspace != nil && h.keyspace.queue != nil {
+		h.keyspace.queue.close()
+	}
+}
+
+// installKeyspaceHook 将键空间事件通知设置到当前的逻辑数据库
+func (h *CommandHandler) installKeyspaceHook() {
+	hook := h.keyspace
+	if hook == nil {
+		return
+	}
+
+	if !hook.events.publishes() && hook.queue == nil {
+		h.dbs.SetKeyEventHandler(0, nil)
+		return
+	}
+
+	h.dbs.SetKeyEventHandler(hook.events.Types, func(db int, event storage.KeyEvent) {
+		if hook.events.publishes() {
+			h.publishKeyspaceEvent(hook.events, db, event)
+		}
+		if hook.queue != nil {
+			hook.queue.push(db, event)
+		}
+	})
+}
+
+// publishKeyspaceEvent 将键空间事件发布到 __keyspace@<db>__:<key> 和 __keyevent@<db>__:<event>
+func (h *CommandHandler) publishKeyspaceEvent(events KeyspaceEvents, db int, event storage.KeyEvent) {
+	name := event.Type.String()
+	index := strconv.Itoa(db)
+
+	if events.Keyspace {
+		h.pubsub.Publish("__keyspace@"+index+"__:"+event.Key, []byte(name))
+	}
+	if events.Keyevent {
+		h.pubsub.Publish("__keyevent@"+index+"__:"+name, []byte(event.Key))
+	}
+}
@@ -0,0 +1,147 @@
+package tcp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yndnr/tokenginx/internal/storage"
+)
+
+// TestParseKeyspaceEvents 测试 notify-keyspace-events 配置解析
+func TestParseKeyspaceEvents(t *testing.T) {
+	tests := []struct {
+		flags    string
+		expected KeyspaceEvents
+		str      string
+	}{
+		{"", KeyspaceEvents{}, ""},
+		{"Ex", KeyspaceEvents{Keyevent: true, Types: storage.KeyEventExpired}, "Ex"},
+		{"K$g", KeyspaceEvents{Keyspace: true, Types: storage.KeyEventSet | storage.KeyEventDel | storage.KeyEventExpire}, "Kg$"},
+		{"KEA", KeyspaceEvents{Keyspace: true, Keyevent: true, Types: storage.KeyEventAll}, "KEA"},
+		{"Eg$xe", KeyspaceEvents{Keyevent: true, Types: storage.KeyEventAll}, "EA"},
+	}
+
+	for _, tt := range tests {
+		events, err := ParseKeyspaceEvents(tt.flags)
+		if err != nil {
+			t.Errorf("ParseKeyspaceEvents(%q) failed: %v", tt.flags, err)
+			continue
+		}
+		if events != tt.expected {
+			t.Errorf("ParseKeyspaceEvents(%q) = %+v, want %+v", tt.flags, events, tt.expected)
+		}
+		if events.String() != tt.str {
+			t.Errorf("String() = %q, want %q", events.String(), tt.str)
+		}
+	}
+
+	if _, err := ParseKeyspaceEvents("Ez"); err == nil {
+		t.Error("Expected error for unknown class")
+	}
+}
+
+// TestCommandHandler_KeyeventExpired 测试过期事件发布到 __keyevent@<db>__:expired
+func TestCommandHandler_KeyeventExpired(t *testing.T) {
+	handler := NewCommandHandler(storage.NewShardedMap(1024))
+	handler.SetKeyspaceEvents(KeyspaceEvents{Keyevent: true, Types: storage.KeyEventExpired}, nil)
+
+	subscriber := newClient(1, "")
+	handler.HandleClientCommand(subscriber, newCommand("SUBSCRIBE", "__keyevent@2__:expired"))
+	expectPush(t, subscriber.sub, "subscribe", "__keyevent@2__:expired", "1")
+
+	c := newClient(2, "")
+	handler.HandleClientCommand(c, newCommand("SELECT", "2"))
+	handler.HandleClientCommand(c, newCommand("SET", "session:1", "alice", "PX", "50"))
+	handler.HandleClientCommand(c, newCommand("DEL", "session:2"))
+
+	time.Sleep(100 * time.Millisecond)
+	handler.HandleClientCommand(c, newCommand("GET", "session:1"))
+
+	expectPush(t, subscriber.sub, "message", "__keyevent@2__:expired", "session:1")
+	if len(subscriber.sub.out) != 0 {
+		t.Errorf("Expected only the expired event, got %d more", len(subscriber.sub.out))
+	}
+}
+
+// TestCommandHandler_KeyspaceChannel 测试键空间频道和 Go 回调
+func TestCommandHandler_KeyspaceChannel(t *testing.T) {
+	handler := NewCommandHandler(storage.NewShardedMap(1024))
+
+	received := make(chan storage.KeyEvent, 16)
+	events, _ := ParseKeyspaceEvents("Kg$")
+	handler.SetKeyspaceEvents(events, func(db int, event storage.KeyEvent) {
+		received <- event
+	})
+
+	subscriber := newClient(1, "")
+	handler.HandleClientCommand(subscriber, newCommand("PSUBSCRIBE", "__keyspace@0__:session:*"))
+	expectPush(t, subscriber.sub, "psubscribe", "__keyspace@0__:session:*", "1")
+
+	handler.HandleCommand(newCommand("SET", "session:1", "alice"))
+	handler.HandleCommand(newCommand("DEL", "session:1"))
+
+	expectPush(t, subscriber.sub, "pmessage", "__keyspace@0__:session:*", "__keyspace@0__:session:1", "set")
+	expectPush(t, subscriber.sub, "pmessage", "__keyspace@0__:session:*", "__keyspace@0__:session:1", "del")
+
+	for _, expected := range []storage.KeyEventType{storage.KeyEventSet, storage.KeyEventDel} {
+		select {
+		case event := <-received:
+			if event.Type != expected || event.Key != "session:1" {
+				t.Errorf("Expected %s callback for session:1, got %+v", expected, event)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected %s callback", expected)
+		}
+	}
+}
+
+// TestCommandHandler_KeyspaceCallbackAccessesStorage 测试回调在锁外执行，可以访问事件所在的数据库
+func TestCommandHandler_KeyspaceCallbackAccessesStorage(t *testing.T) {
+	handler := NewCommandHandler(storage.NewShardedMap(1024))
+
+	values := make(chan string, 1)
+	handler.SetKeyspaceEvents(KeyspaceEvents{Types: storage.KeyEventSet}, func(db int, event storage.KeyEvent) {
+		// 在分片锁内同步调用时，这里的 GET 会死锁
+		reply := handler.HandleCommand(newCommand("GET", event.Key))
+		values <- string(reply.Bulk)
+	})
+	defer handler.closeKeyspaceEvents()
+
+	handler.HandleCommand(newCommand("SET", "session:1", "alice"))
+
+	select {
+	case value := <-values:
+		if value != "alice" {
+			t.Errorf("Expected callback to read alice, got %q", value)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Callback did not run")
+	}
+}
+
+// TestKeyEventQueue_Full 测试队列已满时丢弃事件而不阻塞写入
+func TestKeyEventQueue_Full(t *testing.T) {
+	release := make(chan struct{})
+	q := newKeyEventQueue(2, func(db int, event storage.KeyEvent) {
+		<-release
+	})
+	defer q.close()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			q.push(0, storage.KeyEvent{Type: storage.KeyEventSet, Key: "session:1"})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("push blocked on a full queue")
+	}
+	if q.dropped.Load() == 0 {
+		t.Error("Expected some events to be dropped")
+	}
+	close(release)
+}
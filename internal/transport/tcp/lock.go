@@ -0,0 +1,148 @@
+package tcp
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/yndnr/tokenginx/internal/storage"
+	"github.com/yndnr/tokenginx/internal/transport/resp"
+)
+
+// handleLockAcquire 处理 LOCK.ACQUIRE 命令
+//
+// 格式：LOCK.ACQUIRE key owner milliseconds
+// 返回：获取成功时返回 fencing token；锁已被持有时返回 Null
+func (h *CommandHandler) handleLockAcquire(c *Client, args [][]byte) *resp.Value {
+	ttl, errReply := parseLockTTL(args[2])
+	if errReply != nil {
+		return errReply
+	}
+
+	token, ok, err := h.db(c).AcquireLock(string(args[0]), string(args[1]), ttl)
+	if err != nil {
+		return lockError(err)
+	}
+	if !ok {
+		return &resp.Value{
+			Type: resp.BulkString,
+			Null: true,
+		}
+	}
+
+	return &resp.Value{
+		Type: resp.Integer,
+		Int:  int64(token),
+	}
+}
+
+// handleLockRelease 处理 LOCK.RELEASE 命令
+//
+// 格式：LOCK.RELEASE key owner
+// 返回：释放成功返回 1；锁不存在、已过期或由其他持有者持有返回 0
+func (h *CommandHandler) handleLockRelease(c *Client, args [][]byte) *resp.Value {
+	released, err := h.db(c).ReleaseLock(string(args[0]), string(args[1]))
+	if err != nil {
+		return lockError(err)
+	}
+
+	result := int64(0)
+	if released {
+		result = 1
+	}
+
+	return &resp.Value{
+		Type: resp.Integer,
+		Int:  result,
+	}
+}
+
+// handleLockExtend 处理 LOCK.EXTEND 命令
+//
+// 格式：LOCK.EXTEND key owner milliseconds
+// 返回：续期成功返回 1；锁不存在、已过期或由其他持有者持有返回 0
+// 注意：续期不改变 fencing token
+func (h *CommandHandler) handleLockExtend(c *Client, args [][]byte) *resp.Value {
+	ttl, errReply := parseLockTTL(args[2])
+	if errReply != nil {
+		return errReply
+	}
+
+	extended, err := h.db(c).ExtendLock(string(args[0]), string(args[1]), ttl)
+	if err != nil {
+		return lockError(err)
+	}
+
+	result := int64(0)
+	if extended {
+		result = 1
+	}
+
+	return &resp.Value{
+		Type: resp.Integer,
+		Int:  result,
+	}
+}
+
+// handleLockInfo 处理 LOCK.INFO 命令
+//
+// 格式：LOCK.INFO key
+// 返回：[持有者, fencing token, 剩余有效期（毫秒）]；锁未被持有时返回 Null Array
+func (h *CommandHandler) handleLockInfo(c *Client, args [][]byte) *resp.Value {
+	lock, held, err := h.db(c).GetLock(string(args[0]))
+	if err != nil {
+		return lockError(err)
+	}
+	if !held {
+		return &resp.Value{
+			Type: resp.Array,
+			Null: true,
+		}
+	}
+
+	remaining := lock.ExpiresAt - time.Now().UnixMilli()
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return &resp.Value{
+		Type: resp.Array,
+		Array: []resp.Value{
+			{Type: resp.BulkString, Bulk: []byte(lock.Owner)},
+			{Type: resp.Integer, Int: int64(lock.Token)},
+			{Type: resp.Integer, Int: remaining},
+		},
+	}
+}
+
+// parseLockTTL 解析锁的有效期（毫秒，必须大于 0 且不会溢出）
+func parseLockTTL(arg []byte) (int64, *resp.Value) {
+	ttl, err := strconv.ParseInt(string(arg), 10, 64)
+	if err != nil {
+		return 0, &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR 参数必须是整数",
+		}
+	}
+	if _, ok := absoluteExpiry("PX", ttl, time.Now().UnixMilli()); !ok {
+		return 0, &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR 锁的有效期必须是正整数（毫秒）",
+		}
+	}
+	return ttl, nil
+}
+
+// lockError 将锁操作的错误转换为错误响应
+func lockError(err error) *resp.Value {
+	if errors.Is(err, storage.ErrNotLock) {
+		return &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR 键保存的不是锁",
+		}
+	}
+	return &resp.Value{
+		Type: resp.Error,
+		Str:  "ERR " + err.Error(),
+	}
+}
@@ -0,0 +1,88 @@
+package tcp
+
+import (
+	"testing"
+
+	"github.com/yndnr/tokenginx/internal/storage"
+	"github.com/yndnr/tokenginx/internal/transport/resp"
+)
+
+// TestCommandHandler_Lock 测试 LOCK.ACQUIRE / LOCK.RELEASE / LOCK.EXTEND / LOCK.INFO 命令
+func TestCommandHandler_Lock(t *testing.T) {
+	handler := NewCommandHandler(storage.NewShardedMap(1024))
+
+	response := handler.HandleCommand(newCommand("LOCK.ACQUIRE", "lock:refresh:alice", "replica-1", "5000"))
+	if response.Type != resp.Integer || response.Int <= 0 {
+		t.Fatalf("Expected fencing token, got %+v", response)
+	}
+	token := response.Int
+
+	response = handler.HandleCommand(newCommand("LOCK.ACQUIRE", "lock:refresh:alice", "replica-2", "5000"))
+	if response.Type != resp.BulkString || !response.Null {
+		t.Errorf("Expected Null while the lock is held, got %+v", response)
+	}
+
+	response = handler.HandleCommand(newCommand("LOCK.INFO", "lock:refresh:alice"))
+	if response.Type != resp.Array || len(response.Array) != 3 {
+		t.Fatalf("Expected [owner, token, pttl], got %+v", response)
+	}
+	if string(response.Array[0].Bulk) != "replica-1" || response.Array[1].Int != token {
+		t.Errorf("Unexpected LOCK.INFO reply: %+v", response.Array)
+	}
+	if pttl := response.Array[2].Int; pttl <= 0 || pttl > 5000 {
+		t.Errorf("Expected remaining TTL in (0, 5000], got %d", pttl)
+	}
+
+	response = handler.HandleCommand(newCommand("LOCK.EXTEND", "lock:refresh:alice", "replica-2", "60000"))
+	if response.Int != 0 {
+		t.Errorf("Expected extend by another owner to return 0, got %+v", response)
+	}
+	response = handler.HandleCommand(newCommand("LOCK.EXTEND", "lock:refresh:alice", "replica-1", "60000"))
+	if response.Int != 1 {
+		t.Errorf("Expected extend by the owner to return 1, got %+v", response)
+	}
+
+	response = handler.HandleCommand(newCommand("LOCK.RELEASE", "lock:refresh:alice", "replica-2"))
+	if response.Int != 0 {
+		t.Errorf("Expected release by another owner to return 0, got %+v", response)
+	}
+	response = handler.HandleCommand(newCommand("LOCK.RELEASE", "lock:refresh:alice", "replica-1"))
+	if response.Int != 1 {
+		t.Errorf("Expected release by the owner to return 1, got %+v", response)
+	}
+
+	response = handler.HandleCommand(newCommand("LOCK.INFO", "lock:refresh:alice"))
+	if response.Type != resp.Array || !response.Null {
+		t.Errorf("Expected Null Array after release, got %+v", response)
+	}
+
+	response = handler.HandleCommand(newCommand("LOCK.ACQUIRE", "lock:refresh:alice", "replica-2", "5000"))
+	if response.Type != resp.Integer || response.Int <= token {
+		t.Errorf("Expected a larger fencing token, got %+v", response)
+	}
+}
+
+// TestCommandHandler_LockErrors 测试锁命令的参数和类型错误
+func TestCommandHandler_LockErrors(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	handler := NewCommandHandler(sm)
+	sm.Set("session:1", "data", 0)
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"LOCK.ACQUIRE", "lock:a", "owner", "abc"}, "ERR 参数必须是整数"},
+		{[]string{"LOCK.ACQUIRE", "lock:a", "owner", "0"}, "ERR 锁的有效期必须是正整数（毫秒）"},
+		{[]string{"LOCK.EXTEND", "lock:a", "owner", "-1"}, "ERR 锁的有效期必须是正整数（毫秒）"},
+		{[]string{"LOCK.ACQUIRE", "session:1", "owner", "1000"}, "ERR 键保存的不是锁"},
+		{[]string{"LOCK.RELEASE", "session:1", "owner"}, "ERR 键保存的不是锁"},
+		{[]string{"LOCK.INFO", "session:1"}, "ERR 键保存的不是锁"},
+	}
+	for _, tt := range tests {
+		response := handler.HandleCommand(newCommand(tt.args...))
+		if response.Type != resp.Error || response.Str != tt.want {
+			t.Errorf("%v: expected %q, got %+v", tt.args, tt.want, response)
+		}
+	}
+}
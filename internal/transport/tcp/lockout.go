@@ -0,0 +1,150 @@
+package tcp
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/yndnr/tokenginx/internal/storage"
+	"github.com/yndnr/tokenginx/internal/transport/resp"
+)
+
+// maxLockoutSeconds 失败计数保留时间和锁定时间的上限（秒），约 100 年
+const maxLockoutSeconds = 100 * 365 * 24 * 3600
+
+// handleLockoutFail 处理 LOCKOUT.FAIL 命令
+//
+// 格式：LOCKOUT.FAIL key window threshold:seconds [threshold:seconds ...]
+// 返回：[连续失败次数, 是否锁定(0/1), 剩余锁定秒数]
+//
+// 注意事项：
+//   - window 为失败计数的保留时间（秒），距最后一次失败超过 window 且未被锁定时计数清零
+//   - 每条规则表示连续失败达到 threshold 次后锁定 seconds 秒，threshold 必须严格递增
+//   - 达到第一级阈值后，每次失败都按已达到的最高一级重新锁定
+func (h *CommandHandler) handleLockoutFail(c *Client, args [][]byte) *resp.Value {
+	window, err := strconv.ParseInt(string(args[1]), 10, 64)
+	if err != nil || window <= 0 || window > maxLockoutSeconds {
+		return &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR 保留时间必须是正整数（秒）",
+		}
+	}
+
+	policy := storage.LockoutPolicy{Window: time.Duration(window) * time.Second}
+	for _, arg := range args[2:] {
+		tier, errReply := parseLockoutTier(arg)
+		if errReply != nil {
+			return errReply
+		}
+		if n := len(policy.Tiers); n > 0 && tier.Threshold <= policy.Tiers[n-1].Threshold {
+			return &resp.Value{
+				Type: resp.Error,
+				Str:  "ERR 锁定阈值必须严格递增",
+			}
+		}
+		policy.Tiers = append(policy.Tiers, tier)
+	}
+
+	status, err := h.db(c).RecordFailure(string(args[0]), policy)
+	if err != nil {
+		return lockoutError(err)
+	}
+
+	return lockoutReply(status)
+}
+
+// handleLockoutStatus 处理 LOCKOUT.STATUS 命令
+//
+// 格式：LOCKOUT.STATUS key
+// 返回：与 LOCKOUT.FAIL 相同，没有失败记录时为 [0, 0, 0]
+func (h *CommandHandler) handleLockoutStatus(c *Client, args [][]byte) *resp.Value {
+	status, err := h.db(c).GetLockout(string(args[0]))
+	if err != nil {
+		return lockoutError(err)
+	}
+
+	return lockoutReply(status)
+}
+
+// handleLockoutReset 处理 LOCKOUT.RESET 命令
+//
+// 格式：LOCKOUT.RESET key
+// 返回：清除了失败记录返回 1，没有记录返回 0
+// 注意：登录成功后调用以清零计数，也可用于管理员解锁
+func (h *CommandHandler) handleLockoutReset(c *Client, args [][]byte) *resp.Value {
+	reset, err := h.db(c).ResetLockout(string(args[0]))
+	if err != nil {
+		return lockoutError(err)
+	}
+
+	result := int64(0)
+	if reset {
+		result = 1
+	}
+
+	return &resp.Value{
+		Type: resp.Integer,
+		Int:  result,
+	}
+}
+
+// parseLockoutTier 解析 threshold:seconds 格式的锁定规则
+func parseLockoutTier(arg []byte) (storage.LockoutTier, *resp.Value) {
+	errReply := &resp.Value{
+		Type: resp.Error,
+		Str:  "ERR 锁定规则格式应为 threshold:seconds（均为正整数）",
+	}
+
+	thresholdPart, secondsPart, found := bytes.Cut(arg, []byte(":"))
+	if !found {
+		return storage.LockoutTier{}, errReply
+	}
+	threshold, err := strconv.ParseInt(string(thresholdPart), 10, 64)
+	if err != nil || threshold <= 0 {
+		return storage.LockoutTier{}, errReply
+	}
+	seconds, err := strconv.ParseInt(string(secondsPart), 10, 64)
+	if err != nil || seconds <= 0 || seconds > maxLockoutSeconds {
+		return storage.LockoutTier{}, errReply
+	}
+
+	return storage.LockoutTier{
+		Threshold: threshold,
+		Duration:  time.Duration(seconds) * time.Second,
+	}, nil
+}
+
+// lockoutReply 构建失败记录状态的响应
+func lockoutReply(status storage.LockoutStatus) *resp.Value {
+	now := time.Now().UnixMilli()
+
+	locked, remaining := int64(0), int64(0)
+	if status.Locked(now) {
+		locked = 1
+		remaining = ceilSeconds(time.Duration(status.LockedUntil-now) * time.Millisecond)
+	}
+
+	return &resp.Value{
+		Type: resp.Array,
+		Array: []resp.Value{
+			{Type: resp.Integer, Int: status.Failures},
+			{Type: resp.Integer, Int: locked},
+			{Type: resp.Integer, Int: remaining},
+		},
+	}
+}
+
+// lockoutError 将失败记录操作的错误转换为错误响应
+func lockoutError(err error) *resp.Value {
+	if errors.Is(err, storage.ErrNotLockout) {
+		return &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR 键保存的不是失败记录",
+		}
+	}
+	return &resp.Value{
+		Type: resp.Error,
+		Str:  "ERR " + err.Error(),
+	}
+}
@@ -0,0 +1,88 @@
+package tcp
+
+import (
+	"testing"
+
+	"github.com/yndnr/tokenginx/internal/storage"
+	"github.com/yndnr/tokenginx/internal/transport/resp"
+)
+
+// lockoutFields 将 LOCKOUT.FAIL / LOCKOUT.STATUS 的响应转换为整数列表
+func lockoutFields(t *testing.T, response *resp.Value) []int64 {
+	t.Helper()
+	if response.Type != resp.Array || len(response.Array) != 3 {
+		t.Fatalf("Expected 3 element array, got %+v", response)
+	}
+	return []int64{response.Array[0].Int, response.Array[1].Int, response.Array[2].Int}
+}
+
+// TestCommandHandler_Lockout 测试 LOCKOUT.FAIL / LOCKOUT.STATUS / LOCKOUT.RESET 命令
+func TestCommandHandler_Lockout(t *testing.T) {
+	handler := NewCommandHandler(storage.NewShardedMap(1024))
+	fail := newCommand("LOCKOUT.FAIL", "lockout:user:alice", "900", "2:60", "4:300")
+
+	tests := []struct {
+		failures  int64
+		locked    int64
+		remaining int64
+	}{
+		{1, 0, 0},
+		{2, 1, 60},
+		{3, 1, 60},
+		{4, 1, 300},
+	}
+	for _, tt := range tests {
+		fields := lockoutFields(t, handler.HandleCommand(fail))
+		if fields[0] != tt.failures || fields[1] != tt.locked || fields[2] != tt.remaining {
+			t.Errorf("Expected [%d %d %d], got %v", tt.failures, tt.locked, tt.remaining, fields)
+		}
+	}
+
+	fields := lockoutFields(t, handler.HandleCommand(newCommand("LOCKOUT.STATUS", "lockout:user:alice")))
+	if fields[0] != 4 || fields[1] != 1 {
+		t.Errorf("Expected locked status with 4 failures, got %v", fields)
+	}
+
+	response := handler.HandleCommand(newCommand("LOCKOUT.RESET", "lockout:user:alice"))
+	if response.Type != resp.Integer || response.Int != 1 {
+		t.Errorf("Expected :1, got %+v", response)
+	}
+	fields = lockoutFields(t, handler.HandleCommand(newCommand("LOCKOUT.STATUS", "lockout:user:alice")))
+	if fields[0] != 0 || fields[1] != 0 || fields[2] != 0 {
+		t.Errorf("Expected [0 0 0] after reset, got %v", fields)
+	}
+	response = handler.HandleCommand(newCommand("LOCKOUT.RESET", "lockout:user:alice"))
+	if response.Int != 0 {
+		t.Errorf("Expected :0 without a record, got %+v", response)
+	}
+}
+
+// TestCommandHandler_LockoutErrors 测试锁定命令的参数和类型错误
+func TestCommandHandler_LockoutErrors(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	handler := NewCommandHandler(sm)
+	sm.Set("session:1", "data", 0)
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"LOCKOUT.FAIL", "k", "0", "3:60"}, "ERR 保留时间必须是正整数（秒）"},
+		{[]string{"LOCKOUT.FAIL", "k", "900", "3"}, "ERR 锁定规则格式应为 threshold:seconds（均为正整数）"},
+		{[]string{"LOCKOUT.FAIL", "k", "900", "3:0"}, "ERR 锁定规则格式应为 threshold:seconds（均为正整数）"},
+		{[]string{"LOCKOUT.FAIL", "k", "900", "x:60"}, "ERR 锁定规则格式应为 threshold:seconds（均为正整数）"},
+		{[]string{"LOCKOUT.FAIL", "k", "900", "5:60", "5:300"}, "ERR 锁定阈值必须严格递增"},
+		{[]string{"LOCKOUT.FAIL", "session:1", "900", "3:60"}, "ERR 键保存的不是失败记录"},
+		{[]string{"LOCKOUT.STATUS", "session:1"}, "ERR 键保存的不是失败记录"},
+		{[]string{"LOCKOUT.RESET", "session:1"}, "ERR 键保存的不是失败记录"},
+	}
+	for _, tt := range tests {
+		response := handler.HandleCommand(newCommand(tt.args...))
+		if response.Type != resp.Error || response.Str != tt.want {
+			t.Errorf("%v: expected %q, got %+v", tt.args, tt.want, response)
+		}
+	}
+	if sm.Exists("k") {
+		t.Error("Expected invalid arguments not to create a record")
+	}
+}
@@ -0,0 +1,231 @@
+package tcp
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/yndnr/tokenginx/internal/storage"
+	"github.com/yndnr/tokenginx/internal/transport/resp"
+	"github.com/yndnr/tokenginx/pkg/module"
+)
+
+// LoadModule 加载扩展模块，将模块的命令注册到命令表
+//
+// 参数说明：
+//   - m: 扩展模块
+//
+// 返回值：
+//   - error: 模块名为空、命令定义无效或与已有命令重名时的错误信息
+//
+// 注意事项：
+//   - 应在服务器启动前调用
+//   - 加载是原子的：任一命令无效时模块的全部命令都不会注册
+//   - 模块命令的分组（COMMAND DOCS 中的 group）为模块名
+func (h *CommandHandler) LoadModule(m module.Module) error {
+	if m == nil || m.Name() == "" {
+		return fmt.Errorf("模块名不能为空")
+	}
+	name := m.Name()
+
+	// 先在临时命令表中校验全部命令，避免注册一半
+	staged := NewCommandTable()
+	for _, mc := range m.Commands() {
+		cmd := &Command{
+			Name:     mc.Name,
+			Arity:    mc.Arity,
+			Flags:    commandFlags(mc.Flags),
+			FirstKey: mc.FirstKey,
+			LastKey:  mc.LastKey,
+			Step:     mc.Step,
+			Group:    name,
+			Since:    mc.Since,
+			Summary:  mc.Summary,
+		}
+		if mc.Handler != nil {
+			cmd.Handler = h.moduleHandler(name, mc.Handler)
+		}
+
+		if err := staged.Register(cmd); err != nil {
+			return fmt.Errorf("加载模块 %s 失败: %w", name, err)
+		}
+		if _, exists := h.commands.Lookup(cmd.Name); exists {
+			return fmt.Errorf("加载模块 %s 失败: 命令 %s 已存在", name, cmd.Name)
+		}
+	}
+
+	h.modulesMu.Lock()
+	defer h.modulesMu.Unlock()
+
+	for _, loaded := range h.modules {
+		if loaded == name {
+			return fmt.Errorf("模块 %s 已加载", name)
+		}
+	}
+
+	for _, cmd := range staged.Commands() {
+		if err := h.commands.Register(cmd); err != nil {
+			return fmt.Errorf("加载模块 %s 失败: %w", name, err)
+		}
+	}
+	h.modules = append(h.modules, name)
+
+	return nil
+}
+
+// Modules 返回已加载的扩展模块名，按加载顺序排列
+func (h *CommandHandler) Modules() []string {
+	h.modulesMu.RLock()
+	defer h.modulesMu.RUnlock()
+
+	return append([]string(nil), h.modules...)
+}
+
+// modulesReply 构建 HELLO 响应中的 modules 字段
+func (h *CommandHandler) modulesReply() resp.Value {
+	modules := h.Modules()
+	result := make([]resp.Value, len(modules))
+	for i, name := range modules {
+		result[i] = resp.Value{
+			Type: resp.Map,
+			Array: []resp.Value{
+				{Type: resp.BulkString, Bulk: []byte("name")},
+				{Type: resp.BulkString, Bulk: []byte(name)},
+			},
+		}
+	}
+	return resp.Value{Type: resp.Array, Array: result}
+}
+
+// moduleHandler 将模块的处理函数适配为命令表的处理函数
+//
+// 模块处理函数中的 panic 会被恢复并记录日志，客户端收到错误响应。
+func (h *CommandHandler) moduleHandler(name string, fn module.HandlerFunc) CommandFunc {
+	return func(c *Client, args [][]byte) (reply *resp.Value) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("[ERROR] 模块 %s 命令执行失败: %v", name, r)
+				reply = &resp.Value{
+					Type: resp.Error,
+					Str:  "ERR 模块命令执行失败",
+				}
+			}
+		}()
+
+		ctx := &module.Context{
+			Client: module.ClientInfo{
+				ID:       c.ID,
+				Addr:     c.Addr,
+				Identity: c.Identity,
+				Name:     c.Name,
+				Protocol: c.Protocol,
+				DB:       c.DB,
+			},
+			Storage: moduleStorage{sm: h.db(c)},
+		}
+
+		value := moduleReply(fn(ctx, module.Args(args)))
+		return &value
+	}
+}
+
+// moduleReply 将模块命令的响应转换为 RESP 响应，nil 转换为 Null
+func moduleReply(v *module.Value) resp.Value {
+	if v == nil {
+		return resp.Value{Type: resp.BulkString, Null: true}
+	}
+
+	switch v.Kind {
+	case module.KindNull:
+		return resp.Value{Type: resp.BulkString, Null: true}
+	case module.KindSimpleString:
+		return resp.Value{Type: resp.SimpleString, Str: v.Str}
+	case module.KindError:
+		return resp.Value{Type: resp.Error, Str: v.Str}
+	case module.KindInteger:
+		return resp.Value{Type: resp.Integer, Int: v.Int}
+	case module.KindBulkString:
+		return resp.Value{Type: resp.BulkString, Bulk: v.Bulk}
+	case module.KindDouble:
+		return resp.Value{Type: resp.Double, Double: v.Double}
+	case module.KindBoolean:
+		return resp.Value{Type: resp.Boolean, Bool: v.Bool}
+	case module.KindArray, module.KindMap:
+		if v.Kind == module.KindMap && len(v.Elems)%2 != 0 {
+			return resp.Value{Type: resp.Error, Str: "ERR Map 需要偶数个元素"}
+		}
+		array := make([]resp.Value, len(v.Elems))
+		for i, elem := range v.Elems {
+			array[i] = moduleReply(elem)
+		}
+		if v.Kind == module.KindMap {
+			return resp.Value{Type: resp.Map, Array: array}
+		}
+		return resp.Value{Type: resp.Array, Array: array}
+	default:
+		return resp.Value{Type: resp.Error, Str: fmt.Sprintf("ERR 未知的模块响应类型 %d", v.Kind)}
+	}
+}
+
+// commandFlags 将模块命令标志转换为命令表标志
+func commandFlags(f module.Flag) CommandFlag {
+	var flags CommandFlag
+	if f&module.Write != 0 {
+		flags |= FlagWrite
+	}
+	if f&module.Readonly != 0 {
+		flags |= FlagReadonly
+	}
+	if f&module.Admin != 0 {
+		flags |= FlagAdmin
+	}
+	if f&module.Fast != 0 {
+		flags |= FlagFast
+	}
+	return flags
+}
+
+// moduleStorage 将 ShardedMap 适配为 module.Storage
+type moduleStorage struct {
+	sm *storage.ShardedMap
+}
+
+func (s moduleStorage) Get(key string) (interface{}, bool) {
+	return s.sm.Get(key)
+}
+
+func (s moduleStorage) Set(key string, value interface{}, ttl int) error {
+	return s.sm.Set(key, value, ttl)
+}
+
+func (s moduleStorage) SetNX(key string, value interface{}, ttl int) bool {
+	return s.sm.SetNX(key, value, ttl)
+}
+
+func (s moduleStorage) Delete(key string) bool {
+	return s.sm.Delete(key)
+}
+
+func (s moduleStorage) Exists(key string) bool {
+	return s.sm.Exists(key)
+}
+
+func (s moduleStorage) TTL(key string) int64 {
+	return storage.TTL(s.sm, key)
+}
+
+func (s moduleStorage) Expire(key string, ttl int) bool {
+	return storage.Expire(s.sm, key, ttl)
+}
+
+func (s moduleStorage) GetWithVersion(key string) (interface{}, uint64, bool) {
+	return s.sm.GetWithVersion(key)
+}
+
+func (s moduleStorage) CompareAndSet(key string, expected uint64, value interface{}, ttl int) (uint64, bool) {
+	var opts storage.SetOptions
+	if ttl > 0 {
+		opts.ExpiresAt = time.Now().UnixMilli() + int64(ttl)*1000
+	}
+	return s.sm.CompareAndSet(key, expected, value, opts)
+}
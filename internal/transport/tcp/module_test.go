@@ -0,0 +1,220 @@
+package tcp
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/yndnr/tokenginx/internal/storage"
+	"github.com/yndnr/tokenginx/internal/transport/resp"
+	"github.com/yndnr/tokenginx/pkg/module"
+)
+
+// testModule 测试用的扩展模块
+type testModule struct {
+	name     string
+	commands []module.Command
+}
+
+func (m testModule) Name() string               { return m.name }
+func (m testModule) Commands() []module.Command { return m.commands }
+
+// tenantModule 返回提供 TENANT.SET / TENANT.GET / TENANT.PANIC 的模块
+func tenantModule() testModule {
+	return testModule{
+		name: "tenant",
+		commands: []module.Command{
+			{
+				Name: "tenant.set", Arity: -4, Flags: module.Write,
+				FirstKey: 2, LastKey: 2, Step: 1,
+				Handler: func(ctx *module.Context, args module.Args) *module.Value {
+					opts, err := args.Options(3, map[string]int{"EX": 1})
+					if err != nil {
+						return module.ErrorFromErr(err)
+					}
+					ttl, _ := opts.Int("EX")
+					key := args.String(0) + ":" + args.String(1)
+					if err := ctx.Storage.Set(key, args.String(2), int(ttl)); err != nil {
+						return module.Error("ERR %v", err)
+					}
+					return module.OK()
+				},
+			},
+			{
+				Name: "tenant.get", Arity: 3, Flags: module.Readonly | module.Fast,
+				FirstKey: 2, LastKey: 2, Step: 1,
+				Handler: func(ctx *module.Context, args module.Args) *module.Value {
+					value, ok := ctx.Storage.Get(args.String(0) + ":" + args.String(1))
+					if !ok {
+						return module.Null()
+					}
+					return module.BulkString(value.(string))
+				},
+			},
+			{
+				Name: "tenant.panic", Arity: 1,
+				Handler: func(ctx *module.Context, args module.Args) *module.Value {
+					panic("boom")
+				},
+			},
+		},
+	}
+}
+
+// TestCommandHandler_LoadModule 测试加载模块并执行模块命令
+func TestCommandHandler_LoadModule(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	handler := NewCommandHandler(sm)
+
+	if err := handler.LoadModule(tenantModule()); err != nil {
+		t.Fatalf("LoadModule failed: %v", err)
+	}
+
+	response := handler.HandleCommand(newCommand("TENANT.SET", "acme", "s1", "alice", "EX", "60"))
+	if response.Type != resp.SimpleString || response.Str != "OK" {
+		t.Fatalf("Expected OK, got %+v", response)
+	}
+	if ttl := storage.TTL(sm, "acme:s1"); ttl <= 0 || ttl > 60 {
+		t.Errorf("Expected TTL in (0, 60], got %d", ttl)
+	}
+
+	response = handler.HandleCommand(newCommand("tenant.get", "acme", "s1"))
+	if string(response.Bulk) != "alice" {
+		t.Errorf("Expected alice, got %+v", response)
+	}
+
+	response = handler.HandleCommand(newCommand("TENANT.SET", "acme", "s1", "alice", "PX", "1"))
+	if response.Type != resp.Error {
+		t.Errorf("Expected syntax error for unknown option, got %+v", response)
+	}
+
+	response = handler.HandleCommand(newCommand("TENANT.GET", "acme"))
+	if response.Type != resp.Error {
+		t.Errorf("Expected arity error, got %+v", response)
+	}
+
+	response = handler.HandleCommand(newCommand("TENANT.PANIC"))
+	if response.Type != resp.Error || response.Str != "ERR 模块命令执行失败" {
+		t.Errorf("Expected recovered panic error, got %+v", response)
+	}
+
+	cmd, exists := handler.Commands().Lookup("tenant.get")
+	if !exists || cmd.Group != "tenant" || cmd.Flags != FlagReadonly|FlagFast {
+		t.Errorf("Unexpected registered command: %+v", cmd)
+	}
+
+	if modules := handler.Modules(); len(modules) != 1 || modules[0] != "tenant" {
+		t.Errorf("Expected [tenant], got %v", modules)
+	}
+}
+
+// TestModuleStorage_CompareAndSet 测试模块存储接口的版本号读写
+func TestModuleStorage_CompareAndSet(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	var store module.Storage = moduleStorage{sm: sm}
+
+	version, ok := store.CompareAndSet("acme:s1", 0, "alice", 60)
+	if !ok {
+		t.Fatal("Expected create to succeed")
+	}
+	if ttl := store.TTL("acme:s1"); ttl <= 0 || ttl > 60 {
+		t.Errorf("Expected TTL in (0, 60], got %d", ttl)
+	}
+
+	value, current, found := store.GetWithVersion("acme:s1")
+	if !found || value != "alice" || current != version {
+		t.Errorf("Unexpected GetWithVersion result: %v, %d, %v", value, current, found)
+	}
+
+	if _, ok := store.CompareAndSet("acme:s1", version+1, "bob", 0); ok {
+		t.Error("Expected mismatched version to fail")
+	}
+	if _, ok := store.CompareAndSet("acme:s1", version, "bob", 0); !ok {
+		t.Error("Expected matching version to succeed")
+	}
+}
+
+// TestCommandHandler_LoadModuleInvalid 测试无效模块不会注册任何命令
+func TestCommandHandler_LoadModuleInvalid(t *testing.T) {
+	handler := NewCommandHandler(storage.NewShardedMap(1024))
+	count := handler.Commands().Len()
+
+	invalid := []testModule{
+		{name: ""},
+		{name: "shadow", commands: []module.Command{
+			{Name: "shadow.ok", Arity: 1, Handler: tenantModule().commands[2].Handler},
+			{Name: "GET", Arity: 2, Handler: tenantModule().commands[1].Handler},
+		}},
+		{name: "broken", commands: []module.Command{
+			{Name: "broken.ok", Arity: 1, Handler: tenantModule().commands[2].Handler},
+			{Name: "broken.nohandler", Arity: 1},
+		}},
+	}
+	for _, m := range invalid {
+		if err := handler.LoadModule(m); err == nil {
+			t.Errorf("LoadModule(%q): expected error, got nil", m.name)
+		}
+	}
+
+	if handler.Commands().Len() != count {
+		t.Errorf("Expected %d commands after failed loads, got %d", count, handler.Commands().Len())
+	}
+
+	if err := handler.LoadModule(tenantModule()); err != nil {
+		t.Fatalf("LoadModule failed: %v", err)
+	}
+	if err := handler.LoadModule(testModule{name: "tenant"}); err == nil {
+		t.Error("Expected error when loading a module twice")
+	}
+}
+
+// TestCommandHandler_HelloModules 测试 HELLO 列出已加载的模块
+func TestCommandHandler_HelloModules(t *testing.T) {
+	handler := NewCommandHandler(storage.NewShardedMap(1024))
+	if err := handler.LoadModule(tenantModule()); err != nil {
+		t.Fatalf("LoadModule failed: %v", err)
+	}
+
+	response := handler.HandleCommand(newCommand("HELLO", "3"))
+	modules := response.Array[len(response.Array)-1]
+	if len(modules.Array) != 1 || string(modules.Array[0].Array[1].Bulk) != "tenant" {
+		t.Errorf("Expected modules [tenant], got %+v", modules)
+	}
+}
+
+// TestModuleReply 测试模块响应到 RESP 响应的转换
+func TestModuleReply(t *testing.T) {
+	tests := []struct {
+		name  string
+		value *module.Value
+		want  string
+	}{
+		{"nil", nil, "$-1\r\n"},
+		{"zero value", &module.Value{}, "$-1\r\n"},
+		{"ok", module.OK(), "+OK\r\n"},
+		{"error", module.Error("ERR bad"), "-ERR bad\r\n"},
+		{"integer", module.Integer(7), ":7\r\n"},
+		{"bulk", module.BulkString("v"), "$1\r\nv\r\n"},
+		{"double", module.Double(1.5), "$3\r\n1.5\r\n"},
+		{"boolean", module.Boolean(true), ":1\r\n"},
+		{"array", module.Array(module.Integer(1), nil), "*2\r\n:1\r\n$-1\r\n"},
+		{"map", module.Map(module.BulkString("k"), module.Integer(1)), "*2\r\n$1\r\nk\r\n:1\r\n"},
+		{"odd map", &module.Value{Kind: module.KindMap, Elems: []*module.Value{module.OK()}}, "-ERR Map 需要偶数个元素\r\n"},
+		{"unknown kind", &module.Value{Kind: 100}, "-ERR 未知的模块响应类型 100\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			writer := resp.NewWriter(&buf)
+			reply := moduleReply(tt.value)
+			if err := writer.WriteValue(&reply); err != nil {
+				t.Fatalf("WriteValue failed: %v", err)
+			}
+			writer.Flush()
+
+			if buf.String() != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package tcp
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yndnr/tokenginx/internal/storage"
+	"github.com/yndnr/tokenginx/internal/transport/resp"
+)
+
+// defaultOTPAttempts OTP.SET 未指定 ATTEMPTS 时的最大验证次数
+const defaultOTPAttempts = 5
+
+// handleOTPSet 处理 OTP.SET 命令
+//
+// 格式：OTP.SET key code seconds [ATTEMPTS n]
+// 返回：+OK
+//
+// 注意事项：
+//   - 只保存加盐哈希，GET 该键不会返回验证码
+//   - 覆盖已有的验证码，重新发送时尝试次数重新计算
+func (h *CommandHandler) handleOTPSet(c *Client, args [][]byte) *resp.Value {
+	ttl, err := strconv.Atoi(string(args[2]))
+	if err != nil {
+		return &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR 参数必须是整数",
+		}
+	}
+	if _, ok := absoluteExpiry("EX", int64(ttl), time.Now().UnixMilli()); !ok {
+		return &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR 验证码有效期必须是正整数（秒）",
+		}
+	}
+
+	attempts := defaultOTPAttempts
+	switch {
+	case len(args) == 5 && strings.ToUpper(string(args[3])) == "ATTEMPTS":
+		attempts, err = strconv.Atoi(string(args[4]))
+		if err != nil || attempts <= 0 {
+			return &resp.Value{
+				Type: resp.Error,
+				Str:  "ERR 尝试次数必须是正整数",
+			}
+		}
+	case len(args) != 3:
+		return syntaxErrorReply()
+	}
+
+	if err := h.db(c).SetOTP(string(args[0]), string(args[1]), ttl, attempts); err != nil {
+		return &resp.Value{
+			Type: resp.Error,
+			Str:  fmt.Sprintf("ERR 保存验证码失败: %v", err),
+		}
+	}
+
+	return &resp.Value{
+		Type: resp.SimpleString,
+		Str:  "OK",
+	}
+}
+
+// handleVerify 处理 VERIFY 命令
+//
+// 格式：VERIFY key candidate
+// 返回：[结果, 剩余尝试次数]，结果为 OK（成功，验证码已消费）、WRONG_CODE（错误）
+// 或 LOCKED（尝试次数已用完，验证码已作废）；验证码不存在或已过期时返回 Null Array
+func (h *CommandHandler) handleVerify(c *Client, args [][]byte) *resp.Value {
+	result, remaining, err := h.db(c).VerifyOTP(string(args[0]), string(args[1]))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotOTP) {
+			return &resp.Value{
+				Type: resp.Error,
+				Str:  "ERR 键保存的不是验证码",
+			}
+		}
+		return &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR " + err.Error(),
+		}
+	}
+
+	var status string
+	switch result {
+	case storage.OTPSuccess:
+		status = "OK"
+	case storage.OTPWrongCode:
+		status = "WRONG_CODE"
+	case storage.OTPLocked:
+		status = "LOCKED"
+	default:
+		return &resp.Value{
+			Type: resp.Array,
+			Null: true,
+		}
+	}
+
+	return &resp.Value{
+		Type: resp.Array,
+		Array: []resp.Value{
+			{Type: resp.SimpleString, Str: status},
+			{Type: resp.Integer, Int: int64(remaining)},
+		},
+	}
+}
@@ -0,0 +1,81 @@
+package tcp
+
+import (
+	"testing"
+
+	"github.com/yndnr/tokenginx/internal/storage"
+	"github.com/yndnr/tokenginx/internal/transport/resp"
+)
+
+// verifyReply 返回 VERIFY 响应中的结果和剩余尝试次数
+func verifyReply(t *testing.T, response *resp.Value) (string, int64) {
+	t.Helper()
+	if response.Type != resp.Array || len(response.Array) != 2 {
+		t.Fatalf("Expected [status, remaining], got %+v", response)
+	}
+	return response.Array[0].Str, response.Array[1].Int
+}
+
+// TestCommandHandler_OTP 测试 OTP.SET 和 VERIFY 命令
+func TestCommandHandler_OTP(t *testing.T) {
+	handler := NewCommandHandler(storage.NewShardedMap(1024))
+
+	response := handler.HandleCommand(newCommand("OTP.SET", "otp:email:alice", "482913", "600", "ATTEMPTS", "2"))
+	if response.Type != resp.SimpleString || response.Str != "OK" {
+		t.Fatalf("Expected +OK, got %+v", response)
+	}
+
+	response = handler.HandleCommand(newCommand("GET", "otp:email:alice"))
+	if response.Type != resp.Error || string(response.Bulk) == "482913" {
+		t.Errorf("Expected GET to return WRONGTYPE without revealing the code, got %+v", response)
+	}
+
+	if status, remaining := verifyReply(t, handler.HandleCommand(newCommand("VERIFY", "otp:email:alice", "000000"))); status != "WRONG_CODE" || remaining != 1 {
+		t.Errorf("Expected WRONG_CODE with 1 attempt left, got %s, %d", status, remaining)
+	}
+	if status, _ := verifyReply(t, handler.HandleCommand(newCommand("VERIFY", "otp:email:alice", "482913"))); status != "OK" {
+		t.Errorf("Expected OK, got %s", status)
+	}
+
+	response = handler.HandleCommand(newCommand("VERIFY", "otp:email:alice", "482913"))
+	if response.Type != resp.Array || !response.Null {
+		t.Errorf("Expected Null Array for a consumed code, got %+v", response)
+	}
+
+	// 默认 5 次尝试
+	handler.HandleCommand(newCommand("OTP.SET", "otp:sms:bob", "1234", "600"))
+	for i := 0; i < 4; i++ {
+		handler.HandleCommand(newCommand("VERIFY", "otp:sms:bob", "0000"))
+	}
+	if status, remaining := verifyReply(t, handler.HandleCommand(newCommand("VERIFY", "otp:sms:bob", "0000"))); status != "LOCKED" || remaining != 0 {
+		t.Errorf("Expected LOCKED after 5 wrong attempts, got %s, %d", status, remaining)
+	}
+	if status, _ := verifyReply(t, handler.HandleCommand(newCommand("VERIFY", "otp:sms:bob", "1234"))); status != "LOCKED" {
+		t.Errorf("Expected correct code to stay LOCKED, got %s", status)
+	}
+}
+
+// TestCommandHandler_OTPErrors 测试 OTP.SET 和 VERIFY 的参数和类型错误
+func TestCommandHandler_OTPErrors(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	handler := NewCommandHandler(sm)
+	sm.Set("session:1", "data", 0)
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"OTP.SET", "k", "1234", "abc"}, "ERR 参数必须是整数"},
+		{[]string{"OTP.SET", "k", "1234", "0"}, "ERR 验证码有效期必须是正整数（秒）"},
+		{[]string{"OTP.SET", "k", "1234", "600", "ATTEMPTS", "0"}, "ERR 尝试次数必须是正整数"},
+		{[]string{"OTP.SET", "k", "1234", "600", "TRIES", "3"}, "ERR 语法错误"},
+		{[]string{"OTP.SET", "k", "1234", "600", "ATTEMPTS"}, "ERR 语法错误"},
+		{[]string{"VERIFY", "session:1", "data"}, "ERR 键保存的不是验证码"},
+	}
+	for _, tt := range tests {
+		response := handler.HandleCommand(newCommand(tt.args...))
+		if response.Type != resp.Error || response.Str != tt.want {
+			t.Errorf("%v: expected %q, got %+v", tt.args, tt.want, response)
+		}
+	}
+}
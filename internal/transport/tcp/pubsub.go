@@ -0,0 +1,486 @@
+package tcp
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/yndnr/tokenginx/internal/glob"
+	"github.com/yndnr/tokenginx/internal/transport/resp"
+)
+
+// defaultPubSubBuffer 订阅连接输出缓冲区可容纳的消息数
+//
+// 订阅者消费过慢、缓冲区写满时断开该连接（与 Redis 的 client-output-buffer-limit pubsub 相同），
+// 避免一个慢订阅者阻塞 PUBLISH 或无限占用内存。
+const defaultPubSubBuffer = 1024
+
+// PubSub 频道订阅表
+//
+// PubSub 记录每个频道和每个模式的订阅者，供 PUBLISH 查找接收方。
+// PUBLISH 只持有读锁，并以非阻塞方式把消息放入订阅者的输出缓冲区，
+// 因此发布开销只与该频道的订阅者数量和模式数量成正比，不会被慢订阅者阻塞。
+//
+// 示例：
+//
+//	ps := NewPubSub()
+//	receivers := ps.Publish("session:revoked", []byte("sess_01HX"))
+type PubSub struct {
+	mu       sync.RWMutex
+	channels map[string]map[*subscriber]struct{} // 频道 -> 订阅者
+	patterns map[string]*patternSubscribers      // 模式 -> 订阅者
+}
+
+// patternSubscribers 订阅同一个模式的订阅者
+type patternSubscribers struct {
+	pattern     *glob.Pattern // 预编译的模式
+	subscribers map[*subscriber]struct{}
+}
+
+// NewPubSub 创建一个空的频道订阅表
+func NewPubSub() *PubSub {
+	return &PubSub{
+		channels: make(map[string]map[*subscriber]struct{}),
+		patterns: make(map[string]*patternSubscribers),
+	}
+}
+
+// Publish 向频道发布消息
+//
+// 参数说明：
+//   - channel: 频道名
+//   - message: 消息内容
+//
+// 返回值：
+//   - int: 收到消息的订阅者数量（按频道订阅和模式订阅分别计数）
+//
+// 注意事项：
+//   - 只把消息放入订阅者的输出缓冲区，不等待写出
+//   - 缓冲区已满的订阅者不计入接收方，其连接会被断开
+func (ps *PubSub) Publish(channel string, message []byte) int {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	receivers := 0
+
+	if subscribers := ps.channels[channel]; len(subscribers) > 0 {
+		msg := &resp.Value{
+			Type: resp.Push,
+			Array: []resp.Value{
+				{Type: resp.BulkString, Bulk: []byte("message")},
+				{Type: resp.BulkString, Bulk: []byte(channel)},
+				{Type: resp.BulkString, Bulk: message},
+			},
+		}
+		for sub := range subscribers {
+			if sub.deliver(msg) {
+				receivers++
+			}
+		}
+	}
+
+	for pattern, p := range ps.patterns {
+		if !p.pattern.Match(channel) {
+			continue
+		}
+		msg := &resp.Value{
+			Type: resp.Push,
+			Array: []resp.Value{
+				{Type: resp.BulkString, Bulk: []byte("pmessage")},
+				{Type: resp.BulkString, Bulk: []byte(pattern)},
+				{Type: resp.BulkString, Bulk: []byte(channel)},
+				{Type: resp.BulkString, Bulk: message},
+			},
+		}
+		for sub := range p.subscribers {
+			if sub.deliver(msg) {
+				receivers++
+			}
+		}
+	}
+
+	return receivers
+}
+
+// subscribe 将订阅者加入频道
+func (ps *PubSub) subscribe(sub *subscriber, channel string) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	subscribers, ok := ps.channels[channel]
+	if !ok {
+		subscribers = make(map[*subscriber]struct{})
+		ps.channels[channel] = subscribers
+	}
+	subscribers[sub] = struct{}{}
+}
+
+// unsubscribe 将订阅者移出频道，频道没有订阅者时删除
+func (ps *PubSub) unsubscribe(sub *subscriber, channel string) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	ps.removeChannel(sub, channel)
+}
+
+// psubscribe 将订阅者加入模式
+func (ps *PubSub) psubscribe(sub *subscriber, pattern string) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	p, ok := ps.patterns[pattern]
+	if !ok {
+		p = &patternSubscribers{
+			pattern:     glob.Compile(pattern),
+			subscribers: make(map[*subscriber]struct{}),
+		}
+		ps.patterns[pattern] = p
+	}
+	p.subscribers[sub] = struct{}{}
+}
+
+// punsubscribe 将订阅者移出模式，模式没有订阅者时删除
+func (ps *PubSub) punsubscribe(sub *subscriber, pattern string) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	ps.removePattern(sub, pattern)
+}
+
+// unsubscribeAll 将订阅者移出它订阅的全部频道和模式（连接关闭时调用）
+func (ps *PubSub) unsubscribeAll(sub *subscriber) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	for channel := range sub.channels {
+		ps.removeChannel(sub, channel)
+	}
+	for pattern := range sub.patterns {
+		ps.removePattern(sub, pattern)
+	}
+}
+
+// removeChannel 将订阅者移出频道（调用方必须持有写锁）
+func (ps *PubSub) removeChannel(sub *subscriber, channel string) {
+	subscribers := ps.channels[channel]
+	delete(subscribers, sub)
+	if len(subscribers) == 0 {
+		delete(ps.channels, channel)
+	}
+}
+
+// removePattern 将订阅者移出模式（调用方必须持有写锁）
+func (ps *PubSub) removePattern(sub *subscriber, pattern string) {
+	p, ok := ps.patterns[pattern]
+	if !ok {
+		return
+	}
+	delete(p.subscribers, sub)
+	if len(p.subscribers) == 0 {
+		delete(ps.patterns, pattern)
+	}
+}
+
+// subscriber 处于推送模式的连接
+//
+// 连接第一次执行 SUBSCRIBE、PSUBSCRIBE 等订阅命令后进入推送模式：
+// 此后该连接的全部输出（命令响应和发布的消息）都放入有界的 out 队列，
+// 由独立的写 Goroutine 按顺序写出，读取命令的 Goroutine 不再直接写连接。
+type subscriber struct {
+	out       chan *resp.Value // 有界输出缓冲区
+	closed    chan struct{}    // 连接关闭、输出缓冲区溢出或服务器关闭时关闭
+	closeOnce sync.Once
+	onClose   func() // 关闭时调用（断开连接），nil 表示无操作
+
+	overflowed atomic.Bool  // 是否因输出缓冲区溢出而关闭
+	protocol   atomic.Int32 // 写出时使用的 RESP 协议版本
+
+	// 订阅的频道和模式，只由所属连接的 Goroutine 访问
+	channels map[string]struct{}
+	patterns map[string]struct{}
+}
+
+// newSubscriber 创建一个订阅者
+func newSubscriber(buffer, protocol int) *subscriber {
+	sub := &subscriber{
+		out:      make(chan *resp.Value, buffer),
+		closed:   make(chan struct{}),
+		channels: make(map[string]struct{}),
+		patterns: make(map[string]struct{}),
+	}
+	sub.protocol.Store(int32(protocol))
+
+	return sub
+}
+
+// deliver 以非阻塞方式投递一条发布的消息
+//
+// 输出缓冲区已满时标记溢出并关闭订阅者，返回 false。
+func (sub *subscriber) deliver(msg *resp.Value) bool {
+	select {
+	case <-sub.closed:
+		return false
+	default:
+	}
+
+	select {
+	case sub.out <- msg:
+		return true
+	default:
+		sub.overflowed.Store(true)
+		sub.close()
+		return false
+	}
+}
+
+// reply 把命令响应放入输出队列
+//
+// 队列已满时等待写 Goroutine 腾出空间，订阅者已关闭时返回 false。
+func (sub *subscriber) reply(value *resp.Value) bool {
+	select {
+	case sub.out <- value:
+		return true
+	case <-sub.closed:
+		return false
+	}
+}
+
+// close 关闭订阅者（可重复调用）
+func (sub *subscriber) close() {
+	sub.closeOnce.Do(func() {
+		close(sub.closed)
+		if sub.onClose != nil {
+			sub.onClose()
+		}
+	})
+}
+
+// isClosed 返回订阅者是否已关闭
+func (sub *subscriber) isClosed() bool {
+	select {
+	case <-sub.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// count 返回订阅的频道和模式总数
+func (sub *subscriber) count() int {
+	return len(sub.channels) + len(sub.patterns)
+}
+
+// subscribeCommand 判断命令是否会改变连接的订阅状态
+func subscribeCommand(name string) bool {
+	switch name {
+	case "subscribe", "unsubscribe", "psubscribe", "punsubscribe":
+		return true
+	}
+	return false
+}
+
+// subscriber 返回连接的订阅者，第一次调用时创建订阅者并进入推送模式
+func (h *CommandHandler) subscriber(c *Client) *subscriber {
+	if c.sub == nil {
+		c.sub = newSubscriber(defaultPubSubBuffer, c.Protocol)
+		if c.enterPushMode != nil {
+			c.enterPushMode(c.sub)
+		}
+	}
+	return c.sub
+}
+
+// handleSubscribe 处理 SUBSCRIBE 命令
+//
+// 格式：SUBSCRIBE channel [channel ...]
+// 返回：每个频道推送一条 ["subscribe", channel, 订阅总数]
+//
+// 注意事项：
+//   - 连接进入推送模式，此后收到 ["message", channel, message]
+//   - RESP2 连接在仍有订阅时只能执行 (P)SUBSCRIBE、(P)UNSUBSCRIBE 和 PING
+func (h *CommandHandler) handleSubscribe(c *Client, args [][]byte) *resp.Value {
+	sub := h.subscriber(c)
+
+	for _, arg := range args {
+		channel := string(arg)
+		_, subscribed := sub.channels[channel]
+		sub.channels[channel] = struct{}{}
+
+		// 先放入确认再加入订阅表，保证确认先于该频道的第一条消息写出
+		if !sub.reply(subscriptionReply("subscribe", arg, sub.count())) {
+			return nil
+		}
+		if !subscribed {
+			h.pubsub.subscribe(sub, channel)
+		}
+	}
+
+	return nil
+}
+
+// handleUnsubscribe 处理 UNSUBSCRIBE 命令
+//
+// 格式：UNSUBSCRIBE [channel ...]
+// 返回：每个频道推送一条 ["unsubscribe", channel, 剩余订阅总数]；
+// 不指定频道时退订全部频道，没有订阅任何频道时推送 ["unsubscribe", nil, 订阅总数]
+func (h *CommandHandler) handleUnsubscribe(c *Client, args [][]byte) *resp.Value {
+	if c.sub == nil {
+		return unsubscribeReplies(c, "unsubscribe", args)
+	}
+	sub := c.sub
+
+	if len(args) == 0 {
+		args = sortedNames(sub.channels)
+		if len(args) == 0 {
+			sub.reply(subscriptionReply("unsubscribe", nil, sub.count()))
+			return nil
+		}
+	}
+
+	for _, arg := range args {
+		channel := string(arg)
+		if _, ok := sub.channels[channel]; ok {
+			h.pubsub.unsubscribe(sub, channel)
+			delete(sub.channels, channel)
+		}
+		if !sub.reply(subscriptionReply("unsubscribe", arg, sub.count())) {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// handlePSubscribe 处理 PSUBSCRIBE 命令
+//
+// 格式：PSUBSCRIBE pattern [pattern ...]
+// 返回：每个模式推送一条 ["psubscribe", pattern, 订阅总数]
+//
+// 注意事项：
+//   - 模式使用与 KEYS 相同的 glob 语法，如 "session:revoked:*"
+//   - 匹配的消息以 ["pmessage", pattern, channel, message] 推送
+func (h *CommandHandler) handlePSubscribe(c *Client, args [][]byte) *resp.Value {
+	sub := h.subscriber(c)
+
+	for _, arg := range args {
+		pattern := string(arg)
+		_, subscribed := sub.patterns[pattern]
+		sub.patterns[pattern] = struct{}{}
+
+		if !sub.reply(subscriptionReply("psubscribe", arg, sub.count())) {
+			return nil
+		}
+		if !subscribed {
+			h.pubsub.psubscribe(sub, pattern)
+		}
+	}
+
+	return nil
+}
+
+// handlePUnsubscribe 处理 PUNSUBSCRIBE 命令
+//
+// 格式：PUNSUBSCRIBE [pattern ...]
+// 返回：与 UNSUBSCRIBE 相同，消息类型为 "punsubscribe"
+func (h *CommandHandler) handlePUnsubscribe(c *Client, args [][]byte) *resp.Value {
+	if c.sub == nil {
+		return unsubscribeReplies(c, "punsubscribe", args)
+	}
+	sub := c.sub
+
+	if len(args) == 0 {
+		args = sortedNames(sub.patterns)
+		if len(args) == 0 {
+			sub.reply(subscriptionReply("punsubscribe", nil, sub.count()))
+			return nil
+		}
+	}
+
+	for _, arg := range args {
+		pattern := string(arg)
+		if _, ok := sub.patterns[pattern]; ok {
+			h.pubsub.punsubscribe(sub, pattern)
+			delete(sub.patterns, pattern)
+		}
+		if !sub.reply(subscriptionReply("punsubscribe", arg, sub.count())) {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// handlePublish 处理 PUBLISH 命令
+//
+// 格式：PUBLISH channel message
+// 返回：收到消息的订阅者数量
+func (h *CommandHandler) handlePublish(c *Client, args [][]byte) *resp.Value {
+	return &resp.Value{
+		Type: resp.Integer,
+		Int:  int64(h.pubsub.Publish(string(args[0]), args[1])),
+	}
+}
+
+// unsubscribeReplies 回复从未订阅过的连接发送的 (P)UNSUBSCRIBE
+//
+// 与 Redis 相同，每个名称回复一条 [kind, name, 0]，不指定名称时回复 [kind, nil, 0]。
+// 不创建订阅者，连接保持普通响应模式，空闲超时等也不受影响；
+// 第一条作为命令的响应返回，其余放入 c.extraReplies。
+func unsubscribeReplies(c *Client, kind string, args [][]byte) *resp.Value {
+	if len(args) == 0 {
+		return subscriptionReply(kind, nil, 0)
+	}
+
+	for _, arg := range args[1:] {
+		c.extraReplies = append(c.extraReplies, subscriptionReply(kind, arg, 0))
+	}
+	return subscriptionReply(kind, args[0], 0)
+}
+
+// subscriptionReply 构建订阅状态变化的推送：[kind, name, count]，name 为 nil 时为 Null
+func subscriptionReply(kind string, name []byte, count int) *resp.Value {
+	nameValue := resp.Value{Type: resp.BulkString, Bulk: name}
+	if name == nil {
+		nameValue.Null = true
+	}
+
+	return &resp.Value{
+		Type: resp.Push,
+		Array: []resp.Value{
+			{Type: resp.BulkString, Bulk: []byte(kind)},
+			nameValue,
+			{Type: resp.Integer, Int: int64(count)},
+		},
+	}
+}
+
+// sortedNames 按字典序返回集合中的名称
+func sortedNames(set map[string]struct{}) [][]byte {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([][]byte, len(names))
+	for i, name := range names {
+		result[i] = []byte(name)
+	}
+	return result
+}
+
+// subscribedPing 构建 RESP2 订阅模式下的 PING 响应：["pong", message]
+func subscribedPing(args [][]byte) *resp.Value {
+	message := []byte{}
+	if len(args) > 0 {
+		message = args[0]
+	}
+
+	return &resp.Value{
+		Type: resp.Array,
+		Array: []resp.Value{
+			{Type: resp.BulkString, Bulk: []byte("pong")},
+			{Type: resp.BulkString, Bulk: message},
+		},
+	}
+}
@@ -0,0 +1,244 @@
+package tcp
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/yndnr/tokenginx/internal/storage"
+	"github.com/yndnr/tokenginx/internal/transport/resp"
+)
+
+// pushStrings 将推送消息转换为字符串列表（Null 转换为 "<nil>"，整数转换为十进制）
+func pushStrings(value *resp.Value) []string {
+	result := make([]string, len(value.Array))
+	for i, v := range value.Array {
+		switch {
+		case v.Type == resp.Integer:
+			result[i] = fmt.Sprintf("%d", v.Int)
+		case v.Null:
+			result[i] = "<nil>"
+		default:
+			result[i] = string(v.Bulk)
+		}
+	}
+	return result
+}
+
+// expectPush 从订阅者的输出队列中取出一条消息并与期望值比较
+func expectPush(t *testing.T, sub *subscriber, expected ...string) {
+	t.Helper()
+
+	select {
+	case value := <-sub.out:
+		if got := fmt.Sprint(pushStrings(value)); got != fmt.Sprint(expected) {
+			t.Errorf("Expected push %v, got %v", expected, got)
+		}
+	default:
+		t.Errorf("Expected push %v, got nothing", expected)
+	}
+}
+
+// TestCommandHandler_SubscribePublish 测试 SUBSCRIBE 和 PUBLISH
+func TestCommandHandler_SubscribePublish(t *testing.T) {
+	handler := NewCommandHandler(storage.NewShardedMap(1024))
+	subscriber := newClient(1, "")
+	publisher := newClient(2, "")
+
+	if response := handler.HandleClientCommand(subscriber, newCommand("SUBSCRIBE", "session:revoked", "user:logout")); response != nil {
+		t.Fatalf("Expected SUBSCRIBE replies to be pushed, got %+v", response)
+	}
+	expectPush(t, subscriber.sub, "subscribe", "session:revoked", "1")
+	expectPush(t, subscriber.sub, "subscribe", "user:logout", "2")
+
+	response := handler.HandleClientCommand(publisher, newCommand("PUBLISH", "session:revoked", "sess_01HX"))
+	if response.Type != resp.Integer || response.Int != 1 {
+		t.Errorf("Expected 1 receiver, got %+v", response)
+	}
+	expectPush(t, subscriber.sub, "message", "session:revoked", "sess_01HX")
+
+	response = handler.HandleClientCommand(publisher, newCommand("PUBLISH", "nobody", "x"))
+	if response.Int != 0 {
+		t.Errorf("Expected 0 receivers, got %d", response.Int)
+	}
+
+	// 退订全部频道后不再收到消息
+	handler.HandleClientCommand(subscriber, newCommand("UNSUBSCRIBE"))
+	expectPush(t, subscriber.sub, "unsubscribe", "session:revoked", "1")
+	expectPush(t, subscriber.sub, "unsubscribe", "user:logout", "0")
+
+	response = handler.HandleClientCommand(publisher, newCommand("PUBLISH", "session:revoked", "sess_02HX"))
+	if response.Int != 0 {
+		t.Errorf("Expected 0 receivers after UNSUBSCRIBE, got %d", response.Int)
+	}
+
+	handler.HandleClientCommand(subscriber, newCommand("UNSUBSCRIBE"))
+	expectPush(t, subscriber.sub, "unsubscribe", "<nil>", "0")
+}
+
+// TestCommandHandler_PSubscribe 测试按模式订阅
+func TestCommandHandler_PSubscribe(t *testing.T) {
+	handler := NewCommandHandler(storage.NewShardedMap(1024))
+	c := newClient(1, "")
+
+	handler.HandleClientCommand(c, newCommand("PSUBSCRIBE", "session:revoked:*"))
+	expectPush(t, c.sub, "psubscribe", "session:revoked:*", "1")
+	handler.HandleClientCommand(c, newCommand("SUBSCRIBE", "session:revoked:tenant-a"))
+	expectPush(t, c.sub, "subscribe", "session:revoked:tenant-a", "2")
+
+	// 频道订阅和模式订阅各收到一次
+	response := handler.HandleCommand(newCommand("PUBLISH", "session:revoked:tenant-a", "sess_01HX"))
+	if response.Int != 2 {
+		t.Errorf("Expected 2 receivers, got %d", response.Int)
+	}
+	expectPush(t, c.sub, "message", "session:revoked:tenant-a", "sess_01HX")
+	expectPush(t, c.sub, "pmessage", "session:revoked:*", "session:revoked:tenant-a", "sess_01HX")
+
+	handler.HandleClientCommand(c, newCommand("PUNSUBSCRIBE", "session:revoked:*", "unknown:*"))
+	expectPush(t, c.sub, "punsubscribe", "session:revoked:*", "1")
+	expectPush(t, c.sub, "punsubscribe", "unknown:*", "1")
+
+	response = handler.HandleCommand(newCommand("PUBLISH", "session:revoked:tenant-b", "sess_02HX"))
+	if response.Int != 0 {
+		t.Errorf("Expected 0 receivers after PUNSUBSCRIBE, got %d", response.Int)
+	}
+}
+
+// TestCommandHandler_SubscribedModeRESP2 测试 RESP2 订阅模式下的命令限制
+func TestCommandHandler_SubscribedModeRESP2(t *testing.T) {
+	handler := NewCommandHandler(storage.NewShardedMap(1024))
+	c := newClient(1, "")
+
+	handler.HandleClientCommand(c, newCommand("SUBSCRIBE", "session:revoked"))
+	expectPush(t, c.sub, "subscribe", "session:revoked", "1")
+
+	response := handler.HandleClientCommand(c, newCommand("GET", "session:1"))
+	if response.Type != resp.Error {
+		t.Errorf("Expected GET to be rejected in subscribed mode, got %+v", response)
+	}
+
+	response = handler.HandleClientCommand(c, newCommand("PING"))
+	if got := pushStrings(response); response.Type != resp.Array || fmt.Sprint(got) != "[pong ]" {
+		t.Errorf("Expected [pong ], got %+v", response)
+	}
+
+	// RESP3 连接可以在订阅期间执行普通命令
+	c.Protocol = resp.RESP3
+	response = handler.HandleClientCommand(c, newCommand("GET", "session:1"))
+	if response.Type == resp.Error {
+		t.Errorf("Expected GET to be allowed over RESP3, got %s", response.Str)
+	}
+
+	// 退订后恢复普通模式
+	c.Protocol = resp.RESP2
+	handler.HandleClientCommand(c, newCommand("UNSUBSCRIBE", "session:revoked"))
+	expectPush(t, c.sub, "unsubscribe", "session:revoked", "0")
+	response = handler.HandleClientCommand(c, newCommand("PING"))
+	if response.Type != resp.SimpleString || response.Str != "PONG" {
+		t.Errorf("Expected PONG after UNSUBSCRIBE, got %+v", response)
+	}
+}
+
+// TestCommandHandler_UnsubscribeWithoutSubscription 测试未订阅的连接执行 (P)UNSUBSCRIBE 时保持普通响应模式
+func TestCommandHandler_UnsubscribeWithoutSubscription(t *testing.T) {
+	handler := NewCommandHandler(storage.NewShardedMap(1024))
+	c := newClient(1, "")
+
+	response := handler.HandleClientCommand(c, newCommand("UNSUBSCRIBE"))
+	if response == nil {
+		t.Fatal("Expected UNSUBSCRIBE reply to be returned directly")
+	}
+	if got := fmt.Sprint(pushStrings(response)); got != "[unsubscribe <nil> 0]" {
+		t.Errorf("Unexpected UNSUBSCRIBE reply %v", got)
+	}
+
+	response = handler.HandleClientCommand(c, newCommand("PUNSUBSCRIBE", "a:*", "b:*"))
+	if got := fmt.Sprint(pushStrings(response)); got != "[punsubscribe a:* 0]" {
+		t.Errorf("Unexpected PUNSUBSCRIBE reply %v", got)
+	}
+	if len(c.extraReplies) != 1 || fmt.Sprint(pushStrings(c.extraReplies[0])) != "[punsubscribe b:* 0]" {
+		t.Errorf("Expected one extra reply for b:*, got %+v", c.extraReplies)
+	}
+
+	if c.sub != nil {
+		t.Fatal("Expected connection not to enter push mode")
+	}
+	response = handler.HandleClientCommand(c, newCommand("SET", "k", "v"))
+	if response == nil || response.Str != "OK" {
+		t.Errorf("Expected OK in normal reply mode, got %+v", response)
+	}
+}
+
+// TestCommandHandler_SubscribeInMulti 测试 MULTI 中不能订阅
+func TestCommandHandler_SubscribeInMulti(t *testing.T) {
+	handler := NewCommandHandler(storage.NewShardedMap(1024))
+	c := newClient(1, "")
+
+	handler.HandleClientCommand(c, newCommand("MULTI"))
+	response := handler.HandleClientCommand(c, newCommand("SUBSCRIBE", "session:revoked"))
+	if response == nil || response.Type != resp.Error {
+		t.Fatalf("Expected SUBSCRIBE to be rejected in MULTI, got %+v", response)
+	}
+	if c.sub != nil {
+		t.Error("Expected connection not to enter push mode")
+	}
+
+	response = handler.HandleClientCommand(c, newCommand("EXEC"))
+	if response.Type != resp.Error {
+		t.Errorf("Expected EXEC to abort, got %+v", response)
+	}
+}
+
+// TestPubSub_SlowSubscriber 测试输出缓冲区已满的订阅者被断开
+func TestPubSub_SlowSubscriber(t *testing.T) {
+	ps := NewPubSub()
+	slow := newSubscriber(1, resp.RESP2)
+	closed := false
+	slow.onClose = func() { closed = true }
+	fast := newSubscriber(defaultPubSubBuffer, resp.RESP2)
+
+	ps.subscribe(slow, "session:revoked")
+	ps.subscribe(fast, "session:revoked")
+
+	if receivers := ps.Publish("session:revoked", []byte("sess_01HX")); receivers != 2 {
+		t.Errorf("Expected 2 receivers, got %d", receivers)
+	}
+	if receivers := ps.Publish("session:revoked", []byte("sess_02HX")); receivers != 1 {
+		t.Errorf("Expected slow subscriber to be skipped, got %d receivers", receivers)
+	}
+	if !slow.overflowed.Load() || !slow.isClosed() || !closed {
+		t.Error("Expected slow subscriber to be closed on overflow")
+	}
+	if len(fast.out) != 2 {
+		t.Errorf("Expected fast subscriber to receive both messages, got %d", len(fast.out))
+	}
+}
+
+// TestPubSub_ManySubscribers 测试大量订阅者的发布和清理
+func TestPubSub_ManySubscribers(t *testing.T) {
+	ps := NewPubSub()
+	subscribers := make([]*subscriber, 5000)
+	for i := range subscribers {
+		sub := newSubscriber(4, resp.RESP2)
+		sub.channels["session:revoked"] = struct{}{}
+		sub.patterns[fmt.Sprintf("tenant:%d:*", i%10)] = struct{}{}
+		ps.subscribe(sub, "session:revoked")
+		for pattern := range sub.patterns {
+			ps.psubscribe(sub, pattern)
+		}
+		subscribers[i] = sub
+	}
+
+	if receivers := ps.Publish("session:revoked", []byte("sess_01HX")); receivers != 5000 {
+		t.Errorf("Expected 5000 receivers, got %d", receivers)
+	}
+	if receivers := ps.Publish("tenant:3:revoked", []byte("sess_02HX")); receivers != 500 {
+		t.Errorf("Expected 500 pattern receivers, got %d", receivers)
+	}
+
+	for _, sub := range subscribers {
+		ps.unsubscribeAll(sub)
+	}
+	if len(ps.channels) != 0 || len(ps.patterns) != 0 {
+		t.Errorf("Expected registry to be empty, got %d channels and %d patterns", len(ps.channels), len(ps.patterns))
+	}
+}
@@ -0,0 +1,202 @@
+package tcp
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yndnr/tokenginx/internal/storage"
+	"github.com/yndnr/tokenginx/internal/transport/resp"
+)
+
+const (
+	// maxRateLimitCount 限流次数类参数（突发数、请求数、额度）的上限
+	maxRateLimitCount = math.MaxInt32
+
+	// maxRateLimitPeriod 限流周期和窗口的上限（秒），约 100 年
+	maxRateLimitPeriod = 100 * 365 * 24 * 3600
+
+	// maxSlidingLogLimit 滑动窗口日志的 limit 上限
+	//
+	// 日志记录窗口内每个通过的请求，limit 更大时应使用 COUNTER 算法。
+	maxSlidingLogLimit = 10000
+)
+
+// handleRLThrottle 处理 RL.THROTTLE 命令（GCRA）
+//
+// 格式：RL.THROTTLE key max_burst count_per_period period [quantity]
+// 返回：[是否被限流(0/1), 上限, 剩余, 重试等待秒数, 完全恢复秒数]，与 redis-cell 的 CL.THROTTLE 相同
+//
+// 注意事项：
+//   - 每 period 秒补充 count_per_period 个额度，允许的突发请求数为 max_burst + 1
+//   - quantity 默认为 1，为 0 时只查询不消耗额度
+func (h *CommandHandler) handleRLThrottle(c *Client, args [][]byte) *resp.Value {
+	if len(args) > 5 {
+		return syntaxErrorReply()
+	}
+
+	burst, errReply := parseRateLimitArg(args[1], 0, maxRateLimitCount)
+	if errReply != nil {
+		return errReply
+	}
+	count, errReply := parseRateLimitArg(args[2], 1, maxRateLimitCount)
+	if errReply != nil {
+		return errReply
+	}
+	period, errReply := parseRateLimitArg(args[3], 1, maxRateLimitPeriod)
+	if errReply != nil {
+		return errReply
+	}
+	quantity, errReply := parseRateLimitQuantity(args[4:])
+	if errReply != nil {
+		return errReply
+	}
+
+	// 容差和额度以微秒计算，不能溢出
+	periodMicros := period * int64(time.Second/time.Microsecond)
+	if max(burst+1, quantity) > math.MaxInt64/4/periodMicros {
+		return &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR 限流参数超出范围",
+		}
+	}
+
+	limit := storage.GCRALimit{
+		Burst:  burst,
+		Count:  count,
+		Period: time.Duration(period) * time.Second,
+	}
+	result, err := h.db(c).ThrottleGCRA(string(args[0]), limit, quantity)
+	if err != nil {
+		return rateLimitError(err)
+	}
+
+	return rateLimitReply(result)
+}
+
+// handleRLWindow 处理 RL.WINDOW 命令（滑动窗口）
+//
+// 格式：RL.WINDOW key LOG|COUNTER limit window [quantity]
+// 返回：与 RL.THROTTLE 相同
+//
+// 注意事项：
+//   - 任意 window 秒内最多允许 limit 个请求
+//   - LOG 记录每个通过的请求，结果精确，limit 不能超过 10000
+//   - COUNTER 按上一个固定窗口的重叠比例估算，内存占用固定
+func (h *CommandHandler) handleRLWindow(c *Client, args [][]byte) *resp.Value {
+	if len(args) > 5 {
+		return syntaxErrorReply()
+	}
+
+	algorithm := strings.ToUpper(string(args[1]))
+	maxLimit := int64(maxRateLimitCount)
+	switch algorithm {
+	case "LOG":
+		maxLimit = maxSlidingLogLimit
+	case "COUNTER":
+	default:
+		return syntaxErrorReply()
+	}
+
+	limit, errReply := parseRateLimitArg(args[2], 1, maxLimit)
+	if errReply != nil {
+		return errReply
+	}
+	window, errReply := parseRateLimitArg(args[3], 1, maxRateLimitPeriod)
+	if errReply != nil {
+		return errReply
+	}
+	quantity, errReply := parseRateLimitQuantity(args[4:])
+	if errReply != nil {
+		return errReply
+	}
+
+	key := string(args[0])
+	windowDuration := time.Duration(window) * time.Second
+
+	var (
+		result storage.RateLimitResult
+		err    error
+	)
+	if algorithm == "LOG" {
+		result, err = h.db(c).ThrottleSlidingLog(key, limit, windowDuration, quantity)
+	} else {
+		result, err = h.db(c).ThrottleSlidingCounter(key, limit, windowDuration, quantity)
+	}
+	if err != nil {
+		return rateLimitError(err)
+	}
+
+	return rateLimitReply(result)
+}
+
+// parseRateLimitArg 解析限流参数，必须是 [minValue, maxValue] 范围内的整数
+func parseRateLimitArg(arg []byte, minValue, maxValue int64) (int64, *resp.Value) {
+	n, err := strconv.ParseInt(string(arg), 10, 64)
+	if err != nil {
+		return 0, &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR 参数必须是整数",
+		}
+	}
+	if n < minValue || n > maxValue {
+		return 0, &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR 限流参数超出范围",
+		}
+	}
+	return n, nil
+}
+
+// parseRateLimitQuantity 解析可选的 quantity 参数，默认为 1
+func parseRateLimitQuantity(args [][]byte) (int64, *resp.Value) {
+	if len(args) == 0 {
+		return 1, nil
+	}
+	return parseRateLimitArg(args[0], 0, maxRateLimitCount)
+}
+
+// rateLimitReply 构建限流判定结果的响应
+//
+// 时间向上取整到秒；允许时重试等待为 -1，与 redis-cell 相同。
+func rateLimitReply(result storage.RateLimitResult) *resp.Value {
+	limited := int64(1)
+	retryAfter := int64(-1)
+	if result.Allowed {
+		limited = 0
+	} else if result.RetryAfter >= 0 {
+		retryAfter = ceilSeconds(result.RetryAfter)
+	}
+
+	return &resp.Value{
+		Type: resp.Array,
+		Array: []resp.Value{
+			{Type: resp.Integer, Int: limited},
+			{Type: resp.Integer, Int: result.Limit},
+			{Type: resp.Integer, Int: result.Remaining},
+			{Type: resp.Integer, Int: retryAfter},
+			{Type: resp.Integer, Int: ceilSeconds(result.ResetAfter)},
+		},
+	}
+}
+
+// ceilSeconds 将时长向上取整到秒
+func ceilSeconds(d time.Duration) int64 {
+	return int64((d + time.Second - 1) / time.Second)
+}
+
+// rateLimitError 将限流错误转换为错误响应
+func rateLimitError(err error) *resp.Value {
+	if errors.Is(err, storage.ErrNotRateLimit) {
+		return &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR 键保存的不是该算法的限流状态",
+		}
+	}
+	return &resp.Value{
+		Type: resp.Error,
+		Str:  "ERR " + err.Error(),
+	}
+}
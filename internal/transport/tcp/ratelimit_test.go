@@ -0,0 +1,103 @@
+package tcp
+
+import (
+	"testing"
+
+	"github.com/yndnr/tokenginx/internal/storage"
+	"github.com/yndnr/tokenginx/internal/transport/resp"
+)
+
+// rateLimitFields 将 RL.THROTTLE / RL.WINDOW 的响应转换为整数列表
+func rateLimitFields(t *testing.T, response *resp.Value) []int64 {
+	t.Helper()
+	if response.Type != resp.Array || len(response.Array) != 5 {
+		t.Fatalf("Expected 5 element array, got %+v", response)
+	}
+	fields := make([]int64, len(response.Array))
+	for i, v := range response.Array {
+		fields[i] = v.Int
+	}
+	return fields
+}
+
+// TestCommandHandler_RLThrottle 测试 RL.THROTTLE 命令
+func TestCommandHandler_RLThrottle(t *testing.T) {
+	handler := NewCommandHandler(storage.NewShardedMap(1024))
+
+	// 每 60 秒 1 次，突发 3 次
+	for i, remaining := range []int64{2, 1, 0} {
+		fields := rateLimitFields(t, handler.HandleCommand(newCommand("RL.THROTTLE", "rl:login:alice", "2", "1", "60")))
+		if fields[0] != 0 || fields[1] != 3 || fields[2] != remaining || fields[3] != -1 {
+			t.Errorf("Request %d: unexpected reply %v", i+1, fields)
+		}
+	}
+
+	fields := rateLimitFields(t, handler.HandleCommand(newCommand("RL.THROTTLE", "rl:login:alice", "2", "1", "60")))
+	if fields[0] != 1 || fields[2] != 0 {
+		t.Errorf("Expected request to be limited, got %v", fields)
+	}
+	if fields[3] != 60 || fields[4] != 180 {
+		t.Errorf("Expected retry after 60s and reset after 180s, got %v", fields)
+	}
+
+	// quantity 为 0 只查询
+	fields = rateLimitFields(t, handler.HandleCommand(newCommand("RL.THROTTLE", "rl:login:bob", "2", "1", "60", "0")))
+	if fields[0] != 0 || fields[2] != 3 || fields[4] != 0 {
+		t.Errorf("Expected untouched limit, got %v", fields)
+	}
+}
+
+// TestCommandHandler_RLWindow 测试 RL.WINDOW 命令
+func TestCommandHandler_RLWindow(t *testing.T) {
+	handler := NewCommandHandler(storage.NewShardedMap(1024))
+
+	for _, algorithm := range []string{"LOG", "counter"} {
+		key := "rl:ip:" + algorithm
+		for i, remaining := range []int64{1, 0} {
+			fields := rateLimitFields(t, handler.HandleCommand(newCommand("RL.WINDOW", key, algorithm, "2", "86400")))
+			if fields[0] != 0 || fields[1] != 2 || fields[2] != remaining || fields[3] != -1 {
+				t.Errorf("%s request %d: unexpected reply %v", algorithm, i+1, fields)
+			}
+		}
+
+		fields := rateLimitFields(t, handler.HandleCommand(newCommand("RL.WINDOW", key, algorithm, "2", "86400")))
+		if fields[0] != 1 || fields[3] <= 0 || fields[4] <= 0 {
+			t.Errorf("%s: expected request to be limited, got %v", algorithm, fields)
+		}
+
+		fields = rateLimitFields(t, handler.HandleCommand(newCommand("RL.WINDOW", key, algorithm, "2", "86400", "3")))
+		if fields[0] != 1 || fields[3] != -1 {
+			t.Errorf("%s: expected quantity above the limit to never be allowed, got %v", algorithm, fields)
+		}
+	}
+}
+
+// TestCommandHandler_RateLimitErrors 测试限流命令的参数和类型错误
+func TestCommandHandler_RateLimitErrors(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	handler := NewCommandHandler(sm)
+	sm.Set("session:1", "data", 0)
+	handler.HandleCommand(newCommand("RL.THROTTLE", "rl:gcra", "1", "1", "1"))
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"RL.THROTTLE", "k", "abc", "1", "60"}, "ERR 参数必须是整数"},
+		{[]string{"RL.THROTTLE", "k", "-1", "1", "60"}, "ERR 限流参数超出范围"},
+		{[]string{"RL.THROTTLE", "k", "1", "0", "60"}, "ERR 限流参数超出范围"},
+		{[]string{"RL.THROTTLE", "k", "1", "1", "0"}, "ERR 限流参数超出范围"},
+		{[]string{"RL.THROTTLE", "k", "2147483647", "1", "3153600000"}, "ERR 限流参数超出范围"},
+		{[]string{"RL.THROTTLE", "k", "1", "1", "60", "1", "2"}, "ERR 语法错误"},
+		{[]string{"RL.WINDOW", "k", "FIXED", "1", "60"}, "ERR 语法错误"},
+		{[]string{"RL.WINDOW", "k", "LOG", "10001", "60"}, "ERR 限流参数超出范围"},
+		{[]string{"RL.THROTTLE", "session:1", "1", "1", "60"}, "ERR 键保存的不是该算法的限流状态"},
+		{[]string{"RL.WINDOW", "rl:gcra", "LOG", "1", "60"}, "ERR 键保存的不是该算法的限流状态"},
+	}
+	for _, tt := range tests {
+		response := handler.HandleCommand(newCommand(tt.args...))
+		if response.Type != resp.Error || response.Str != tt.want {
+			t.Errorf("%v: expected %q, got %+v", tt.args, tt.want, response)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package tcp
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/yndnr/tokenginx/internal/glob"
+	"github.com/yndnr/tokenginx/internal/storage"
+	"github.com/yndnr/tokenginx/internal/transport/resp"
+)
+
+// handleScan 处理 SCAN 命令
+//
+// 格式：SCAN cursor [MATCH pattern] [COUNT count] [TYPE type]
+// 返回：[下一次迭代的游标, [键 ...]]，游标为 "0" 表示遍历结束
+//
+// 注意事项：
+//   - COUNT 是每次迭代的工作量提示，MATCH 和 TYPE 在取出键之后过滤，因此可能返回空列表
+//   - 启用键哈希时返回的是哈希后的键（保留原始前缀）
+func (h *CommandHandler) handleScan(c *Client, args [][]byte) *resp.Value {
+	cursor, err := strconv.ParseUint(string(args[0]), 10, 64)
+	if err != nil {
+		return &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR 无效的游标",
+		}
+	}
+
+	count := storage.DefaultScanCount
+	var pattern *glob.Pattern
+	typeName := ""
+
+	for i := 1; i < len(args); i++ {
+		option := strings.ToUpper(string(args[i]))
+		if i+1 >= len(args) {
+			return syntaxErrorReply()
+		}
+		i++
+
+		switch option {
+		case "MATCH":
+			pattern = glob.Compile(string(args[i]))
+
+		case "COUNT":
+			n, err := strconv.Atoi(string(args[i]))
+			if err != nil {
+				return &resp.Value{
+					Type: resp.Error,
+					Str:  "ERR 参数必须是整数",
+				}
+			}
+			if n < 1 {
+				return syntaxErrorReply()
+			}
+			count = n
+
+		case "TYPE":
+			typeName = strings.ToLower(string(args[i]))
+
+		default:
+			return syntaxErrorReply()
+		}
+	}
+
+	entries, next := h.db(c).Scan(cursor, count)
+
+	keys := make([]resp.Value, 0, len(entries))
+	for _, entry := range entries {
+		if typeName != "" && entry.Type != typeName {
+			continue
+		}
+		if pattern != nil && !pattern.Match(entry.Key) {
+			continue
+		}
+		keys = append(keys, resp.Value{
+			Type: resp.BulkString,
+			Bulk: []byte(entry.Key),
+		})
+	}
+
+	return &resp.Value{
+		Type: resp.Array,
+		Array: []resp.Value{
+			{Type: resp.BulkString, Bulk: []byte(strconv.FormatUint(next, 10))},
+			{Type: resp.Array, Array: keys},
+		},
+	}
+}
+
+// syntaxErrorReply 返回通用的语法错误响应
+func syntaxErrorReply() *resp.Value {
+	return &resp.Value{
+		Type: resp.Error,
+		Str:  "ERR 语法错误",
+	}
+}
@@ -0,0 +1,82 @@
+package tcp
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/yndnr/tokenginx/internal/storage"
+	"github.com/yndnr/tokenginx/internal/transport/resp"
+)
+
+// scanCommand 使用 SCAN 命令遍历全部键
+func scanCommand(t *testing.T, handler *CommandHandler, options ...string) []string {
+	t.Helper()
+
+	var keys []string
+	cursor := "0"
+	for {
+		args := append([]string{"SCAN", cursor}, options...)
+		response := handler.HandleCommand(newCommand(args...))
+		if response.Type != resp.Array || len(response.Array) != 2 {
+			t.Fatalf("Unexpected SCAN reply: %+v", response)
+		}
+		for _, key := range response.Array[1].Array {
+			keys = append(keys, string(key.Bulk))
+		}
+		cursor = string(response.Array[0].Bulk)
+		if cursor == "0" {
+			return keys
+		}
+	}
+}
+
+// TestCommandHandler_Scan 测试 SCAN 的 MATCH / COUNT / TYPE 选项
+func TestCommandHandler_Scan(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	handler := NewCommandHandler(sm)
+	for i := 0; i < 300; i++ {
+		sm.Set(fmt.Sprintf("oauth:token:%d", i), "t", 0)
+		sm.Set(fmt.Sprintf("session:%d", i), "s", 0)
+	}
+
+	if keys := scanCommand(t, handler, "COUNT", "50"); len(keys) != 600 {
+		t.Errorf("Expected 600 keys, got %d", len(keys))
+	}
+	if keys := scanCommand(t, handler, "MATCH", "oauth:token:*", "COUNT", "100"); len(keys) != 300 {
+		t.Errorf("Expected 300 oauth keys, got %d", len(keys))
+	}
+	if keys := scanCommand(t, handler, "MATCH", "session:7"); len(keys) != 1 {
+		t.Errorf("Expected exact match session:7, got %v", keys)
+	}
+	if keys := scanCommand(t, handler, "MATCH", "session:[12]?", "COUNT", "100"); len(keys) != 20 {
+		t.Errorf("Expected 20 keys for session:[12]?, got %d", len(keys))
+	}
+	if keys := scanCommand(t, handler, "TYPE", "string", "COUNT", "1000"); len(keys) != 600 {
+		t.Errorf("Expected 600 string keys, got %d", len(keys))
+	}
+	if keys := scanCommand(t, handler, "TYPE", "hash", "COUNT", "1000"); len(keys) != 0 {
+		t.Errorf("Expected no hash keys, got %d", len(keys))
+	}
+
+	// 锁不是字符串，SCAN TYPE string 不返回
+	handler.HandleCommand(newCommand("LOCK.ACQUIRE", "lock:1", "owner", "10000"))
+	if keys := scanCommand(t, handler, "TYPE", "string", "COUNT", "1000"); len(keys) != 600 {
+		t.Errorf("Expected 600 string keys with a lock present, got %d", len(keys))
+	}
+	if keys := scanCommand(t, handler, "TYPE", "lock", "COUNT", "1000"); len(keys) != 1 || keys[0] != "lock:1" {
+		t.Errorf("Expected only lock:1 for TYPE lock, got %v", keys)
+	}
+
+	invalid := [][]string{
+		{"SCAN", "abc"},
+		{"SCAN", "0", "COUNT", "0"},
+		{"SCAN", "0", "COUNT", "x"},
+		{"SCAN", "0", "COUNT"},
+		{"SCAN", "0", "BOGUS", "1"},
+	}
+	for _, args := range invalid {
+		if response := handler.HandleCommand(newCommand(args...)); response.Type != resp.Error {
+			t.Errorf("%v: expected error, got %+v", args, response)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package tcp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -10,14 +11,17 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/yndnr/tokenginx/internal/security/antireplay"
 	"github.com/yndnr/tokenginx/internal/storage"
 	"github.com/yndnr/tokenginx/internal/transport/resp"
+	"github.com/yndnr/tokenginx/pkg/module"
 )
 
 // Server TCP 服务器
 //
 // Server 实现了一个基于 RESP 协议的 TCP 服务器，用于处理客户端连接和命令请求。
 // 每个客户端连接由独立的 Goroutine 处理，支持高并发。
+// 支持管道（pipelining）：同一批到达的多条命令的响应合并为一次写入。
 //
 // 示例：
 //
@@ -33,6 +37,14 @@ type Server struct {
 	sm       *storage.ShardedMap // 存储引擎
 	handler  *CommandHandler     // 命令处理器
 
+	// 签名验证（nil 表示未启用）
+	verifier      *antireplay.Verifier
+	signatureMode SignatureMode
+
+	// 序列号校验（nil 表示未启用）
+	sequences       *antireplay.SequenceTracker
+	requireSequence bool
+
 	// 状态管理
 	running  atomic.Bool   // 服务器是否运行中
 	wg       sync.WaitGroup // 等待所有连接关闭
@@ -79,6 +91,87 @@ func NewServer(addr string, sm *storage.ShardedMap) *Server {
 	}
 }
 
+// SetNonceStore 设置防重放使用的 Nonce 缓存
+//
+// 注意事项：
+//   - 应在 Start() 之前调用
+//   - Nonce 缓存的生命周期（Start/Stop）由调用方管理
+func (s *Server) SetNonceStore(ns *antireplay.NonceStore) {
+	s.handler.SetNonceStore(ns)
+}
+
+// SetDatabases 设置逻辑数据库
+//
+// 注意事项：
+//   - 应在 Start() 之前调用
+//   - 未设置时使用以 NewServer 传入的存储引擎为 0 号数据库的 16 个逻辑数据库
+func (s *Server) SetDatabases(dbs *storage.Databases) {
+	s.handler.SetDatabases(dbs)
+}
+
+// SetKeyspaceEvents 启用键空间事件通知（见 CommandHandler.SetKeyspaceEvents）
+//
+// 注意事项：
+//   - 应在 Start() 之前调用
+func (s *Server) SetKeyspaceEvents(events KeyspaceEvents, callback func(db int, event storage.KeyEvent)) {
+	s.handler.SetKeyspaceEvents(events, callback)
+}
+
+// SetVerifier 启用 RESP 请求签名验证
+//
+// 参数说明：
+//   - verifier: 签名验证器
+//   - mode: 签名方式（连接握手签名或逐条命令签名）
+//
+// 注意事项：
+//   - 应在 Start() 之前调用
+//   - 启用后未通过验证的命令返回 SIGNATURE_REQUIRED 错误，PING 除外
+func (s *Server) SetVerifier(verifier *antireplay.Verifier, mode SignatureMode) {
+	s.verifier = verifier
+	s.signatureMode = mode
+}
+
+// SetSequenceTracker 启用按客户端的序列号校验
+//
+// 参数说明：
+//   - tracker: 序列号跟踪器
+//   - require: 是否要求所有命令都携带序列号（SEQ 包装）
+//
+// 注意事项：
+//   - 应在 Start() 之前调用
+//   - 序列号按签名认证的客户端 ID 跟踪，需要同时调用 SetVerifier
+func (s *Server) SetSequenceTracker(tracker *antireplay.SequenceTracker, require bool) {
+	s.sequences = tracker
+	s.requireSequence = require
+}
+
+// LoadModule 加载扩展模块
+//
+// 注意事项：
+//   - 应在 Start() 之前调用
+func (s *Server) LoadModule(m module.Module) error {
+	return s.handler.LoadModule(m)
+}
+
+// LoadModules 加载通过 module.Register 注册的全部模块
+//
+// 返回值：
+//   - []string: 已加载的模块名
+//   - error: 任一模块加载失败时的错误信息
+//
+// 注意事项：
+//   - 应在 Start() 之前调用
+func (s *Server) LoadModules() ([]string, error) {
+	var names []string
+	for _, m := range module.Modules() {
+		if err := s.handler.LoadModule(m); err != nil {
+			return names, err
+		}
+		names = append(names, m.Name())
+	}
+	return names, nil
+}
+
 // Start 启动 TCP 服务器
 //
 // 返回值：
@@ -144,6 +237,9 @@ func (s *Server) Stop() {
 	// 等待所有连接处理完成
 	s.wg.Wait()
 
+	// 停止键空间事件回调
+	s.handler.closeKeyspaceEvents()
+
 	s.running.Store(false)
 	log.Println("[INFO] TCP 服务器已停止")
 }
@@ -177,11 +273,12 @@ func (s *Server) handleConnection(conn net.Conn) {
 	defer conn.Close()
 
 	// 更新统计信息
-	s.totalConnections.Add(1)
+	clientID := s.totalConnections.Add(1)
 	s.activeClients.Add(1)
 	defer s.activeClients.Add(-1)
 
 	clientAddr := conn.RemoteAddr().String()
+	client := newClient(clientID, clientAddr)
 	log.Printf("[INFO] 新连接: %s (活跃连接: %d)", clientAddr, s.activeClients.Load())
 
 	// 创建 RESP 解析器和写入器
@@ -192,6 +289,29 @@ func (s *Server) handleConnection(conn net.Conn) {
 	ctx, cancel := context.WithCancel(s.ctx)
 	defer cancel()
 
+	// 进入推送模式（SUBSCRIBE 等）后由写 Goroutine 独占写入器
+	var pushDone chan struct{}
+	client.enterPushMode = func(sub *subscriber) {
+		// 先写出之前管道中的响应，保证输出顺序
+		writer.Flush()
+		sub.onClose = func() { conn.Close() }
+		pushDone = make(chan struct{})
+		go s.pushLoop(writer, sub, clientAddr, pushDone)
+	}
+	defer func() {
+		if client.sub == nil {
+			return
+		}
+		s.handler.pubsub.unsubscribeAll(client.sub)
+		if client.sub.overflowed.Load() {
+			log.Printf("[WARN] 订阅者输出缓冲区已满，断开连接: %s", clientAddr)
+		}
+		client.sub.close()
+		if pushDone != nil {
+			<-pushDone
+		}
+	}()
+
 	// 读取命令循环
 	for {
 		select {
@@ -201,11 +321,15 @@ func (s *Server) handleConnection(conn net.Conn) {
 		default:
 		}
 
-		// 设置读取超时（5 分钟无活动则断开）
-		conn.SetReadDeadline(time.Now().Add(5 * time.Minute))
+		// 设置读取超时（5 分钟无活动则断开），订阅连接只接收消息，不设超时
+		if client.sub == nil {
+			conn.SetReadDeadline(time.Now().Add(5 * time.Minute))
+		} else {
+			conn.SetReadDeadline(time.Time{})
+		}
 
 		// 解析 RESP 命令
-		value, err := parser.Parse()
+		value, err := parser.ParseCommand()
 		if err != nil {
 			if err == io.EOF {
 				// 客户端关闭连接
@@ -213,6 +337,21 @@ func (s *Server) handleConnection(conn net.Conn) {
 				return
 			}
 
+			// 与 Redis 相同，收到 HTTP 请求（POST / Host:）时不回复，直接断开
+			if errors.Is(err, resp.ErrCrossProtocol) {
+				log.Printf("[WARN] 检测到可能的跨协议攻击（收到 POST 或 Host: 命令），断开连接: %s", clientAddr)
+				return
+			}
+
+			// 推送模式下写入器由写 Goroutine 独占，直接断开；
+			// 订阅者已关闭（缓冲区溢出或服务器关闭）时连接已被关闭，不记录错误
+			if client.sub != nil {
+				if !client.sub.isClosed() {
+					log.Printf("[ERROR] 解析命令失败 (%s): %v", clientAddr, err)
+				}
+				return
+			}
+
 			// 解析错误
 			log.Printf("[ERROR] 解析命令失败 (%s): %v", clientAddr, err)
 			writer.WriteError(fmt.Sprintf("ERR 协议错误: %v", err))
@@ -220,16 +359,42 @@ func (s *Server) handleConnection(conn net.Conn) {
 			return
 		}
 
-		// 处理命令并返回响应
-		response := s.handler.HandleCommand(value)
+		// 签名验证通过后处理命令并返回响应
+		command, response := s.authorize(client, value)
+		if command != nil {
+			response = s.handler.HandleClientCommand(client, command)
+		}
+
+		// 推送模式下响应与发布的消息一起按顺序放入输出队列
+		if client.sub != nil {
+			client.sub.protocol.Store(int32(client.Protocol))
+			if response != nil && !client.sub.reply(response) {
+				return
+			}
+			s.totalCommands.Add(1)
+			continue
+		}
+
+		writer.SetProtocol(client.Protocol)
 		if err := writer.WriteValue(response); err != nil {
 			log.Printf("[ERROR] 写入响应失败 (%s): %v", clientAddr, err)
 			return
 		}
+		for _, extra := range client.extraReplies {
+			if err := writer.WriteValue(extra); err != nil {
+				log.Printf("[ERROR] 写入响应失败 (%s): %v", clientAddr, err)
+				return
+			}
+		}
+		client.extraReplies = nil
 
-		if err := writer.Flush(); err != nil {
-			log.Printf("[ERROR] 刷新缓冲区失败 (%s): %v", clientAddr, err)
-			return
+		// 管道模式下解析器缓冲区中还有完整的后续命令，继续处理，
+		// 直到下一次解析即将阻塞读取（缓冲区为空或只剩半条命令）时才一次性刷新整批响应
+		if !parser.CommandBuffered() {
+			if err := writer.Flush(); err != nil {
+				log.Printf("[ERROR] 刷新缓冲区失败 (%s): %v", clientAddr, err)
+				return
+			}
 		}
 
 		// 更新统计信息
@@ -237,6 +402,38 @@ func (s *Server) handleConnection(conn net.Conn) {
 	}
 }
 
+// pushLoop 推送模式下的写 Goroutine
+//
+// 按顺序写出订阅者输出队列中的响应和消息，队列读空时刷新；
+// 订阅者关闭、写入失败或服务器关闭时退出。
+func (s *Server) pushLoop(writer *resp.Writer, sub *subscriber, clientAddr string, done chan<- struct{}) {
+	defer close(done)
+
+	for {
+		select {
+		case value := <-sub.out:
+			writer.SetProtocol(int(sub.protocol.Load()))
+			err := writer.WriteValue(value)
+			if err == nil && len(sub.out) == 0 {
+				err = writer.Flush()
+			}
+			if err != nil {
+				// 连接已由读取 Goroutine 关闭时写入失败是预期的
+				if !sub.isClosed() {
+					log.Printf("[ERROR] 写入推送消息失败 (%s): %v", clientAddr, err)
+				}
+				sub.close()
+				return
+			}
+		case <-sub.closed:
+			return
+		case <-s.ctx.Done():
+			sub.close()
+			return
+		}
+	}
+}
+
 // GetStats 获取服务器统计信息
 type ServerStats struct {
 	Running          bool   // 是否运行中
@@ -2,11 +2,15 @@ package tcp
 
 import (
 	"bufio"
+	"context"
+	"fmt"
+	"io"
 	"net"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/go-redis/redis/v8"
 	"github.com/yndnr/tokenginx/internal/storage"
 	"github.com/yndnr/tokenginx/internal/transport/resp"
 )
@@ -302,6 +306,581 @@ func TestServer_RawProtocol(t *testing.T) {
 	}
 }
 
+// TestServer_Pipeline 测试一次写入多条命令时按顺序返回全部响应
+func TestServer_Pipeline(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	server := NewServer("127.0.0.1:16387", sm)
+
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	conn, err := net.Dial("tcp", "127.0.0.1:16387")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	// SET + GET + PING 在同一次写入中发送
+	pipeline := "*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n" +
+		"*2\r\n$3\r\nGET\r\n$1\r\nk\r\n" +
+		"*1\r\n$4\r\nPING\r\n"
+	if _, err := conn.Write([]byte(pipeline)); err != nil {
+		t.Fatalf("Failed to write pipeline: %v", err)
+	}
+
+	expected := "+OK\r\n$1\r\nv\r\n+PONG\r\n"
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, len(expected))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatalf("Failed to read responses: %v", err)
+	}
+	if string(buf) != expected {
+		t.Errorf("Expected %q, got %q", expected, buf)
+	}
+
+	if stats := server.GetStats(); stats.TotalCommands != 3 {
+		t.Errorf("Expected 3 commands, got %d", stats.TotalCommands)
+	}
+}
+
+// TestServer_InlineCommand 测试 telnet / nc 风格的内联命令
+func TestServer_InlineCommand(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	server := NewServer("127.0.0.1:16390", sm)
+
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	conn, err := net.Dial("tcp", "127.0.0.1:16390")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("PING\r\nSET greeting \"hello world\"\r\nGET greeting\r\n")); err != nil {
+		t.Fatalf("Failed to write inline commands: %v", err)
+	}
+
+	expected := "+PONG\r\n+OK\r\n$11\r\nhello world\r\n"
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, len(expected))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatalf("Failed to read responses: %v", err)
+	}
+	if string(buf) != expected {
+		t.Errorf("Expected %q, got %q", expected, buf)
+	}
+}
+
+// TestServer_CrossProtocol 测试收到 HTTP 请求时不回复、不执行后续命令并断开连接
+func TestServer_CrossProtocol(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	server := NewServer("127.0.0.1:16402", sm)
+
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	conn, err := net.Dial("tcp", "127.0.0.1:16402")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	// 网页通过表单向本地端口发送的请求，正文中夹带了命令
+	request := "POST / HTTP/1.1\r\nHost: 127.0.0.1:16402\r\nContent-Type: text/plain\r\n\r\nSET pwned 1\r\n"
+	if _, err := conn.Write([]byte(request)); err != nil {
+		t.Fatalf("Failed to write request: %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	data, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatalf("Expected the server to close the connection, got %v", err)
+	}
+	if len(data) != 0 {
+		t.Errorf("Expected no reply, got %q", data)
+	}
+	if sm.Exists("pwned") {
+		t.Error("Expected commands after the HTTP request line not to run")
+	}
+}
+
+// TestServer_PipelinePartialCommand 测试管道末尾只收到半条命令时，已完成命令的响应会立即发出
+func TestServer_PipelinePartialCommand(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	server := NewServer("127.0.0.1:16403", sm)
+
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	conn, err := net.Dial("tcp", "127.0.0.1:16403")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	// 一条完整的 PING 加上半条 SET，客户端在收到 PONG 之前不会发送剩余部分
+	if _, err := conn.Write([]byte("*1\r\n$4\r\nPING\r\n*3\r\n$3\r\nSET\r\n$1\r\nk")); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		t.Fatalf("Expected PONG before the rest of the pipeline is sent, got %v", err)
+	}
+	if line != "+PONG\r\n" {
+		t.Errorf("Expected +PONG, got %q", line)
+	}
+
+	if _, err := conn.Write([]byte("\r\n$1\r\nv\r\n")); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	line, err = reader.ReadString('\n')
+	if err != nil {
+		t.Fatalf("Failed to read SET reply: %v", err)
+	}
+	if line != "+OK\r\n" {
+		t.Errorf("Expected +OK, got %q", line)
+	}
+}
+
+// TestServer_UnsubscribeWithoutSubscription 测试未订阅的连接执行 UNSUBSCRIBE 后仍按普通方式响应
+func TestServer_UnsubscribeWithoutSubscription(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	server := NewServer("127.0.0.1:16405", sm)
+
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	conn, err := net.Dial("tcp", "127.0.0.1:16405")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("UNSUBSCRIBE a b\r\nPING\r\n")); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+
+	parser := resp.NewParser(conn)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for _, channel := range []string{"a", "b"} {
+		value, err := parser.Parse()
+		if err != nil {
+			t.Fatalf("Failed to read UNSUBSCRIBE reply: %v", err)
+		}
+		if value.Type != resp.Array || len(value.Array) != 3 || string(value.Array[1].Bulk) != channel || value.Array[2].Int != 0 {
+			t.Errorf("Unexpected UNSUBSCRIBE reply for %s: %+v", channel, value)
+		}
+	}
+
+	value, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("Failed to read PING reply: %v", err)
+	}
+	if value.Type != resp.SimpleString || value.Str != "PONG" {
+		t.Errorf("Expected PONG, got %+v", value)
+	}
+}
+
+// TestServer_Hello3 测试 HELLO 3 之后连接使用 RESP3 响应
+func TestServer_Hello3(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	server := NewServer("127.0.0.1:16391", sm)
+
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	conn, err := net.Dial("tcp", "127.0.0.1:16391")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	writer := resp.NewWriter(conn)
+	parser := resp.NewParser(conn)
+
+	writer.WriteValue(&resp.Value{Type: resp.Array, Array: []resp.Value{
+		{Type: resp.BulkString, Bulk: []byte("HELLO")},
+		{Type: resp.BulkString, Bulk: []byte("3")},
+	}})
+	writer.WriteValue(&resp.Value{Type: resp.Array, Array: []resp.Value{
+		{Type: resp.BulkString, Bulk: []byte("GET")},
+		{Type: resp.BulkString, Bulk: []byte("missing")},
+	}})
+	writer.Flush()
+
+	hello, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("Failed to read HELLO reply: %v", err)
+	}
+	if hello.Type != resp.Map {
+		t.Errorf("Expected RESP3 map reply to HELLO 3, got %c", hello.Type)
+	}
+
+	missing, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("Failed to read GET reply: %v", err)
+	}
+	if missing.Type != resp.Null {
+		t.Errorf("Expected RESP3 null for missing key, got %c", missing.Type)
+	}
+}
+
+// TestServer_CommandGoRedis 测试 go-redis 能够解析 COMMAND 响应
+func TestServer_CommandGoRedis(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	server := NewServer("127.0.0.1:16392", sm)
+
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:16392"})
+	defer client.Close()
+
+	commands, err := client.Command(context.Background()).Result()
+	if err != nil {
+		t.Fatalf("COMMAND failed: %v", err)
+	}
+
+	set, exists := commands["set"]
+	if !exists {
+		t.Fatalf("Expected set in COMMAND reply, got %d commands", len(commands))
+	}
+	if set.Arity != -3 || set.FirstKeyPos != 1 || set.ReadOnly {
+		t.Errorf("Unexpected set info: %+v", set)
+	}
+	if get := commands["get"]; get == nil || !get.ReadOnly {
+		t.Errorf("Expected get to be readonly, got %+v", get)
+	}
+}
+
+// TestServer_SetNXGoRedis 测试 go-redis 的 SetNX（SET key value EX n NX）不会覆盖已有的锁
+func TestServer_SetNXGoRedis(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	server := NewServer("127.0.0.1:16394", sm)
+
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:16394"})
+	defer client.Close()
+
+	ctx := context.Background()
+	acquired, err := client.SetNX(ctx, "lock:order:42", "owner1", 10*time.Second).Result()
+	if err != nil || !acquired {
+		t.Fatalf("First SetNX = %v, %v; want true, nil", acquired, err)
+	}
+
+	acquired, err = client.SetNX(ctx, "lock:order:42", "owner2", 10*time.Second).Result()
+	if err != nil || acquired {
+		t.Errorf("Second SetNX = %v, %v; want false, nil", acquired, err)
+	}
+
+	if owner, _ := client.Get(ctx, "lock:order:42").Result(); owner != "owner1" {
+		t.Errorf("Expected owner1, got %q", owner)
+	}
+}
+
+// TestServer_StringCommandsGoRedis 测试会话处理器常用的 SETEX / MGET / GETDEL
+func TestServer_StringCommandsGoRedis(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	server := NewServer("127.0.0.1:16395", sm)
+
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:16395"})
+	defer client.Close()
+
+	ctx := context.Background()
+	if err := client.SetEX(ctx, "session:a", "alice", time.Minute).Err(); err != nil {
+		t.Fatalf("SETEX failed: %v", err)
+	}
+	if err := client.MSet(ctx, "session:b", "bob", "session:c", "carol").Err(); err != nil {
+		t.Fatalf("MSET failed: %v", err)
+	}
+
+	values, err := client.MGet(ctx, "session:a", "session:missing", "session:c").Result()
+	if err != nil {
+		t.Fatalf("MGET failed: %v", err)
+	}
+	if len(values) != 3 || values[0] != "alice" || values[1] != nil || values[2] != "carol" {
+		t.Errorf("Unexpected MGET result: %v", values)
+	}
+
+	code, err := client.GetDel(ctx, "session:b").Result()
+	if err != nil || code != "bob" {
+		t.Errorf("GETDEL = %q, %v; want bob, nil", code, err)
+	}
+	if _, err := client.GetDel(ctx, "session:b").Result(); err != redis.Nil {
+		t.Errorf("Expected redis.Nil for second GETDEL, got %v", err)
+	}
+}
+
+// TestServer_ScanGoRedis 测试 go-redis 的 SCAN 迭代器
+func TestServer_ScanGoRedis(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	for i := 0; i < 1000; i++ {
+		sm.Set(fmt.Sprintf("session:%d", i), "data", 0)
+	}
+	sm.Set("other", "data", 0)
+
+	server := NewServer("127.0.0.1:16396", sm)
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:16396"})
+	defer client.Close()
+
+	ctx := context.Background()
+	seen := make(map[string]bool)
+	iter := client.Scan(ctx, 0, "session:*", 100).Iterator()
+	for iter.Next(ctx) {
+		seen[iter.Val()] = true
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatalf("SCAN failed: %v", err)
+	}
+	if len(seen) != 1000 || seen["other"] {
+		t.Errorf("Expected 1000 session keys, got %d", len(seen))
+	}
+}
+
+// TestServer_SelectGoRedis 测试 go-redis 客户端通过 DB 选项使用逻辑数据库
+func TestServer_SelectGoRedis(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	server := NewServer("127.0.0.1:16397", sm)
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	db0 := redis.NewClient(&redis.Options{Addr: "127.0.0.1:16397"})
+	defer db0.Close()
+	db2 := redis.NewClient(&redis.Options{Addr: "127.0.0.1:16397", DB: 2})
+	defer db2.Close()
+
+	ctx := context.Background()
+	if err := db2.Set(ctx, "cas:ticket:1", "t", 0).Err(); err != nil {
+		t.Fatalf("SET failed: %v", err)
+	}
+	if err := db0.Get(ctx, "cas:ticket:1").Err(); err != redis.Nil {
+		t.Errorf("Expected key in db2 to be invisible from db0, got %v", err)
+	}
+	if n, _ := db2.DBSize(ctx).Result(); n != 1 {
+		t.Errorf("Expected DBSIZE 1 in db2, got %d", n)
+	}
+
+	moved, err := db2.Move(ctx, "cas:ticket:1", 0).Result()
+	if err != nil || !moved {
+		t.Fatalf("MOVE failed: %v, %v", moved, err)
+	}
+	if value, _ := db0.Get(ctx, "cas:ticket:1").Result(); value != "t" {
+		t.Errorf("Expected 't' in db0 after MOVE, got %q", value)
+	}
+}
+
+// TestServer_TransactionGoRedis 测试 go-redis 的 TxPipelined 和 Watch
+func TestServer_TransactionGoRedis(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	server := NewServer("127.0.0.1:16398", sm)
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:16398"})
+	defer client.Close()
+	other := redis.NewClient(&redis.Options{Addr: "127.0.0.1:16398"})
+	defer other.Close()
+
+	ctx := context.Background()
+	var incr *redis.IntCmd
+	_, err := client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, "session:1", "10", 0)
+		incr = pipe.Incr(ctx, "session:1")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("TxPipelined failed: %v", err)
+	}
+	if incr.Val() != 11 {
+		t.Errorf("Expected 11, got %d", incr.Val())
+	}
+
+	// WATCH 的键在事务之前被其他连接修改
+	err = client.Watch(ctx, func(tx *redis.Tx) error {
+		if err := other.Set(ctx, "session:1", "changed", 0).Err(); err != nil {
+			return err
+		}
+		_, err := tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, "session:1", "mine", 0)
+			return nil
+		})
+		return err
+	}, "session:1")
+	if err != redis.TxFailedErr {
+		t.Errorf("Expected TxFailedErr, got %v", err)
+	}
+	if value, _ := client.Get(ctx, "session:1").Result(); value != "changed" {
+		t.Errorf("Expected 'changed', got %q", value)
+	}
+
+	// 未被修改时事务正常执行
+	err = client.Watch(ctx, func(tx *redis.Tx) error {
+		_, err := tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, "session:1", "mine", 0)
+			return nil
+		})
+		return err
+	}, "session:1")
+	if err != nil {
+		t.Errorf("Expected transaction to succeed, got %v", err)
+	}
+}
+
+// TestServer_PubSubGoRedis 测试 go-redis 的 Subscribe、PSubscribe 和 Publish
+func TestServer_PubSubGoRedis(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	server := NewServer("127.0.0.1:16399", sm)
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:16399"})
+	defer client.Close()
+
+	ctx := context.Background()
+	pubsub := client.Subscribe(ctx, "session:revoked")
+	defer pubsub.Close()
+	if _, err := pubsub.Receive(ctx); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if err := pubsub.PSubscribe(ctx, "tenant:*:revoked"); err != nil {
+		t.Fatalf("PSubscribe failed: %v", err)
+	}
+	if _, err := pubsub.Receive(ctx); err != nil {
+		t.Fatalf("PSubscribe confirmation failed: %v", err)
+	}
+
+	if receivers, err := client.Publish(ctx, "session:revoked", "sess_01HX").Result(); err != nil || receivers != 1 {
+		t.Errorf("Expected 1 receiver, got %d, %v", receivers, err)
+	}
+	msg, err := pubsub.ReceiveMessage(ctx)
+	if err != nil || msg.Channel != "session:revoked" || msg.Payload != "sess_01HX" {
+		t.Errorf("Expected message on session:revoked, got %+v, %v", msg, err)
+	}
+
+	client.Publish(ctx, "tenant:acme:revoked", "sess_02HX")
+	msg, err = pubsub.ReceiveMessage(ctx)
+	if err != nil || msg.Pattern != "tenant:*:revoked" || msg.Channel != "tenant:acme:revoked" {
+		t.Errorf("Expected pattern message, got %+v, %v", msg, err)
+	}
+
+	// 订阅连接上的 PING
+	if err := pubsub.Ping(ctx); err != nil {
+		t.Errorf("Ping failed: %v", err)
+	}
+	if reply, err := pubsub.Receive(ctx); err != nil {
+		t.Errorf("Expected pong, got %v", err)
+	} else if _, ok := reply.(*redis.Pong); !ok {
+		t.Errorf("Expected *redis.Pong, got %T", reply)
+	}
+
+	// 关闭订阅连接后订阅被清理
+	pubsub.Close()
+	time.Sleep(100 * time.Millisecond)
+	if receivers, _ := client.Publish(ctx, "session:revoked", "sess_03HX").Result(); receivers != 0 {
+		t.Errorf("Expected 0 receivers after close, got %d", receivers)
+	}
+}
+
+// TestServer_StopWithSubscriber 测试有订阅连接时服务器可以停止
+func TestServer_StopWithSubscriber(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	server := NewServer("127.0.0.1:16400", sm)
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	conn, err := net.Dial("tcp", "127.0.0.1:16400")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	fmt.Fprint(conn, "SUBSCRIBE session:revoked\r\n")
+	parser := resp.NewParser(conn)
+	if value, err := parser.Parse(); err != nil || len(value.Array) != 3 {
+		t.Fatalf("Expected subscribe confirmation, got %+v, %v", value, err)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		server.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Stop to disconnect subscribers")
+	}
+}
+
 // BenchmarkServer_PING 基准测试：PING 命令
 func BenchmarkServer_PING(b *testing.B) {
 	sm := storage.NewShardedMap(4096)
@@ -370,3 +949,137 @@ func BenchmarkServer_GET(b *testing.B) {
 		parser.Parse()
 	}
 }
+
+// benchmarkPipelineSize 是管道基准测试每批发送的命令数
+const benchmarkPipelineSize = 100
+
+// BenchmarkServer_GoRedisSequential 基准测试：go-redis 逐条发送 GET（每条命令一次往返）
+func BenchmarkServer_GoRedisSequential(b *testing.B) {
+	sm := storage.NewShardedMap(4096)
+	sm.Set("benchkey", "benchvalue", 0)
+
+	server := NewServer("127.0.0.1:16388", sm)
+	if err := server.Start(); err != nil {
+		b.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:16388", PoolSize: 1})
+	defer client.Close()
+
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := client.Get(ctx, "benchkey").Err(); err != nil {
+			b.Fatalf("GET failed: %v", err)
+		}
+	}
+}
+
+// BenchmarkServer_GoRedisPipeline 基准测试：go-redis Pipeline() 批量发送 GET
+//
+// ns/op 按单条命令计算，可与 BenchmarkServer_GoRedisSequential 直接比较。
+func BenchmarkServer_GoRedisPipeline(b *testing.B) {
+	sm := storage.NewShardedMap(4096)
+	sm.Set("benchkey", "benchvalue", 0)
+
+	server := NewServer("127.0.0.1:16389", sm)
+	if err := server.Start(); err != nil {
+		b.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:16389", PoolSize: 1})
+	defer client.Close()
+
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i += benchmarkPipelineSize {
+		n := benchmarkPipelineSize
+		if b.N-i < n {
+			n = b.N - i
+		}
+
+		pipe := client.Pipeline()
+		for j := 0; j < n; j++ {
+			pipe.Get(ctx, "benchkey")
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			b.Fatalf("Pipeline failed: %v", err)
+		}
+	}
+}
+
+// BenchmarkServer_RawPipeline 基准测试：在原始连接上批量发送 GET，不经过客户端库
+//
+// ns/op 按单条命令计算。子测试 whole 每批一次写入，split 把每批拆成多次小写入，
+// 使服务端读到的缓冲区末尾经常只有半条命令。调整刷新策略时，在修改前后的版本上分别运行
+// go test -run '^$' -bench RawPipeline -count 10，再用 benchstat 比较两次结果。
+func BenchmarkServer_RawPipeline(b *testing.B) {
+	sm := storage.NewShardedMap(4096)
+	sm.Set("benchkey", "benchvalue", 0)
+
+	server := NewServer("127.0.0.1:16404", sm)
+	if err := server.Start(); err != nil {
+		b.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	cmd := "*2\r\n$3\r\nGET\r\n$8\r\nbenchkey\r\n"
+	reply := "$10\r\nbenchvalue\r\n"
+	batch := []byte(strings.Repeat(cmd, benchmarkPipelineSize))
+
+	for _, bench := range []struct {
+		name  string
+		chunk int
+	}{
+		{"whole", len(batch)},
+		{"split", 100},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			conn, err := net.Dial("tcp", "127.0.0.1:16404")
+			if err != nil {
+				b.Fatalf("Failed to connect: %v", err)
+			}
+			defer conn.Close()
+
+			replies := make([]byte, len(reply)*benchmarkPipelineSize)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i += benchmarkPipelineSize {
+				n := benchmarkPipelineSize
+				if b.N-i < n {
+					n = b.N - i
+				}
+				data := batch[:n*len(cmd)]
+
+				// 写入与读取并发进行，避免双方缓冲区同时写满
+				done := make(chan error, 1)
+				go func() {
+					for off := 0; off < len(data); off += bench.chunk {
+						end := off + bench.chunk
+						if end > len(data) {
+							end = len(data)
+						}
+						if _, err := conn.Write(data[off:end]); err != nil {
+							done <- err
+							return
+						}
+					}
+					done <- nil
+				}()
+
+				if _, err := io.ReadFull(conn, replies[:n*len(reply)]); err != nil {
+					b.Fatalf("Failed to read replies: %v", err)
+				}
+				if err := <-done; err != nil {
+					b.Fatalf("Failed to write batch: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package tcp
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/yndnr/tokenginx/internal/storage"
+	"github.com/yndnr/tokenginx/internal/transport/resp"
+)
+
+// wrongTypeError 键的值不是字符串时的错误响应
+var wrongTypeError = &resp.Value{
+	Type: resp.Error,
+	Str:  "WRONGTYPE 键的值不是字符串",
+}
+
+// handleMGet 处理 MGET 命令
+//
+// 格式：MGET key [key ...]
+// 返回：与键一一对应的值数组，不存在的键为 Null；任意一个键的值不是字符串时返回 WRONGTYPE 错误
+func (h *CommandHandler) handleMGet(c *Client, args [][]byte) *resp.Value {
+	db := h.db(c)
+	values := make([]resp.Value, len(args))
+	for i, key := range args {
+		value, exists := db.Get(string(key))
+		if !exists {
+			values[i] = resp.Value{Type: resp.BulkString, Null: true}
+			continue
+		}
+		reply := valueReply(value)
+		if reply.Type == resp.Error {
+			return reply
+		}
+		values[i] = *reply
+	}
+
+	return &resp.Value{
+		Type:  resp.Array,
+		Array: values,
+	}
+}
+
+// handleMSet 处理 MSET 命令
+//
+// 格式：MSET key value [key value ...]
+// 返回：+OK
+//
+// 注意事项：
+//   - 跨分片原子写入，写入的键不带过期时间
+func (h *CommandHandler) handleMSet(c *Client, args [][]byte) *resp.Value {
+	pairs, errReply := keyValuePairs("MSET", args)
+	if errReply != nil {
+		return errReply
+	}
+
+	h.db(c).MSet(pairs)
+
+	return &resp.Value{
+		Type: resp.SimpleString,
+		Str:  "OK",
+	}
+}
+
+// handleMSetNX 处理 MSETNX 命令
+//
+// 格式：MSETNX key value [key value ...]
+// 返回：1 表示全部写入，0 表示至少一个键已存在（不写入任何键）
+func (h *CommandHandler) handleMSetNX(c *Client, args [][]byte) *resp.Value {
+	pairs, errReply := keyValuePairs("MSETNX", args)
+	if errReply != nil {
+		return errReply
+	}
+
+	var written int64
+	if h.db(c).MSetNX(pairs) {
+		written = 1
+	}
+
+	return &resp.Value{
+		Type: resp.Integer,
+		Int:  written,
+	}
+}
+
+// keyValuePairs 将 key value [key value ...] 参数转换为键值对
+func keyValuePairs(name string, args [][]byte) ([]storage.KeyValue, *resp.Value) {
+	if len(args)%2 != 0 {
+		return nil, &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR " + name + " 命令参数数量错误",
+		}
+	}
+
+	pairs := make([]storage.KeyValue, 0, len(args)/2)
+	for i := 0; i < len(args); i += 2 {
+		pairs = append(pairs, storage.KeyValue{
+			Key:   string(args[i]),
+			Value: args[i+1],
+		})
+	}
+	return pairs, nil
+}
+
+// handleGetDel 处理 GETDEL 命令
+//
+// 格式：GETDEL key
+// 返回：键的值（读取后删除），键不存在时为 Null；值不是字符串时返回 WRONGTYPE 错误，不删除
+func (h *CommandHandler) handleGetDel(c *Client, args [][]byte) *resp.Value {
+	value, exists, err := h.db(c).GetDel(string(args[0]))
+	if err != nil {
+		return wrongTypeError
+	}
+	if !exists {
+		return &resp.Value{
+			Type: resp.BulkString,
+			Null: true,
+		}
+	}
+
+	return valueReply(value)
+}
+
+// handleGetSet 处理 GETSET 命令
+//
+// 格式：GETSET key value
+// 返回：键的旧值，键不存在时为 Null；旧值不是字符串时返回 WRONGTYPE 错误，不写入
+//
+// 注意事项：
+//   - 与 Redis 相同，新值不带过期时间
+func (h *CommandHandler) handleGetSet(c *Client, args [][]byte) *resp.Value {
+	result := h.db(c).SetWithOptions(string(args[0]), args[1], storage.SetOptions{Get: true})
+	if result.WrongType {
+		return wrongTypeError
+	}
+	if !result.Existed {
+		return &resp.Value{
+			Type: resp.BulkString,
+			Null: true,
+		}
+	}
+
+	return valueReply(result.Old)
+}
+
+// handleSetNX 处理 SETNX 命令
+//
+// 格式：SETNX key value
+// 返回：1 表示写入，0 表示键已存在
+func (h *CommandHandler) handleSetNX(c *Client, args [][]byte) *resp.Value {
+	var written int64
+	if h.db(c).SetNX(string(args[0]), args[1], 0) {
+		written = 1
+	}
+
+	return &resp.Value{
+		Type: resp.Integer,
+		Int:  written,
+	}
+}
+
+// handleSetEX 处理 SETEX 命令
+//
+// 格式：SETEX key seconds value
+// 返回：+OK
+func (h *CommandHandler) handleSetEX(c *Client, args [][]byte) *resp.Value {
+	seconds, err := strconv.ParseInt(string(args[1]), 10, 64)
+	if err != nil {
+		return &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR 参数必须是整数",
+		}
+	}
+
+	expiresAt, ok := absoluteExpiry("EX", seconds, time.Now().UnixMilli())
+	if !ok {
+		return &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR SETEX 命令的过期时间无效",
+		}
+	}
+
+	h.db(c).SetWithOptions(string(args[0]), args[2], storage.SetOptions{ExpiresAt: expiresAt})
+
+	return &resp.Value{
+		Type: resp.SimpleString,
+		Str:  "OK",
+	}
+}
+
+// handleAppend 处理 APPEND 命令
+//
+// 格式：APPEND key value
+// 返回：追加后值的长度
+func (h *CommandHandler) handleAppend(c *Client, args [][]byte) *resp.Value {
+	length, err := h.db(c).Append(string(args[0]), args[1])
+	if err != nil {
+		return wrongTypeError
+	}
+
+	return &resp.Value{
+		Type: resp.Integer,
+		Int:  int64(length),
+	}
+}
+
+// handleStrlen 处理 STRLEN 命令
+//
+// 格式：STRLEN key
+// 返回：值的长度（字节），键不存在时为 0；值不是字符串时返回 WRONGTYPE 错误
+func (h *CommandHandler) handleStrlen(c *Client, args [][]byte) *resp.Value {
+	var length int64
+	if value, exists := h.db(c).Get(string(args[0])); exists {
+		reply := valueReply(value)
+		if reply.Type == resp.Error {
+			return reply
+		}
+		length = int64(len(reply.Bulk))
+	}
+
+	return &resp.Value{
+		Type: resp.Integer,
+		Int:  length,
+	}
+}
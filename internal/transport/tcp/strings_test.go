@@ -0,0 +1,126 @@
+package tcp
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yndnr/tokenginx/internal/storage"
+	"github.com/yndnr/tokenginx/internal/transport/resp"
+)
+
+// TestCommandHandler_MGetMSet 测试 MGET / MSET / MSETNX
+func TestCommandHandler_MGetMSet(t *testing.T) {
+	handler := NewCommandHandler(storage.NewShardedMap(1024))
+
+	response := handler.HandleCommand(newCommand("MSET", "a", "1", "b", "2"))
+	if response.Str != "OK" {
+		t.Fatalf("Expected OK, got %+v", response)
+	}
+
+	response = handler.HandleCommand(newCommand("MGET", "a", "missing", "b"))
+	if len(response.Array) != 3 || string(response.Array[0].Bulk) != "1" ||
+		!response.Array[1].Null || string(response.Array[2].Bulk) != "2" {
+		t.Errorf("Unexpected MGET reply: %+v", response)
+	}
+
+	response = handler.HandleCommand(newCommand("MSET", "a", "1", "b"))
+	if response.Type != resp.Error {
+		t.Errorf("Expected error for odd MSET arguments, got %+v", response)
+	}
+
+	response = handler.HandleCommand(newCommand("MSETNX", "c", "3", "a", "x"))
+	if response.Int != 0 {
+		t.Errorf("Expected 0 when a key exists, got %+v", response)
+	}
+	response = handler.HandleCommand(newCommand("MSETNX", "c", "3", "d", "4"))
+	if response.Int != 1 {
+		t.Errorf("Expected 1, got %+v", response)
+	}
+}
+
+// TestCommandHandler_StringCommands 测试 GETDEL / GETSET / SETNX / SETEX / APPEND / STRLEN
+func TestCommandHandler_StringCommands(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	handler := NewCommandHandler(sm)
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected resp.Value
+	}{
+		{"SETNX new", []string{"SETNX", "k", "v1"}, resp.Value{Type: resp.Integer, Int: 1}},
+		{"SETNX existing", []string{"SETNX", "k", "v2"}, resp.Value{Type: resp.Integer, Int: 0}},
+		{"GETSET", []string{"GETSET", "k", "v3"}, resp.Value{Type: resp.BulkString, Bulk: []byte("v1")}},
+		{"GETSET missing", []string{"GETSET", "other", "x"}, resp.Value{Type: resp.BulkString, Null: true}},
+		{"APPEND", []string{"APPEND", "k", "-tail"}, resp.Value{Type: resp.Integer, Int: 7}},
+		{"STRLEN", []string{"STRLEN", "k"}, resp.Value{Type: resp.Integer, Int: 7}},
+		{"STRLEN missing", []string{"STRLEN", "missing"}, resp.Value{Type: resp.Integer, Int: 0}},
+		{"GETDEL", []string{"GETDEL", "k"}, resp.Value{Type: resp.BulkString, Bulk: []byte("v3-tail")}},
+		{"GETDEL again", []string{"GETDEL", "k"}, resp.Value{Type: resp.BulkString, Null: true}},
+		{"SETEX", []string{"SETEX", "session", "100", "data"}, resp.Value{Type: resp.SimpleString, Str: "OK"}},
+		{"SETEX zero", []string{"SETEX", "session", "0", "data"}, resp.Value{Type: resp.Error, Str: "ERR SETEX 命令的过期时间无效"}},
+		{"SETEX not integer", []string{"SETEX", "session", "abc", "data"}, resp.Value{Type: resp.Error, Str: "ERR 参数必须是整数"}},
+	}
+
+	for _, tt := range tests {
+		response := handler.HandleCommand(newCommand(tt.args...))
+		if response.Type != tt.expected.Type || response.Int != tt.expected.Int ||
+			response.Null != tt.expected.Null || string(response.Bulk) != string(tt.expected.Bulk) ||
+			response.Str != tt.expected.Str {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.expected, response)
+		}
+	}
+
+	if ttl := storage.TTL(sm, "session"); ttl <= 0 || ttl > 100 {
+		t.Errorf("Expected SETEX TTL around 100, got %d", ttl)
+	}
+
+	sm.Set("number", 42, 0)
+	response := handler.HandleCommand(newCommand("APPEND", "number", "x"))
+	if response.Type != resp.Error {
+		t.Errorf("Expected WRONGTYPE error, got %+v", response)
+	}
+}
+
+// TestCommandHandler_NonStringRecords 测试字符串命令对锁、验证码等记录返回 WRONGTYPE，不泄露内部状态
+func TestCommandHandler_NonStringRecords(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	handler := NewCommandHandler(sm)
+
+	sm.Set("plain", "v", 0)
+	sm.AcquireLock("lock", "owner", 10000)
+	sm.RecordFailure("lockout", storage.LockoutPolicy{Window: time.Minute})
+	sm.SetOTP("otp", "123456", 60, 3)
+	sm.ThrottleGCRA("ratelimit", storage.GCRALimit{Burst: 1, Count: 1, Period: time.Second}, 1)
+	sm.PutChallenge("webauthn", storage.WebAuthnChallenge{
+		Challenge: "secret-challenge",
+		Ceremony:  storage.CeremonyAuthentication,
+		RPID:      "example.com",
+	}, 60)
+
+	for _, key := range []string{"lock", "lockout", "otp", "ratelimit", "webauthn"} {
+		commands := [][]string{
+			{"GET", key},
+			{"MGET", "plain", key},
+			{"GETDEL", key},
+			{"STRLEN", key},
+			{"GETSET", key, "x"},
+			{"SET", key, "x", "GET"},
+			{"GETVER", key},
+		}
+		for _, args := range commands {
+			response := handler.HandleCommand(newCommand(args...))
+			if response.Type != resp.Error || !strings.HasPrefix(response.Str, "WRONGTYPE") {
+				t.Errorf("%v: expected WRONGTYPE error, got %+v", args, response)
+			}
+		}
+		if !sm.Exists(key) {
+			t.Errorf("Expected %s to survive the rejected commands", key)
+		}
+	}
+
+	if challenge, found, err := sm.ConsumeChallenge("webauthn", storage.CeremonyAuthentication, "example.com"); err != nil || !found || challenge.Challenge != "secret-challenge" {
+		t.Errorf("Expected challenge untouched, got %+v, %v, %v", challenge, found, err)
+	}
+}
@@ -0,0 +1,264 @@
+package tcp
+
+import (
+	"strconv"
+
+	"github.com/yndnr/tokenginx/internal/storage"
+	"github.com/yndnr/tokenginx/internal/transport/resp"
+)
+
+// transaction MULTI 之后排队等待 EXEC 的命令
+type transaction struct {
+	commands []queuedCommand
+	aborted  bool // 排队时出现错误（未知命令、参数数量错误），EXEC 将放弃整个事务
+}
+
+// queuedCommand 一条排队的命令
+type queuedCommand struct {
+	cmd  *Command
+	argv [][]byte // 完整的命令参数（包括命令名）
+}
+
+// watchedKey WATCH 的一个键
+type watchedKey struct {
+	db      int    // 逻辑数据库编号
+	key     string // 键
+	version uint64 // WATCH 时的写入版本号，0 表示当时不存在
+}
+
+// transactionControl 检查命令在 MULTI 之后是否立即执行而不排队
+func transactionControl(name string) bool {
+	switch name {
+	case "multi", "exec", "discard", "watch":
+		return true
+	}
+	return false
+}
+
+// databaseWrite 检查命令是否会修改整个逻辑数据库（或在数据库之间移动数据）
+//
+// 这些命令在事务之外执行时持有 CommandHandler.txMu 的写锁，与 EXEC 和其他命令互斥。
+func databaseWrite(name string) bool {
+	switch name {
+	case "flushdb", "flushall", "swapdb", "move":
+		return true
+	}
+	return false
+}
+
+// databaseRead 检查命令是否读取整个逻辑数据库而不是指定的键
+//
+// 这些命令在事务之外执行时持有 CommandHandler.txMu 的读锁，并对当前数据库全部分片的事务锁加读锁：
+// 彼此之间、与普通命令之间并发执行，只等待正在执行的事务完成。
+func databaseRead(name string) bool {
+	switch name {
+	case "keys", "scan", "dbsize":
+		return true
+	}
+	return false
+}
+
+// databaseWide 检查命令是否作用于整个逻辑数据库而不是指定的键
+//
+// 出现在事务中时 EXEC 锁定全部已创建的逻辑数据库。
+func databaseWide(name string) bool {
+	return databaseWrite(name) || databaseRead(name)
+}
+
+// databaseWide 检查事务中是否包含作用于整个逻辑数据库的命令
+func (tx *transaction) databaseWide() bool {
+	for _, q := range tx.commands {
+		if databaseWide(q.cmd.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// queue 将命令加入事务队列
+func (tx *transaction) queue(cmd *Command, argv [][]byte) *resp.Value {
+	tx.commands = append(tx.commands, queuedCommand{cmd: cmd, argv: argv})
+
+	return &resp.Value{
+		Type: resp.SimpleString,
+		Str:  "QUEUED",
+	}
+}
+
+// handleMulti 处理 MULTI 命令
+//
+// 格式：MULTI
+// 返回：+OK，之后的命令返回 +QUEUED，直到 EXEC 或 DISCARD
+func (h *CommandHandler) handleMulti(c *Client, args [][]byte) *resp.Value {
+	if c.multi != nil {
+		return &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR MULTI 不能嵌套",
+		}
+	}
+
+	c.multi = &transaction{}
+
+	return &resp.Value{
+		Type: resp.SimpleString,
+		Str:  "OK",
+	}
+}
+
+// handleExec 处理 EXEC 命令
+//
+// 格式：EXEC
+// 返回：每条排队命令的响应组成的数组；WATCH 的键被修改时返回 Null Array
+//
+// 注意事项：
+//   - 执行前按固定顺序锁定全部排队命令和 WATCH 涉及的键所在的分片，
+//     其他连接对这些键的命令要等事务执行完才能执行
+//   - 执行期间持有数据库级事务锁的读锁，其他连接的 FLUSHDB、FLUSHALL、SWAPDB、MOVE
+//     要等事务执行完才能执行；KEYS、SCAN、DBSIZE 对数据库全部分片加读锁，同样不会看到部分结果
+//   - 事务中包含上述命令时改为持有数据库级事务锁的写锁并锁定全部已创建的逻辑数据库的分片，
+//     其他连接的全部命令都要等事务执行完
+//   - 与 Redis 相同，单条命令执行出错不会回滚其他命令，错误作为该命令的响应返回
+//   - 无论成功与否，EXEC 之后都会取消全部 WATCH
+func (h *CommandHandler) handleExec(c *Client, args [][]byte) *resp.Value {
+	if c.multi == nil {
+		return &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR EXEC 之前必须先执行 MULTI",
+		}
+	}
+
+	tx, watched := c.multi, c.watched
+	c.multi, c.watched = nil, nil
+
+	if tx.aborted {
+		return &resp.Value{
+			Type: resp.Error,
+			Str:  "EXECABORT 事务因排队时的错误已放弃",
+		}
+	}
+
+	var unlock func()
+	if tx.databaseWide() {
+		h.txMu.Lock()
+		defer h.txMu.Unlock()
+
+		// 只锁定已创建的数据库，DB(i) 会创建尚未使用的数据库
+		var dbs []*storage.ShardedMap
+		h.dbs.Each(func(index int, sm *storage.ShardedMap) {
+			dbs = append(dbs, sm)
+		})
+		unlock = storage.LockDatabases(dbs)
+	} else {
+		h.txMu.RLock()
+		defer h.txMu.RUnlock()
+
+		unlock = storage.LockKeys(h.transactionKeys(c, tx, watched))
+	}
+	defer unlock()
+
+	for _, w := range watched {
+		if h.dbs.DB(w.db).Version(w.key) != w.version {
+			return &resp.Value{
+				Type: resp.Array,
+				Null: true,
+			}
+		}
+	}
+
+	replies := make([]resp.Value, len(tx.commands))
+	for i, q := range tx.commands {
+		replies[i] = *q.cmd.Handler(c, q.argv[1:])
+	}
+
+	return &resp.Value{
+		Type:  resp.Array,
+		Array: replies,
+	}
+}
+
+// transactionKeys 收集事务需要锁定的键
+//
+// 排队的 SELECT 会改变之后命令使用的逻辑数据库，这里按顺序模拟，
+// 使每个键都在它实际所在的数据库中加锁。
+func (h *CommandHandler) transactionKeys(c *Client, tx *transaction, watched []watchedKey) []storage.KeyRef {
+	var refs []storage.KeyRef
+	for _, w := range watched {
+		refs = append(refs, storage.KeyRef{DB: h.dbs.DB(w.db), Key: w.key})
+	}
+
+	db := c.DB
+	for _, q := range tx.commands {
+		if q.cmd.Name == "select" {
+			if index, err := strconv.Atoi(string(q.argv[1])); err == nil && index >= 0 && index < h.dbs.Count() {
+				db = index
+			}
+			continue
+		}
+		for _, key := range q.cmd.Keys(q.argv) {
+			refs = append(refs, storage.KeyRef{DB: h.dbs.DB(db), Key: string(key)})
+		}
+	}
+
+	return refs
+}
+
+// handleDiscard 处理 DISCARD 命令
+//
+// 格式：DISCARD
+// 返回：+OK，放弃排队的命令并取消全部 WATCH
+func (h *CommandHandler) handleDiscard(c *Client, args [][]byte) *resp.Value {
+	if c.multi == nil {
+		return &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR DISCARD 之前必须先执行 MULTI",
+		}
+	}
+
+	c.multi, c.watched = nil, nil
+
+	return &resp.Value{
+		Type: resp.SimpleString,
+		Str:  "OK",
+	}
+}
+
+// handleWatch 处理 WATCH 命令
+//
+// 格式：WATCH key [key ...]
+// 返回：+OK
+// 注意：EXEC 时任意一个键被修改（包括删除、过期、EXPIRE）则事务不执行
+func (h *CommandHandler) handleWatch(c *Client, args [][]byte) *resp.Value {
+	if c.multi != nil {
+		return &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR WATCH 不能在 MULTI 中使用",
+		}
+	}
+
+	db := h.db(c)
+	for _, key := range args {
+		c.watched = append(c.watched, watchedKey{
+			db:      c.DB,
+			key:     string(key),
+			version: db.Version(string(key)),
+		})
+	}
+
+	return &resp.Value{
+		Type: resp.SimpleString,
+		Str:  "OK",
+	}
+}
+
+// handleUnwatch 处理 UNWATCH 命令
+//
+// 格式：UNWATCH
+// 返回：+OK
+func (h *CommandHandler) handleUnwatch(c *Client, args [][]byte) *resp.Value {
+	c.watched = nil
+
+	return &resp.Value{
+		Type: resp.SimpleString,
+		Str:  "OK",
+	}
+}
@@ -0,0 +1,417 @@
+package tcp
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yndnr/tokenginx/internal/storage"
+	"github.com/yndnr/tokenginx/internal/transport/resp"
+)
+
+// TestCommandHandler_MultiExec 测试 MULTI / EXEC 排队和执行
+func TestCommandHandler_MultiExec(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	handler := NewCommandHandler(sm)
+	c := newClient(1, "")
+
+	response := handler.HandleClientCommand(c, newCommand("MULTI"))
+	if response.Type != resp.SimpleString || response.Str != "OK" {
+		t.Fatalf("Expected +OK, got %+v", response)
+	}
+
+	for _, args := range [][]string{
+		{"SET", "a", "1"},
+		{"INCR", "a"},
+		{"GET", "a"},
+		{"INCR", "missing-value-type"},
+	} {
+		response := handler.HandleClientCommand(c, newCommand(args...))
+		if response.Type != resp.SimpleString || response.Str != "QUEUED" {
+			t.Fatalf("%v: expected +QUEUED, got %+v", args, response)
+		}
+	}
+	if sm.Exists("a") {
+		t.Fatal("Expected queued commands not to run before EXEC")
+	}
+
+	response = handler.HandleClientCommand(c, newCommand("EXEC"))
+	if response.Type != resp.Array || len(response.Array) != 4 {
+		t.Fatalf("Expected 4 replies, got %+v", response)
+	}
+	if response.Array[1].Int != 2 || string(response.Array[2].Bulk) != "2" {
+		t.Errorf("Unexpected EXEC replies: %+v", response.Array)
+	}
+	if c.multi != nil {
+		t.Error("Expected transaction state to be cleared after EXEC")
+	}
+
+	response = handler.HandleClientCommand(c, newCommand("EXEC"))
+	if response.Type != resp.Error {
+		t.Errorf("Expected error for EXEC without MULTI, got %+v", response)
+	}
+}
+
+// TestCommandHandler_MultiErrors 测试事务中的错误处理
+func TestCommandHandler_MultiErrors(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	handler := NewCommandHandler(sm)
+	c := newClient(1, "")
+
+	// 嵌套 MULTI 返回错误但不放弃事务
+	handler.HandleClientCommand(c, newCommand("MULTI"))
+	if response := handler.HandleClientCommand(c, newCommand("MULTI")); response.Type != resp.Error {
+		t.Errorf("Expected error for nested MULTI, got %+v", response)
+	}
+	if response := handler.HandleClientCommand(c, newCommand("WATCH", "a")); response.Type != resp.Error {
+		t.Errorf("Expected error for WATCH inside MULTI, got %+v", response)
+	}
+	handler.HandleClientCommand(c, newCommand("SET", "a", "1"))
+	response := handler.HandleClientCommand(c, newCommand("EXEC"))
+	if response.Type != resp.Array || len(response.Array) != 1 {
+		t.Errorf("Expected transaction to run, got %+v", response)
+	}
+
+	// 排队时的错误使 EXEC 放弃整个事务
+	handler.HandleClientCommand(c, newCommand("MULTI"))
+	handler.HandleClientCommand(c, newCommand("SET", "b", "1"))
+	if response := handler.HandleClientCommand(c, newCommand("GET")); response.Type != resp.Error {
+		t.Errorf("Expected arity error, got %+v", response)
+	}
+	if response := handler.HandleClientCommand(c, newCommand("NOSUCHCMD")); response.Type != resp.Error {
+		t.Errorf("Expected unknown command error, got %+v", response)
+	}
+	response = handler.HandleClientCommand(c, newCommand("EXEC"))
+	if response.Type != resp.Error || response.Str[:9] != "EXECABORT" {
+		t.Errorf("Expected EXECABORT, got %+v", response)
+	}
+	if sm.Exists("b") {
+		t.Error("Expected aborted transaction not to run")
+	}
+
+	// DISCARD
+	handler.HandleClientCommand(c, newCommand("MULTI"))
+	handler.HandleClientCommand(c, newCommand("SET", "c", "1"))
+	if response := handler.HandleClientCommand(c, newCommand("DISCARD")); response.Str != "OK" {
+		t.Errorf("Expected +OK, got %+v", response)
+	}
+	if sm.Exists("c") || c.multi != nil {
+		t.Error("Expected DISCARD to drop queued commands")
+	}
+	if response := handler.HandleClientCommand(c, newCommand("DISCARD")); response.Type != resp.Error {
+		t.Errorf("Expected error for DISCARD without MULTI, got %+v", response)
+	}
+}
+
+// TestCommandHandler_Watch 测试 WATCH 乐观锁
+func TestCommandHandler_Watch(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	handler := NewCommandHandler(sm)
+	c := newClient(1, "")
+	other := newClient(2, "")
+
+	tests := []struct {
+		name    string
+		prepare func()
+		touch   []string // 其他连接在 WATCH 之后执行的命令，nil 表示不修改
+		aborted bool
+	}{
+		{"unchanged", func() { sm.Set("k", "1", 0) }, nil, false},
+		{"modified", func() { sm.Set("k", "1", 0) }, []string{"SET", "k", "2"}, true},
+		{"same value", func() { sm.Set("k", "1", 0) }, []string{"SET", "k", "1"}, true},
+		{"deleted", func() { sm.Set("k", "1", 0) }, []string{"DEL", "k"}, true},
+		{"expire", func() { sm.Set("k", "1", 0) }, []string{"EXPIRE", "k", "100"}, true},
+		{"created", func() { sm.Delete("k") }, []string{"SET", "k", "1"}, true},
+		{"other key", func() { sm.Set("k", "1", 0) }, []string{"SET", "other", "1"}, false},
+		{"mset", func() { sm.Set("k", "1", 0) }, []string{"MSET", "k", "1"}, true},
+	}
+
+	for _, tt := range tests {
+		tt.prepare()
+		handler.HandleClientCommand(c, newCommand("WATCH", "k"))
+		if tt.touch != nil {
+			handler.HandleClientCommand(other, newCommand(tt.touch...))
+		}
+		handler.HandleClientCommand(c, newCommand("MULTI"))
+		handler.HandleClientCommand(c, newCommand("SET", "result", tt.name))
+		response := handler.HandleClientCommand(c, newCommand("EXEC"))
+
+		if tt.aborted {
+			if response.Type != resp.Array || !response.Null {
+				t.Errorf("%s: expected Null Array, got %+v", tt.name, response)
+			}
+		} else if response.Type != resp.Array || len(response.Array) != 1 {
+			t.Errorf("%s: expected transaction to run, got %+v", tt.name, response)
+		}
+		if len(c.watched) != 0 {
+			t.Errorf("%s: expected EXEC to clear watched keys", tt.name)
+		}
+	}
+
+	// UNWATCH 之后修改键不影响事务
+	sm.Set("k", "1", 0)
+	handler.HandleClientCommand(c, newCommand("WATCH", "k"))
+	handler.HandleClientCommand(c, newCommand("UNWATCH"))
+	handler.HandleClientCommand(other, newCommand("SET", "k", "2"))
+	handler.HandleClientCommand(c, newCommand("MULTI"))
+	handler.HandleClientCommand(c, newCommand("GET", "k"))
+	response := handler.HandleClientCommand(c, newCommand("EXEC"))
+	if response.Type != resp.Array || len(response.Array) != 1 {
+		t.Errorf("Expected transaction to run after UNWATCH, got %+v", response)
+	}
+}
+
+// TestCommandHandler_MultiSelect 测试事务中的 SELECT
+func TestCommandHandler_MultiSelect(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	handler := NewCommandHandler(sm)
+	c := newClient(1, "")
+
+	handler.HandleClientCommand(c, newCommand("MULTI"))
+	handler.HandleClientCommand(c, newCommand("SET", "a", "0"))
+	handler.HandleClientCommand(c, newCommand("SELECT", "1"))
+	handler.HandleClientCommand(c, newCommand("SET", "a", "1"))
+	handler.HandleClientCommand(c, newCommand("EXEC"))
+
+	if value, _ := sm.Get("a"); value == nil || string(value.([]byte)) != "0" {
+		t.Errorf("Expected '0' in db0, got %v", value)
+	}
+	if c.DB != 1 {
+		t.Errorf("Expected SELECT in transaction to change DB, got %d", c.DB)
+	}
+	if response := handler.HandleClientCommand(c, newCommand("GET", "a")); string(response.Bulk) != "1" {
+		t.Errorf("Expected '1' in db1, got %+v", response)
+	}
+}
+
+// TestCommandHandler_ExecAtomic 测试 EXEC 执行期间其他连接看不到中间状态
+func TestCommandHandler_ExecAtomic(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	handler := NewCommandHandler(sm)
+	sm.Set("from", int64(10000), 0)
+	sm.Set("to", int64(0), 0)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(id int64) {
+			defer wg.Done()
+			c := newClient(id, "")
+			for i := 0; i < 500; i++ {
+				handler.HandleClientCommand(c, newCommand("MULTI"))
+				handler.HandleClientCommand(c, newCommand("DECR", "from"))
+				handler.HandleClientCommand(c, newCommand("INCR", "to"))
+				handler.HandleClientCommand(c, newCommand("EXEC"))
+			}
+		}(int64(g))
+	}
+
+	// 读取方用 MGET 同时读取两个键，总和必须始终为 10000
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	readers.Add(1)
+	go func() {
+		defer readers.Done()
+		c := newClient(100, "")
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			response := handler.HandleClientCommand(c, newCommand("MGET", "from", "to"))
+			from, _ := strconv.Atoi(string(response.Array[0].Bulk))
+			to, _ := strconv.Atoi(string(response.Array[1].Bulk))
+			if from+to != 10000 {
+				t.Errorf("Observed partial transaction: from=%d to=%d", from, to)
+				return
+			}
+		}
+	}()
+
+	wg.Wait()
+	close(done)
+	readers.Wait()
+
+	if value, _ := sm.Get("to"); value != int64(2000) {
+		t.Errorf("Expected to=800, got %v", value)
+	}
+}
+
+// TestCommandHandler_WaitsForTransaction 测试普通命令等待持有同一分片事务锁的 EXEC
+func TestCommandHandler_WaitsForTransaction(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	handler := NewCommandHandler(sm)
+
+	// 模拟正在执行的 EXEC
+	unlock := storage.LockKeys([]storage.KeyRef{{DB: sm, Key: "a"}})
+
+	done := make(chan struct{})
+	go func() {
+		handler.HandleCommand(newCommand("SET", "a", "1"))
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("Expected SET to wait for the transaction lock")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// 不涉及该分片的命令不受影响
+	if response := handler.HandleCommand(newCommand("PING")); response.Str != "PONG" {
+		t.Errorf("Expected PONG, got %+v", response)
+	}
+
+	unlock()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected SET to run after the transaction finished")
+	}
+}
+
+// waitsFor 检查 fn 在 release 调用之前是否被阻塞，并在 release 之后完成
+func waitsFor(t *testing.T, name string, fn func(), release func()) {
+	t.Helper()
+
+	done := make(chan struct{})
+	go func() {
+		fn()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatalf("%s: expected to wait for the running transaction", name)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("%s: expected to run after the transaction finished", name)
+	}
+}
+
+// TestCommandHandler_DatabaseWideWaitsForTransaction 测试作用于整个数据库的命令等待正在执行的事务
+func TestCommandHandler_DatabaseWideWaitsForTransaction(t *testing.T) {
+	// 修改整个数据库的命令等待数据库级事务锁
+	for _, args := range [][]string{
+		{"FLUSHDB"}, {"FLUSHALL"}, {"SWAPDB", "0", "1"}, {"MOVE", "a", "1"},
+	} {
+		handler := NewCommandHandler(storage.NewShardedMap(1024))
+
+		// 模拟正在执行的 EXEC
+		handler.txMu.RLock()
+		waitsFor(t, fmt.Sprint(args), func() {
+			handler.HandleCommand(newCommand(args...))
+		}, handler.txMu.RUnlock)
+	}
+
+	// 读取整个数据库的命令等待事务锁定的分片
+	for _, args := range [][]string{{"KEYS", "*"}, {"SCAN", "0"}, {"DBSIZE"}} {
+		handler := NewCommandHandler(storage.NewShardedMap(1024))
+
+		handler.txMu.RLock()
+		unlock := storage.LockKeys([]storage.KeyRef{{DB: handler.dbs.DB(0), Key: "a"}})
+		waitsFor(t, fmt.Sprint(args), func() {
+			handler.HandleCommand(newCommand(args...))
+		}, func() {
+			unlock()
+			handler.txMu.RUnlock()
+		})
+	}
+}
+
+// TestCommandHandler_DatabaseReadsRunConcurrently 测试 KEYS、SCAN、DBSIZE 不独占数据库级事务锁
+func TestCommandHandler_DatabaseReadsRunConcurrently(t *testing.T) {
+	handler := NewCommandHandler(storage.NewShardedMap(1024))
+	handler.HandleCommand(newCommand("SET", "a", "1"))
+
+	// 模拟另一个连接正在执行的 KEYS 和不涉及同一分片的 EXEC
+	handler.txMu.RLock()
+	defer handler.txMu.RUnlock()
+	unlock := storage.RLockDatabases([]*storage.ShardedMap{handler.dbs.DB(0)})
+	defer unlock()
+
+	for _, args := range [][]string{{"KEYS", "*"}, {"SCAN", "0"}, {"DBSIZE"}} {
+		done := make(chan struct{})
+		go func() {
+			handler.HandleCommand(newCommand(args...))
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("%v: expected not to wait for other readers", args)
+		}
+	}
+}
+
+// TestCommandHandler_ExecWithFlushDB 测试包含 FLUSHDB 的事务锁定整个数据库
+func TestCommandHandler_ExecWithFlushDB(t *testing.T) {
+	handler := NewCommandHandler(storage.NewShardedMap(1024))
+
+	c := newClient(1, "")
+	handler.HandleClientCommand(c, newCommand("MULTI"))
+	handler.HandleClientCommand(c, newCommand("MSET", "a", "1", "b", "1"))
+	handler.HandleClientCommand(c, newCommand("FLUSHDB"))
+	if !c.multi.databaseWide() {
+		t.Fatal("Expected transaction with FLUSHDB to be database-wide")
+	}
+
+	// 模拟另一个连接正在执行的 GET：EXEC 要等它结束才能锁定全部分片
+	unlock := handler.dbs.DB(0).RLockKeys([]string{"zzz"})
+
+	done := make(chan *resp.Value)
+	go func() {
+		done <- handler.HandleClientCommand(c, newCommand("EXEC"))
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("Expected EXEC to wait for every shard of the database")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unlock()
+	select {
+	case response := <-done:
+		if response.Type != resp.Array || len(response.Array) != 2 {
+			t.Errorf("Expected 2 replies, got %+v", response)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected EXEC to run after the other command finished")
+	}
+
+	if n := handler.HandleCommand(newCommand("DBSIZE")).Int; n != 0 {
+		t.Errorf("Expected empty database, got %d keys", n)
+	}
+}
+
+// TestCommandHandler_ExecLocksExistingDatabases 测试包含 FLUSHALL 的事务不会创建尚未使用的数据库
+func TestCommandHandler_ExecLocksExistingDatabases(t *testing.T) {
+	handler := NewCommandHandler(storage.NewShardedMap(1024))
+	handler.SetDatabases(storage.NewDatabases(storage.NewShardedMap(1024), 16))
+
+	c := newClient(1, "")
+	handler.HandleClientCommand(c, newCommand("MULTI"))
+	handler.HandleClientCommand(c, newCommand("FLUSHALL"))
+	response := handler.HandleClientCommand(c, newCommand("EXEC"))
+	if response.Type != resp.Array || len(response.Array) != 1 {
+		t.Fatalf("Expected 1 reply, got %+v", response)
+	}
+
+	created := 0
+	handler.dbs.Each(func(index int, sm *storage.ShardedMap) {
+		created++
+	})
+	if created != 1 {
+		t.Errorf("Expected only database 0 to exist, got %d", created)
+	}
+}
@@ -0,0 +1,295 @@
+package tcp
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/yndnr/tokenginx/internal/security/antireplay"
+	"github.com/yndnr/tokenginx/internal/transport/resp"
+)
+
+// SignatureMode RESP 请求的签名方式
+type SignatureMode int
+
+const (
+	// SignatureHandshake 连接握手时签名一次
+	//
+	// 客户端连接后先发送 AUTH.SIGN client_id timestamp nonce signature，
+	// 验证通过后该连接上的后续命令无需再签名。
+	SignatureHandshake SignatureMode = iota
+
+	// SignaturePerCommand 每条命令都需要签名
+	//
+	// 客户端将命令包装为 SIGNED client_id timestamp nonce signature command [arg ...]，
+	// 签名覆盖被包装命令的全部参数。
+	SignaturePerCommand
+)
+
+// ParseSignatureMode 解析配置文件中的签名方式（handshake | command）
+func ParseSignatureMode(s string) (SignatureMode, error) {
+	switch strings.ToLower(s) {
+	case "", "handshake":
+		return SignatureHandshake, nil
+	case "command":
+		return SignaturePerCommand, nil
+	default:
+		return 0, fmt.Errorf("未知的签名方式: %s", s)
+	}
+}
+
+// String 返回签名方式的配置名称
+func (m SignatureMode) String() string {
+	if m == SignaturePerCommand {
+		return "command"
+	}
+	return "handshake"
+}
+
+// authorize 在命令交给 CommandHandler 之前执行签名验证和序列号校验
+//
+// 返回值：
+//   - *resp.Value: 需要执行的命令（SIGNED / SEQ 包装已被去除），为 nil 时不执行命令
+//   - *resp.Value: 直接返回给客户端的响应（握手结果或验证错误），为 nil 时执行命令
+//
+// 注意事项：
+//   - 未启用签名验证时，AUTH.SIGN 和 SIGNED 命令返回错误，其他命令原样放行
+//   - PING 不修改任何数据，始终无需签名，便于健康检查
+//   - HELLO 只协商协议版本，始终无需签名，RESP3 客户端连接后会先于 AUTH.SIGN 发送
+func (s *Server) authorize(c *Client, value *resp.Value) (*resp.Value, *resp.Value) {
+	args, ok := commandArgs(value)
+	if !ok {
+		// 格式错误交给 CommandHandler 统一报告
+		return value, nil
+	}
+
+	switch strings.ToUpper(string(args[0])) {
+	case "AUTH.SIGN":
+		return nil, s.handleAuthSign(c, args[1:])
+	case "SIGNED":
+		inner, response := s.handleSigned(c, args[1:])
+		if response != nil {
+			return nil, response
+		}
+		return s.checkSequence(c, inner)
+	case "PING", "HELLO":
+		return value, nil
+	}
+
+	if s.verifier != nil && (s.signatureMode != SignatureHandshake || c.Identity == "") {
+		return nil, &resp.Value{
+			Type: resp.Error,
+			Str:  "SIGNATURE_REQUIRED 请求需要签名",
+		}
+	}
+
+	return s.checkSequence(c, args)
+}
+
+// handleAuthSign 处理 AUTH.SIGN 握手命令
+//
+// 格式：AUTH.SIGN client_id timestamp nonce signature
+// 签名字符串：client_id + "\n" + timestamp + "\n" + nonce + "\n"
+// 返回：+OK 或签名错误
+func (s *Server) handleAuthSign(c *Client, args [][]byte) *resp.Value {
+	if s.verifier == nil {
+		return &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR 签名验证未启用",
+		}
+	}
+
+	if len(args) != 4 {
+		return &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR AUTH.SIGN 命令需要 4 个参数",
+		}
+	}
+
+	req := &antireplay.SignedRequest{
+		ClientID:  string(args[0]),
+		Timestamp: string(args[1]),
+		Nonce:     string(args[2]),
+		Signature: string(args[3]),
+	}
+	if err := s.verifier.Verify(req); err != nil {
+		return s.signatureErrorReply(c, req.ClientID, err)
+	}
+
+	c.Identity = req.ClientID
+
+	return &resp.Value{
+		Type: resp.SimpleString,
+		Str:  "OK",
+	}
+}
+
+// handleSigned 处理 SIGNED 包装命令
+//
+// 格式：SIGNED client_id timestamp nonce signature command [arg ...]
+// 签名字符串：client_id + "\n" + timestamp + "\n" + nonce + "\n" + RESP(command [arg ...])
+// 返回：验证通过后返回被包装的命令参数
+func (s *Server) handleSigned(c *Client, args [][]byte) ([][]byte, *resp.Value) {
+	if s.verifier == nil {
+		return nil, &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR 签名验证未启用",
+		}
+	}
+
+	if len(args) < 5 {
+		return nil, &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR SIGNED 命令至少需要 5 个参数",
+		}
+	}
+
+	inner := args[4:]
+	req := &antireplay.SignedRequest{
+		ClientID:  string(args[0]),
+		Timestamp: string(args[1]),
+		Nonce:     string(args[2]),
+		Signature: string(args[3]),
+		Payload:   antireplay.CommandPayload(inner),
+	}
+	if err := s.verifier.Verify(req); err != nil {
+		return nil, s.signatureErrorReply(c, req.ClientID, err)
+	}
+
+	c.Identity = req.ClientID
+
+	return inner, nil
+}
+
+// checkSequence 处理 SEQ 包装命令并校验序列号
+//
+// 格式：SEQ sequence command [arg ...]
+// 返回：校验通过后返回被包装的命令；未使用 SEQ 包装时原样返回命令
+//
+// 注意事项：
+//   - 序列号按已认证的客户端 ID 跟踪，因此 SEQ 需要先通过签名认证
+//   - 启用 enable_sequence 后，除 PING 外的所有命令都必须使用 SEQ 包装
+func (s *Server) checkSequence(c *Client, args [][]byte) (*resp.Value, *resp.Value) {
+	name := strings.ToUpper(string(args[0]))
+	if name != "SEQ" {
+		if s.requireSequence && name != "PING" {
+			return nil, &resp.Value{
+				Type: resp.Error,
+				Str:  "SEQUENCE_REQUIRED 请求需要序列号",
+			}
+		}
+		return argsCommand(args), nil
+	}
+
+	if s.sequences == nil {
+		return nil, &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR 序列号验证未启用",
+		}
+	}
+
+	if len(args) < 3 {
+		return nil, &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR SEQ 命令至少需要 2 个参数",
+		}
+	}
+
+	if c.Identity == "" {
+		return nil, &resp.Value{
+			Type: resp.Error,
+			Str:  "SIGNATURE_REQUIRED 序列号需要先通过签名认证",
+		}
+	}
+
+	seq, err := strconv.ParseUint(string(args[1]), 10, 64)
+	if err != nil {
+		seq = 0
+	}
+
+	if err := s.sequences.Check(c.Identity, seq); err != nil {
+		log.Printf("[WARN] 序列号校验失败 (%s, client_id=%q, seq=%s): %v", c.Addr, c.Identity, args[1], err)
+
+		var msg string
+		switch {
+		case errors.Is(err, antireplay.ErrSequenceStale):
+			msg = "SEQUENCE_STALE 序列号已过期"
+		case errors.Is(err, antireplay.ErrSequenceDuplicate):
+			msg = "SEQUENCE_DUPLICATE 序列号已被使用"
+		default:
+			msg = "INVALID_SEQUENCE 序列号必须是正整数"
+		}
+
+		return nil, &resp.Value{
+			Type: resp.Error,
+			Str:  msg,
+		}
+	}
+
+	return argsCommand(args[2:]), nil
+}
+
+// signatureErrorReply 将签名验证错误转换为带错误码的 RESP 错误
+//
+// 错误码与 HTTP 接口的 error 字段保持一致（大写）。
+func (s *Server) signatureErrorReply(c *Client, clientID string, err error) *resp.Value {
+	log.Printf("[WARN] 签名验证失败 (%s, client_id=%q): %v", c.Addr, clientID, err)
+
+	var msg string
+	switch {
+	case errors.Is(err, antireplay.ErrUnknownClient):
+		msg = "UNKNOWN_CLIENT 未知的客户端"
+	case errors.Is(err, antireplay.ErrInvalidTimestamp):
+		msg = "INVALID_TIMESTAMP 时间戳格式无效"
+	case errors.Is(err, antireplay.ErrTimestampExpired):
+		msg = "TIMESTAMP_EXPIRED 请求时间戳超出允许的时间窗口"
+	case errors.Is(err, antireplay.ErrInvalidSignature):
+		msg = "INVALID_SIGNATURE 签名验证失败"
+	case errors.Is(err, antireplay.ErrNonceReused):
+		msg = "NONCE_REUSED Nonce 已被使用"
+	case errors.Is(err, antireplay.ErrNonceCacheFull):
+		msg = "NONCE_CACHE_FULL Nonce 缓存已满"
+	default:
+		msg = fmt.Sprintf("INVALID_NONCE %v", err)
+	}
+
+	return &resp.Value{
+		Type: resp.Error,
+		Str:  msg,
+	}
+}
+
+// commandArgs 提取命令的全部参数（包括命令名）
+//
+// 返回值：
+//   - [][]byte: 参数列表
+//   - bool: 命令是否为非空的 Bulk String 数组
+func commandArgs(value *resp.Value) ([][]byte, bool) {
+	if value.Type != resp.Array || len(value.Array) == 0 {
+		return nil, false
+	}
+
+	args := make([][]byte, len(value.Array))
+	for i, v := range value.Array {
+		if v.Type != resp.BulkString {
+			return nil, false
+		}
+		args[i] = v.Bulk
+	}
+
+	return args, true
+}
+
+// argsCommand 将参数列表构建为 RESP 命令
+func argsCommand(args [][]byte) *resp.Value {
+	command := &resp.Value{
+		Type:  resp.Array,
+		Array: make([]resp.Value, len(args)),
+	}
+	for i, arg := range args {
+		command.Array[i] = resp.Value{Type: resp.BulkString, Bulk: arg}
+	}
+	return command
+}
@@ -0,0 +1,241 @@
+package tcp
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yndnr/tokenginx/internal/security/antireplay"
+	"github.com/yndnr/tokenginx/internal/storage"
+	"github.com/yndnr/tokenginx/internal/transport/resp"
+)
+
+// newSignedServer 创建启用签名验证的测试服务器（不启动监听）
+func newSignedServer(t *testing.T, mode SignatureMode) *Server {
+	verifier, err := antireplay.NewVerifier(&antireplay.VerifierConfig{
+		Window:  5 * time.Minute,
+		Clients: []antireplay.ClientKey{{ClientID: "app1", Secret: []byte("secret")}},
+	}, antireplay.NewNonceStore(nil))
+	if err != nil {
+		t.Fatalf("Failed to create verifier: %v", err)
+	}
+
+	server := NewServer(":0", storage.NewShardedMap(1024))
+	server.SetVerifier(verifier, mode)
+	return server
+}
+
+// dispatch 模拟 handleConnection 中的验证和处理流程
+func dispatch(s *Server, c *Client, cmd *resp.Value) *resp.Value {
+	command, response := s.authorize(c, cmd)
+	if command != nil {
+		response = s.handler.HandleCommand(command)
+	}
+	return response
+}
+
+// signArgs 构造签名参数（client_id timestamp nonce signature）
+func signArgs(t *testing.T, secret, nonce string, payload []byte) []string {
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	sig, err := antireplay.Sign(antireplay.AlgorithmHMACSHA256, []byte(secret), "app1", ts, nonce, payload)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	return []string{"app1", ts, nonce, sig}
+}
+
+// TestAuthorize_Disabled 测试未启用签名验证时命令原样放行
+func TestAuthorize_Disabled(t *testing.T) {
+	server := NewServer(":0", storage.NewShardedMap(1024))
+	client := newClient(1, "test")
+
+	response := dispatch(server, client, newCommand("SET", "k", "v"))
+	if response.Type != resp.SimpleString || response.Str != "OK" {
+		t.Errorf("Expected OK, got %v", response)
+	}
+
+	response = dispatch(server, client, newCommand("AUTH.SIGN", "a", "b", "c", "d"))
+	if response.Type != resp.Error {
+		t.Errorf("Expected error when signature disabled, got %v", response)
+	}
+}
+
+// TestAuthorize_Handshake 测试握手签名
+func TestAuthorize_Handshake(t *testing.T) {
+	server := newSignedServer(t, SignatureHandshake)
+	client := newClient(1, "test")
+
+	// 未认证时拒绝普通命令，PING 除外
+	response := dispatch(server, client, newCommand("GET", "k"))
+	if response.Type != resp.Error || !strings.HasPrefix(response.Str, "SIGNATURE_REQUIRED") {
+		t.Errorf("Expected SIGNATURE_REQUIRED, got %v", response)
+	}
+	response = dispatch(server, client, newCommand("PING"))
+	if response.Type != resp.SimpleString || response.Str != "PONG" {
+		t.Errorf("Expected PONG, got %v", response)
+	}
+
+	// 密钥错误
+	args := append([]string{"AUTH.SIGN"}, signArgs(t, "wrong", "n1", nil)...)
+	response = dispatch(server, client, newCommand(args...))
+	if response.Type != resp.Error || !strings.HasPrefix(response.Str, "INVALID_SIGNATURE") {
+		t.Errorf("Expected INVALID_SIGNATURE, got %v", response)
+	}
+
+	// 握手成功
+	args = append([]string{"AUTH.SIGN"}, signArgs(t, "secret", "n2", nil)...)
+	response = dispatch(server, client, newCommand(args...))
+	if response.Type != resp.SimpleString || response.Str != "OK" {
+		t.Fatalf("Expected OK, got %v", response)
+	}
+	if client.Identity != "app1" {
+		t.Errorf("Expected identity app1, got %q", client.Identity)
+	}
+
+	// 重放握手
+	response = dispatch(server, client, newCommand(args...))
+	if response.Type != resp.Error || !strings.HasPrefix(response.Str, "NONCE_REUSED") {
+		t.Errorf("Expected NONCE_REUSED, got %v", response)
+	}
+
+	// 认证后普通命令放行
+	response = dispatch(server, client, newCommand("SET", "k", "v"))
+	if response.Type != resp.SimpleString || response.Str != "OK" {
+		t.Errorf("Expected OK after handshake, got %v", response)
+	}
+}
+
+// TestAuthorize_PerCommand 测试逐条命令签名
+func TestAuthorize_PerCommand(t *testing.T) {
+	server := newSignedServer(t, SignaturePerCommand)
+	client := newClient(1, "test")
+
+	inner := [][]byte{[]byte("SET"), []byte("k"), []byte("v")}
+	args := append([]string{"SIGNED"}, signArgs(t, "secret", "n1", antireplay.CommandPayload(inner))...)
+	args = append(args, "SET", "k", "v")
+
+	response := dispatch(server, client, newCommand(args...))
+	if response.Type != resp.SimpleString || response.Str != "OK" {
+		t.Fatalf("Expected OK, got %v", response)
+	}
+
+	// 逐条签名模式下，即使已认证，未包装的命令也会被拒绝
+	response = dispatch(server, client, newCommand("GET", "k"))
+	if response.Type != resp.Error || !strings.HasPrefix(response.Str, "SIGNATURE_REQUIRED") {
+		t.Errorf("Expected SIGNATURE_REQUIRED, got %v", response)
+	}
+
+	// 篡改被包装的命令
+	args[len(args)-1] = "evil"
+	args[3] = "n2"
+	response = dispatch(server, client, newCommand(args...))
+	if response.Type != resp.Error || !strings.HasPrefix(response.Str, "INVALID_SIGNATURE") {
+		t.Errorf("Expected INVALID_SIGNATURE, got %v", response)
+	}
+
+	// 过期的时间戳
+	old := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	sig, _ := antireplay.Sign(antireplay.AlgorithmHMACSHA256, []byte("secret"), "app1", old, "n3", antireplay.CommandPayload(inner))
+	response = dispatch(server, client, newCommand("SIGNED", "app1", old, "n3", sig, "SET", "k", "v"))
+	if response.Type != resp.Error || !strings.HasPrefix(response.Str, "TIMESTAMP_EXPIRED") {
+		t.Errorf("Expected TIMESTAMP_EXPIRED, got %v", response)
+	}
+
+	// 未知客户端
+	response = dispatch(server, client, newCommand("SIGNED", "app9", old, "n4", sig, "SET", "k", "v"))
+	if response.Type != resp.Error || !strings.HasPrefix(response.Str, "UNKNOWN_CLIENT") {
+		t.Errorf("Expected UNKNOWN_CLIENT, got %v", response)
+	}
+}
+
+// TestAuthorize_NonceCheckIsolated 测试 NONCE.CHECK 不影响签名请求的 Nonce 校验
+func TestAuthorize_NonceCheckIsolated(t *testing.T) {
+	server := newSignedServer(t, SignaturePerCommand)
+	// 与 cmd/server 相同，NONCE.CHECK 使用独立的缓存
+	server.SetNonceStore(antireplay.NewNonceStore(&antireplay.NonceStoreConfig{MaxSize: 1}))
+	client := newClient(1, "test")
+
+	// 抢先写入签名 Nonce 可能使用的键，并写满 NONCE.CHECK 的缓存
+	for _, nonce := range []string{"app1:n1", "4:app1n1", "n1", "fill-1", "fill-2"} {
+		response := dispatch(server, client, newCommand("NONCE.CHECK", nonce))
+		if response.Type == resp.Integer && response.Int == 0 {
+			t.Fatalf("NONCE.CHECK %s: expected first use", nonce)
+		}
+	}
+
+	inner := [][]byte{[]byte("SET"), []byte("k"), []byte("v")}
+	args := append([]string{"SIGNED"}, signArgs(t, "secret", "n1", antireplay.CommandPayload(inner))...)
+	args = append(args, "SET", "k", "v")
+
+	response := dispatch(server, client, newCommand(args...))
+	if response.Type != resp.SimpleString || response.Str != "OK" {
+		t.Errorf("Expected OK, got %v", response)
+	}
+}
+
+// TestParseSignatureMode 测试签名方式解析
+func TestParseSignatureMode(t *testing.T) {
+	if mode, err := ParseSignatureMode("command"); err != nil || mode != SignaturePerCommand {
+		t.Errorf("Expected SignaturePerCommand, got %v, %v", mode, err)
+	}
+	if mode, err := ParseSignatureMode(""); err != nil || mode != SignatureHandshake {
+		t.Errorf("Expected SignatureHandshake, got %v, %v", mode, err)
+	}
+	if _, err := ParseSignatureMode("never"); err == nil {
+		t.Error("Expected error for unknown mode")
+	}
+}
+
+// TestAuthorize_Sequence 测试序列号校验
+func TestAuthorize_Sequence(t *testing.T) {
+	server := newSignedServer(t, SignatureHandshake)
+	server.SetSequenceTracker(antireplay.NewSequenceTracker(nil), true)
+	client := newClient(1, "test")
+
+	// 未认证时不能使用序列号
+	response := dispatch(server, client, newCommand("SEQ", "1", "SET", "k", "v"))
+	if response.Type != resp.Error || !strings.HasPrefix(response.Str, "SIGNATURE_REQUIRED") {
+		t.Errorf("Expected SIGNATURE_REQUIRED, got %v", response)
+	}
+
+	args := append([]string{"AUTH.SIGN"}, signArgs(t, "secret", "n1", nil)...)
+	if response = dispatch(server, client, newCommand(args...)); response.Str != "OK" {
+		t.Fatalf("Expected OK, got %v", response)
+	}
+
+	// 启用后必须携带序列号
+	response = dispatch(server, client, newCommand("SET", "k", "v"))
+	if response.Type != resp.Error || !strings.HasPrefix(response.Str, "SEQUENCE_REQUIRED") {
+		t.Errorf("Expected SEQUENCE_REQUIRED, got %v", response)
+	}
+
+	response = dispatch(server, client, newCommand("SEQ", "20", "SET", "k", "v"))
+	if response.Type != resp.SimpleString || response.Str != "OK" {
+		t.Errorf("Expected OK, got %v", response)
+	}
+
+	response = dispatch(server, client, newCommand("SEQ", "20", "SET", "k", "v"))
+	if response.Type != resp.Error || !strings.HasPrefix(response.Str, "SEQUENCE_DUPLICATE") {
+		t.Errorf("Expected SEQUENCE_DUPLICATE, got %v", response)
+	}
+
+	response = dispatch(server, client, newCommand("SEQ", "1", "SET", "k", "v"))
+	if response.Type != resp.Error || !strings.HasPrefix(response.Str, "SEQUENCE_STALE") {
+		t.Errorf("Expected SEQUENCE_STALE, got %v", response)
+	}
+
+	response = dispatch(server, client, newCommand("SEQ", "abc", "SET", "k", "v"))
+	if response.Type != resp.Error || !strings.HasPrefix(response.Str, "INVALID_SEQUENCE") {
+		t.Errorf("Expected INVALID_SEQUENCE, got %v", response)
+	}
+
+	// SIGNED 包装内也可以携带序列号，签名覆盖序列号
+	inner := [][]byte{[]byte("SEQ"), []byte("21"), []byte("GET"), []byte("k")}
+	args = append([]string{"SIGNED"}, signArgs(t, "secret", "n2", antireplay.CommandPayload(inner))...)
+	args = append(args, "SEQ", "21", "GET", "k")
+	response = dispatch(server, client, newCommand(args...))
+	if response.Type != resp.BulkString || string(response.Bulk) != "v" {
+		t.Errorf("Expected 'v', got %v", response)
+	}
+}
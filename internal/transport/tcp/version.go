@@ -0,0 +1,109 @@
+package tcp
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yndnr/tokenginx/internal/storage"
+	"github.com/yndnr/tokenginx/internal/transport/resp"
+)
+
+// handleGetVer 处理 GETVER 命令
+//
+// 格式：GETVER key
+// 返回：[值, 写入版本号]，键不存在时返回 Null Array；值不是字符串时返回 WRONGTYPE 错误
+func (h *CommandHandler) handleGetVer(c *Client, args [][]byte) *resp.Value {
+	value, version, exists := h.db(c).GetWithVersion(string(args[0]))
+	if !exists {
+		return &resp.Value{
+			Type: resp.Array,
+			Null: true,
+		}
+	}
+
+	reply := valueReply(value)
+	if reply.Type == resp.Error {
+		return reply
+	}
+
+	return &resp.Value{
+		Type: resp.Array,
+		Array: []resp.Value{
+			*reply,
+			{Type: resp.Integer, Int: int64(version)},
+		},
+	}
+}
+
+// handleCAS 处理 CAS 命令
+//
+// 格式：CAS key expected_version value [EX seconds | PX milliseconds | KEEPTTL]
+// 返回：写入成功时返回新的版本号；版本号不匹配时返回 Null
+//
+// 注意事项：
+//   - expected_version 为 0 表示仅当键不存在时写入
+//   - 与 SET 相同，不带过期选项时写入的键永不过期，需要保留原过期时间时使用 KEEPTTL
+func (h *CommandHandler) handleCAS(c *Client, args [][]byte) *resp.Value {
+	expected, err := strconv.ParseUint(string(args[1]), 10, 64)
+	if err != nil {
+		return &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR 版本号必须是非负整数",
+		}
+	}
+
+	opts, errReply := parseCASOptions(args[3:])
+	if errReply != nil {
+		return errReply
+	}
+
+	version, ok := h.db(c).CompareAndSet(string(args[0]), expected, args[2], opts)
+	if !ok {
+		return &resp.Value{
+			Type: resp.BulkString,
+			Null: true,
+		}
+	}
+
+	return &resp.Value{
+		Type: resp.Integer,
+		Int:  int64(version),
+	}
+}
+
+// parseCASOptions 解析 CAS 的过期选项（EX / PX / KEEPTTL，最多一个）
+func parseCASOptions(args [][]byte) (storage.SetOptions, *resp.Value) {
+	var opts storage.SetOptions
+	if len(args) == 0 {
+		return opts, nil
+	}
+
+	option := strings.ToUpper(string(args[0]))
+	switch {
+	case option == "KEEPTTL" && len(args) == 1:
+		opts.KeepTTL = true
+		return opts, nil
+
+	case (option == "EX" || option == "PX") && len(args) == 2:
+		n, err := strconv.ParseInt(string(args[1]), 10, 64)
+		if err != nil {
+			return opts, &resp.Value{
+				Type: resp.Error,
+				Str:  "ERR 参数必须是整数",
+			}
+		}
+
+		expiresAt, ok := absoluteExpiry(option, n, time.Now().UnixMilli())
+		if !ok {
+			return opts, &resp.Value{
+				Type: resp.Error,
+				Str:  "ERR CAS 命令的过期时间无效",
+			}
+		}
+		opts.ExpiresAt = expiresAt
+		return opts, nil
+	}
+
+	return opts, syntaxErrorReply()
+}
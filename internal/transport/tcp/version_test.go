@@ -0,0 +1,80 @@
+package tcp
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/yndnr/tokenginx/internal/storage"
+	"github.com/yndnr/tokenginx/internal/transport/resp"
+)
+
+// TestCommandHandler_GetVerCAS 测试 GETVER 和 CAS 命令
+func TestCommandHandler_GetVerCAS(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	handler := NewCommandHandler(sm)
+
+	response := handler.HandleCommand(newCommand("GETVER", "session:1"))
+	if response.Type != resp.Array || !response.Null {
+		t.Errorf("Expected Null Array for missing key, got %+v", response)
+	}
+
+	// 创建
+	response = handler.HandleCommand(newCommand("CAS", "session:1", "0", "v1", "EX", "100"))
+	if response.Type != resp.Integer || response.Int <= 0 {
+		t.Fatalf("Expected new version, got %+v", response)
+	}
+	created := response.Int
+
+	response = handler.HandleCommand(newCommand("GETVER", "session:1"))
+	if response.Type != resp.Array || len(response.Array) != 2 {
+		t.Fatalf("Expected [value, version], got %+v", response)
+	}
+	if string(response.Array[0].Bulk) != "v1" || response.Array[1].Int != created {
+		t.Errorf("Unexpected GETVER reply: %+v", response.Array)
+	}
+
+	// 版本号匹配，保留过期时间
+	response = handler.HandleCommand(newCommand("CAS", "session:1", strconv.FormatInt(created, 10), "v2", "KEEPTTL"))
+	if response.Type != resp.Integer || response.Int <= created {
+		t.Fatalf("Expected larger version, got %+v", response)
+	}
+	if ttl := storage.TTL(sm, "session:1"); ttl < 99 {
+		t.Errorf("Expected TTL to be kept, got %d", ttl)
+	}
+
+	// 旧版本号
+	response = handler.HandleCommand(newCommand("CAS", "session:1", strconv.FormatInt(created, 10), "v3"))
+	if response.Type != resp.BulkString || !response.Null {
+		t.Errorf("Expected Null for stale version, got %+v", response)
+	}
+	if value, _ := sm.Get("session:1"); string(value.([]byte)) != "v2" {
+		t.Errorf("Expected 'v2', got %v", value)
+	}
+
+	// 任何写入都会改变版本号
+	_, version, _ := sm.GetWithVersion("session:1")
+	handler.HandleCommand(newCommand("EXPIRE", "session:1", "50"))
+	response = handler.HandleCommand(newCommand("CAS", "session:1", strconv.FormatUint(version, 10), "v4"))
+	if !response.Null {
+		t.Errorf("Expected EXPIRE to change version, got %+v", response)
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"CAS", "k", "-1", "v"}, "ERR 版本号必须是非负整数"},
+		{[]string{"CAS", "k", "abc", "v"}, "ERR 版本号必须是非负整数"},
+		{[]string{"CAS", "k", "0", "v", "EX"}, "ERR 语法错误"},
+		{[]string{"CAS", "k", "0", "v", "EX", "abc"}, "ERR 参数必须是整数"},
+		{[]string{"CAS", "k", "0", "v", "EX", "0"}, "ERR CAS 命令的过期时间无效"},
+		{[]string{"CAS", "k", "0", "v", "NX"}, "ERR 语法错误"},
+		{[]string{"CAS", "k", "0", "v", "KEEPTTL", "EX", "1"}, "ERR 语法错误"},
+	}
+	for _, tt := range tests {
+		response := handler.HandleCommand(newCommand(tt.args...))
+		if response.Type != resp.Error || response.Str != tt.want {
+			t.Errorf("%v: expected %q, got %+v", tt.args, tt.want, response)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package tcp
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yndnr/tokenginx/internal/storage"
+	"github.com/yndnr/tokenginx/internal/transport/resp"
+)
+
+// webAuthnChallengeBytes 服务端生成的 challenge 长度（字节）
+//
+// WebAuthn 规范要求 challenge 至少 16 字节。
+const webAuthnChallengeBytes = 32
+
+// handleWebAuthnBegin 处理 WEBAUTHN.BEGIN 命令
+//
+// 格式：WEBAUTHN.BEGIN key registration|authentication rp_id user_handle seconds [CHALLENGE challenge]
+// 返回：challenge（base64url）；仪式 ID 已被使用时返回 Null
+//
+// 注意事项：
+//   - key 为不透明的仪式 ID，challenge 与仪式类型、依赖方 ID 和用户句柄绑定
+//   - 不指定 CHALLENGE 时由服务端生成 32 字节的随机 challenge
+//   - 可发现凭据的认证仪式不知道用户，user_handle 传空字符串
+func (h *CommandHandler) handleWebAuthnBegin(c *Client, args [][]byte) *resp.Value {
+	ceremony, errReply := parseCeremony(args[1])
+	if errReply != nil {
+		return errReply
+	}
+
+	ttl, err := strconv.Atoi(string(args[4]))
+	if err != nil {
+		return &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR 参数必须是整数",
+		}
+	}
+	if _, ok := absoluteExpiry("EX", int64(ttl), time.Now().UnixMilli()); !ok {
+		return &resp.Value{
+			Type: resp.Error,
+			Str:  "ERR challenge 有效期必须是正整数（秒）",
+		}
+	}
+
+	var challenge string
+	switch {
+	case len(args) == 7 && strings.ToUpper(string(args[5])) == "CHALLENGE":
+		challenge = string(args[6])
+		if challenge == "" {
+			return &resp.Value{
+				Type: resp.Error,
+				Str:  "ERR challenge 不能为空",
+			}
+		}
+	case len(args) == 5:
+		random := make([]byte, webAuthnChallengeBytes)
+		if _, err := rand.Read(random); err != nil {
+			return &resp.Value{
+				Type: resp.Error,
+				Str:  fmt.Sprintf("ERR 生成 challenge 失败: %v", err),
+			}
+		}
+		challenge = base64.RawURLEncoding.EncodeToString(random)
+	default:
+		return syntaxErrorReply()
+	}
+
+	stored := h.db(c).PutChallenge(string(args[0]), storage.WebAuthnChallenge{
+		Challenge:  challenge,
+		Ceremony:   ceremony,
+		RPID:       string(args[2]),
+		UserHandle: string(args[3]),
+	}, ttl)
+	if !stored {
+		return &resp.Value{
+			Type: resp.BulkString,
+			Null: true,
+		}
+	}
+
+	return &resp.Value{
+		Type: resp.BulkString,
+		Bulk: []byte(challenge),
+	}
+}
+
+// handleWebAuthnConsume 处理 WEBAUTHN.CONSUME 命令
+//
+// 格式：WEBAUTHN.CONSUME key registration|authentication rp_id
+// 返回：[challenge, user_handle]；不存在、已过期或已被消费时返回 Null Array
+//
+// 注意事项：
+//   - 取出即删除，同一个 challenge 只能被消费一次
+//   - 仪式类型或依赖方 ID 不符时返回错误，challenge 同样被删除
+func (h *CommandHandler) handleWebAuthnConsume(c *Client, args [][]byte) *resp.Value {
+	ceremony, errReply := parseCeremony(args[1])
+	if errReply != nil {
+		return errReply
+	}
+
+	challenge, found, err := h.db(c).ConsumeChallenge(string(args[0]), ceremony, string(args[2]))
+	if err != nil {
+		var msg string
+		switch {
+		case errors.Is(err, storage.ErrCeremonyMismatch):
+			msg = "ERR 仪式类型不匹配"
+		case errors.Is(err, storage.ErrRPIDMismatch):
+			msg = "ERR 依赖方 ID 不匹配"
+		case errors.Is(err, storage.ErrNotChallenge):
+			msg = "ERR 键保存的不是 WebAuthn challenge"
+		default:
+			msg = "ERR " + err.Error()
+		}
+		return &resp.Value{
+			Type: resp.Error,
+			Str:  msg,
+		}
+	}
+	if !found {
+		return &resp.Value{
+			Type: resp.Array,
+			Null: true,
+		}
+	}
+
+	return &resp.Value{
+		Type: resp.Array,
+		Array: []resp.Value{
+			{Type: resp.BulkString, Bulk: []byte(challenge.Challenge)},
+			{Type: resp.BulkString, Bulk: []byte(challenge.UserHandle)},
+		},
+	}
+}
+
+// parseCeremony 解析仪式类型（registration | authentication）
+func parseCeremony(arg []byte) (string, *resp.Value) {
+	switch ceremony := strings.ToLower(string(arg)); ceremony {
+	case storage.CeremonyRegistration, storage.CeremonyAuthentication:
+		return ceremony, nil
+	}
+
+	return "", &resp.Value{
+		Type: resp.Error,
+		Str:  "ERR 仪式类型必须是 registration 或 authentication",
+	}
+}
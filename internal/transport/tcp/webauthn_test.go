@@ -0,0 +1,87 @@
+package tcp
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/yndnr/tokenginx/internal/storage"
+	"github.com/yndnr/tokenginx/internal/transport/resp"
+)
+
+// TestCommandHandler_WebAuthn 测试 WEBAUTHN.BEGIN 和 WEBAUTHN.CONSUME 命令
+func TestCommandHandler_WebAuthn(t *testing.T) {
+	handler := NewCommandHandler(storage.NewShardedMap(1024))
+
+	response := handler.HandleCommand(newCommand("WEBAUTHN.BEGIN", "webauthn:ceremony:c1", "registration", "example.com", "dXNlcjAwMQ", "300"))
+	if response.Type != resp.BulkString || response.Null {
+		t.Fatalf("Expected generated challenge, got %+v", response)
+	}
+	challenge := string(response.Bulk)
+	if raw, err := base64.RawURLEncoding.DecodeString(challenge); err != nil || len(raw) != 32 {
+		t.Errorf("Expected 32 byte base64url challenge, got %q (%v)", challenge, err)
+	}
+
+	response = handler.HandleCommand(newCommand("WEBAUTHN.BEGIN", "webauthn:ceremony:c1", "registration", "example.com", "dXNlcjAwMQ", "300"))
+	if response.Type != resp.BulkString || !response.Null {
+		t.Errorf("Expected Null for a ceremony ID in use, got %+v", response)
+	}
+
+	response = handler.HandleCommand(newCommand("WEBAUTHN.CONSUME", "webauthn:ceremony:c1", "REGISTRATION", "example.com"))
+	if response.Type != resp.Array || len(response.Array) != 2 {
+		t.Fatalf("Expected [challenge, user_handle], got %+v", response)
+	}
+	if string(response.Array[0].Bulk) != challenge || string(response.Array[1].Bulk) != "dXNlcjAwMQ" {
+		t.Errorf("Unexpected reply: %+v", response.Array)
+	}
+
+	response = handler.HandleCommand(newCommand("WEBAUTHN.CONSUME", "webauthn:ceremony:c1", "registration", "example.com"))
+	if response.Type != resp.Array || !response.Null {
+		t.Errorf("Expected Null Array after consume, got %+v", response)
+	}
+
+	// 调用方提供 challenge，可发现凭据不带用户句柄
+	response = handler.HandleCommand(newCommand("WEBAUTHN.BEGIN", "webauthn:ceremony:c2", "authentication", "example.com", "", "300", "CHALLENGE", "abc123"))
+	if string(response.Bulk) != "abc123" {
+		t.Errorf("Expected provided challenge, got %+v", response)
+	}
+	response = handler.HandleCommand(newCommand("WEBAUTHN.CONSUME", "webauthn:ceremony:c2", "registration", "example.com"))
+	if response.Type != resp.Error || response.Str != "ERR 仪式类型不匹配" {
+		t.Errorf("Expected ceremony mismatch, got %+v", response)
+	}
+	response = handler.HandleCommand(newCommand("WEBAUTHN.CONSUME", "webauthn:ceremony:c2", "authentication", "example.com"))
+	if !response.Null {
+		t.Errorf("Expected mismatched consume to delete the challenge, got %+v", response)
+	}
+
+	handler.HandleCommand(newCommand("WEBAUTHN.BEGIN", "webauthn:ceremony:c3", "authentication", "example.com", "", "300"))
+	response = handler.HandleCommand(newCommand("WEBAUTHN.CONSUME", "webauthn:ceremony:c3", "authentication", "evil.example"))
+	if response.Type != resp.Error || response.Str != "ERR 依赖方 ID 不匹配" {
+		t.Errorf("Expected RP ID mismatch, got %+v", response)
+	}
+}
+
+// TestCommandHandler_WebAuthnErrors 测试 WebAuthn 命令的参数错误
+func TestCommandHandler_WebAuthnErrors(t *testing.T) {
+	sm := storage.NewShardedMap(1024)
+	handler := NewCommandHandler(sm)
+	sm.Set("session:1", "data", 0)
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"WEBAUTHN.BEGIN", "k", "login", "example.com", "u", "300"}, "ERR 仪式类型必须是 registration 或 authentication"},
+		{[]string{"WEBAUTHN.BEGIN", "k", "registration", "example.com", "u", "abc"}, "ERR 参数必须是整数"},
+		{[]string{"WEBAUTHN.BEGIN", "k", "registration", "example.com", "u", "0"}, "ERR challenge 有效期必须是正整数（秒）"},
+		{[]string{"WEBAUTHN.BEGIN", "k", "registration", "example.com", "u", "300", "CHALLENGE", ""}, "ERR challenge 不能为空"},
+		{[]string{"WEBAUTHN.BEGIN", "k", "registration", "example.com", "u", "300", "NONCE", "x"}, "ERR 语法错误"},
+		{[]string{"WEBAUTHN.CONSUME", "k", "enroll", "example.com"}, "ERR 仪式类型必须是 registration 或 authentication"},
+		{[]string{"WEBAUTHN.CONSUME", "session:1", "registration", "example.com"}, "ERR 键保存的不是 WebAuthn challenge"},
+	}
+	for _, tt := range tests {
+		response := handler.HandleCommand(newCommand(tt.args...))
+		if response.Type != resp.Error || response.Str != tt.want {
+			t.Errorf("%v: expected %q, got %+v", tt.args, tt.want, response)
+		}
+	}
+}
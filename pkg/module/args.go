@@ -0,0 +1,131 @@
+package module
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrNotInteger 参数不是合法的整数
+	ErrNotInteger = errors.New("value is not an integer")
+
+	// ErrSyntax 选项语法错误（未知选项或缺少选项参数）
+	ErrSyntax = errors.New("syntax error")
+)
+
+// Args 命令参数（不包括命令名）
+//
+// 示例：
+//
+//	// TENANT.SET tenant key value [EX seconds] [NX]
+//	tenant, key, value := args.String(0), args.String(1), args.Bytes(2)
+//	opts, err := args.Options(3, map[string]int{"EX": 1, "NX": 0})
+//	if err != nil {
+//	    return module.ErrorFromErr(err)
+//	}
+//	ttl := 0
+//	if opts.Has("EX") {
+//	    seconds, err := opts.Int("EX")
+//	    ...
+//	}
+type Args [][]byte
+
+// Len 返回参数个数
+func (a Args) Len() int {
+	return len(a)
+}
+
+// Bytes 返回第 i 个参数，越界时返回 nil
+func (a Args) Bytes(i int) []byte {
+	if i < 0 || i >= len(a) {
+		return nil
+	}
+	return a[i]
+}
+
+// String 返回第 i 个参数的字符串形式，越界时返回空字符串
+func (a Args) String(i int) string {
+	return string(a.Bytes(i))
+}
+
+// Int 将第 i 个参数解析为 64 位整数
+//
+// 返回值：
+//   - int64: 解析结果
+//   - error: 参数越界或不是合法整数时返回 ErrNotInteger
+func (a Args) Int(i int) (int64, error) {
+	if i < 0 || i >= len(a) {
+		return 0, ErrNotInteger
+	}
+	return parseInt(a[i])
+}
+
+// Options 解析从第 from 个参数开始的关键字选项
+//
+// 参数说明：
+//   - from: 第一个选项的位置
+//   - spec: 选项名（大写）→ 该选项需要的参数个数，0 表示开关选项
+//
+// 返回值：
+//   - Options: 出现的选项及其参数，选项名不区分大小写
+//   - error: 未知选项或选项参数不足时返回包装了 ErrSyntax 的错误
+//
+// 注意事项：
+//   - 同一选项出现多次时以最后一次为准
+func (a Args) Options(from int, spec map[string]int) (Options, error) {
+	opts := make(Options)
+
+	for i := from; i < len(a); i++ {
+		name := strings.ToUpper(string(a[i]))
+		n, known := spec[name]
+		if !known {
+			return nil, fmt.Errorf("%w: unknown option %s", ErrSyntax, name)
+		}
+		if i+n >= len(a) {
+			return nil, fmt.Errorf("%w: option %s requires %d argument(s)", ErrSyntax, name, n)
+		}
+
+		opts[name] = a[i+1 : i+1+n]
+		i += n
+	}
+
+	return opts, nil
+}
+
+// Options 由 Args.Options 解析出的关键字选项
+type Options map[string][][]byte
+
+// Has 检查选项是否出现
+func (o Options) Has(name string) bool {
+	_, exists := o[strings.ToUpper(name)]
+	return exists
+}
+
+// String 返回选项的第一个参数，选项不存在或没有参数时返回空字符串
+func (o Options) String(name string) string {
+	values := o[strings.ToUpper(name)]
+	if len(values) == 0 {
+		return ""
+	}
+	return string(values[0])
+}
+
+// Int 将选项的第一个参数解析为 64 位整数
+func (o Options) Int(name string) (int64, error) {
+	values := o[strings.ToUpper(name)]
+	if len(values) == 0 {
+		return 0, ErrNotInteger
+	}
+	return parseInt(values[0])
+}
+
+// parseInt 解析十进制整数
+func parseInt(b []byte) (int64, error) {
+	n, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil {
+		return 0, ErrNotInteger
+	}
+	return n, nil
+}
@@ -0,0 +1,70 @@
+package module
+
+import (
+	"errors"
+	"testing"
+)
+
+// newArgs 由字符串构建参数列表
+func newArgs(args ...string) Args {
+	result := make(Args, len(args))
+	for i, arg := range args {
+		result[i] = []byte(arg)
+	}
+	return result
+}
+
+// TestArgs 测试按位置读取参数
+func TestArgs(t *testing.T) {
+	args := newArgs("acme", "42", "x")
+
+	if args.Len() != 3 || args.String(0) != "acme" || string(args.Bytes(2)) != "x" {
+		t.Errorf("Unexpected args: %q", args)
+	}
+	if args.String(5) != "" || args.Bytes(-1) != nil {
+		t.Error("Expected empty values for out-of-range index")
+	}
+
+	if n, err := args.Int(1); err != nil || n != 42 {
+		t.Errorf("Int(1) = %d, %v; want 42, nil", n, err)
+	}
+	if _, err := args.Int(2); !errors.Is(err, ErrNotInteger) {
+		t.Errorf("Int(2): expected ErrNotInteger, got %v", err)
+	}
+	if _, err := args.Int(3); !errors.Is(err, ErrNotInteger) {
+		t.Errorf("Int(3): expected ErrNotInteger, got %v", err)
+	}
+}
+
+// TestArgs_Options 测试关键字选项解析
+func TestArgs_Options(t *testing.T) {
+	spec := map[string]int{"EX": 1, "NX": 0, "RANGE": 2}
+
+	opts, err := newArgs("key", "ex", "60", "NX", "range", "1", "5").Options(1, spec)
+	if err != nil {
+		t.Fatalf("Options failed: %v", err)
+	}
+	if !opts.Has("nx") || opts.Has("XX") {
+		t.Errorf("Unexpected switches: %v", opts)
+	}
+	if n, err := opts.Int("EX"); err != nil || n != 60 {
+		t.Errorf("EX = %d, %v; want 60, nil", n, err)
+	}
+	if len(opts["RANGE"]) != 2 || opts.String("RANGE") != "1" {
+		t.Errorf("Unexpected RANGE values: %q", opts["RANGE"])
+	}
+	if _, err := opts.Int("NX"); !errors.Is(err, ErrNotInteger) {
+		t.Errorf("Int(NX): expected ErrNotInteger, got %v", err)
+	}
+
+	invalid := []Args{
+		newArgs("key", "PX", "1"),
+		newArgs("key", "EX"),
+		newArgs("key", "RANGE", "1"),
+	}
+	for _, args := range invalid {
+		if _, err := args.Options(1, spec); !errors.Is(err, ErrSyntax) {
+			t.Errorf("Options(%q): expected ErrSyntax, got %v", args, err)
+		}
+	}
+}
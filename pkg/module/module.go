@@ -0,0 +1,188 @@
+// Package module 是 TokenginX 的命令扩展接口
+//
+// 嵌入 TokenginX 的 Go 程序可以通过模块注册自定义命令（例如按租户读取会话），
+// 而无需修改 internal/transport/tcp。模块的命令与内置命令使用同一张命令表，
+// 因此同样支持参数个数校验、COMMAND 查询和按标志做访问控制。
+//
+// 示例：
+//
+//	type tenantModule struct{}
+//
+//	func (tenantModule) Name() string { return "tenant" }
+//
+//	func (tenantModule) Commands() []module.Command {
+//	    return []module.Command{{
+//	        Name:     "tenant.get",
+//	        Arity:    3,
+//	        Flags:    module.Readonly | module.Fast,
+//	        FirstKey: 2, LastKey: 2, Step: 1,
+//	        Summary:  "读取租户的会话",
+//	        Handler: func(ctx *module.Context, args module.Args) *module.Value {
+//	            value, ok := ctx.Storage.Get(args.String(0) + ":" + args.String(1))
+//	            if !ok {
+//	                return module.Null()
+//	            }
+//	            return module.BulkString(fmt.Sprint(value))
+//	        },
+//	    }}
+//	}
+//
+//	func init() {
+//	    module.Register(tenantModule{})
+//	}
+package module
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Flag 命令标志，可按位组合
+type Flag uint32
+
+const (
+	// Write 命令会修改数据
+	Write Flag = 1 << iota
+
+	// Readonly 命令只读取数据
+	Readonly
+
+	// Admin 管理命令，ACL 应只授予管理员
+	Admin
+
+	// Fast 命令的时间复杂度为 O(1) 或 O(log N)
+	Fast
+)
+
+// Storage 是模块可以访问的存储引擎接口
+//
+// 键的哈希存储（security.key_hashing）等配置对模块同样透明生效。
+// 命令执行时的 Storage 对应发送命令的连接当前选择的逻辑数据库（SELECT）。
+type Storage interface {
+	// Get 获取键的值
+	Get(key string) (interface{}, bool)
+
+	// Set 设置键的值，ttl 为过期时间（秒），0 表示永不过期
+	Set(key string, value interface{}, ttl int) error
+
+	// SetNX 仅当键不存在时设置键的值
+	SetNX(key string, value interface{}, ttl int) bool
+
+	// Delete 删除键
+	Delete(key string) bool
+
+	// Exists 检查键是否存在
+	Exists(key string) bool
+
+	// TTL 返回剩余生存时间（秒），-1 表示键不存在，-2 表示永不过期
+	TTL(key string) int64
+
+	// Expire 更新键的过期时间（秒），0 表示永不过期
+	Expire(key string, ttl int) bool
+
+	// GetWithVersion 获取键的值和写入版本号，每次修改键都会得到更大的版本号
+	GetWithVersion(key string) (interface{}, uint64, bool)
+
+	// CompareAndSet 仅当键的写入版本号等于 expected 时写入（0 表示仅当键不存在时），
+	// ttl 为过期时间（秒），0 表示永不过期；返回新版本号（失败时为当前版本号）和是否写入
+	CompareAndSet(key string, expected uint64, value interface{}, ttl int) (uint64, bool)
+}
+
+// ClientInfo 发送命令的客户端连接信息（只读副本）
+type ClientInfo struct {
+	ID       int64  // 连接 ID
+	Addr     string // 客户端地址
+	Identity string // 通过签名认证的客户端 ID，空表示未认证
+	Name     string // 客户端名称（HELLO ... SETNAME）
+	Protocol int    // 协商的 RESP 协议版本（2 或 3）
+	DB       int    // 当前选择的逻辑数据库（SELECT）
+}
+
+// Context 命令执行上下文
+type Context struct {
+	Client  ClientInfo // 客户端连接信息
+	Storage Storage    // 存储引擎
+}
+
+// HandlerFunc 模块命令的处理函数
+//
+// 参数说明：
+//   - ctx: 执行上下文
+//   - args: 命令参数（不包括命令名），参数个数已按 Arity 校验
+//
+// 注意事项：
+//   - 处理函数中的 panic 会被恢复并返回错误，不会导致服务器退出
+type HandlerFunc func(ctx *Context, args Args) *Value
+
+// Command 模块提供的一个命令
+//
+// 字段含义与 COMMAND INFO 一致：Arity 包括命令名，负数表示至少 -Arity 个参数；
+// 键位置从命令名之后的第一个参数开始计为 1，LastKey 为 -1 表示直到最后一个参数。
+type Command struct {
+	Name     string      // 命令名，建议使用 "模块名.命令" 的形式避免冲突
+	Arity    int         // 参数个数（包括命令名）
+	Flags    Flag        // 命令标志
+	FirstKey int         // 第一个键的位置，0 表示没有键
+	LastKey  int         // 最后一个键的位置
+	Step     int         // 相邻两个键之间的间隔
+	Since    string      // 引入该命令的版本
+	Summary  string      // 命令说明
+	Handler  HandlerFunc // 处理函数
+}
+
+// Module 命令扩展模块
+type Module interface {
+	// Name 返回模块名，在 COMMAND DOCS 中作为命令分组，在 HELLO 的 modules 中列出
+	Name() string
+
+	// Commands 返回模块提供的命令
+	Commands() []Command
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Module)
+)
+
+// Register 将模块注册到全局模块表
+//
+// 服务器启动时会加载全局模块表中的全部模块。通常在模块包的 init 函数中调用，
+// 嵌入方只需以空白导入的方式引入模块包。
+//
+// 注意事项：
+//   - 模块为 nil、模块名为空或重复注册时 panic（与 database/sql.Register 一致）
+func Register(m Module) {
+	if m == nil {
+		panic("module: Register module is nil")
+	}
+
+	name := m.Name()
+	if name == "" {
+		panic("module: Register module name is empty")
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, exists := registry[name]; exists {
+		panic(fmt.Sprintf("module: Register called twice for module %s", name))
+	}
+	registry[name] = m
+}
+
+// Modules 返回全局模块表中的全部模块，按模块名排序
+func Modules() []Module {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	modules := make([]Module, 0, len(registry))
+	for _, m := range registry {
+		modules = append(modules, m)
+	}
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].Name() < modules[j].Name()
+	})
+
+	return modules
+}
@@ -0,0 +1,75 @@
+package module
+
+import (
+	"testing"
+)
+
+// namedModule 只有名称、没有命令的测试模块
+type namedModule string
+
+func (m namedModule) Name() string        { return string(m) }
+func (m namedModule) Commands() []Command { return nil }
+
+// TestRegister 测试全局模块注册
+func TestRegister(t *testing.T) {
+	Register(namedModule("zeta"))
+	Register(namedModule("alpha"))
+
+	modules := Modules()
+	if len(modules) < 2 {
+		t.Fatalf("Expected at least 2 modules, got %d", len(modules))
+	}
+	for i := 1; i < len(modules); i++ {
+		if modules[i-1].Name() > modules[i].Name() {
+			t.Errorf("Expected modules sorted by name, got %s before %s", modules[i-1].Name(), modules[i].Name())
+		}
+	}
+
+	for _, m := range []Module{nil, namedModule(""), namedModule("alpha")} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Register(%v): expected panic", m)
+				}
+			}()
+			Register(m)
+		}()
+	}
+}
+
+// TestReplies 测试响应构建函数
+func TestReplies(t *testing.T) {
+	if v := OK(); v.Kind != KindSimpleString || v.Str != "OK" {
+		t.Errorf("Unexpected OK: %+v", v)
+	}
+	if v := Error("ERR %s", "bad"); v.Kind != KindError || v.Str != "ERR bad" {
+		t.Errorf("Unexpected Error: %+v", v)
+	}
+	if v := ErrorFromErr(ErrNotInteger); v.Kind != KindError {
+		t.Errorf("Unexpected ErrorFromErr: %+v", v)
+	}
+	if v := Null(); v.Kind != KindNull {
+		t.Errorf("Expected Null, got %+v", v)
+	}
+	if v := (Value{}); v.Kind != KindNull {
+		t.Errorf("Expected zero Value to be Null, got %+v", v)
+	}
+	if v := Double(1.5); v.Kind != KindDouble || v.Double != 1.5 {
+		t.Errorf("Unexpected Double: %+v", v)
+	}
+	if v := Boolean(true); v.Kind != KindBoolean || !v.Bool {
+		t.Errorf("Unexpected Boolean: %+v", v)
+	}
+
+	array := Array(BulkString("a"), Integer(1))
+	if array.Kind != KindArray || len(array.Elems) != 2 || array.Elems[1].Int != 1 {
+		t.Errorf("Unexpected Array: %+v", array)
+	}
+
+	if m := Map(BulkString("k"), Bulk([]byte("v"))); m.Kind != KindMap || len(m.Elems) != 2 {
+		t.Errorf("Unexpected Map: %+v", m)
+	}
+	if m := Map(BulkString("k")); m.Kind != KindError {
+		t.Errorf("Expected error for odd Map pairs, got %+v", m)
+	}
+}
@@ -0,0 +1,130 @@
+package module
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kind 响应类型
+type Kind int
+
+const (
+	// KindNull Null（RESP2 连接为 $-1，RESP3 连接为 _），也是 Value 零值的类型
+	KindNull Kind = iota
+
+	// KindSimpleString Simple String，值为 Str
+	KindSimpleString
+
+	// KindError 错误，值为 Str
+	KindError
+
+	// KindInteger Integer，值为 Int
+	KindInteger
+
+	// KindBulkString Bulk String，值为 Bulk
+	KindBulkString
+
+	// KindDouble Double（RESP2 连接降级为 Bulk String），值为 Double
+	KindDouble
+
+	// KindBoolean Boolean（RESP2 连接降级为 Integer 1 或 0），值为 Bool
+	KindBoolean
+
+	// KindArray Array，元素为 Elems
+	KindArray
+
+	// KindMap Map（RESP2 连接降级为扁平 Array），元素为按 键, 值 ... 顺序排列的 Elems
+	KindMap
+)
+
+// Value 是命令的响应，通常使用本包的 OK、BulkString、Error 等函数构建
+//
+// 服务器在模块命令返回后把 Value 转换为 RESP 响应，模块不依赖内部的协议实现。
+// 只有 Kind 对应的字段有意义，Elems 中的 nil 元素按 Null 处理。
+type Value struct {
+	Kind   Kind     // 响应类型
+	Str    string   // Simple String 或错误的值
+	Int    int64    // Integer 的值
+	Bulk   []byte   // Bulk String 的值（二进制安全）
+	Double float64  // Double 的值
+	Bool   bool     // Boolean 的值
+	Elems  []*Value // Array 或 Map 的元素
+}
+
+// OK 返回 +OK
+func OK() *Value {
+	return &Value{Kind: KindSimpleString, Str: "OK"}
+}
+
+// SimpleString 返回 Simple String
+func SimpleString(s string) *Value {
+	return &Value{Kind: KindSimpleString, Str: s}
+}
+
+// Error 返回错误响应
+//
+// 按 Redis 的约定，消息应以大写的错误码开头，例如 "ERR 租户不存在" 或 "NOTFOUND 会话不存在"。
+func Error(format string, a ...interface{}) *Value {
+	return &Value{Kind: KindError, Str: fmt.Sprintf(format, a...)}
+}
+
+// ErrorFromErr 将 Args 的解析错误转换为错误响应
+//
+// ErrNotInteger 和 ErrSyntax 转换为 "ERR ..."，其他错误原样附在 "ERR " 之后。
+func ErrorFromErr(err error) *Value {
+	switch {
+	case errors.Is(err, ErrNotInteger):
+		return Error("ERR 参数必须是整数")
+	case errors.Is(err, ErrSyntax):
+		return Error("ERR 语法错误: %v", err)
+	default:
+		return Error("ERR %v", err)
+	}
+}
+
+// Integer 返回 Integer
+func Integer(n int64) *Value {
+	return &Value{Kind: KindInteger, Int: n}
+}
+
+// Bulk 返回 Bulk String
+func Bulk(b []byte) *Value {
+	return &Value{Kind: KindBulkString, Bulk: b}
+}
+
+// BulkString 返回 Bulk String
+func BulkString(s string) *Value {
+	return &Value{Kind: KindBulkString, Bulk: []byte(s)}
+}
+
+// Double 返回 Double（RESP2 连接降级为 Bulk String）
+func Double(f float64) *Value {
+	return &Value{Kind: KindDouble, Double: f}
+}
+
+// Boolean 返回 Boolean（RESP2 连接降级为 Integer 1 或 0）
+func Boolean(b bool) *Value {
+	return &Value{Kind: KindBoolean, Bool: b}
+}
+
+// Null 返回 Null（RESP2 连接为 $-1，RESP3 连接为 _）
+func Null() *Value {
+	return &Value{Kind: KindNull}
+}
+
+// Array 返回由 values 组成的 Array
+func Array(values ...*Value) *Value {
+	return &Value{Kind: KindArray, Elems: values}
+}
+
+// Map 返回 Map（RESP2 连接降级为扁平 Array）
+//
+// 参数说明：
+//   - pairs: 按 键, 值, 键, 值 ... 顺序排列的键值对，长度必须是偶数
+func Map(pairs ...*Value) *Value {
+	if len(pairs)%2 != 0 {
+		return Error("ERR Map 需要偶数个元素")
+	}
+
+	return &Value{Kind: KindMap, Elems: pairs}
+}
@@ -0,0 +1,142 @@
+// Package server 用于在 Go 程序中嵌入 TokenginX 服务器
+//
+// 嵌入方通过 module.Register 注册自定义命令模块，再启动服务器；
+// 服务器启动时会加载全部已注册的模块，与 tokenginx 命令行程序使用同一机制。
+//
+// 示例：
+//
+//	import (
+//	    "github.com/yndnr/tokenginx/pkg/server"
+//	    _ "example.com/tenant" // 在 init 中调用 module.Register
+//	)
+//
+//	srv, err := server.New(&server.Config{Addr: ":6380"})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	if err := srv.Start(); err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer srv.Stop()
+package server
+
+import (
+	"time"
+
+	"github.com/yndnr/tokenginx/internal/storage"
+	"github.com/yndnr/tokenginx/internal/transport/tcp"
+	"github.com/yndnr/tokenginx/pkg/module"
+)
+
+// Config 嵌入式服务器配置
+type Config struct {
+	// Addr 监听地址，如 ":6380"
+	Addr string
+
+	// InitialCapacity 存储引擎的初始容量，0 使用默认值
+	InitialCapacity int
+
+	// Databases 逻辑数据库数量，0 使用默认值（16）
+	Databases int
+
+	// CleanupInterval 过期键的清理间隔，0 使用默认值
+	CleanupInterval time.Duration
+
+	// KeysPerScan 每次清理扫描的键数量，0 使用默认值
+	KeysPerScan int
+
+	// NotifyKeyspaceEvents 键空间事件通知，格式与 Redis 的 notify-keyspace-events 相同（如 "Ex"），
+	// 空字符串表示不通知；其中的事件类别同时决定 OnKeyEvent 收到哪些事件
+	NotifyKeyspaceEvents string
+
+	// OnKeyEvent 键空间事件回调，nil 表示不回调
+	//
+	// 回调在独立的 Goroutine 中按事件发生的顺序调用，可以访问本服务器的数据。
+	// 事件先进入长度为 4096 的有界队列，回调处理过慢导致队列已满时丢弃新事件并记录警告，
+	// 发送审计事件、SAML LogoutRequest 等耗时操作仍应转交其他 Goroutine。
+	OnKeyEvent func(KeyEvent)
+}
+
+// KeyEvent 键空间事件
+type KeyEvent struct {
+	DB   int    // 逻辑数据库编号
+	Type string // 事件名："set"、"del"、"expire"、"expired"、"evicted"
+	Key  string // 键（启用键哈希时为哈希后的键）
+}
+
+// Server 嵌入式 TokenginX 服务器
+type Server struct {
+	dbs *storage.Databases
+	ttl *storage.TTLManager
+	tcp *tcp.Server
+}
+
+// New 创建嵌入式服务器，并加载通过 module.Register 注册的全部模块
+//
+// 参数说明：
+//   - cfg: 服务器配置
+//
+// 返回值：
+//   - *Server: 服务器实例（尚未启动）
+//   - error: 键空间事件配置无效或模块加载失败时的错误信息
+func New(cfg *Config) (*Server, error) {
+	events, err := tcp.ParseKeyspaceEvents(cfg.NotifyKeyspaceEvents)
+	if err != nil {
+		return nil, err
+	}
+
+	dbs := storage.NewDatabases(storage.NewShardedMap(cfg.InitialCapacity), cfg.Databases)
+
+	ttlConfig := storage.DefaultTTLManagerConfig()
+	if cfg.CleanupInterval > 0 {
+		ttlConfig.CleanupInterval = cfg.CleanupInterval
+	}
+	if cfg.KeysPerScan > 0 {
+		ttlConfig.KeysPerScan = cfg.KeysPerScan
+	}
+
+	s := &Server{
+		dbs: dbs,
+		ttl: storage.NewDatabasesTTLManager(dbs, ttlConfig),
+		tcp: tcp.NewServer(cfg.Addr, dbs.DB(0)),
+	}
+	s.tcp.SetDatabases(dbs)
+	if cfg.OnKeyEvent != nil {
+		onKeyEvent := cfg.OnKeyEvent
+		s.tcp.SetKeyspaceEvents(events, func(db int, event storage.KeyEvent) {
+			onKeyEvent(KeyEvent{DB: db, Type: event.Type.String(), Key: event.Key})
+		})
+	} else {
+		s.tcp.SetKeyspaceEvents(events, nil)
+	}
+
+	if _, err := s.tcp.LoadModules(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// LoadModule 加载一个未通过 module.Register 注册的模块
+//
+// 注意事项：
+//   - 应在 Start() 之前调用
+func (s *Server) LoadModule(m module.Module) error {
+	return s.tcp.LoadModule(m)
+}
+
+// Start 启动过期键清理和 TCP 服务器（非阻塞）
+func (s *Server) Start() error {
+	s.ttl.Start()
+	if err := s.tcp.Start(); err != nil {
+		s.ttl.Stop()
+		return err
+	}
+	return nil
+}
+
+// Stop 停止服务器
+func (s *Server) Stop() {
+	s.tcp.Stop()
+	s.ttl.Stop()
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/yndnr/tokenginx/pkg/module"
+)
+
+// echoModule 提供 EMBED.ECHO 命令的测试模块
+type echoModule struct{}
+
+func (echoModule) Name() string { return "embed" }
+
+func (echoModule) Commands() []module.Command {
+	return []module.Command{{
+		Name:  "embed.echo",
+		Arity: 2,
+		Flags: module.Readonly | module.Fast,
+		Handler: func(ctx *module.Context, args module.Args) *module.Value {
+			return module.Bulk(args.Bytes(0))
+		},
+	}}
+}
+
+// TestServer_Embedded 测试嵌入式服务器加载模块并通过 go-redis 调用
+func TestServer_Embedded(t *testing.T) {
+	srv, err := New(&Config{Addr: "127.0.0.1:16393"})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := srv.LoadModule(echoModule{}); err != nil {
+		t.Fatalf("LoadModule failed: %v", err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer srv.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:16393"})
+	defer client.Close()
+
+	ctx := context.Background()
+	result, err := client.Do(ctx, "EMBED.ECHO", "hello").Text()
+	if err != nil || result != "hello" {
+		t.Errorf("EMBED.ECHO = %q, %v; want hello, nil", result, err)
+	}
+
+	if err := client.Set(ctx, "session:1", "alice", 0).Err(); err != nil {
+		t.Errorf("SET failed: %v", err)
+	}
+}
+
+// TestServer_KeyEvents 测试过期事件的 Go 回调和 __keyevent@0__:expired 通知
+func TestServer_KeyEvents(t *testing.T) {
+	events := make(chan KeyEvent, 16)
+	srv, err := New(&Config{
+		Addr:                 "127.0.0.1:16401",
+		CleanupInterval:      50 * time.Millisecond,
+		NotifyKeyspaceEvents: "Ex",
+		OnKeyEvent: func(event KeyEvent) {
+			events <- event
+		},
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer srv.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:16401"})
+	defer client.Close()
+
+	ctx := context.Background()
+	pubsub := client.Subscribe(ctx, "__keyevent@0__:expired")
+	defer pubsub.Close()
+	if _, err := pubsub.Receive(ctx); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	// 定期清理删除过期的会话，不需要再访问该键
+	client.Set(ctx, "session:1", "alice", 100*time.Millisecond)
+
+	select {
+	case event := <-events:
+		if event.DB != 0 || event.Type != "expired" || event.Key != "session:1" {
+			t.Errorf("Expected expired event for session:1, got %+v", event)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Expected expired callback")
+	}
+
+	msgCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	msg, err := pubsub.ReceiveMessage(msgCtx)
+	if err != nil || msg.Channel != "__keyevent@0__:expired" || msg.Payload != "session:1" {
+		t.Errorf("Expected expired notification, got %+v, %v", msg, err)
+	}
+}
+
+// TestNew_InvalidKeyspaceEvents 测试无效的键空间事件配置
+func TestNew_InvalidKeyspaceEvents(t *testing.T) {
+	if _, err := New(&Config{Addr: "127.0.0.1:0", NotifyKeyspaceEvents: "Ez"}); err == nil {
+		t.Error("Expected error for unknown keyspace event class")
+	}
+}